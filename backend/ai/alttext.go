@@ -0,0 +1,67 @@
+package ai
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxAltTextLength bounds generated alt text, in characters; screen
+// readers handle a few sentences well, but not paragraphs
+const MaxAltTextLength = 500
+
+// altTextTokens is the completion budget for alt text
+const altTextTokens = 200
+
+const (
+	AltTextSystemPrompt = "You write alt text for photographs so that people using screen readers know what they show. Describe what is visible factually and specifically: the subject, setting, colors, light, and any legible text. Do not interpret, praise, or guess at what isn't shown. You MUST respond with only the alt text, nothing else."
+	AltTextUserPrompt   = "Write alt text for this photograph in one to three sentences. Don't start with \"Image of\" or \"Photo of\". You MUST provide _only_ the alt text as your response."
+)
+
+// AltTextGenerator is implemented by clients that can describe a photo for
+// accessibility
+type AltTextGenerator interface {
+	GenerateAltText(ctx context.Context, imageURL string, opts AltTextOptions) (string, error)
+}
+
+// AltTextOptions adjusts alt text generation. The zero value requests alt
+// text in the model's default language.
+type AltTextOptions struct {
+	// Language is the language the alt text should be written in
+	Language string
+}
+
+// Prompt returns the user prompt requesting alt text
+func (o AltTextOptions) Prompt() string {
+	prompt := AltTextUserPrompt
+	if o.Language != "" {
+		prompt += " Write it in " + o.Language + "."
+	}
+	return prompt
+}
+
+// altTextLabelPattern matches labels models sometimes put before the alt
+// text despite being asked not to
+var altTextLabelPattern = regexp.MustCompile(`(?i)^(?:\*\*)?alt(?:ernative)?[ -]text(?:\*\*)?\s*:\s*(?:\*\*)?\s*`)
+
+// CleanAltText tidies a model's alt text: a leading label, quotes and
+// runs of whitespace are removed, and text longer than MaxAltTextLength is
+// cut at the last sentence (or word) that fits.
+func CleanAltText(response string) string {
+	text := strings.TrimSpace(stripReasoning(response))
+	text = altTextLabelPattern.ReplaceAllString(text, "")
+	text = strings.Join(strings.Fields(cleanTitle(text)), " ")
+	if utf8.RuneCountInString(text) <= MaxAltTextLength {
+		return text
+	}
+
+	cut := string([]rune(text)[:MaxAltTextLength])
+	if i := strings.LastIndex(cut, ". "); i > 0 {
+		return cut[:i+1]
+	}
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		return cut[:i]
+	}
+	return cut
+}
@@ -0,0 +1,309 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+)
+
+const (
+	BackendBedrock      = "bedrock"
+	DefaultBedrockModel = "amazon.nova-lite-v1:0"
+	// bedrockService is the service name Bedrock requests are signed for
+	bedrockService = "bedrock"
+)
+
+// BedrockClient generates titles with a vision model on AWS Bedrock, e.g.
+// Claude or Nova, through the Converse API. Requests are signed with static
+// or temporary credentials from the config or the standard AWS environment
+// variables; shared credentials files and instance roles aren't read.
+// Alternatively a Bedrock API key may be used.
+type BedrockClient struct {
+	endpoint string
+	region   string
+	creds    awsCredentials
+	apiKey   string
+	model    string
+	client   *http.Client
+
+	maxTokens  int
+	titleWords int
+}
+
+// BedrockAuth holds the credentials for a BedrockClient: either AWS access
+// keys, or a Bedrock API key
+type BedrockAuth struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	APIKey          string
+}
+
+type bedrockRequest struct {
+	System          []bedrockContentBlock  `json:"system,omitempty"`
+	Messages        []bedrockMessage       `json:"messages"`
+	InferenceConfig bedrockInferenceConfig `json:"inferenceConfig"`
+}
+
+type bedrockInferenceConfig struct {
+	MaxTokens int `json:"maxTokens"`
+}
+
+type bedrockMessage struct {
+	Role    string                `json:"role"`
+	Content []bedrockContentBlock `json:"content"`
+}
+
+type bedrockContentBlock struct {
+	Text  string        `json:"text,omitempty"`
+	Image *bedrockImage `json:"image,omitempty"`
+}
+
+type bedrockImage struct {
+	// Format is jpeg, png, gif or webp
+	Format string `json:"format"`
+	Source struct {
+		// Bytes is base64 encoded by encoding/json
+		Bytes []byte `json:"bytes"`
+	} `json:"source"`
+}
+
+type bedrockResponse struct {
+	Output struct {
+		Message struct {
+			Content []struct {
+				Text string `json:"text"`
+			} `json:"content"`
+		} `json:"message"`
+	} `json:"output"`
+	Usage *struct {
+		InputTokens  int `json:"inputTokens"`
+		OutputTokens int `json:"outputTokens"`
+	} `json:"usage,omitempty"`
+	// Message is set on errors
+	Message string `json:"message"`
+}
+
+func init() {
+	Register(BackendBedrock, func(settings Settings) (Client, error) {
+		region := settings.Get("region", firstEnv("AWS_REGION", "AWS_DEFAULT_REGION"))
+		auth := BedrockAuth{
+			AccessKeyID:     settings.Get("access_key_id", os.Getenv("AWS_ACCESS_KEY_ID")),
+			SecretAccessKey: settings.Get("secret_access_key", os.Getenv("AWS_SECRET_ACCESS_KEY")),
+			SessionToken:    settings.Get("session_token", os.Getenv("AWS_SESSION_TOKEN")),
+			APIKey:          settings.Get("api_key", os.Getenv("AWS_BEARER_TOKEN_BEDROCK")),
+		}
+		client, err := NewBedrockClient(settings["url"], region, auth, settings.Get("model", DefaultBedrockModel))
+		if err != nil {
+			return nil, err
+		}
+		if client.maxTokens, err = settings.Int("max_tokens", DefaultMaxTokens); err != nil {
+			return nil, err
+		}
+		if client.titleWords, err = settings.Int("title_words", 0); err != nil {
+			return nil, err
+		}
+		return client, nil
+	})
+}
+
+// firstEnv returns the value of the first of the environment variables that
+// is set
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// NewBedrockClient creates a client for Bedrock in region. endpoint
+// overrides the regional Bedrock runtime endpoint, e.g. for a VPC endpoint.
+func NewBedrockClient(endpoint, region string, auth BedrockAuth, model string) (*BedrockClient, error) {
+	if region == "" {
+		return nil, fmt.Errorf("AWS region is required")
+	}
+	if auth.APIKey == "" && (auth.AccessKeyID == "" || auth.SecretAccessKey == "") {
+		return nil, fmt.Errorf("AWS access key ID and secret access key, or a Bedrock API key, are required")
+	}
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", region)
+	}
+	if model == "" {
+		model = DefaultBedrockModel
+	}
+
+	log.Printf("Bedrock client configured with endpoint: %s, Model: %s", endpoint, model)
+
+	return &BedrockClient{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		region:   region,
+		creds: awsCredentials{
+			AccessKeyID:     auth.AccessKeyID,
+			SecretAccessKey: auth.SecretAccessKey,
+			SessionToken:    auth.SessionToken,
+		},
+		apiKey:    auth.APIKey,
+		model:     model,
+		client:    &http.Client{Timeout: constants.OllamaClientTimeout, Transport: Transport},
+		maxTokens: DefaultMaxTokens,
+	}, nil
+}
+
+var (
+	_ TagGenerator     = (*BedrockClient)(nil)
+	_ AltTextGenerator = (*BedrockClient)(nil)
+)
+
+func (c *BedrockClient) GenerateTitle(ctx context.Context, imageURL string, opts TitleOptions) (string, error) {
+	if opts.Words == 0 {
+		opts.Words = c.titleWords
+	}
+
+	text, err := c.complete(ctx, imageURL, SystemPrompt, UserPrompt+opts.PromptSuffix(), c.maxTokens)
+	if err != nil {
+		return "", err
+	}
+
+	title := cleanTitle(text)
+	if title == "" {
+		return "", fmt.Errorf("received empty title from API")
+	}
+
+	log.Printf("Successfully generated title: %s", title)
+	return title, nil
+}
+
+// GenerateTags suggests keyword tags for an image
+func (c *BedrockClient) GenerateTags(ctx context.Context, imageURL string, opts TagOptions) ([]string, error) {
+	text, err := c.complete(ctx, imageURL, TagSystemPrompt, opts.Prompt(), max(c.maxTokens, opts.MaxTokens()))
+	if err != nil {
+		return nil, err
+	}
+
+	tags := ParseTags(text, opts)
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("received no tags from API")
+	}
+
+	log.Printf("Successfully generated %d tags", len(tags))
+	return tags, nil
+}
+
+// GenerateAltText describes an image for screen readers
+func (c *BedrockClient) GenerateAltText(ctx context.Context, imageURL string, opts AltTextOptions) (string, error) {
+	text, err := c.complete(ctx, imageURL, AltTextSystemPrompt, opts.Prompt(), max(c.maxTokens, altTextTokens))
+	if err != nil {
+		return "", err
+	}
+
+	altText := CleanAltText(text)
+	if altText == "" {
+		return "", fmt.Errorf("received empty alt text from API")
+	}
+
+	log.Printf("Successfully generated alt text: %s", altText)
+	return altText, nil
+}
+
+// complete sends an image with the given prompts through the Converse API
+// and returns the model's trimmed reply
+func (c *BedrockClient) complete(ctx context.Context, imageURL, systemPrompt, userPrompt string, maxTokens int) (string, error) {
+	img, err := FetchImage(ctx, imageURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to download image: %w", err)
+	}
+	img, err = img.ConvertTo(StandardImageTypes...)
+	if err != nil {
+		return "", fmt.Errorf("failed to prepare image: %w", err)
+	}
+
+	image := &bedrockImage{Format: strings.TrimPrefix(img.ContentType, "image/")}
+	image.Source.Bytes = img.Data
+	reqBody := bedrockRequest{
+		System: []bedrockContentBlock{{Text: systemPrompt}},
+		Messages: []bedrockMessage{
+			{
+				Role: "user",
+				Content: []bedrockContentBlock{
+					{Image: image},
+					{Text: userPrompt},
+				},
+			},
+		},
+		InferenceConfig: bedrockInferenceConfig{MaxTokens: maxTokens},
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// Model IDs contain colons, which are escaped as the AWS SDKs do
+	apiURL := c.endpoint + "/model/" + awsURIEncode(c.model, true) + "/converse"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	} else {
+		signAWSRequest(req, jsonData, c.creds, c.region, bedrockService, time.Now())
+	}
+
+	log.Printf("Sending request to Bedrock for image: %s", imageURL)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var apiResp bedrockResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		}
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if apiResp.Usage != nil {
+		ReportUsage(ctx, Usage{InputTokens: apiResp.Usage.InputTokens, OutputTokens: apiResp.Usage.OutputTokens})
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if apiResp.Message != "" {
+			errType, _, _ := strings.Cut(resp.Header.Get("X-Amzn-Errortype"), ":")
+			log.Printf("Bedrock API error (HTTP %d): %s", resp.StatusCode, apiResp.Message)
+			return "", VisionError(c.model, fmt.Errorf("API error: %s (%s)", apiResp.Message, errType))
+		}
+		return "", fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var text strings.Builder
+	for _, block := range apiResp.Output.Message.Content {
+		text.WriteString(block.Text)
+	}
+
+	reply := strings.TrimSpace(stripReasoning(text.String()))
+	if reply == "" {
+		return "", fmt.Errorf("received empty response from API")
+	}
+
+	return reply, nil
+}
@@ -0,0 +1,242 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+)
+
+const (
+	// BackendHTTPCaptioner is a simple image captioning service, such as
+	// BLIP or LLaVA behind a small web app, that takes an image and
+	// replies with a caption
+	BackendHTTPCaptioner = "http_captioner"
+)
+
+// Ways of sending the image to a captioning service, set with the
+// request_format setting
+const (
+	// CaptionerFormatRaw sends the image as the request body
+	CaptionerFormatRaw = "raw"
+	// CaptionerFormatMultipart sends the image as the "image" field of a
+	// multipart form, with the prompt as the "prompt" field
+	CaptionerFormatMultipart = "multipart"
+	// CaptionerFormatJSON sends a JSON object with the base64-encoded
+	// image as "image" and the prompt as "prompt"
+	CaptionerFormatJSON = "json"
+)
+
+// CaptionerFormats lists the valid request formats
+var CaptionerFormats = []string{CaptionerFormatRaw, CaptionerFormatMultipart, CaptionerFormatJSON}
+
+// captionFields are the response fields a caption is looked for in, in
+// order, unless the caption_field setting names one
+var captionFields = []string{"caption", "generated_text", "text", "title"}
+
+// CaptionerClient generates titles with an external captioning service:
+// the image is POSTed to the service, which replies with JSON holding the
+// caption, or with the caption as plain text. Services that take no prompt
+// ignore title options such as the style and language.
+type CaptionerClient struct {
+	apiURL        string
+	apiKey        string
+	requestFormat string
+	captionField  string
+	client        *http.Client
+}
+
+type captionerRequest struct {
+	// Image is base64 encoded by encoding/json
+	Image       []byte `json:"image"`
+	ContentType string `json:"content_type"`
+	Prompt      string `json:"prompt"`
+}
+
+func init() {
+	Register(BackendHTTPCaptioner, func(settings Settings) (Client, error) {
+		return NewCaptionerClient(settings["url"], settings["api_key"], settings.Get("request_format", CaptionerFormatRaw), settings["caption_field"])
+	})
+}
+
+// NewCaptionerClient creates a client for the captioning service at apiURL.
+// apiKey is sent as a bearer token only if set. captionField names the
+// response field holding the caption; if empty, the usual field names are
+// tried.
+func NewCaptionerClient(apiURL, apiKey, requestFormat, captionField string) (*CaptionerClient, error) {
+	if apiURL == "" {
+		return nil, fmt.Errorf("captioner URL is required")
+	}
+	if parsed, err := url.Parse(apiURL); err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid captioner URL %q", apiURL)
+	}
+	if requestFormat == "" {
+		requestFormat = CaptionerFormatRaw
+	}
+	valid := false
+	for _, f := range CaptionerFormats {
+		valid = valid || requestFormat == f
+	}
+	if !valid {
+		return nil, fmt.Errorf("request_format must be one of %s, got %q", strings.Join(CaptionerFormats, ", "), requestFormat)
+	}
+
+	log.Printf("HTTP captioner client configured with URL: %s, request format: %s", apiURL, requestFormat)
+
+	return &CaptionerClient{
+		apiURL:        apiURL,
+		apiKey:        apiKey,
+		requestFormat: requestFormat,
+		captionField:  captionField,
+		client:        &http.Client{Timeout: constants.OllamaClientTimeout, Transport: Transport},
+	}, nil
+}
+
+// GenerateTitle sends an image to the captioning service and returns its
+// caption as a title
+func (c *CaptionerClient) GenerateTitle(ctx context.Context, imageURL string, opts TitleOptions) (string, error) {
+	img, err := FetchImage(ctx, imageURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to download image: %w", err)
+	}
+	img, err = img.ConvertTo(StandardImageTypes...)
+	if err != nil {
+		return "", fmt.Errorf("failed to prepare image: %w", err)
+	}
+
+	req, err := c.newRequest(ctx, img, UserPrompt+opts.PromptSuffix())
+	if err != nil {
+		return "", err
+	}
+
+	log.Printf("Sending request to HTTP captioner for image: %s", imageURL)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("captioner request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	caption, err := c.parseCaption(body)
+	if err != nil {
+		return "", err
+	}
+
+	title := captionTitle(caption)
+	if title == "" {
+		return "", fmt.Errorf("received empty caption")
+	}
+
+	log.Printf("Successfully generated title: %s", title)
+	return title, nil
+}
+
+// newRequest builds the request sending img to the service in the
+// configured format
+func (c *CaptionerClient) newRequest(ctx context.Context, img *Image, prompt string) (*http.Request, error) {
+	var body bytes.Buffer
+	contentType := img.ContentType
+	switch c.requestFormat {
+	case CaptionerFormatMultipart:
+		form := multipart.NewWriter(&body)
+		part, err := form.CreateFormFile("image", "image."+strings.TrimPrefix(img.ContentType, "image/"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to build form: %w", err)
+		}
+		if _, err := part.Write(img.Data); err != nil {
+			return nil, fmt.Errorf("failed to build form: %w", err)
+		}
+		if err := form.WriteField("prompt", prompt); err != nil {
+			return nil, fmt.Errorf("failed to build form: %w", err)
+		}
+		if err := form.Close(); err != nil {
+			return nil, fmt.Errorf("failed to build form: %w", err)
+		}
+		contentType = form.FormDataContentType()
+	case CaptionerFormatJSON:
+		if err := json.NewEncoder(&body).Encode(captionerRequest{Image: img.Data, ContentType: img.ContentType, Prompt: prompt}); err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		contentType = "application/json"
+	default:
+		body.Write(img.Data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiURL, &body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "application/json, text/plain")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	return req, nil
+}
+
+// parseCaption finds the caption in a response: a JSON object or the first
+// of an array of them (as Hugging Face's inference API returns), a JSON
+// string, or plain text
+func (c *CaptionerClient) parseCaption(body []byte) (string, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[' && trimmed[0] != '"') {
+		return string(trimmed), nil
+	}
+
+	var reply interface{}
+	if err := json.Unmarshal(trimmed, &reply); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if list, ok := reply.([]interface{}); ok && len(list) > 0 {
+		reply = list[0]
+	}
+	switch reply := reply.(type) {
+	case string:
+		return reply, nil
+	case map[string]interface{}:
+		fields := captionFields
+		if c.captionField != "" {
+			fields = []string{c.captionField}
+		}
+		for _, field := range fields {
+			if caption, ok := reply[field].(string); ok {
+				return caption, nil
+			}
+		}
+		if msg, ok := reply["error"].(string); ok {
+			return "", fmt.Errorf("captioner error: %s", msg)
+		}
+		return "", fmt.Errorf("no caption in response; expected one of the fields %s", strings.Join(fields, ", "))
+	default:
+		return "", fmt.Errorf("unexpected response: %s", string(trimmed))
+	}
+}
+
+// captionTitle turns a caption into a title. Captioning models tend to
+// write lowercase sentences ("a dog running on a beach."), so the first
+// letter is capitalized and a trailing period dropped.
+func captionTitle(caption string) string {
+	title := strings.TrimSuffix(cleanTitle(caption), ".")
+	r, size := utf8.DecodeRuneInString(title)
+	if r == utf8.RuneError {
+		return title
+	}
+	return string(unicode.ToUpper(r)) + title[size:]
+}
@@ -0,0 +1,132 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+)
+
+// ImageChecker is implemented by Fetchers that can check whether an image
+// exists without retrieving it
+type ImageChecker interface {
+	Check(ctx context.Context, ref ImageRef) error
+}
+
+// CheckImage checks that FetchImage could retrieve an image, without
+// retrieving it: with the fetcher for its storage disk if it can check
+// images, or else with a HEAD request for its URL. The image's contents
+// aren't validated. All errors wrap ErrImageUnavailable.
+func CheckImage(ctx context.Context, imageURL string) error {
+	if imageStorage != nil {
+		ok, err := imageStorage.check(ctx, imageURL)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrImageUnavailable, err)
+		}
+		if ok {
+			return nil
+		}
+	}
+
+	if err := headImage(ctx, imageURL, nil); err != nil {
+		return fmt.Errorf("%w: %w", ErrImageUnavailable, err)
+	}
+	return nil
+}
+
+// check checks an image with its disk's fetcher. ok is false if there is
+// none, or it can't check the image, so its URL should be checked instead.
+func (s *storage) check(ctx context.Context, imageURL string) (ok bool, err error) {
+	ref := ImageRef{URL: imageURL, ShortPath: s.shortPath(imageURL), Disk: storageDisk(ctx)}
+	checker, found := s.disks[ref.Disk].(ImageChecker)
+	if !found {
+		return false, nil
+	}
+
+	err = checker.Check(ctx, ref)
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, errors.ErrUnsupported) {
+		return false, nil
+	}
+	return true, err
+}
+
+// headImage checks that an image URL resolves, sending the given extra
+// request headers. Servers that don't allow HEAD are sent a GET whose body
+// is left unread.
+func headImage(ctx context.Context, imageURL string, extraHeader http.Header) error {
+	if imageURL == "" {
+		return fmt.Errorf("image URL cannot be empty")
+	}
+
+	client := http.Client{Timeout: constants.ImageDownloadTimeout, Transport: Transport}
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequestWithContext(ctx, method, imageURL, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		for name, values := range extraHeader {
+			req.Header[name] = values
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to reach image: %w", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented {
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+		}
+		return nil
+	}
+	return fmt.Errorf("server allows neither HEAD nor GET requests")
+}
+
+func (f *httpFetcher) Check(ctx context.Context, ref ImageRef) error {
+	return headImage(ctx, ref.URL, f.header)
+}
+
+func (f *lycheeFetcher) Check(ctx context.Context, ref ImageRef) error {
+	if ref.ShortPath == "" {
+		return errNoShortPath
+	}
+	return headImage(ctx, f.baseURL+"/uploads/"+escapePath(ref.ShortPath), f.header)
+}
+
+func (f *localFetcher) Check(ctx context.Context, ref ImageRef) error {
+	path, err := f.path(ref)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("not a file: %s", path)
+	}
+	if info.Size() == 0 {
+		return fmt.Errorf("image file %s is empty", path)
+	}
+	return nil
+}
+
+func (f *s3Fetcher) Check(ctx context.Context, ref ImageRef) error {
+	key, err := f.key(ref)
+	if err != nil {
+		return err
+	}
+	resp, err := f.do(ctx, http.MethodHead, key)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
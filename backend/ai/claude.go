@@ -0,0 +1,263 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+)
+
+const (
+	BackendClaude      = "claude"
+	DefaultClaudeURL   = "https://api.anthropic.com/v1/messages"
+	DefaultClaudeModel = "claude-sonnet-4-5"
+	claudeAPIVersion   = "2023-06-01"
+)
+
+// ClaudeClient generates titles with Anthropic's Messages API
+type ClaudeClient struct {
+	apiURL string
+	apiKey string
+	model  string
+	client *http.Client
+
+	maxTokens  int
+	titleWords int
+}
+
+type claudeRequest struct {
+	Model     string          `json:"model"`
+	System    string          `json:"system"`
+	Messages  []claudeMessage `json:"messages"`
+	MaxTokens int             `json:"max_tokens"`
+}
+
+type claudeMessage struct {
+	Role    string               `json:"role"`
+	Content []claudeContentBlock `json:"content"`
+}
+
+type claudeContentBlock struct {
+	Type   string             `json:"type"`
+	Text   string             `json:"text,omitempty"`
+	Source *claudeImageSource `json:"source,omitempty"`
+}
+
+type claudeImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+type claudeResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Usage *struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage,omitempty"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+func init() {
+	Register(BackendClaude, func(settings Settings) (Client, error) {
+		client, err := NewClaudeClient(settings.Get("url", DefaultClaudeURL), settings["api_key"], settings.Get("model", DefaultClaudeModel))
+		if err != nil {
+			return nil, err
+		}
+		if client.maxTokens, err = settings.Int("max_tokens", DefaultMaxTokens); err != nil {
+			return nil, err
+		}
+		if client.titleWords, err = settings.Int("title_words", 0); err != nil {
+			return nil, err
+		}
+		return client, nil
+	})
+}
+
+func NewClaudeClient(apiURL, apiKey, model string) (*ClaudeClient, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("API key is required")
+	}
+	if apiURL == "" {
+		apiURL = DefaultClaudeURL
+	}
+	if model == "" {
+		model = DefaultClaudeModel
+	}
+
+	log.Printf("Claude client configured with URL: %s, Model: %s", apiURL, model)
+
+	return &ClaudeClient{
+		apiURL:    apiURL,
+		apiKey:    apiKey,
+		model:     model,
+		client:    &http.Client{Timeout: constants.OllamaClientTimeout, Transport: Transport},
+		maxTokens: DefaultMaxTokens,
+	}, nil
+}
+
+var (
+	_ TagGenerator     = (*ClaudeClient)(nil)
+	_ AltTextGenerator = (*ClaudeClient)(nil)
+)
+
+func (c *ClaudeClient) GenerateTitle(ctx context.Context, imageURL string, opts TitleOptions) (string, error) {
+	if opts.Words == 0 {
+		opts.Words = c.titleWords
+	}
+
+	text, err := c.complete(ctx, imageURL, SystemPrompt, UserPrompt+opts.PromptSuffix(), c.maxTokens)
+	if err != nil {
+		return "", err
+	}
+
+	title := strings.Trim(text, `"'`)
+	if title == "" {
+		return "", fmt.Errorf("received empty title from API")
+	}
+
+	log.Printf("Successfully generated title: %s", title)
+	return title, nil
+}
+
+// GenerateTags suggests keyword tags for an image
+func (c *ClaudeClient) GenerateTags(ctx context.Context, imageURL string, opts TagOptions) ([]string, error) {
+	text, err := c.complete(ctx, imageURL, TagSystemPrompt, opts.Prompt(), max(c.maxTokens, opts.MaxTokens()))
+	if err != nil {
+		return nil, err
+	}
+
+	tags := ParseTags(text, opts)
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("received no tags from API")
+	}
+
+	log.Printf("Successfully generated %d tags", len(tags))
+	return tags, nil
+}
+
+// GenerateAltText describes an image for screen readers
+func (c *ClaudeClient) GenerateAltText(ctx context.Context, imageURL string, opts AltTextOptions) (string, error) {
+	text, err := c.complete(ctx, imageURL, AltTextSystemPrompt, opts.Prompt(), max(c.maxTokens, altTextTokens))
+	if err != nil {
+		return "", err
+	}
+
+	altText := CleanAltText(text)
+	if altText == "" {
+		return "", fmt.Errorf("received empty alt text from API")
+	}
+
+	log.Printf("Successfully generated alt text: %s", altText)
+	return altText, nil
+}
+
+// complete sends an image with the given prompts and returns the model's
+// trimmed reply
+func (c *ClaudeClient) complete(ctx context.Context, imageURL, systemPrompt, userPrompt string, maxTokens int) (string, error) {
+	img, err := FetchImage(ctx, imageURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to download image: %w", err)
+	}
+	img, err = img.ConvertTo(StandardImageTypes...)
+	if err != nil {
+		return "", fmt.Errorf("failed to prepare image: %w", err)
+	}
+
+	reqBody := claudeRequest{
+		Model:  c.model,
+		System: systemPrompt,
+		Messages: []claudeMessage{
+			{
+				Role: "user",
+				Content: []claudeContentBlock{
+					{
+						Type: "image",
+						Source: &claudeImageSource{
+							Type:      "base64",
+							MediaType: img.ContentType,
+							Data:      base64.StdEncoding.EncodeToString(img.Data),
+						},
+					},
+					{
+						Type: "text",
+						Text: userPrompt,
+					},
+				},
+			},
+		},
+		MaxTokens: maxTokens,
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("anthropic-version", claudeAPIVersion)
+
+	log.Printf("Sending request to Claude API for image: %s", imageURL)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var apiResp claudeResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		}
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if apiResp.Usage != nil {
+		ReportUsage(ctx, Usage{InputTokens: apiResp.Usage.InputTokens, OutputTokens: apiResp.Usage.OutputTokens})
+	}
+
+	if apiResp.Error != nil {
+		log.Printf("Claude API error (HTTP %d): %s", resp.StatusCode, apiResp.Error.Message)
+		return "", fmt.Errorf("API error: %s (%s)", apiResp.Error.Message, apiResp.Error.Type)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var text strings.Builder
+	for _, block := range apiResp.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+
+	reply := strings.TrimSpace(text.String())
+	if reply == "" {
+		return "", fmt.Errorf("received empty response from API")
+	}
+
+	return reply, nil
+}
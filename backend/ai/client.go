@@ -1,7 +1,167 @@
 package ai
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ErrImageUnavailable is wrapped by errors from GenerateTitle when the image
+// itself could not be fetched or is unusable, as opposed to a failure of
+// the AI backend. Callers may retry with a different image variant.
+var ErrImageUnavailable = errors.New("image unavailable")
+
+// ErrNoVision is matched by errors from clients whose model can't accept
+// images, which won't go away until a vision model is configured
+var ErrNoVision = errors.New("model does not support images")
+
+// NoVisionError reports that a model can't accept images. It matches
+// ErrNoVision.
+type NoVisionError struct {
+	// Model is empty if the server chooses the model
+	Model string
+}
+
+func (e NoVisionError) Error() string {
+	if e.Model == "" {
+		return "the model does not support images; configure a vision model"
+	}
+	return fmt.Sprintf("model %q does not support images; configure a vision model", e.Model)
+}
+
+func (e NoVisionError) Is(target error) bool { return target == ErrNoVision }
+
+// noVisionPattern matches the errors servers return when sent an image for
+// a model without vision support: Ollama, llama.cpp without a projector
+// (mmproj), LM Studio, OpenAI and Bedrock respectively
+var noVisionPattern = regexp.MustCompile(`(?i)missing data required for image input|image input is not supported|does not support (images|image input|vision)|image_url is only supported by certain models|doesn't support the image content block`)
+
+// VisionError returns a NoVisionError for model if err is a server's complaint
+// that model can't accept images, and err otherwise, so that the cause is
+// reported plainly rather than as a generic failure
+func VisionError(model string, err error) error {
+	if err != nil && !errors.Is(err, ErrNoVision) && noVisionPattern.MatchString(err.Error()) {
+		log.Printf("AI model can't accept images: %v", err)
+		return NoVisionError{Model: model}
+	}
+	return err
+}
 
 type Client interface {
-	GenerateTitle(ctx context.Context, imageURL string) (string, error)
+	GenerateTitle(ctx context.Context, imageURL string, opts TitleOptions) (string, error)
+}
+
+// TitleOptions adjusts title generation, e.g. per-album style preferences.
+// The zero value requests the default behavior.
+type TitleOptions struct {
+	// Style is a free-form description of the desired title style
+	Style string
+	// Language is the language the title should be written in
+	Language string
+	// Words is the maximum number of words the title should have; 0 leaves
+	// the length to the prompt
+	Words int
+	// Location is where the photo was taken, e.g. "Paris, France"
+	Location string
+	// TakenOn is the date the photo was taken, formatted for the library's
+	// locale, e.g. "May 4, 2024"
+	TakenOn string
+}
+
+// PromptSuffix returns additional prompt instructions for the options,
+// prefixed with a space, or an empty string if there are none
+func (o TitleOptions) PromptSuffix() string {
+	var b strings.Builder
+	if o.Style != "" {
+		b.WriteString(" The title should match this style: ")
+		b.WriteString(o.Style)
+		b.WriteString(".")
+	}
+	if o.Words > 0 {
+		fmt.Fprintf(&b, " Use no more than %d words.", o.Words)
+	}
+	if o.Language != "" {
+		b.WriteString(" Write the title in ")
+		b.WriteString(o.Language)
+		b.WriteString(".")
+	}
+	if o.Location != "" {
+		b.WriteString(" The photo was taken in ")
+		b.WriteString(o.Location)
+		b.WriteString("; mention the place only if it suits the title.")
+	}
+	if o.TakenOn != "" {
+		b.WriteString(" The photo was taken on ")
+		b.WriteString(o.TakenOn)
+		b.WriteString("; mention the date only if it suits the title, written the same way.")
+	}
+	return b.String()
+}
+
+// TitleStreamer is implemented by clients that can stream a title as it is
+// generated. StreamTitle calls onToken with each piece of text as it
+// arrives, then returns the complete title as GenerateTitle would.
+type TitleStreamer interface {
+	StreamTitle(ctx context.Context, imageURL string, opts TitleOptions, onToken func(string)) (string, error)
+}
+
+// Model preparation states reported by ModelStatusReporter
+const (
+	ModelReady   = "ready"
+	ModelPulling = "pulling"
+	ModelMissing = "missing"
+	ModelFailed  = "failed"
+	// ModelNoVision means the model is present but can't accept images
+	ModelNoVision = "no_vision"
+	ModelUnknown  = "unknown"
+)
+
+// ModelStatus describes whether a backend's model is available, and the
+// progress of downloading it if not
+type ModelStatus struct {
+	Model          string `json:"model"`
+	State          string `json:"state"`
+	CompletedBytes int64  `json:"completed_bytes,omitempty"`
+	TotalBytes     int64  `json:"total_bytes,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// ModelStatusReporter is implemented by clients whose model may need to be
+// prepared (e.g. downloaded) before titles can be generated
+type ModelStatusReporter interface {
+	ModelStatus() ModelStatus
+}
+
+// ModelInfo describes a model offered by an AI server
+type ModelInfo struct {
+	Name string `json:"name"`
+	// Size is the model's size on disk in bytes, where the server reports it
+	Size       int64      `json:"size,omitempty"`
+	ModifiedAt *time.Time `json:"modified_at,omitempty"`
+}
+
+// ModelList lists the models an AI server offers
+type ModelList struct {
+	// Configured is the model the client is configured to use, and
+	// Installed whether the server offers it
+	Configured string      `json:"configured"`
+	Installed  bool        `json:"installed"`
+	Models     []ModelInfo `json:"models"`
+}
+
+// ModelLister is implemented by clients that can list the models their
+// server offers, e.g. for a model picker
+type ModelLister interface {
+	ListModels(ctx context.Context) (ModelList, error)
+}
+
+// Pinger is implemented by clients that can check their server is reachable
+// without generating anything. Ping returns the server's version, or an
+// empty string if it doesn't report one.
+type Pinger interface {
+	Ping(ctx context.Context) (string, error)
 }
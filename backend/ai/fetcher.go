@@ -0,0 +1,177 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+)
+
+// Fetcher retrieves images from where a Lychee storage disk keeps them.
+// Errors wrapping fs.ErrNotExist or errors.ErrUnsupported make FetchImage
+// download the image from its URL instead.
+type Fetcher interface {
+	Fetch(ctx context.Context, ref ImageRef) (*Image, error)
+}
+
+// ImageRef identifies an image to fetch
+type ImageRef struct {
+	// URL is the image's URL, built from the image URL pattern
+	URL string
+	// ShortPath is the size variant's path on its storage disk, recovered
+	// from URL; empty if URL wasn't built from the pattern
+	ShortPath string
+	// Disk is the Lychee storage disk holding the image
+	Disk string
+}
+
+// errNoShortPath is returned by fetchers needing a short path for images
+// whose URL wasn't built from the image URL pattern
+var errNoShortPath = fmt.Errorf("image URL doesn't match the image URL pattern: %w", errors.ErrUnsupported)
+
+// storage selects a Fetcher for each image by its storage disk
+type storage struct {
+	// prefix and suffix surround {short_path} in the image URL pattern
+	prefix, suffix string
+	disks          map[string]Fetcher
+}
+
+// imageStorage is set by UseStorage; nil downloads every image
+var imageStorage *storage
+
+// UseStorage makes FetchImage retrieve images with the Fetcher configured
+// for their storage disk, rather than downloading them. Short paths are
+// recovered from image URLs built from imageURLPattern. Images on disks
+// without a fetcher are downloaded as before. It must be called before any
+// images are fetched.
+func UseStorage(imageURLPattern string, disks map[string]Fetcher) error {
+	prefix, suffix, ok := strings.Cut(imageURLPattern, constants.ImageURLShortPath)
+	if !ok || strings.Contains(suffix, constants.ImageURLShortPath) {
+		return fmt.Errorf("image URL pattern must contain %s exactly once", constants.ImageURLShortPath)
+	}
+	imageStorage = &storage{prefix: prefix, suffix: suffix, disks: disks}
+	return nil
+}
+
+// storageDiskKey is the context key for the storage disk of images fetched
+type storageDiskKey struct{}
+
+// WithStorageDisk returns a context under which FetchImage retrieves images
+// from the given Lychee storage disk, e.g. a photo's size_variants
+// storage_disk. Without it, constants.DefaultStorageDisk is assumed.
+func WithStorageDisk(ctx context.Context, disk string) context.Context {
+	if disk == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, storageDiskKey{}, disk)
+}
+
+// storageDisk returns the storage disk set with WithStorageDisk
+func storageDisk(ctx context.Context) string {
+	if disk, ok := ctx.Value(storageDiskKey{}).(string); ok {
+		return disk
+	}
+	return constants.DefaultStorageDisk
+}
+
+// shortPath returns the short path imageURL was built from, if it matches
+// the image URL pattern
+func (s *storage) shortPath(imageURL string) string {
+	if !strings.HasPrefix(imageURL, s.prefix) {
+		return ""
+	}
+	shortPath := strings.TrimPrefix(imageURL, s.prefix)
+	if s.suffix == "" {
+		// Drop a cache-busting query string
+		shortPath, _, _ = strings.Cut(shortPath, "?")
+	} else {
+		var ok bool
+		if shortPath, ok = strings.CutSuffix(shortPath, s.suffix); !ok {
+			return ""
+		}
+	}
+	if unescaped, err := url.PathUnescape(shortPath); err == nil {
+		shortPath = unescaped
+	}
+	return shortPath
+}
+
+// fetch retrieves an image with its disk's fetcher. ok is false if there
+// is none, or it can't provide the image, so it should be downloaded.
+func (s *storage) fetch(ctx context.Context, imageURL string) (img *Image, ok bool, err error) {
+	ref := ImageRef{URL: imageURL, ShortPath: s.shortPath(imageURL), Disk: storageDisk(ctx)}
+	fetcher, found := s.disks[ref.Disk]
+	if !found {
+		return nil, false, nil
+	}
+
+	img, err = fetcher.Fetch(ctx, ref)
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, errors.ErrUnsupported) {
+		log.Printf("Image %s can't be read from storage disk %s (%v); downloading it", imageURL, ref.Disk, err)
+		return nil, false, nil
+	}
+	return img, true, err
+}
+
+// httpFetcher downloads images from their URLs, sending extra headers
+type httpFetcher struct {
+	header http.Header
+}
+
+// NewHTTPFetcher creates a Fetcher downloading images from their URLs with
+// the given extra request headers, e.g. for authentication
+func NewHTTPFetcher(headers map[string]string) Fetcher {
+	header := make(http.Header, len(headers))
+	for name, value := range headers {
+		header.Set(name, value)
+	}
+	return &httpFetcher{header: header}
+}
+
+func (f *httpFetcher) Fetch(ctx context.Context, ref ImageRef) (*Image, error) {
+	return fetchImage(ctx, ref.URL, f.header)
+}
+
+// lycheeFetcher downloads images through Lychee itself
+type lycheeFetcher struct {
+	baseURL string
+	header  http.Header
+}
+
+// NewLycheeFetcher creates a Fetcher downloading images from the Lychee
+// instance at baseURL, by their short paths below its uploads directory,
+// whatever the image URL pattern. apiToken, if set, is sent as Lychee
+// expects in the Authorization header, for instances that only serve
+// images to logged-in users.
+func NewLycheeFetcher(baseURL, apiToken string) (Fetcher, error) {
+	if parsed, err := url.Parse(baseURL); err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid Lychee URL %q", baseURL)
+	}
+	header := make(http.Header)
+	if apiToken != "" {
+		header.Set("Authorization", apiToken)
+	}
+	return &lycheeFetcher{baseURL: strings.TrimSuffix(baseURL, "/"), header: header}, nil
+}
+
+func (f *lycheeFetcher) Fetch(ctx context.Context, ref ImageRef) (*Image, error) {
+	if ref.ShortPath == "" {
+		return nil, errNoShortPath
+	}
+	return fetchImage(ctx, f.baseURL+"/uploads/"+escapePath(ref.ShortPath), f.header)
+}
+
+// escapePath escapes each segment of a slash-separated path for a URL
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
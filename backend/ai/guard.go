@@ -0,0 +1,186 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+)
+
+// ErrBackendUnavailable is returned by Guard.Do without calling the backend
+// while its circuit breaker is open, after repeated failures
+var ErrBackendUnavailable = errors.New("AI backend is unavailable")
+
+// Circuit breaker states reported by Guard.State
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half_open"
+)
+
+// permanentError marks an error that retrying won't fix
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so Guard.Do doesn't retry it
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// Guard retries failed AI backend requests with exponential backoff, and
+// stops sending requests for a while once several in a row have failed,
+// so callers fail fast rather than each waiting out the full timeout while
+// the backend is down. It is safe for concurrent use; a nil Guard calls
+// the backend directly.
+type Guard struct {
+	mu       sync.Mutex
+	failures int
+	// openedAt is when the circuit last opened; zero while it's closed
+	openedAt time.Time
+	// probing is true while a trial request is in flight after the cooldown
+	probing bool
+}
+
+// NewGuard creates a Guard with a closed circuit
+func NewGuard() *Guard {
+	return &Guard{}
+}
+
+// Do calls fn, retrying it if it fails, unless the circuit is open.
+// Failures caused by the image rather than the backend, errors wrapped with
+// Permanent, and cancellation of ctx aren't retried.
+func (g *Guard) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if g == nil {
+		return fn(ctx)
+	}
+	if !g.allow() {
+		return ErrBackendUnavailable
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn(ctx)
+		if err == nil || !retryable(ctx, err) || attempt >= constants.AIRetryAttempts {
+			break
+		}
+
+		delay := backoff(attempt)
+		log.Printf("AI request failed (attempt %d/%d), retrying in %s: %v", attempt, constants.AIRetryAttempts, delay.Round(time.Millisecond), err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+			continue
+		}
+		break
+	}
+
+	g.record(ctx, err)
+	return err
+}
+
+// State returns the circuit breaker's state
+func (g *Guard) State() string {
+	if g == nil {
+		return CircuitClosed
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	switch {
+	case g.openedAt.IsZero():
+		return CircuitClosed
+	case g.probing || time.Since(g.openedAt) >= constants.AIBreakerCooldown:
+		return CircuitHalfOpen
+	default:
+		return CircuitOpen
+	}
+}
+
+// Reset closes the circuit and forgets past failures, e.g. when the AI
+// backend is replaced
+func (g *Guard) Reset() {
+	if g == nil {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.failures = 0
+	g.openedAt = time.Time{}
+	g.probing = false
+}
+
+// allow reports whether a request may be sent. Once the cooldown has passed
+// after the circuit opened, a single trial request is let through.
+func (g *Guard) allow() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.openedAt.IsZero() {
+		return true
+	}
+	if g.probing || time.Since(g.openedAt) < constants.AIBreakerCooldown {
+		return false
+	}
+	g.probing = true
+	return true
+}
+
+// record updates the circuit with the outcome of a request
+func (g *Guard) record(ctx context.Context, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	switch {
+	case err == nil:
+		if !g.openedAt.IsZero() {
+			log.Printf("AI backend recovered; resuming requests")
+		}
+		g.failures = 0
+		g.openedAt = time.Time{}
+		g.probing = false
+
+	case errors.Is(err, ErrImageUnavailable) || errors.Is(err, ErrNoVision) || errors.Is(ctx.Err(), context.Canceled):
+		// Says nothing about the backend's health, so a trial request
+		// will be needed again
+		g.probing = false
+
+	default:
+		g.failures++
+		if g.probing || g.failures >= constants.AIBreakerThreshold {
+			log.Printf("AI backend failed %d request(s) in a row; refusing requests for %s", g.failures, constants.AIBreakerCooldown)
+			g.openedAt = time.Now()
+		}
+		g.probing = false
+	}
+}
+
+// retryable reports whether a failed request should be tried again
+func retryable(ctx context.Context, err error) bool {
+	var permanent permanentError
+	return ctx.Err() == nil && !errors.Is(err, ErrImageUnavailable) && !errors.Is(err, ErrNoVision) && !errors.As(err, &permanent)
+}
+
+// backoff returns the delay before retry attempt+1: the base delay doubled
+// for each earlier attempt, capped, with up to half of it randomized so
+// concurrent requests don't retry in lockstep
+func backoff(attempt int) time.Duration {
+	delay := constants.AIRetryMaxDelay
+	if attempt < 16 {
+		delay = min(constants.AIRetryBaseDelay<<(attempt-1), constants.AIRetryMaxDelay)
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
@@ -0,0 +1,129 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+)
+
+// heifBrands maps ISO base media file brands to the MIME type of the HEIF
+// images carrying them
+var heifBrands = map[string]string{
+	"heic": constants.MimeHEIC,
+	"heix": constants.MimeHEIC,
+	"heim": constants.MimeHEIC,
+	"heis": constants.MimeHEIC,
+	"hevc": constants.MimeHEIC,
+	"hevx": constants.MimeHEIC,
+	"avif": constants.MimeAVIF,
+	"avis": constants.MimeAVIF,
+	"mif1": constants.MimeHEIF,
+	"msf1": constants.MimeHEIF,
+}
+
+// detectHEIF returns the MIME type of a HEIC, AVIF or other HEIF image from
+// the brands in its ftyp box, or an empty string. The generic mif1 brand is
+// only used when no more specific brand is listed.
+func detectHEIF(data []byte) string {
+	if len(data) < 16 || string(data[4:8]) != "ftyp" {
+		return ""
+	}
+	size := int(data[0])<<24 | int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+	if size < 16 || size > len(data) {
+		size = min(len(data), 64)
+	}
+
+	// The major brand, then compatible brands after the minor version
+	brands := []string{string(data[8:12])}
+	for i := 16; i+4 <= size; i += 4 {
+		brands = append(brands, string(data[i:i+4]))
+	}
+
+	found := ""
+	for _, brand := range brands {
+		switch mimeType := heifBrands[brand]; mimeType {
+		case "":
+		case constants.MimeHEIF:
+			if found == "" {
+				found = mimeType
+			}
+		default:
+			return mimeType
+		}
+	}
+	return found
+}
+
+// heifConverter is a command-line tool that converts HEIF images to JPEG
+type heifConverter struct {
+	name string
+	args func(in, out string) []string
+}
+
+// heifConverters are tried in order: libheif's heif-dec (formerly
+// heif-convert), then ImageMagick
+var heifConverters = []heifConverter{
+	{"heif-dec", func(in, out string) []string { return []string{"-q", strconv.Itoa(transcodeQuality), in, out} }},
+	{"heif-convert", func(in, out string) []string { return []string{"-q", strconv.Itoa(transcodeQuality), in, out} }},
+	{"magick", func(in, out string) []string { return []string{in, "-quality", strconv.Itoa(transcodeQuality), out} }},
+}
+
+var (
+	heifConverterOnce sync.Once
+	heifConverterPath string
+	heifConverterArgs func(in, out string) []string
+)
+
+// HEIFConverter returns the path of the tool used to convert HEIC and AVIF
+// images to JPEG, or an empty string if none is installed
+func HEIFConverter() string {
+	heifConverterOnce.Do(func() {
+		for _, c := range heifConverters {
+			if path, err := exec.LookPath(c.name); err == nil {
+				heifConverterPath = path
+				heifConverterArgs = c.args
+				return
+			}
+		}
+	})
+	return heifConverterPath
+}
+
+// convertHEIF converts a HEIF image to JPEG using an external tool, since
+// Go has no HEVC or AV1 image decoder
+func convertHEIF(data []byte) ([]byte, error) {
+	if HEIFConverter() == "" {
+		return nil, fmt.Errorf("no HEIC/AVIF converter is installed (install libheif's heif-dec or ImageMagick)")
+	}
+
+	dir, err := os.MkdirTemp("", "lychee-heif-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "in.heif")
+	out := filepath.Join(dir, "out"+constants.ExtJPG)
+	if err := os.WriteFile(in, data, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), constants.ImageConvertTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, heifConverterPath, heifConverterArgs(in, out)...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%s failed: %w: %s", filepath.Base(heifConverterPath), err, output)
+	}
+
+	converted, err := os.ReadFile(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read converted image: %w", err)
+	}
+	return converted, nil
+}
@@ -0,0 +1,63 @@
+package ai
+
+import "sync"
+
+// Holder holds the AI client in use, so the backend can be replaced while
+// the server runs without interrupting requests already using the old
+// client. It is safe for concurrent use; a nil Holder holds no client.
+type Holder struct {
+	mu      sync.RWMutex
+	backend string
+	model   string
+	client  Client
+}
+
+// NewHolder creates a Holder for client, which may be nil if backend is
+// unconfigured or failed to initialize. model is the configured model, or
+// empty for the backend's default.
+func NewHolder(backend, model string, client Client) *Holder {
+	return &Holder{backend: backend, model: model, client: client}
+}
+
+// Client returns the client in use, or nil if AI title generation is
+// unavailable
+func (h *Holder) Client() Client {
+	if h == nil {
+		return nil
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.client
+}
+
+// Backend returns the name of the configured backend, or an empty string if
+// none is configured
+func (h *Holder) Backend() string {
+	if h == nil {
+		return ""
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.backend
+}
+
+// Model returns the configured model, or an empty string for the backend's
+// default
+func (h *Holder) Model() string {
+	if h == nil {
+		return ""
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.model
+}
+
+// Swap replaces the client in use. Requests already holding the old client
+// finish with it.
+func (h *Holder) Swap(backend, model string, client Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.backend = backend
+	h.model = model
+	h.client = client
+}
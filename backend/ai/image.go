@@ -0,0 +1,213 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/gif" // register decoder for transcoding
+	"image/jpeg"
+	_ "image/png" // register decoder for transcoding
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+)
+
+// maxImageDownloadSize caps how much of a response is read when fetching an
+// image, so a misconfigured URL can't exhaust memory
+const maxImageDownloadSize = 10 * constants.MaxImageSize
+
+// transcodeQuality is the JPEG quality used when transcoding images
+const transcodeQuality = 90
+
+// imageSignatures maps supported MIME types to the magic bytes their files
+// start with. WebP files additionally carry "WEBP" at offset 8.
+var imageSignatures = map[string][]byte{
+	constants.MimeJPEG: {0xFF, 0xD8, 0xFF},
+	constants.MimePNG:  {0x89, 0x50, 0x4E, 0x47},
+	constants.MimeGIF:  {0x47, 0x49, 0x46, 0x38},
+	constants.MimeWEBP: {0x52, 0x49, 0x46, 0x46},
+}
+
+// StandardImageTypes are the formats vision APIs generally accept
+var StandardImageTypes = []string{constants.MimeJPEG, constants.MimePNG, constants.MimeGIF, constants.MimeWEBP}
+
+// Image is an image fetched for title generation
+type Image struct {
+	Data []byte
+	// ContentType is the MIME type detected from the image data
+	ContentType string
+}
+
+// FetchImage retrieves an image with the fetcher for its storage disk (see
+// UseStorage and WithStorageDisk), or else downloads it, and validates it
+// by both its Content-Type header and its file signature. For a video, a
+// JPEG of a frame from it is returned instead. All errors wrap
+// ErrImageUnavailable.
+func FetchImage(ctx context.Context, imageURL string) (*Image, error) {
+	if imageStorage != nil {
+		img, ok, err := imageStorage.fetch(ctx, imageURL)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrImageUnavailable, err)
+		}
+		if ok {
+			return img, nil
+		}
+	}
+
+	img, err := fetchImage(ctx, imageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrImageUnavailable, err)
+	}
+	return img, nil
+}
+
+// fetchImage downloads an image, sending the given extra request headers
+func fetchImage(ctx context.Context, imageURL string, extraHeader http.Header) (*Image, error) {
+	if imageURL == "" {
+		return nil, fmt.Errorf("image URL cannot be empty")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range extraHeader {
+		req.Header[name] = values
+	}
+
+	client := http.Client{Timeout: constants.ImageDownloadTimeout, Transport: Transport}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	}
+
+	header := resp.Header.Get("Content-Type")
+	log.Printf("Downloaded image: Content-Type=%s, Status=%d, URL=%s", header, resp.StatusCode, imageURL)
+
+	if isVideoContentType(header) {
+		return extractKeyframe(ctx, imageURL, extraHeader)
+	}
+	if !isSupportedContentType(header) {
+		return nil, fmt.Errorf("unsupported image type: %s", header)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageDownloadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image data: %w", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("received empty image data")
+	}
+	if len(data) > maxImageDownloadSize {
+		return nil, fmt.Errorf("image is larger than %d bytes", maxImageDownloadSize)
+	}
+
+	contentType := DetectImageType(data)
+	if contentType == "" && isVideo(data) {
+		// A video served without a video/* Content-Type
+		return extractKeyframe(ctx, imageURL, extraHeader)
+	}
+	if contentType == "" {
+		return nil, fmt.Errorf("invalid image format or corrupted data")
+	}
+
+	if len(data) > constants.MaxImageSize {
+		log.Printf("Warning: Large image detected (%d bytes), may cause performance issues", len(data))
+	}
+
+	log.Printf("Image validation successful: %d bytes, %s", len(data), contentType)
+	return &Image{Data: data, ContentType: contentType}, nil
+}
+
+// isSupportedContentType reports whether a Content-Type header names a
+// supported image type. Generic binary types are allowed, since some
+// servers don't label images; the file signature is checked regardless.
+func isSupportedContentType(header string) bool {
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(header))
+	}
+
+	switch mediaType {
+	case "", "application/octet-stream", "binary/octet-stream":
+		return true
+	case "image/jpg":
+		return true
+	case constants.MimeHEIC, constants.MimeHEIF, constants.MimeAVIF:
+		return true
+	}
+	_, ok := imageSignatures[mediaType]
+	return ok
+}
+
+// isVideoContentType reports whether a Content-Type header names a video
+func isVideoContentType(header string) bool {
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(header))
+	}
+	return strings.HasPrefix(mediaType, "video/")
+}
+
+// DetectImageType returns the MIME type of a supported image from its file
+// signature, or an empty string if the data isn't a supported image
+func DetectImageType(data []byte) string {
+	if mimeType := detectHEIF(data); mimeType != "" {
+		return mimeType
+	}
+	for mimeType, sig := range imageSignatures {
+		if !bytes.HasPrefix(data, sig) {
+			continue
+		}
+		if mimeType == constants.MimeWEBP && (len(data) < 12 || string(data[8:12]) != "WEBP") {
+			continue
+		}
+		return mimeType
+	}
+	return ""
+}
+
+// ConvertTo returns the image unchanged if its type is one of accepted, or
+// transcoded to JPEG otherwise. JPEG, PNG and GIF images are transcoded
+// directly, and HEIC and AVIF images with an external tool (see
+// HEIFConverter); for animated GIFs the first frame is used.
+func (img *Image) ConvertTo(accepted ...string) (*Image, error) {
+	for _, t := range accepted {
+		if t == img.ContentType {
+			return img, nil
+		}
+	}
+
+	switch img.ContentType {
+	case constants.MimeHEIC, constants.MimeHEIF, constants.MimeAVIF:
+		data, err := convertHEIF(img.Data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: cannot convert %s image: %w", ErrImageUnavailable, img.ContentType, err)
+		}
+		log.Printf("Converted %s image to JPEG (%d -> %d bytes)", img.ContentType, len(img.Data), len(data))
+		return &Image{Data: data, ContentType: constants.MimeJPEG}, nil
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(img.Data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot transcode %s image: %w", ErrImageUnavailable, img.ContentType, err)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, decoded, &jpeg.Options{Quality: transcodeQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode JPEG: %w", err)
+	}
+
+	log.Printf("Transcoded %s image to JPEG (%d -> %d bytes)", img.ContentType, len(img.Data), buf.Len())
+	return &Image{Data: buf.Bytes(), ContentType: constants.MimeJPEG}, nil
+}
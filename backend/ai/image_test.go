@@ -0,0 +1,201 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+)
+
+// readFixture returns the contents of a file in testdata
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	return data
+}
+
+func TestDetectImageType(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    string
+	}{
+		{"photo.jpg", constants.MimeJPEG},
+		{"photo.png", constants.MimePNG},
+		{"animated.gif", constants.MimeGIF},
+		{"pixel.webp", constants.MimeWEBP},
+		// The start of a HEIC file, up to its ftyp box
+		{"header.heic", constants.MimeHEIC},
+		// An MP4 shares HEIC's ftyp box but isn't an image
+		{"header.mp4", ""},
+		{"not-an-image.txt", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			if got := DetectImageType(readFixture(t, tt.fixture)); got != tt.want {
+				t.Errorf("DetectImageType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectImageTypeRIFFWithoutWebP(t *testing.T) {
+	// A WAV file starts with the same RIFF header as a WebP image
+	wav := append([]byte("RIFF\x24\x00\x00\x00WAVE"), make([]byte, 32)...)
+	if got := DetectImageType(wav); got != "" {
+		t.Errorf("DetectImageType(WAV) = %q, want none", got)
+	}
+	if got := DetectImageType(readFixture(t, "pixel.webp")[:10]); got != "" {
+		t.Errorf("DetectImageType(truncated WebP) = %q, want none", got)
+	}
+}
+
+func TestIsVideo(t *testing.T) {
+	if !isVideo(readFixture(t, "header.mp4")) {
+		t.Error("isVideo(MP4) = false")
+	}
+	if isVideo(readFixture(t, "header.heic")) {
+		t.Error("isVideo(HEIC) = true")
+	}
+}
+
+func TestFetchImage(t *testing.T) {
+	tests := []struct {
+		name        string
+		fixture     string
+		contentType string
+		status      int
+		wantType    string
+		wantErr     string
+	}{
+		{name: "JPEG", fixture: "photo.jpg", contentType: "image/jpeg", wantType: constants.MimeJPEG},
+		{name: "nonstandard image/jpg", fixture: "photo.jpg", contentType: "image/jpg", wantType: constants.MimeJPEG},
+		{name: "PNG with parameters", fixture: "photo.png", contentType: "image/png; charset=binary", wantType: constants.MimePNG},
+		{name: "unlabelled GIF", fixture: "animated.gif", contentType: "application/octet-stream", wantType: constants.MimeGIF},
+		{name: "WebP", fixture: "pixel.webp", contentType: "image/webp", wantType: constants.MimeWEBP},
+		{name: "HEIC", fixture: "header.heic", contentType: "image/heic", wantType: constants.MimeHEIC},
+		{name: "mislabelled type is detected", fixture: "photo.png", contentType: "image/jpeg", wantType: constants.MimePNG},
+		{name: "text labelled as an image", fixture: "not-an-image.txt", contentType: "image/jpeg", wantErr: "invalid image format"},
+		{name: "HTML page", fixture: "photo.jpg", contentType: "text/html", wantErr: "unsupported image type"},
+		{name: "missing image", fixture: "photo.jpg", contentType: "image/jpeg", status: http.StatusNotFound, wantErr: "HTTP 404"},
+		{name: "empty body", contentType: "image/jpeg", wantErr: "empty image data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data []byte
+			if tt.fixture != "" {
+				data = readFixture(t, tt.fixture)
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				w.Write(data)
+			}))
+			defer server.Close()
+
+			img, err := FetchImage(context.Background(), server.URL+"/image")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("FetchImage error = %v, want one containing %q", err, tt.wantErr)
+				}
+				if !errors.Is(err, ErrImageUnavailable) {
+					t.Errorf("FetchImage error %v doesn't wrap ErrImageUnavailable", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FetchImage: %v", err)
+			}
+			if img.ContentType != tt.wantType {
+				t.Errorf("ContentType = %q, want %q", img.ContentType, tt.wantType)
+			}
+			if !bytes.Equal(img.Data, data) {
+				t.Error("Data differs from the served image")
+			}
+		})
+	}
+}
+
+func TestFetchImageEmptyURL(t *testing.T) {
+	_, err := FetchImage(context.Background(), "")
+	if !errors.Is(err, ErrImageUnavailable) {
+		t.Errorf("FetchImage(\"\") error = %v, want ErrImageUnavailable", err)
+	}
+}
+
+func TestConvertTo(t *testing.T) {
+	t.Run("accepted type is unchanged", func(t *testing.T) {
+		img := &Image{Data: readFixture(t, "pixel.webp"), ContentType: constants.MimeWEBP}
+		got, err := img.ConvertTo(StandardImageTypes...)
+		if err != nil {
+			t.Fatalf("ConvertTo: %v", err)
+		}
+		if got != img {
+			t.Error("ConvertTo returned a copy of an accepted image")
+		}
+	})
+
+	t.Run("PNG is transcoded to JPEG", func(t *testing.T) {
+		img := &Image{Data: readFixture(t, "photo.png"), ContentType: constants.MimePNG}
+		got := convertToJPEG(t, img)
+		if got.Bounds() != image.Rect(0, 0, 16, 12) {
+			t.Errorf("transcoded bounds = %v, want 16x12", got.Bounds())
+		}
+	})
+
+	t.Run("animated GIF uses its first frame", func(t *testing.T) {
+		img := &Image{Data: readFixture(t, "animated.gif"), ContentType: constants.MimeGIF}
+		got := convertToJPEG(t, img)
+		// The first frame is red, the second blue
+		r, _, b, _ := got.At(8, 6).RGBA()
+		if r < 0xc000 || b > 0x4000 {
+			t.Errorf("transcoded pixel is not red: r=%#x b=%#x", r, b)
+		}
+	})
+
+	t.Run("undecodable WebP", func(t *testing.T) {
+		// Without a WebP decoder, WebP images can only be passed through
+		img := &Image{Data: readFixture(t, "pixel.webp"), ContentType: constants.MimeWEBP}
+		if _, err := img.ConvertTo(constants.MimeJPEG); !errors.Is(err, ErrImageUnavailable) {
+			t.Errorf("ConvertTo error = %v, want ErrImageUnavailable", err)
+		}
+	})
+
+	t.Run("truncated HEIC", func(t *testing.T) {
+		img := &Image{Data: readFixture(t, "header.heic"), ContentType: constants.MimeHEIC}
+		if _, err := img.ConvertTo(StandardImageTypes...); !errors.Is(err, ErrImageUnavailable) {
+			t.Errorf("ConvertTo error = %v, want ErrImageUnavailable", err)
+		}
+	})
+}
+
+// convertToJPEG converts img to a JPEG, the only accepted type, and
+// decodes the result
+func convertToJPEG(t *testing.T, img *Image) image.Image {
+	t.Helper()
+	converted, err := img.ConvertTo(constants.MimeJPEG)
+	if err != nil {
+		t.Fatalf("ConvertTo: %v", err)
+	}
+	if converted.ContentType != constants.MimeJPEG || DetectImageType(converted.Data) != constants.MimeJPEG {
+		t.Fatalf("ConvertTo returned %s data labelled %s", DetectImageType(converted.Data), converted.ContentType)
+	}
+	decoded, err := jpeg.Decode(bytes.NewReader(converted.Data))
+	if err != nil {
+		t.Fatalf("failed to decode transcoded JPEG: %v", err)
+	}
+	return decoded
+}
@@ -0,0 +1,199 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrQueueFull is returned by Limiter.Acquire when every generation slot is
+// busy and the queue waiting for one is full
+var ErrQueueFull = errors.New("too many AI generations are in progress")
+
+// QueueFullError reports the limiter's state when a generation was refused
+type QueueFullError struct {
+	LimiterStats
+}
+
+func (e *QueueFullError) Error() string {
+	return fmt.Sprintf("%s (%d running, %d queued)", ErrQueueFull, e.Active, e.Queued)
+}
+
+func (e *QueueFullError) Unwrap() error { return ErrQueueFull }
+
+// LimiterStats describes the generations a Limiter is running and queueing
+type LimiterStats struct {
+	Active        int `json:"active"`
+	Queued        int `json:"queued"`
+	MaxConcurrent int `json:"max_concurrent"`
+	MaxQueued     int `json:"max_queued"`
+}
+
+// Limiter caps the number of AI generations running at once, so several
+// users generating titles together don't overload the backend (e.g. a GPU
+// running Ollama). Generations beyond the cap wait in a first-come,
+// first-served queue. It is safe for concurrent use; a nil Limiter doesn't
+// limit generations.
+type Limiter struct {
+	maxConcurrent int
+	maxQueued     int
+
+	mu     sync.Mutex
+	active int
+	queue  []*limiterWaiter
+}
+
+// limiterWaiter is a generation waiting for a slot
+type limiterWaiter struct {
+	// ready is closed when the waiter is handed a slot
+	ready chan struct{}
+	// moved is signalled when the waiter moves up the queue
+	moved chan struct{}
+}
+
+// NewLimiter creates a Limiter running at most maxConcurrent generations
+// at once, with at most maxQueued waiting. It returns nil, which doesn't
+// limit generations, if maxConcurrent isn't positive.
+func NewLimiter(maxConcurrent, maxQueued int) *Limiter {
+	if maxConcurrent <= 0 {
+		return nil
+	}
+	return &Limiter{maxConcurrent: maxConcurrent, maxQueued: max(maxQueued, 0)}
+}
+
+// Acquire waits for a generation slot, returning a function that releases
+// it. If the queue is full it returns a *QueueFullError right away. While
+// waiting, onQueued, if not nil, is called with the generation's position
+// in the queue (1 is next) whenever it changes.
+func (l *Limiter) Acquire(ctx context.Context, onQueued func(position int)) (release func(), err error) {
+	return l.acquire(ctx, true, onQueued)
+}
+
+// Wait waits for a generation slot like Acquire, but joins the queue even
+// when it is full, for background work that should wait its turn rather
+// than fail
+func (l *Limiter) Wait(ctx context.Context) (release func(), err error) {
+	return l.acquire(ctx, false, nil)
+}
+
+func (l *Limiter) acquire(ctx context.Context, bounded bool, onQueued func(position int)) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+	if l.active < l.maxConcurrent && len(l.queue) == 0 {
+		l.active++
+		l.mu.Unlock()
+		return l.releaser(), nil
+	}
+	if bounded && len(l.queue) >= l.maxQueued {
+		stats := l.statsLocked()
+		l.mu.Unlock()
+		return nil, &QueueFullError{LimiterStats: stats}
+	}
+	w := &limiterWaiter{ready: make(chan struct{}), moved: make(chan struct{}, 1)}
+	l.queue = append(l.queue, w)
+	position := len(l.queue)
+	l.mu.Unlock()
+
+	for {
+		// A waiter handed a slot while moving up has position 0
+		if onQueued != nil && position > 0 {
+			onQueued(position)
+		}
+		select {
+		case <-w.ready:
+			return l.releaser(), nil
+		case <-w.moved:
+			l.mu.Lock()
+			position = l.positionLocked(w)
+			l.mu.Unlock()
+		case <-ctx.Done():
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			if l.positionLocked(w) == 0 {
+				// Handed a slot as the context ended; pass it on
+				l.releaseLocked()
+			} else {
+				l.removeLocked(w)
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// releaser returns a function releasing a slot once, however often it's
+// called
+func (l *Limiter) releaser() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.releaseLocked()
+		})
+	}
+}
+
+// releaseLocked hands a finished generation's slot to the first waiter, if
+// any
+func (l *Limiter) releaseLocked() {
+	if len(l.queue) == 0 {
+		l.active--
+		return
+	}
+	next := l.queue[0]
+	l.queue = l.queue[1:]
+	close(next.ready)
+	l.notifyMovedLocked(0)
+}
+
+// removeLocked takes a waiter that gave up out of the queue
+func (l *Limiter) removeLocked(w *limiterWaiter) {
+	for i, queued := range l.queue {
+		if queued == w {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			l.notifyMovedLocked(i)
+			return
+		}
+	}
+}
+
+// notifyMovedLocked tells the waiters from index from onward that they
+// moved up the queue
+func (l *Limiter) notifyMovedLocked(from int) {
+	for _, w := range l.queue[from:] {
+		select {
+		case w.moved <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// positionLocked returns w's position in the queue, or 0 if it isn't queued
+func (l *Limiter) positionLocked(w *limiterWaiter) int {
+	for i, queued := range l.queue {
+		if queued == w {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// Stats returns the number of generations running and waiting
+func (l *Limiter) Stats() LimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.statsLocked()
+}
+
+func (l *Limiter) statsLocked() LimiterStats {
+	return LimiterStats{
+		Active:        l.active,
+		Queued:        len(l.queue),
+		MaxConcurrent: l.maxConcurrent,
+		MaxQueued:     l.maxQueued,
+	}
+}
@@ -0,0 +1,84 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// localFetcher reads images from a directory size variants' short paths
+// are relative to, such as Lychee's public/uploads
+type localFetcher struct {
+	dir string
+}
+
+// NewLocalFetcher creates a Fetcher reading images from dir, the directory
+// size variants' short paths are relative to (Lychee's public/uploads for
+// its local disk). Missing files are reported with fs.ErrNotExist, so they
+// are downloaded instead.
+func NewLocalFetcher(dir string) (Fetcher, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid uploads directory %s: %w", dir, err)
+	}
+	return &localFetcher{dir: abs}, nil
+}
+
+func (f *localFetcher) Fetch(ctx context.Context, ref ImageRef) (*Image, error) {
+	path, err := f.path(ref)
+	if err != nil {
+		return nil, err
+	}
+	return readLocalImage(ctx, path)
+}
+
+// path returns the file holding an image
+func (f *localFetcher) path(ref ImageRef) (string, error) {
+	if ref.ShortPath == "" {
+		return "", errNoShortPath
+	}
+	// Short paths come from Lychee's database, but never read outside the
+	// uploads directory regardless
+	rel := filepath.FromSlash(ref.ShortPath)
+	if !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("refusing to read image outside the uploads directory: %s", ref.ShortPath)
+	}
+	return filepath.Join(f.dir, rel), nil
+}
+
+// readLocalImage reads and validates an image from disk as fetchImage does
+// for downloads. Errors for missing files wrap fs.ErrNotExist.
+func readLocalImage(ctx context.Context, path string) (*Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxImageDownloadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("image file %s is empty", path)
+	}
+
+	contentType := DetectImageType(data)
+	if contentType == "" && isVideo(data) {
+		// Prefixing the path stops ffmpeg from taking a colon in it for a
+		// protocol
+		return extractFrame(ctx, "file:"+path, fileProtocols, nil)
+	}
+	if contentType == "" {
+		return nil, fmt.Errorf("invalid image format or corrupted data in %s", path)
+	}
+	if len(data) > maxImageDownloadSize {
+		return nil, fmt.Errorf("image %s is larger than %d bytes", path, maxImageDownloadSize)
+	}
+
+	log.Printf("Read image from uploads directory: %d bytes, %s, %s", len(data), contentType, path)
+	return &Image{Data: data, ContentType: contentType}, nil
+}
@@ -1,6 +1,7 @@
 package ai
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/base64"
@@ -9,15 +10,20 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"sort"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/cdzombak/lychee-meta-tool/backend/constants"
 )
 
 const (
-	DefaultModel = "gpt-4o"
-	SystemPrompt = "You are a professional photo curator. Provide concise, eloquent titles for artistic photographs. The title should be just a few words, never more than 10 words. You MUST provide only the title as your response, nothing else."
-	UserPrompt   = "Provide a title for this photograph. The title should be eloquent and concise, suitable for an artistic photograph but not pretentious. The title should be just a few words at most; shorter is usually better. You MUST provide _only_ the title as your response."
+	BackendOpenAI    = "openai"
+	DefaultModel     = "gpt-4o"
+	DefaultMaxTokens = 50
+	SystemPrompt     = "You are a professional photo curator. Provide concise, eloquent titles for artistic photographs. The title should be just a few words, never more than 10 words. You MUST provide only the title as your response, nothing else."
+	UserPrompt       = "Provide a title for this photograph. The title should be eloquent and concise, suitable for an artistic photograph but not pretentious. The title should be just a few words at most; shorter is usually better. You MUST provide _only_ the title as your response."
 )
 
 type OpenAIClient struct {
@@ -25,39 +31,168 @@ type OpenAIClient struct {
 	apiKey string
 	model  string
 	client *http.Client
+
+	maxTokens  int
+	titleWords int
+
+	// compatible is set for servers that merely mimic OpenAI's API, such as
+	// llama.cpp and LM Studio: the API key and model are optional, and
+	// baseURL is where the /models endpoint is found
+	compatible bool
+	baseURL    string
+
+	// structuredOutput is the structured output mode; in auto mode,
+	// structuredUnsupported is set once the server rejects response_format
+	structuredOutput      string
+	structuredUnsupported atomic.Bool
 }
 
 type openAIRequest struct {
-	Model    string          `json:"model"`
-	Messages []openAIMessage `json:"messages"`
-	MaxTokens int            `json:"max_tokens"`
+	Model     string          `json:"model,omitempty"`
+	Messages  []openAIMessage `json:"messages"`
+	MaxTokens int             `json:"max_tokens"`
+	Stream    bool            `json:"stream,omitempty"`
+	// StreamOptions asks for token usage in the last chunk of a stream
+	StreamOptions *openAIStreamOptions `json:"stream_options,omitempty"`
+	// ResponseFormat asks for a structured JSON reply
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+}
+
+type openAIResponseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *jsonSchema `json:"json_schema,omitempty"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// openAIUsage is the token usage reported in a response
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// report adds the usage to the usage tracked for ctx
+func (u *openAIUsage) report(ctx context.Context) {
+	if u != nil {
+		ReportUsage(ctx, Usage{InputTokens: u.PromptTokens, OutputTokens: u.CompletionTokens})
+	}
 }
 
 type openAIMessage struct {
-	Role    string                   `json:"role"`
-	Content []openAIMessageContent   `json:"content"`
+	Role    string                 `json:"role"`
+	Content []openAIMessageContent `json:"content"`
 }
 
 type openAIMessageContent struct {
-	Type     string                  `json:"type"`
-	Text     string                  `json:"text,omitempty"`
-	ImageURL *openAIImageURL         `json:"image_url,omitempty"`
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
 }
 
 type openAIImageURL struct {
-	URL    string `json:"url"`
+	URL string `json:"url"`
 }
 
 type openAIResponse struct {
 	Choices []struct {
 		Message struct {
-			Content string `json:"content"`
+			Content openAIContent `json:"content"`
 		} `json:"message"`
+		// Text is the reply of servers answering in the legacy completions
+		// format
+		Text string `json:"text"`
 	} `json:"choices"`
-	Error *struct {
-		Message string `json:"message"`
-		Type    string `json:"type"`
-	} `json:"error,omitempty"`
+	Usage *openAIUsage `json:"usage,omitempty"`
+	Error *openAIError `json:"error,omitempty"`
+}
+
+// openAIStreamChunk is one event of a streamed response
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content openAIContent `json:"content"`
+		} `json:"delta"`
+		Text string `json:"text"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage,omitempty"`
+	Error *openAIError `json:"error,omitempty"`
+}
+
+// openAIContent is a message's text. OpenAI sends a string; some
+// compatible servers send an array of content parts, or null.
+type openAIContent string
+
+func (c *openAIContent) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*c = openAIContent(s)
+		return nil
+	}
+	var parts []openAIMessageContent
+	if err := json.Unmarshal(data, &parts); err != nil {
+		// null, or something without text
+		*c = ""
+		return nil
+	}
+	var b strings.Builder
+	for _, part := range parts {
+		b.WriteString(part.Text)
+	}
+	*c = openAIContent(b.String())
+	return nil
+}
+
+// openAIError is an error reported in a response body. OpenAI sends an
+// object; some compatible servers send just the message.
+type openAIError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
+
+func (e *openAIError) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		e.Message = s
+		return nil
+	}
+	type plain openAIError
+	return json.Unmarshal(data, (*plain)(e))
+}
+
+func (e *openAIError) Error() string {
+	if e.Type == "" {
+		return "API error: " + e.Message
+	}
+	return fmt.Sprintf("API error: %s (%s)", e.Message, e.Type)
+}
+
+func init() {
+	Register(BackendOpenAI, func(settings Settings) (Client, error) {
+		client, err := NewOpenAIClient(settings["url"], settings["api_key"], settings.Get("model", DefaultModel))
+		if err != nil {
+			return nil, err
+		}
+		if err := client.configure(settings); err != nil {
+			return nil, err
+		}
+		return client, nil
+	})
+}
+
+// configure applies the settings shared by the openai and
+// openai_compatible backends
+func (c *OpenAIClient) configure(settings Settings) error {
+	var err error
+	if c.maxTokens, err = settings.Int("max_tokens", DefaultMaxTokens); err != nil {
+		return err
+	}
+	if c.titleWords, err = settings.Int("title_words", 0); err != nil {
+		return err
+	}
+	c.structuredOutput = settings.Get("structured_output", StructuredOutputAuto)
+	return ValidateStructuredOutput(c.structuredOutput)
 }
 
 func NewOpenAIClient(apiURL, apiKey, model string) (*OpenAIClient, error) {
@@ -72,32 +207,290 @@ func NewOpenAIClient(apiURL, apiKey, model string) (*OpenAIClient, error) {
 	}
 
 	client := &http.Client{
-		Timeout: constants.OllamaClientTimeout,
+		Timeout:   constants.OllamaClientTimeout,
+		Transport: Transport,
 	}
 
 	log.Printf("OpenAI client configured with URL: %s, Model: %s", apiURL, model)
 
 	return &OpenAIClient{
-		apiURL: apiURL,
-		apiKey: apiKey,
-		model:  model,
-		client: client,
+		apiURL:           apiURL,
+		apiKey:           apiKey,
+		model:            model,
+		client:           client,
+		maxTokens:        DefaultMaxTokens,
+		structuredOutput: StructuredOutputAuto,
 	}, nil
 }
 
-func (c *OpenAIClient) GenerateTitle(ctx context.Context, imageURL string) (string, error) {
-	if imageURL == "" {
-		return "", fmt.Errorf("image URL cannot be empty")
+var (
+	_ TagGenerator     = (*OpenAIClient)(nil)
+	_ AltTextGenerator = (*OpenAIClient)(nil)
+	_ TitleStreamer    = (*OpenAIClient)(nil)
+	_ ModelLister      = (*OpenAIClient)(nil)
+)
+
+func (c *OpenAIClient) GenerateTitle(ctx context.Context, imageURL string, opts TitleOptions) (string, error) {
+	if opts.Words == 0 {
+		opts.Words = c.titleWords
+	}
+
+	content, structured, err := c.complete(ctx, imageURL, SystemPrompt, UserPrompt+opts.PromptSuffix(), c.maxTokens, titleSchema)
+	if err != nil {
+		return "", err
+	}
+
+	title := parseTitleReply(content, structured)
+	if title == "" {
+		return "", fmt.Errorf("received empty title")
+	}
+
+	log.Printf("Successfully generated title: %s", title)
+	return title, nil
+}
+
+// GenerateTags suggests keyword tags for an image
+func (c *OpenAIClient) GenerateTags(ctx context.Context, imageURL string, opts TagOptions) ([]string, error) {
+	content, structured, err := c.complete(ctx, imageURL, TagSystemPrompt, opts.Prompt(), max(c.maxTokens, opts.MaxTokens()), tagsSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := parseTagsReply(content, structured, opts)
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("received no tags")
+	}
+
+	log.Printf("Successfully generated %d tags", len(tags))
+	return tags, nil
+}
+
+// GenerateAltText describes an image for screen readers
+func (c *OpenAIClient) GenerateAltText(ctx context.Context, imageURL string, opts AltTextOptions) (string, error) {
+	content, structured, err := c.complete(ctx, imageURL, AltTextSystemPrompt, opts.Prompt(), max(c.maxTokens, altTextTokens), altTextSchema)
+	if err != nil {
+		return "", err
+	}
+
+	altText := parseAltTextReply(content, structured)
+	if altText == "" {
+		return "", fmt.Errorf("received empty alt text")
+	}
+
+	log.Printf("Successfully generated alt text: %s", altText)
+	return altText, nil
+}
+
+// StreamTitle generates a title like GenerateTitle, calling onToken with
+// each piece of the reply as the endpoint streams it
+func (c *OpenAIClient) StreamTitle(ctx context.Context, imageURL string, opts TitleOptions, onToken func(string)) (string, error) {
+	if opts.Words == 0 {
+		opts.Words = c.titleWords
+	}
+
+	log.Printf("Streaming request to OpenAI-style endpoint for image: %s", imageURL)
+	resp, structured, err := c.send(ctx, imageURL, SystemPrompt, UserPrompt+opts.PromptSuffix(), c.maxTokens, true, titleSchema)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	// The reply is a series of server-sent events, each carrying a chunk
+	// with the next piece of content, terminated by "[DONE]". A structured
+	// reply's title is passed on as it streams, without the JSON around it.
+	var content strings.Builder
+	var streamer titleStreamer
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return "", fmt.Errorf("failed to parse stream chunk: %w", err)
+		}
+		if chunk.Error != nil {
+			return "", VisionError(c.model, chunk.Error)
+		}
+		chunk.Usage.report(ctx)
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		piece := string(chunk.Choices[0].Delta.Content)
+		if piece == "" {
+			piece = chunk.Choices[0].Text
+		}
+		if piece == "" {
+			continue
+		}
+		content.WriteString(piece)
+		if text := streamer.feed(piece, structured); text != "" {
+			onToken(text)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	title := parseTitleReply(stripReasoning(content.String()), structured)
+	if title == "" {
+		return "", fmt.Errorf("received empty title")
+	}
+
+	log.Printf("Successfully generated title: %s", title)
+	return title, nil
+}
+
+// complete sends an image with the given prompts and returns the model's
+// trimmed reply, and whether a structured reply matching schema was
+// requested
+func (c *OpenAIClient) complete(ctx context.Context, imageURL, systemPrompt, userPrompt string, maxTokens int, schema *jsonSchema) (string, bool, error) {
+	log.Printf("Sending request to OpenAI-style endpoint for image: %s", imageURL)
+	resp, structured, err := c.send(ctx, imageURL, systemPrompt, userPrompt, maxTokens, false, schema)
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var apiResp openAIResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return "", false, fmt.Errorf("failed to parse response: %w", err)
+	}
+	apiResp.Usage.report(ctx)
+
+	if apiResp.Error != nil {
+		return "", false, VisionError(c.model, apiResp.Error)
+	}
+
+	if len(apiResp.Choices) == 0 {
+		return "", false, fmt.Errorf("no choices in response")
+	}
+
+	content := string(apiResp.Choices[0].Message.Content)
+	if content == "" {
+		content = apiResp.Choices[0].Text
 	}
+	content = strings.TrimSpace(stripReasoning(content))
+	if content == "" {
+		return "", false, fmt.Errorf("received empty response")
+	}
+
+	return content, structured, nil
+}
+
+// send sends a chat completion request for an image with the given prompts,
+// returning the successful response and whether a structured reply
+// matching schema was requested. In auto mode, a request the server rejects
+// for its response_format is retried as free text, and later requests are
+// sent as free text.
+func (c *OpenAIClient) send(ctx context.Context, imageURL, systemPrompt, userPrompt string, maxTokens int, stream bool, schema *jsonSchema) (*http.Response, bool, error) {
+	dataURI, err := imageDataURI(ctx, imageURL)
+	if err != nil {
+		return nil, false, err
+	}
+
+	format := c.responseFormat(schema)
+	for {
+		structured := format != nil
+		prompt, tokens := userPrompt, maxTokens
+		if structured {
+			prompt += structuredPromptSuffix(schema)
+			tokens += structuredTokenOverhead
+		}
+		req, err := c.newRequest(ctx, dataURI, systemPrompt, prompt, tokens, stream, format)
+		if err != nil {
+			return nil, false, err
+		}
+
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to send request: %w", err)
+		}
+		if resp.StatusCode == http.StatusOK {
+			return resp, structured, nil
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if structured && c.structuredOutput == StructuredOutputAuto && rejectsResponseFormat(resp.StatusCode, body) {
+			log.Printf("OpenAI-style endpoint does not support structured output; using free text replies: %s", string(body))
+			c.structuredUnsupported.Store(true)
+			format = nil
+			continue
+		}
+		log.Printf("OpenAI API error (HTTP %d): %s", resp.StatusCode, string(body))
+		return nil, false, VisionError(c.model, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body)))
+	}
+}
+
+// responseFormat returns the response format requesting a reply matching
+// schema in the configured mode, or nil for a free text reply
+func (c *OpenAIClient) responseFormat(schema *jsonSchema) *openAIResponseFormat {
+	switch {
+	case c.structuredOutput == StructuredOutputOff:
+		return nil
+	case c.structuredOutput == StructuredOutputAuto && c.structuredUnsupported.Load():
+		return nil
+	case c.structuredOutput == StructuredOutputJSONObject:
+		return &openAIResponseFormat{Type: "json_object"}
+	default:
+		return &openAIResponseFormat{Type: "json_schema", JSONSchema: schema}
+	}
+}
+
+// structuredPromptSuffix returns the prompt suffix asking for a reply
+// matching schema
+func structuredPromptSuffix(schema *jsonSchema) string {
+	switch schema {
+	case tagsSchema:
+		return tagsJSONPrompt
+	case altTextSchema:
+		return altTextJSONPrompt
+	default:
+		return titleJSONPrompt
+	}
+}
+
+// rejectsResponseFormat reports whether an error response is the server
+// rejecting a request's response_format
+func rejectsResponseFormat(status int, body []byte) bool {
+	if status != http.StatusBadRequest && status != http.StatusUnprocessableEntity {
+		return false
+	}
+	lower := strings.ToLower(string(body))
+	return strings.Contains(lower, "response_format") || strings.Contains(lower, "json_schema")
+}
 
-	imageData, contentType, err := downloadImage(ctx, imageURL)
+// imageDataURI fetches an image and returns it as a data URI in a format
+// vision models accept
+func imageDataURI(ctx context.Context, imageURL string) (string, error) {
+	img, err := FetchImage(ctx, imageURL)
 	if err != nil {
 		return "", fmt.Errorf("failed to download image: %w", err)
 	}
+	img, err = img.ConvertTo(StandardImageTypes...)
+	if err != nil {
+		return "", fmt.Errorf("failed to prepare image: %w", err)
+	}
 
-	base64Image := base64.StdEncoding.EncodeToString(imageData)
-	dataURI := fmt.Sprintf("data:%s;base64,%s", contentType, base64Image)
+	base64Image := base64.StdEncoding.EncodeToString(img.Data)
+	return fmt.Sprintf("data:%s;base64,%s", img.ContentType, base64Image), nil
+}
 
+// newRequest builds a chat completion request for an image, as a data URI,
+// with the given prompts
+func (c *OpenAIClient) newRequest(ctx context.Context, dataURI, systemPrompt, userPrompt string, maxTokens int, stream bool, format *openAIResponseFormat) (*http.Request, error) {
 	reqBody := openAIRequest{
 		Model: c.model,
 		Messages: []openAIMessage{
@@ -106,7 +499,7 @@ func (c *OpenAIClient) GenerateTitle(ctx context.Context, imageURL string) (stri
 				Content: []openAIMessageContent{
 					{
 						Type: "text",
-						Text: SystemPrompt,
+						Text: systemPrompt,
 					},
 				},
 			},
@@ -115,7 +508,7 @@ func (c *OpenAIClient) GenerateTitle(ctx context.Context, imageURL string) (stri
 				Content: []openAIMessageContent{
 					{
 						Type: "text",
-						Text: UserPrompt,
+						Text: userPrompt,
 					},
 					{
 						Type: "image_url",
@@ -126,109 +519,96 @@ func (c *OpenAIClient) GenerateTitle(ctx context.Context, imageURL string) (stri
 				},
 			},
 		},
-		MaxTokens: 50,
+		MaxTokens:      maxTokens,
+		Stream:         stream,
+		ResponseFormat: format,
+	}
+	// Not every compatible server accepts stream_options
+	if stream && !c.compatible {
+		reqBody.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
 	}
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, "POST", c.apiURL, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
-
-	log.Printf("Sending request to OpenAI-style endpoint for image: %s", imageURL)
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
+	c.authorize(req)
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("OpenAI API error (HTTP %d): %s", resp.StatusCode, string(body))
-		return "", fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
-	}
+	return req, nil
+}
 
-	var apiResp openAIResponse
-	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+// authorize adds the API key to a request, if there is one
+func (c *OpenAIClient) authorize(req *http.Request) {
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
 	}
+}
 
-	if apiResp.Error != nil {
-		return "", fmt.Errorf("API error: %s (%s)", apiResp.Error.Message, apiResp.Error.Type)
-	}
+// openAIModelsResponse is the response of an OpenAI-style /models endpoint
+type openAIModelsResponse struct {
+	Data []struct {
+		ID      string `json:"id"`
+		Created int64  `json:"created"`
+	} `json:"data"`
+}
 
-	if len(apiResp.Choices) == 0 {
-		return "", fmt.Errorf("no choices in response")
+// ListModels lists the models offered by the server, from the /models
+// endpoint alongside the configured /chat/completions endpoint
+func (c *OpenAIClient) ListModels(ctx context.Context) (ModelList, error) {
+	base, ok := c.baseURL, c.baseURL != ""
+	if !ok {
+		base, ok = strings.CutSuffix(strings.TrimSuffix(c.apiURL, "/"), "/chat/completions")
 	}
-
-	title := strings.TrimSpace(apiResp.Choices[0].Message.Content)
-	title = strings.Trim(title, `"'`)
-
-	if title == "" {
-		return "", fmt.Errorf("received empty title")
+	if !ok {
+		return ModelList{}, fmt.Errorf("cannot find the models endpoint for %s", c.apiURL)
 	}
 
-	log.Printf("Successfully generated title: %s", title)
-	return title, nil
-}
-
-func downloadImage(ctx context.Context, imageURL string) ([]byte, string, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/models", nil)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to create request: %w", err)
+		return ModelList{}, fmt.Errorf("failed to create request: %w", err)
 	}
+	c.authorize(req)
 
-	client := http.Client{Timeout: constants.ImageDownloadTimeout}
-	resp, err := client.Do(req)
+	resp, err := c.client.Do(req)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to download: %w", err)
+		return ModelList{}, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return ModelList{}, fmt.Errorf("models request failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
-	contentType := resp.Header.Get("Content-Type")
-	log.Printf("Downloaded image: Content-Type=%s, Status=%d, URL=%s", contentType, resp.StatusCode, imageURL)
-
-	validTypes := []string{constants.MimeJPEG, "image/jpg", constants.MimePNG, constants.MimeWEBP, constants.MimeGIF}
-	valid := false
-	contentTypeLower := strings.ToLower(contentType)
-	for _, validType := range validTypes {
-		if strings.Contains(contentTypeLower, validType) {
-			valid = true
-			break
-		}
-	}
-	if !valid {
-		return nil, "", fmt.Errorf("unsupported image type: %s", contentType)
+	var apiResp openAIModelsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return ModelList{}, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	imageData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to read image data: %w", err)
-	}
-
-	if len(imageData) == 0 {
-		return nil, "", fmt.Errorf("received empty image data")
+	list := ModelList{Configured: c.model, Models: make([]ModelInfo, 0, len(apiResp.Data))}
+	for _, m := range apiResp.Data {
+		info := ModelInfo{Name: m.ID}
+		if m.Created > 0 {
+			created := time.Unix(m.Created, 0).UTC()
+			info.ModifiedAt = &created
+		}
+		list.Models = append(list.Models, info)
+		if m.ID == c.model {
+			list.Installed = true
+		}
 	}
-
-	if len(imageData) > constants.MaxImageSize {
-		log.Printf("Warning: Large image detected (%d bytes), may cause performance issues", len(imageData))
+	// Without a configured model, compatible servers use whichever model
+	// they have loaded
+	if c.model == "" {
+		list.Installed = len(list.Models) > 0
 	}
-
-	log.Printf("Image validation successful: %d bytes", len(imageData))
-	return imageData, contentType, nil
+	sort.Slice(list.Models, func(i, j int) bool { return list.Models[i].Name < list.Models[j].Name })
+	return list, nil
 }
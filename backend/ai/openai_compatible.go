@@ -0,0 +1,73 @@
+package ai
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+)
+
+const (
+	// BackendOpenAICompatible is a local server mimicking OpenAI's API,
+	// such as llama.cpp's llama-server or LM Studio
+	BackendOpenAICompatible = "openai_compatible"
+	// DefaultCompatiblePath is the chat completions endpoint's path below
+	// the server's base URL
+	DefaultCompatiblePath = "/chat/completions"
+)
+
+func init() {
+	Register(BackendOpenAICompatible, func(settings Settings) (Client, error) {
+		client, err := NewOpenAICompatibleClient(settings["url"], settings.Get("path", DefaultCompatiblePath), settings["api_key"], settings["model"])
+		if err != nil {
+			return nil, err
+		}
+		if err := client.configure(settings); err != nil {
+			return nil, err
+		}
+		return client, nil
+	})
+}
+
+// NewOpenAICompatibleClient creates a client for a server offering an
+// OpenAI-style chat completions API at baseURL (e.g.
+// http://localhost:1234/v1 for LM Studio, or http://localhost:8080/v1 for
+// llama-server) plus path. apiKey is sent only if set, and model may be
+// empty to use the model the server has loaded.
+func NewOpenAICompatibleClient(baseURL, path, apiKey, model string) (*OpenAIClient, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("server URL is required")
+	}
+	parsed, err := url.Parse(baseURL)
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid server URL %q", baseURL)
+	}
+	if path == "" {
+		path = DefaultCompatiblePath
+	}
+
+	// Accept the full endpoint URL too, as the openai backend expects
+	base := strings.TrimSuffix(baseURL, "/")
+	path = "/" + strings.TrimPrefix(path, "/")
+	base = strings.TrimSuffix(base, path)
+
+	modelName := model
+	if modelName == "" {
+		modelName = "(server default)"
+	}
+	log.Printf("OpenAI-compatible client configured with URL: %s, Model: %s", base+path, modelName)
+
+	return &OpenAIClient{
+		apiURL:           base + path,
+		apiKey:           apiKey,
+		model:            model,
+		client:           &http.Client{Timeout: constants.OllamaClientTimeout, Transport: Transport},
+		maxTokens:        DefaultMaxTokens,
+		structuredOutput: StructuredOutputAuto,
+		compatible:       true,
+		baseURL:          base,
+	}, nil
+}
@@ -0,0 +1,103 @@
+package ai
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Settings holds backend-specific configuration values, keyed by setting name
+// (e.g. "url", "api_key", "model").
+type Settings map[string]string
+
+// Get returns the named setting, or fallback if it is unset or empty.
+func (s Settings) Get(key, fallback string) string {
+	if v, ok := s[key]; ok && v != "" {
+		return v
+	}
+	return fallback
+}
+
+// Int returns the named setting as a non-negative integer, or fallback if it
+// is unset, empty or zero.
+func (s Settings) Int(key string, fallback int) (int, error) {
+	v, ok := s[key]
+	if !ok || v == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("setting %s must be a non-negative integer, got %q", key, v)
+	}
+	if n == 0 {
+		return fallback, nil
+	}
+	return n, nil
+}
+
+// Float returns the named setting as a number, or fallback if it is unset or
+// empty. Unlike Int, zero is a valid value.
+func (s Settings) Float(key string, fallback float64) (float64, error) {
+	v, ok := s[key]
+	if !ok || v == "" {
+		return fallback, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("setting %s must be a non-negative number, got %q", key, v)
+	}
+	return f, nil
+}
+
+// Factory constructs a Client from backend-specific settings.
+type Factory func(settings Settings) (Client, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+)
+
+// Register makes an AI backend available under the given name.
+// It is intended to be called from a backend package's init function,
+// and panics if the name is empty or already registered.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if name == "" {
+		panic("ai: Register called with empty backend name")
+	}
+	if factory == nil {
+		panic("ai: Register factory is nil for backend " + name)
+	}
+	if _, exists := registry[name]; exists {
+		panic("ai: Register called twice for backend " + name)
+	}
+	registry[name] = factory
+}
+
+// New constructs a Client for the named backend.
+func New(name string, settings Settings) (Client, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown AI backend %q (available: %v)", name, Backends())
+	}
+	return factory(settings)
+}
+
+// Backends returns the sorted names of all registered backends.
+func Backends() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,167 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+)
+
+// s3Service is the service name S3 requests are signed for
+const s3Service = "s3"
+
+// S3Options configures an S3 fetcher
+type S3Options struct {
+	Bucket string
+	Region string
+	// Endpoint overrides AWS's endpoint for S3-compatible storage such as
+	// MinIO; objects are then addressed path-style
+	Endpoint string
+	// Prefix is prepended to short paths to form object keys, as Lychee's
+	// s3 disk root
+	Prefix string
+	// Credentials not set here are read from the standard AWS environment
+	// variables; with none at all, requests are sent unsigned, for public
+	// buckets
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// s3Fetcher reads images from an S3 bucket, such as one Lychee's s3 storage
+// disk uses
+type s3Fetcher struct {
+	baseURL string
+	prefix  string
+	region  string
+	creds   awsCredentials
+	client  *http.Client
+}
+
+// NewS3Fetcher creates a Fetcher reading images from an S3 bucket by their
+// short paths
+func NewS3Fetcher(opts S3Options) (Fetcher, error) {
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is required")
+	}
+	region := opts.Region
+	if region == "" {
+		region = firstEnv("AWS_REGION", "AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		return nil, fmt.Errorf("S3 region is required")
+	}
+
+	baseURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com", opts.Bucket, region)
+	if opts.Endpoint != "" {
+		if parsed, err := url.Parse(opts.Endpoint); err != nil || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid S3 endpoint %q", opts.Endpoint)
+		}
+		baseURL = strings.TrimSuffix(opts.Endpoint, "/") + "/" + awsURIEncode(opts.Bucket, true)
+	}
+
+	creds := awsCredentials{
+		AccessKeyID:     opts.AccessKeyID,
+		SecretAccessKey: opts.SecretAccessKey,
+		SessionToken:    opts.SessionToken,
+	}
+	if creds.AccessKeyID == "" {
+		creds = awsCredentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+	}
+
+	log.Printf("S3 image storage configured with URL: %s", baseURL)
+
+	return &s3Fetcher{
+		baseURL: baseURL,
+		prefix:  strings.Trim(opts.Prefix, "/"),
+		region:  region,
+		creds:   creds,
+		client:  &http.Client{Timeout: constants.ImageDownloadTimeout, Transport: Transport},
+	}, nil
+}
+
+func (f *s3Fetcher) Fetch(ctx context.Context, ref ImageRef) (*Image, error) {
+	key, err := f.key(ref)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.do(ctx, http.MethodGet, key)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageDownloadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image data: %w", err)
+	}
+	if len(data) > maxImageDownloadSize {
+		return nil, fmt.Errorf("image is larger than %d bytes", maxImageDownloadSize)
+	}
+
+	contentType := DetectImageType(data)
+	if contentType == "" && isVideo(data) {
+		// ffmpeg can't sign its requests, so let the video be downloaded
+		// from its URL
+		return nil, fmt.Errorf("S3 object %s is a video: %w", key, errors.ErrUnsupported)
+	}
+	if contentType == "" {
+		return nil, fmt.Errorf("invalid image format or corrupted data in S3 object %s", key)
+	}
+
+	log.Printf("Read image from S3: %d bytes, %s, %s", len(data), contentType, key)
+	return &Image{Data: data, ContentType: contentType}, nil
+}
+
+// key returns the object key of an image
+func (f *s3Fetcher) key(ref ImageRef) (string, error) {
+	if ref.ShortPath == "" {
+		return "", errNoShortPath
+	}
+	key := strings.TrimPrefix(ref.ShortPath, "/")
+	if f.prefix != "" {
+		key = f.prefix + "/" + key
+	}
+	return key, nil
+}
+
+// do sends a request for an object, returning the response if it succeeded.
+// Missing objects are reported with os.ErrNotExist.
+func (f *s3Fetcher) do(ctx context.Context, method, key string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, f.baseURL+"/"+awsURIEncode(key, false), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if f.creds.AccessKeyID != "" {
+		signAWSRequest(req, nil, f.creds, f.region, s3Service, time.Now())
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach S3: %w", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, fmt.Errorf("S3 object %s: %w", key, os.ErrNotExist)
+	default:
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("S3 request for %s failed with status %d: %s", key, resp.StatusCode, string(body))
+	}
+}
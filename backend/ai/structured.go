@@ -0,0 +1,241 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Structured output modes for OpenAI-compatible backends, set with the
+// structured_output setting
+const (
+	// StructuredOutputAuto asks for a JSON schema response, falling back to
+	// free text if the server rejects response_format
+	StructuredOutputAuto = "auto"
+	// StructuredOutputJSONSchema always asks for a JSON schema response
+	StructuredOutputJSONSchema = "json_schema"
+	// StructuredOutputJSONObject asks for a JSON object without a schema,
+	// for servers that support JSON mode but not schemas
+	StructuredOutputJSONObject = "json_object"
+	// StructuredOutputOff asks for free text, as older servers expect
+	StructuredOutputOff = "off"
+)
+
+// StructuredOutputModes lists the valid structured output modes
+var StructuredOutputModes = []string{StructuredOutputAuto, StructuredOutputJSONSchema, StructuredOutputJSONObject, StructuredOutputOff}
+
+// structuredTokenOverhead is added to the completion budget of structured
+// requests to cover the JSON around the reply
+const structuredTokenOverhead = 20
+
+// Prompt suffixes asking for structured replies; JSON mode requires the
+// prompt to mention JSON
+const (
+	titleJSONPrompt   = ` Respond with a JSON object whose "title" field holds the title.`
+	tagsJSONPrompt    = ` Respond with a JSON object whose "tags" field holds the list of keywords.`
+	altTextJSONPrompt = ` Respond with a JSON object whose "alt_text" field holds the alt text.`
+)
+
+// jsonSchema is a named JSON schema for a structured reply
+type jsonSchema struct {
+	Name   string          `json:"name"`
+	Strict bool            `json:"strict"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+// Schemas of the structured replies to title and tag requests
+var (
+	titleSchema = &jsonSchema{
+		Name:   "photo_title",
+		Strict: true,
+		Schema: json.RawMessage(`{"type":"object","properties":{"title":{"type":"string","description":"The photo's title, without quotes"}},"required":["title"],"additionalProperties":false}`),
+	}
+	tagsSchema = &jsonSchema{
+		Name:   "photo_tags",
+		Strict: true,
+		Schema: json.RawMessage(`{"type":"object","properties":{"tags":{"type":"array","items":{"type":"string"},"description":"Keywords describing the photo"}},"required":["tags"],"additionalProperties":false}`),
+	}
+)
+
+// altTextSchema is the schema of the structured reply to alt text requests
+var altTextSchema = &jsonSchema{
+	Name:   "photo_alt_text",
+	Strict: true,
+	Schema: json.RawMessage(`{"type":"object","properties":{"alt_text":{"type":"string","description":"Factual description of the photo for screen readers"}},"required":["alt_text"],"additionalProperties":false}`),
+}
+
+// titleReply, tagsReply and altTextReply are the structured replies to
+// title, tag and alt text requests
+type titleReply struct {
+	Title string `json:"title"`
+}
+
+type tagsReply struct {
+	Tags []string `json:"tags"`
+}
+
+type altTextReply struct {
+	AltText string `json:"alt_text"`
+}
+
+// ValidateStructuredOutput checks a structured output mode; empty is auto
+func ValidateStructuredOutput(mode string) error {
+	if mode == "" {
+		return nil
+	}
+	for _, m := range StructuredOutputModes {
+		if mode == m {
+			return nil
+		}
+	}
+	return fmt.Errorf("structured_output must be one of %s, got %q", strings.Join(StructuredOutputModes, ", "), mode)
+}
+
+// cleanTitle trims the whitespace and quotes models often put around free
+// text titles
+func cleanTitle(content string) string {
+	return strings.Trim(strings.TrimSpace(content), `"'`)
+}
+
+// parseTitleReply returns the title from a reply, which is structured if
+// structured output was requested. Servers that ignore response_format
+// reply with free text, which is cleaned up as usual.
+func parseTitleReply(content string, structured bool) string {
+	if structured {
+		var reply titleReply
+		if err := json.Unmarshal([]byte(content), &reply); err == nil {
+			return strings.TrimSpace(reply.Title)
+		}
+	}
+	return cleanTitle(content)
+}
+
+// parseTagsReply returns the tags from a reply like parseTitleReply
+func parseTagsReply(content string, structured bool, opts TagOptions) []string {
+	if structured {
+		var reply tagsReply
+		if err := json.Unmarshal([]byte(content), &reply); err == nil {
+			return ParseTags(strings.Join(reply.Tags, "\n"), opts)
+		}
+	}
+	return ParseTags(content, opts)
+}
+
+// parseAltTextReply returns the alt text from a reply like parseTitleReply
+func parseAltTextReply(content string, structured bool) string {
+	if structured {
+		var reply altTextReply
+		if err := json.Unmarshal([]byte(content), &reply); err == nil {
+			return CleanAltText(reply.AltText)
+		}
+	}
+	return CleanAltText(content)
+}
+
+// titleValuePattern matches the start of a structured reply's title value
+var titleValuePattern = regexp.MustCompile(`"title"\s*:\s*"`)
+
+// Reasoning models served by llama.cpp, LM Studio and the like may think
+// aloud before replying, inside these tags
+const (
+	reasoningStart = "<think>"
+	reasoningEnd   = "</think>"
+)
+
+// stripReasoning removes a reasoning block from the start of a reply
+func stripReasoning(content string) string {
+	trimmed := strings.TrimLeft(content, " \t\r\n")
+	if !strings.HasPrefix(trimmed, reasoningStart) {
+		return content
+	}
+	_, reply, found := strings.Cut(trimmed, reasoningEnd)
+	if !found {
+		// Cut off while still reasoning
+		return ""
+	}
+	return reply
+}
+
+// titleStreamer extracts the title from a reply as it streams, so the
+// title rather than any reasoning or the JSON of a structured reply is
+// shown as it's written
+type titleStreamer struct {
+	content strings.Builder
+	// emitted is the length of the title passed on so far
+	emitted int
+}
+
+// feed adds a piece of the reply, which is structured if structured output
+// was requested, and returns the new text of the title, if any
+func (s *titleStreamer) feed(piece string, structured bool) string {
+	s.content.WriteString(piece)
+	content := s.content.String()
+	trimmed := strings.TrimLeft(content, " \t\r\n")
+	if strings.HasPrefix(trimmed, reasoningStart) || (trimmed != "" && strings.HasPrefix(reasoningStart, trimmed)) {
+		// Possibly the start of a reasoning block; hold back until it ends
+		if !strings.Contains(trimmed, reasoningEnd) {
+			return ""
+		}
+		trimmed = strings.TrimLeft(stripReasoning(trimmed), " \t\r\n")
+	}
+
+	var title string
+	switch {
+	case trimmed == "":
+		return ""
+	case structured && trimmed[0] == '{':
+		loc := titleValuePattern.FindStringIndex(trimmed)
+		if loc == nil {
+			return ""
+		}
+		title = decodeJSONStringPrefix(trimmed[loc[1]:])
+	default:
+		// Free text, including from servers ignoring response_format
+		title = trimmed
+	}
+	if len(title) <= s.emitted {
+		return ""
+	}
+	text := title[s.emitted:]
+	s.emitted = len(title)
+	return text
+}
+
+// decodeJSONStringPrefix decodes as much of the JSON string body raw (after
+// its opening quote) as has arrived, stopping at its closing quote or an
+// incomplete escape
+func decodeJSONStringPrefix(raw string) string {
+	end := 0
+loop:
+	for end < len(raw) {
+		switch raw[end] {
+		case '"':
+			break loop
+		case '\\':
+			if end+1 >= len(raw) {
+				break loop
+			}
+			n := 2
+			if raw[end+1] == 'u' {
+				n = 6
+				// Keep a high surrogate with the low one that follows it
+				if end+n <= len(raw) && strings.ContainsAny(raw[end+2:end+3], "dD") && strings.ContainsAny(raw[end+3:end+4], "89abAB") {
+					n = 12
+				}
+			}
+			if end+n > len(raw) {
+				break loop
+			}
+			end += n
+		default:
+			end++
+		}
+	}
+
+	var s string
+	if err := json.Unmarshal([]byte(`"`+raw[:end]+`"`), &s); err != nil {
+		return ""
+	}
+	return s
+}
@@ -0,0 +1,98 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultTagCount is the number of tags requested when TagOptions.Count is 0
+const DefaultTagCount = 8
+
+// MaxTagCount is the largest number of tags that may be requested
+const MaxTagCount = 25
+
+// maxTagLength bounds the length of a single tag, in characters; longer
+// items are sentences rather than keywords and are dropped
+const maxTagLength = 40
+
+// listMarkerPattern matches a bullet or number starting a list item
+var listMarkerPattern = regexp.MustCompile(`^\s*(?:[-*•#]+|\d+[.)])\s*`)
+
+// tagTokensPerTag is the completion budget allowed per requested tag
+const tagTokensPerTag = 8
+
+const (
+	TagSystemPrompt = "You are a professional photo curator who catalogs photographs with keywords. You MUST respond with only a comma-separated list of keywords, nothing else."
+	TagUserPrompt   = "List up to %d keywords for this photograph, covering its subject, setting, and notable details. Use lowercase single words or short phrases. You MUST provide _only_ the comma-separated keywords as your response."
+)
+
+// TagGenerator is implemented by clients that can suggest keyword tags for
+// a photo
+type TagGenerator interface {
+	GenerateTags(ctx context.Context, imageURL string, opts TagOptions) ([]string, error)
+}
+
+// TagOptions adjusts tag generation. The zero value requests
+// DefaultTagCount tags in the model's default language.
+type TagOptions struct {
+	// Count is the maximum number of tags to return
+	Count int
+	// Language is the language the tags should be written in
+	Language string
+}
+
+// count returns the number of tags to request
+func (o TagOptions) count() int {
+	if o.Count <= 0 {
+		return DefaultTagCount
+	}
+	return min(o.Count, MaxTagCount)
+}
+
+// Prompt returns the user prompt requesting tags
+func (o TagOptions) Prompt() string {
+	prompt := fmt.Sprintf(TagUserPrompt, o.count())
+	if o.Language != "" {
+		prompt += " Write the keywords in " + o.Language + "."
+	}
+	return prompt
+}
+
+// MaxTokens returns a completion budget large enough for the requested tags
+func (o TagOptions) MaxTokens() int {
+	return o.count() * tagTokensPerTag
+}
+
+// ParseTags extracts tags from a model's response, tolerating numbered or
+// bulleted lists as well as comma-separated ones. Duplicates (ignoring case)
+// and overlong items are dropped, and at most opts.Count tags are returned.
+func ParseTags(response string, opts TagOptions) []string {
+	fields := strings.FieldsFunc(response, func(r rune) bool {
+		return r == ',' || r == '\n' || r == ';'
+	})
+
+	seen := make(map[string]bool, len(fields))
+	var tags []string
+	for _, field := range fields {
+		tag := listMarkerPattern.ReplaceAllString(field, "")
+		tag = strings.Trim(tag, "\"'`. ")
+		tag = strings.Join(strings.Fields(tag), " ")
+		if tag == "" || utf8.RuneCountInString(tag) > maxTagLength {
+			continue
+		}
+
+		key := strings.ToLower(tag)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		tags = append(tags, tag)
+		if len(tags) == opts.count() {
+			break
+		}
+	}
+	return tags
+}
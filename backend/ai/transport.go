@@ -0,0 +1,51 @@
+package ai
+
+import (
+	"net/http"
+)
+
+// outboundUserAgent and outboundHeader are set by SetOutboundHeaders
+var (
+	outboundUserAgent string
+	outboundHeader    http.Header
+)
+
+// SetOutboundHeaders sets the User-Agent and extra headers sent with every
+// image download and AI backend request, e.g. the CF-Access-Client-Id and
+// CF-Access-Client-Secret that get requests through Cloudflare Access.
+// Headers a request sets itself, such as an API key, take precedence over
+// extra headers. It must be called before any requests are made.
+func SetOutboundHeaders(userAgent string, headers map[string]string) {
+	outboundUserAgent = userAgent
+	outboundHeader = make(http.Header, len(headers))
+	for name, value := range headers {
+		outboundHeader.Set(name, value)
+	}
+}
+
+// Transport is the http.RoundTripper for image downloads and AI backend
+// requests, adding the headers set with SetOutboundHeaders
+var Transport http.RoundTripper = outboundTransport{base: http.DefaultTransport}
+
+// outboundTransport adds the outbound headers to each request
+type outboundTransport struct {
+	base http.RoundTripper
+}
+
+func (t outboundTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if outboundUserAgent == "" && len(outboundHeader) == 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	// RoundTrippers mustn't modify the request they're given
+	req = req.Clone(req.Context())
+	for name, values := range outboundHeader {
+		if _, ok := req.Header[name]; !ok {
+			req.Header[name] = values
+		}
+	}
+	if outboundUserAgent != "" {
+		req.Header.Set("User-Agent", outboundUserAgent)
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,262 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+)
+
+// Usage counts the tokens used by AI requests
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// usageKey is the context key for the *Usage a tracked request adds to
+type usageKey struct{}
+
+// ReportUsage adds the tokens used by a backend request to the usage being
+// tracked for ctx, if any. Backends call it for every response that says
+// how many tokens it used, so retries are counted too.
+func ReportUsage(ctx context.Context, u Usage) {
+	if total, ok := ctx.Value(usageKey{}).(*Usage); ok {
+		total.InputTokens += u.InputTokens
+		total.OutputTokens += u.OutputTokens
+	}
+}
+
+// Price is what a model costs in US dollars per million tokens
+type Price struct {
+	InputPerMillion  float64
+	OutputPerMillion float64
+}
+
+// DefaultPrices are list prices for the hosted backends' default models, at
+// the time of writing; models without a price have no estimated cost
+var DefaultPrices = map[string]Price{
+	DefaultModel:        {InputPerMillion: 2.50, OutputPerMillion: 10.00},
+	"gpt-4o-mini":       {InputPerMillion: 0.15, OutputPerMillion: 0.60},
+	DefaultClaudeModel:  {InputPerMillion: 3.00, OutputPerMillion: 15.00},
+	"claude-haiku-4-5":  {InputPerMillion: 1.00, OutputPerMillion: 5.00},
+	DefaultBedrockModel: {InputPerMillion: 0.06, OutputPerMillion: 0.24},
+}
+
+// cost returns the estimated cost of u in US dollars
+func (p Price) cost(u Usage) float64 {
+	return (float64(u.InputTokens)*p.InputPerMillion + float64(u.OutputTokens)*p.OutputPerMillion) / 1e6
+}
+
+// UsageRecord describes one tracked AI request, including its retries
+type UsageRecord struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	Model     string    `json:"model"`
+	LatencyMS int64     `json:"latency_ms"`
+	Usage
+	// EstimatedCost is in US dollars; nil if the model has no price
+	EstimatedCost *float64 `json:"estimated_cost_usd,omitempty"`
+	Error         string   `json:"error,omitempty"`
+}
+
+// UsageTotals sums the usage of a number of requests
+type UsageTotals struct {
+	Requests int `json:"requests"`
+	Failures int `json:"failures"`
+	Usage
+	// EstimatedCost covers only requests to models with a price
+	EstimatedCost    float64 `json:"estimated_cost_usd"`
+	AverageLatencyMS int64   `json:"average_latency_ms"`
+	totalLatency     time.Duration
+}
+
+// add counts rec in the totals
+func (t *UsageTotals) add(rec UsageRecord) {
+	t.Requests++
+	if rec.Error != "" {
+		t.Failures++
+	}
+	t.InputTokens += rec.InputTokens
+	t.OutputTokens += rec.OutputTokens
+	if rec.EstimatedCost != nil {
+		t.EstimatedCost += *rec.EstimatedCost
+	}
+	t.totalLatency += time.Duration(rec.LatencyMS) * time.Millisecond
+	t.AverageLatencyMS = (t.totalLatency / time.Duration(t.Requests)).Milliseconds()
+}
+
+// Sub returns the usage counted in t but not in earlier, an earlier
+// snapshot of the same totals
+func (t UsageTotals) Sub(earlier UsageTotals) UsageTotals {
+	diff := UsageTotals{
+		Requests:      t.Requests - earlier.Requests,
+		Failures:      t.Failures - earlier.Failures,
+		Usage:         Usage{InputTokens: t.InputTokens - earlier.InputTokens, OutputTokens: t.OutputTokens - earlier.OutputTokens},
+		EstimatedCost: t.EstimatedCost - earlier.EstimatedCost,
+		totalLatency:  t.totalLatency - earlier.totalLatency,
+	}
+	if diff.Requests > 0 {
+		diff.AverageLatencyMS = (diff.totalLatency / time.Duration(diff.Requests)).Milliseconds()
+	}
+	return diff
+}
+
+// UsageReport is a snapshot of the usage recorded by a UsageTracker
+type UsageReport struct {
+	Backend string    `json:"backend"`
+	Since   time.Time `json:"since"`
+	UsageTotals
+	// Models breaks the totals down by model
+	Models map[string]UsageTotals `json:"models"`
+	// Recent lists the most recent requests, newest first
+	Recent []UsageRecord `json:"recent"`
+}
+
+// UsageTracker records the model, latency, token counts and estimated cost
+// of AI requests since the server started. It is safe for concurrent use;
+// a nil UsageTracker calls the backend without tracking.
+type UsageTracker struct {
+	prices map[string]Price
+	since  time.Time
+
+	mu      sync.Mutex
+	backend string
+	model   string
+	totals  UsageTotals
+	models  map[string]*UsageTotals
+	// recent is a ring of the latest records; next is where the next
+	// record goes once it is full
+	recent []UsageRecord
+	next   int
+}
+
+// NewUsageTracker creates a UsageTracker for requests to model on backend;
+// an empty model is the backend's default. prices adds to or overrides
+// DefaultPrices.
+func NewUsageTracker(backend, model string, prices map[string]Price) *UsageTracker {
+	merged := make(map[string]Price, len(DefaultPrices)+len(prices))
+	for m, p := range DefaultPrices {
+		merged[m] = p
+	}
+	for m, p := range prices {
+		merged[m] = p
+	}
+	return &UsageTracker{
+		backend: backend,
+		model:   trackedModel(backend, model),
+		prices:  merged,
+		since:   time.Now(),
+		models:  make(map[string]*UsageTotals),
+	}
+}
+
+// trackedModel returns the model requests are recorded under; an empty
+// model is the backend's default
+func trackedModel(backend, model string) string {
+	if model == "" {
+		switch backend {
+		case BackendOpenAI:
+			return DefaultModel
+		case BackendClaude:
+			return DefaultClaudeModel
+		case BackendBedrock:
+			return DefaultBedrockModel
+		}
+	}
+	return model
+}
+
+// SetModel records later requests as made to model on backend, after the
+// AI backend is reloaded. Usage recorded so far is kept.
+func (t *UsageTracker) SetModel(backend, model string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.backend = backend
+	t.model = trackedModel(backend, model)
+}
+
+// Track calls fn and records its latency and the tokens the backend
+// reports using, under the given operation (e.g. "title"). Requests refused
+// without reaching the backend aren't recorded.
+func (t *UsageTracker) Track(ctx context.Context, operation string, fn func(ctx context.Context) error) error {
+	if t == nil {
+		return fn(ctx)
+	}
+
+	t.mu.Lock()
+	model := t.model
+	t.mu.Unlock()
+
+	usage := &Usage{}
+	start := time.Now()
+	err := fn(context.WithValue(ctx, usageKey{}, usage))
+	if errors.Is(err, ErrBackendUnavailable) {
+		return err
+	}
+
+	rec := UsageRecord{
+		Time:      start,
+		Operation: operation,
+		Model:     model,
+		LatencyMS: time.Since(start).Milliseconds(),
+		Usage:     *usage,
+	}
+	if price, ok := t.prices[model]; ok {
+		cost := price.cost(rec.Usage)
+		rec.EstimatedCost = &cost
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	t.record(rec)
+	return err
+}
+
+// record adds rec to the totals and recent requests
+func (t *UsageTracker) record(rec UsageRecord) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.totals.add(rec)
+	m, ok := t.models[rec.Model]
+	if !ok {
+		m = &UsageTotals{}
+		t.models[rec.Model] = m
+	}
+	m.add(rec)
+
+	if len(t.recent) < constants.AIUsageRecentRequests {
+		t.recent = append(t.recent, rec)
+		return
+	}
+	t.recent[t.next] = rec
+	t.next = (t.next + 1) % len(t.recent)
+}
+
+// Report returns the usage recorded so far
+func (t *UsageTracker) Report() UsageReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	report := UsageReport{
+		Backend:     t.backend,
+		Since:       t.since,
+		UsageTotals: t.totals,
+		Models:      make(map[string]UsageTotals, len(t.models)),
+		Recent:      make([]UsageRecord, 0, len(t.recent)),
+	}
+	for m, totals := range t.models {
+		report.Models[m] = *totals
+	}
+	// Walk back from the newest record
+	for i := range t.recent {
+		idx := (t.next - 1 - i + 2*len(t.recent)) % len(t.recent)
+		report.Recent = append(report.Recent, t.recent[idx])
+	}
+	return report
+}
@@ -0,0 +1,160 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+)
+
+// matroskaSignature starts Matroska and WebM files
+var matroskaSignature = []byte{0x1A, 0x45, 0xDF, 0xA3}
+
+// isVideo reports whether data looks like the start of a video file: an
+// ISO base media file (MP4, MOV) that isn't a HEIF image, or Matroska/WebM
+func isVideo(data []byte) bool {
+	if len(data) >= 12 && string(data[4:8]) == "ftyp" {
+		return detectHEIF(data) == ""
+	}
+	return bytes.HasPrefix(data, matroskaSignature)
+}
+
+var (
+	ffmpegOnce sync.Once
+	ffmpegPath string
+	// ffprobePath is optional; without it, keyframes are taken from the
+	// start of the video
+	ffprobePath string
+)
+
+// VideoFrameExtractor returns the path of ffmpeg, used to extract a frame
+// from videos for title generation, or an empty string if it isn't installed
+func VideoFrameExtractor() string {
+	ffmpegOnce.Do(func() {
+		ffmpegPath, _ = exec.LookPath("ffmpeg")
+		ffprobePath, _ = exec.LookPath("ffprobe")
+	})
+	return ffmpegPath
+}
+
+// Protocols ffmpeg may use to read videos: only network protocols for URLs,
+// so a crafted URL can't make ffmpeg read local files, and only files for
+// videos in the local uploads directory
+const (
+	networkProtocols = "http,https,tcp,tls"
+	fileProtocols    = "file"
+)
+
+// extractKeyframe returns a JPEG of the frame in the middle of the video at
+// videoURL, or of its first frame if the duration can't be determined.
+// ffmpeg reads the video directly from the URL, fetching only the parts it
+// needs, so large videos aren't downloaded in full. Its requests carry the
+// outbound headers and any extra headers given.
+func extractKeyframe(ctx context.Context, videoURL string, extraHeader http.Header) (*Image, error) {
+	return extractFrame(ctx, videoURL, networkProtocols, ffmpegHeaderArgs(extraHeader))
+}
+
+// ffmpegHeaderArgs returns ffmpeg input options sending the outbound
+// User-Agent and headers with HTTP requests, with extraHeader taking
+// precedence as it does for downloads
+func ffmpegHeaderArgs(extraHeader http.Header) []string {
+	header := outboundHeader.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	for name, values := range extraHeader {
+		header[name] = values
+	}
+
+	var args []string
+	if outboundUserAgent != "" {
+		args = append(args, "-user_agent", outboundUserAgent)
+	}
+	if len(header) > 0 {
+		var lines strings.Builder
+		for name, values := range header {
+			for _, value := range values {
+				fmt.Fprintf(&lines, "%s: %s\r\n", name, value)
+			}
+		}
+		args = append(args, "-headers", lines.String())
+	}
+	return args
+}
+
+// extractFrame extracts a frame like extractKeyframe from input, which
+// ffmpeg may read using only the given protocols, passing it inputArgs
+func extractFrame(ctx context.Context, input, protocols string, inputArgs []string) (*Image, error) {
+	if VideoFrameExtractor() == "" {
+		return nil, fmt.Errorf("cannot extract a frame from video: ffmpeg is not installed")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, constants.VideoFrameTimeout)
+	defer cancel()
+
+	var offset float64
+	if duration, err := videoDuration(ctx, input, protocols, inputArgs); err != nil {
+		log.Printf("Failed to get video duration, using first frame: %v", err)
+	} else {
+		offset = duration / 2
+	}
+
+	args := []string{
+		"-hide_banner", "-loglevel", "error",
+		"-protocol_whitelist", protocols,
+		"-ss", strconv.FormatFloat(offset, 'f', 3, 64),
+	}
+	args = append(args, inputArgs...)
+	args = append(args,
+		"-i", input,
+		"-frames:v", "1",
+		"-f", "image2", "-c:v", "mjpeg", "-q:v", "2",
+		"pipe:1",
+	)
+	cmd := exec.CommandContext(ctx, ffmpegPath, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("ffmpeg extracted no frame from video")
+	}
+
+	log.Printf("Extracted frame at %.1fs from video (%d bytes)", offset, stdout.Len())
+	return &Image{Data: stdout.Bytes(), ContentType: constants.MimeJPEG}, nil
+}
+
+// videoDuration returns the length of the video at input in seconds
+func videoDuration(ctx context.Context, input, protocols string, inputArgs []string) (float64, error) {
+	if ffprobePath == "" {
+		return 0, fmt.Errorf("ffprobe is not installed")
+	}
+
+	args := []string{
+		"-v", "error",
+		"-protocol_whitelist", protocols,
+		"-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+	}
+	args = append(args, inputArgs...)
+	args = append(args, input)
+	cmd := exec.CommandContext(ctx, ffprobePath, args...)
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe failed: %w", err)
+	}
+	duration, err := strconv.ParseFloat(strings.TrimSpace(string(output)), 64)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("unexpected duration %q", strings.TrimSpace(string(output)))
+	}
+	return duration, nil
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"unicode"
+)
+
+// DefaultForwardAuthHeaders are the identity headers set by Cloudflare
+// Access and by common forward-auth proxies (oauth2-proxy, Authelia,
+// Traefik forward auth)
+var DefaultForwardAuthHeaders = []string{"Cf-Access-Authenticated-User-Email", "X-Forwarded-User"}
+
+// maxIdentityLength bounds identities taken from headers
+const maxIdentityLength = 255
+
+// ForwardAuth reads the identity of the user an upstream auth proxy, such
+// as Cloudflare Access, has authenticated. Headers are only trusted on
+// requests from the proxy's addresses, since any client can set them.
+type ForwardAuth struct {
+	headers []string
+	trusted []netip.Prefix
+	// roles and defaultRole are set by SetRoles
+	roles       map[string]Scope
+	defaultRole Scope
+}
+
+// NewForwardAuth creates a ForwardAuth reading the first of headers that is
+// set (DefaultForwardAuthHeaders if none are given) on requests from the
+// trustedProxies addresses or CIDR ranges
+func NewForwardAuth(headers, trustedProxies []string) (*ForwardAuth, error) {
+	if len(headers) == 0 {
+		headers = DefaultForwardAuthHeaders
+	}
+	if len(trustedProxies) == 0 {
+		return nil, fmt.Errorf("at least one trusted proxy is required")
+	}
+
+	f := &ForwardAuth{headers: headers}
+	for _, proxy := range trustedProxies {
+		prefix, err := parseProxyPrefix(proxy)
+		if err != nil {
+			return nil, err
+		}
+		f.trusted = append(f.trusted, prefix)
+	}
+	return f, nil
+}
+
+// parseProxyPrefix parses a trusted proxy given as an IP address or a CIDR
+// range
+func parseProxyPrefix(proxy string) (netip.Prefix, error) {
+	if strings.Contains(proxy, "/") {
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid trusted proxy range %q: %w", proxy, err)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(proxy)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid trusted proxy address %q: %w", proxy, err)
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+// Identity returns the user the proxy authenticated for r, or false if r
+// didn't come from a trusted proxy or carries no usable identity
+func (f *ForwardAuth) Identity(r *http.Request) (string, bool) {
+	if !f.trustedAddr(r.RemoteAddr) {
+		return "", false
+	}
+	for _, header := range f.headers {
+		identity := strings.TrimSpace(r.Header.Get(header))
+		if identity != "" && validIdentity(identity) {
+			return identity, true
+		}
+	}
+	return "", false
+}
+
+// trustedAddr reports whether remoteAddr, as in http.Request, is a trusted
+// proxy
+func (f *ForwardAuth) trustedAddr(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range f.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// validIdentity rejects identities that are too long or could corrupt logs
+func validIdentity(identity string) bool {
+	if len(identity) > maxIdentityLength {
+		return false
+	}
+	return strings.IndexFunc(identity, unicode.IsControl) < 0
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying the identity of the person
+// behind a request, as reported by a forward-auth proxy
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor returns who is making a request, for auditing and per-user
+// preferences: the forward-auth identity, or else "token:" and the name of
+// the API token used. It is empty for anonymous requests.
+func Actor(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	if token, ok := FromContext(ctx); ok {
+		return "token:" + token.Name
+	}
+	return ""
+}
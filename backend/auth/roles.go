@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"context"
+	"strings"
+)
+
+// SetRoles gives forward-auth users roles: each identity in roles, matched
+// case-insensitively, has its role, and any other identity has
+// defaultRole, or no role if it is empty. Users without a role need an API
+// token, and are refused when no tokens are configured.
+func (f *ForwardAuth) SetRoles(roles map[string]Scope, defaultRole Scope) {
+	f.roles = make(map[string]Scope, len(roles))
+	for identity, role := range roles {
+		f.roles[strings.ToLower(identity)] = role
+	}
+	f.defaultRole = defaultRole
+}
+
+// Role returns the role of a forward-auth identity, or false if it has none
+func (f *ForwardAuth) Role(identity string) (Scope, bool) {
+	if role, ok := f.roles[strings.ToLower(identity)]; ok {
+		return role, true
+	}
+	return f.defaultRole, f.defaultRole != ""
+}
+
+// HasRoles reports whether any forward-auth users are given a role. Once
+// they are, requests from users without one are refused unless they carry
+// an API token. A nil ForwardAuth has no roles.
+func (f *ForwardAuth) HasRoles() bool {
+	return f != nil && (len(f.roles) > 0 || f.defaultRole != "")
+}
+
+type roleKey struct{}
+
+// WithRole returns a copy of ctx carrying the role of the forward-auth user
+// behind a request
+func WithRole(ctx context.Context, role Scope) context.Context {
+	return context.WithValue(ctx, roleKey{}, role)
+}
+
+// RoleFromContext returns the role of the forward-auth user behind a
+// request, if they have one
+func RoleFromContext(ctx context.Context) (Scope, bool) {
+	role, ok := ctx.Value(roleKey{}).(Scope)
+	return role, ok && role != ""
+}
+
+// Granted returns the access a request was authenticated with: its API
+// token's scope, or else its forward-auth user's role. It is false when
+// the request wasn't authenticated, as when authentication is disabled.
+func Granted(ctx context.Context) (Scope, bool) {
+	if token, ok := FromContext(ctx); ok {
+		return token.Scope, true
+	}
+	return RoleFromContext(ctx)
+}
@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+)
+
+// Session is a login opened with an API token. Its secret is accepted in
+// place of the token until the session has gone unused for the idle
+// timeout or reaches the absolute timeout; each use renews the idle
+// timeout. A session ends with the token it was opened with.
+type Session struct {
+	// ID identifies the session for listing and revocation; unlike the
+	// secret, it grants no access
+	ID         string    `json:"id"`
+	TokenName  string    `json:"token"`
+	Scope      Scope     `json:"scope"`
+	LycheeUser string    `json:"lychee_user,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	// ExpiresAt is when the session ends unless it is used before then
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SessionStore holds the open sessions. Sessions are kept in memory and do
+// not survive a restart.
+type SessionStore struct {
+	tokens   *TokenStore
+	idle     time.Duration
+	absolute time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]*Session // keyed by the SHA-256 of the secret
+}
+
+// NewSessionStore creates an empty SessionStore for sessions opened with
+// the tokens in tokens
+func NewSessionStore(tokens *TokenStore, idle, absolute time.Duration) *SessionStore {
+	return &SessionStore{
+		tokens:   tokens,
+		idle:     idle,
+		absolute: absolute,
+		sessions: map[string]*Session{},
+	}
+}
+
+// Open starts a session for token and returns it with its secret
+func (s *SessionStore) Open(token *Token, userAgent string) (Session, string, error) {
+	buf := make([]byte, 40)
+	if _, err := rand.Read(buf); err != nil {
+		return Session{}, "", fmt.Errorf("failed to generate session: %w", err)
+	}
+	id, secret := hex.EncodeToString(buf[:8]), hex.EncodeToString(buf[8:])
+
+	now := time.Now()
+	session := &Session{
+		ID:         id,
+		TokenName:  token.Name,
+		Scope:      token.Scope,
+		LycheeUser: token.LycheeUser,
+		UserAgent:  userAgent,
+		CreatedAt:  now,
+		LastUsedAt: now,
+	}
+	session.ExpiresAt = s.expiry(session)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneLocked(now)
+	s.evictLocked(token.Name)
+	s.sessions[hashSecret(secret)] = session
+	return *session, secret, nil
+}
+
+// Lookup finds the session matching secret and the token it was opened
+// with, renewing the session's idle timeout
+func (s *SessionStore) Lookup(secret string) (*Token, Session, bool) {
+	if secret == "" {
+		return nil, Session{}, false
+	}
+	key := hashSecret(secret)
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[key]
+	if !ok {
+		return nil, Session{}, false
+	}
+	token, ok := s.tokens.get(session.TokenName)
+	if !ok || !now.Before(session.ExpiresAt) {
+		delete(s.sessions, key)
+		return nil, Session{}, false
+	}
+
+	session.LastUsedAt = now
+	session.ExpiresAt = s.expiry(session)
+	return token, *session, true
+}
+
+// Revoke ends the session with the given ID
+func (s *SessionStore) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, session := range s.sessions {
+		if session.ID == id {
+			delete(s.sessions, key)
+			return nil
+		}
+	}
+	return fmt.Errorf("session %q not found", id)
+}
+
+// List returns the open sessions, most recently used first
+func (s *SessionStore) List() []Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneLocked(time.Now())
+	sessions := make([]Session, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		sessions = append(sessions, *session)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt) })
+	return sessions
+}
+
+// expiry returns when session ends if it isn't used again: after the idle
+// timeout, but no later than the absolute timeout
+func (s *SessionStore) expiry(session *Session) time.Time {
+	expires := session.LastUsedAt.Add(s.idle)
+	if absolute := session.CreatedAt.Add(s.absolute); absolute.Before(expires) {
+		return absolute
+	}
+	return expires
+}
+
+// pruneLocked drops expired sessions and those whose token was revoked
+func (s *SessionStore) pruneLocked(now time.Time) {
+	for key, session := range s.sessions {
+		if _, ok := s.tokens.get(session.TokenName); !ok || !now.Before(session.ExpiresAt) {
+			delete(s.sessions, key)
+		}
+	}
+}
+
+// evictLocked ends the least recently used session opened with the named
+// token if it has constants.MaxSessionsPerToken open
+func (s *SessionStore) evictLocked(tokenName string) {
+	var (
+		count  int
+		oldest string
+	)
+	for key, session := range s.sessions {
+		if session.TokenName != tokenName {
+			continue
+		}
+		count++
+		if oldest == "" || session.LastUsedAt.Before(s.sessions[oldest].LastUsedAt) {
+			oldest = key
+		}
+	}
+	if count >= constants.MaxSessionsPerToken {
+		delete(s.sessions, oldest)
+	}
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+type sessionKey struct{}
+
+// WithSession returns a copy of ctx carrying the session a request was
+// authenticated with
+func WithSession(ctx context.Context, session Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, session)
+}
+
+// SessionFromContext returns the session a request was authenticated
+// with, if any
+func SessionFromContext(ctx context.Context) (Session, bool) {
+	session, ok := ctx.Value(sessionKey{}).(Session)
+	return session, ok
+}
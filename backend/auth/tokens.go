@@ -0,0 +1,214 @@
+// Package auth implements API token authentication with scoped permissions.
+//
+// Tokens are defined in the configuration file or created at runtime via the
+// admin API. Each token carries a scope; scopes are hierarchical, so a token
+// with the edit scope may also read, and an admin token may do anything.
+// When no tokens are defined, authentication is disabled entirely. Clients
+// may exchange a token for a session (see SessionStore), whose secret
+// expires, rather than keep the token itself.
+//
+// A token may also be bound to a Lychee user, in which case requests made
+// with it are further limited to what that user may edit in Lychee itself.
+//
+// Users identified by a forward-auth proxy may be given a role instead of a
+// token. Roles are scopes under another name: viewer is read, editor is
+// edit, and admin is admin.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Scope is a permission level granted to an API token or, as a role, to a
+// forward-auth user
+type Scope string
+
+const (
+	ScopeRead  Scope = "read"
+	ScopeEdit  Scope = "edit"
+	ScopeAdmin Scope = "admin"
+)
+
+// scopeRank orders scopes so that higher scopes imply lower ones
+var scopeRank = map[Scope]int{
+	ScopeRead:  1,
+	ScopeEdit:  2,
+	ScopeAdmin: 3,
+}
+
+// roleScopes maps role names to the scopes they stand for
+var roleScopes = map[string]Scope{
+	"viewer": ScopeRead,
+	"editor": ScopeEdit,
+}
+
+// ParseScope validates a scope or role name
+func ParseScope(s string) (Scope, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	if scope, ok := roleScopes[name]; ok {
+		return scope, nil
+	}
+	scope := Scope(name)
+	if _, ok := scopeRank[scope]; !ok {
+		return "", fmt.Errorf("unknown scope %q (supported: read, edit, admin, or the roles viewer, editor, admin)", s)
+	}
+	return scope, nil
+}
+
+// Allows reports whether a token with scope s may perform an action requiring required
+func (s Scope) Allows(required Scope) bool {
+	return scopeRank[s] >= scopeRank[required]
+}
+
+// Token is a named API credential
+type Token struct {
+	Name  string `json:"name"`
+	Scope Scope  `json:"scope"`
+	// LycheeUser is the username of the Lychee user whose album access
+	// permissions apply to requests made with this token, if any
+	LycheeUser string `json:"lychee_user,omitempty"`
+	secret     string
+	// Runtime is true for tokens created via the admin API, which are
+	// held in memory only and do not survive a restart
+	Runtime bool `json:"runtime"`
+}
+
+// TokenStore holds the set of valid API tokens
+type TokenStore struct {
+	mu     sync.RWMutex
+	tokens map[string]*Token // keyed by name
+}
+
+// NewTokenStore creates an empty TokenStore
+func NewTokenStore() *TokenStore {
+	return &TokenStore{tokens: map[string]*Token{}}
+}
+
+// Add registers a token with the given secret. It fails if the name is
+// already in use.
+func (s *TokenStore) Add(token Token, secret string) error {
+	if token.Name == "" {
+		return fmt.Errorf("token name is required")
+	}
+	if secret == "" {
+		return fmt.Errorf("token secret is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.tokens[token.Name]; exists {
+		return fmt.Errorf("token %q already exists", token.Name)
+	}
+	token.secret = secret
+	s.tokens[token.Name] = &token
+	return nil
+}
+
+// Generate creates a new runtime token with a random secret and returns the secret
+func (s *TokenStore) Generate(name string, scope Scope, lycheeUser string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	secret := hex.EncodeToString(buf)
+
+	if err := s.Add(Token{Name: name, Scope: scope, LycheeUser: lycheeUser, Runtime: true}, secret); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// Revoke removes a runtime token. Tokens defined in the config file cannot
+// be revoked at runtime.
+func (s *TokenStore) Revoke(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[name]
+	if !ok {
+		return fmt.Errorf("token %q not found", name)
+	}
+	if !token.Runtime {
+		return fmt.Errorf("token %q is defined in the config file and cannot be revoked at runtime", name)
+	}
+	delete(s.tokens, name)
+	return nil
+}
+
+// List returns all tokens sorted by name, without their secrets
+func (s *TokenStore) List() []Token {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tokens := make([]Token, 0, len(s.tokens))
+	for _, t := range s.tokens {
+		tokens = append(tokens, Token{Name: t.Name, Scope: t.Scope, LycheeUser: t.LycheeUser, Runtime: t.Runtime})
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].Name < tokens[j].Name })
+	return tokens
+}
+
+// Enabled reports whether any tokens are defined
+func (s *TokenStore) Enabled() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.tokens) > 0
+}
+
+// Lookup finds the token matching secret, comparing in constant time
+func (s *TokenStore) Lookup(secret string) (*Token, bool) {
+	if secret == "" {
+		return nil, false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var match *Token
+	for _, t := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(t.secret), []byte(secret)) == 1 {
+			match = t
+		}
+	}
+	return match, match != nil
+}
+
+// get returns the token with the given name
+func (s *TokenStore) get(name string) (*Token, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	token, ok := s.tokens[name]
+	return token, ok
+}
+
+// BearerToken extracts the token from an "Authorization: Bearer" header
+func BearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if len(header) > len(prefix) && strings.EqualFold(header[:len(prefix)], prefix) {
+		return strings.TrimSpace(header[len(prefix):])
+	}
+	return ""
+}
+
+type contextKey struct{}
+
+// WithToken returns a copy of ctx carrying the authenticated token
+func WithToken(ctx context.Context, token *Token) context.Context {
+	return context.WithValue(ctx, contextKey{}, token)
+}
+
+// FromContext returns the token a request was authenticated with, if any
+func FromContext(ctx context.Context) (*Token, bool) {
+	token, ok := ctx.Value(contextKey{}).(*Token)
+	return token, ok && token != nil
+}
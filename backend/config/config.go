@@ -2,14 +2,22 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
+	"net/mail"
+	"net/netip"
 	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/locale"
 	"gopkg.in/yaml.v3"
 )
 
@@ -20,17 +28,62 @@ const (
 	MaxPort = 65535
 
 	// Default values (using shared constants)
-	DefaultServerPort = constants.DefaultServerPort
-	DefaultMySQLPort  = constants.DefaultDatabasePort
+	DefaultServerPort   = constants.DefaultServerPort
+	DefaultMySQLPort    = constants.DefaultDatabasePort
 	DefaultPostgresPort = constants.DefaultPostgresPort
 
 	// Database types
 	DatabaseMySQL    = "mysql"
 	DatabasePostgres = "postgres"
 	DatabaseSQLite   = "sqlite"
+
+	// MinAPITokenLength is the minimum length of a configured API token
+	MinAPITokenLength = 16
+
+	// DefaultSlowQueryMs is how long a query takes before it is logged
+	// as slow
+	DefaultSlowQueryMs = 1000
+
+	// DefaultMaxConcurrentQueries and DefaultMaxQueuedQueries limit the
+	// expensive read requests, such as the needs-metadata queue, running
+	// and waiting at once
+	DefaultMaxConcurrentQueries = 4
+	DefaultMaxQueuedQueries     = 20
+
+	// DefaultReconcileMinutes is how often edits made directly in Lychee are checked for
+	DefaultReconcileMinutes = 15
+
+	// DefaultMaintenanceHours is how often the tool's own state is pruned
+	DefaultMaintenanceHours = 24
+
+	// DefaultSessionIdleMinutes and DefaultSessionAbsoluteHours are how
+	// long an API session lasts unused and at most
+	DefaultSessionIdleMinutes   = 60
+	DefaultSessionAbsoluteHours = 24
+
+	// DefaultMetricsJob and DefaultMetricsPushSeconds are the job metrics
+	// are pushed under and how often they are pushed
+	DefaultMetricsJob         = "lychee-meta-tool"
+	DefaultMetricsPushSeconds = 60
+
+	// Default SMTP ports for STARTTLS and implicit TLS
+	DefaultSMTPPort            = 587
+	DefaultSMTPImplicitTLSPort = 465
+
+	// DefaultSummaryWeekday is when summary emails are sent by default
+	DefaultSummaryWeekday = "monday"
 )
 
-var modelNamePattern = regexp.MustCompile(`^[a-zA-Z0-9._:/\-]+$`)
+var validTokenScopes = []string{"read", "edit", "admin", "viewer", "editor"}
+
+// imageURLPlaceholderPattern matches placeholders in image_url_template
+var imageURLPlaceholderPattern = regexp.MustCompile(`\{[^{}]*\}`)
+
+var (
+	modelNamePattern   = regexp.MustCompile(`^[a-zA-Z0-9._:/\-]+$`)
+	backendNamePattern = regexp.MustCompile(`^[a-z0-9_\-]+$`)
+	tokenNamePattern   = regexp.MustCompile(`^[a-zA-Z0-9_\-]{1,64}$`)
+)
 
 type DatabaseConfig struct {
 	Type     string `yaml:"type" json:"type"`
@@ -40,34 +93,452 @@ type DatabaseConfig struct {
 	Password string `yaml:"password" json:"password"`
 	Database string `yaml:"database" json:"database"`
 	Path     string `yaml:"path" json:"path"` // For SQLite
+	// QueryTimeoutSeconds cancels queries, including reading their results,
+	// that run longer; 0 leaves them unbounded
+	QueryTimeoutSeconds int `yaml:"query_timeout_seconds" json:"query_timeout_seconds"`
+	// SlowQueryMs logs queries that take longer, and lists them at
+	// /api/admin/db/slow; defaults to 1000, and -1 disables it
+	SlowQueryMs int `yaml:"slow_query_ms" json:"slow_query_ms"`
+	// MaxConcurrentQueries caps the expensive read requests, such as
+	// listing the needs-metadata queue or counting photos per album,
+	// running at once; defaults to 4, and -1 leaves them unlimited
+	MaxConcurrentQueries int `yaml:"max_concurrent_queries" json:"max_concurrent_queries"`
+	// MaxQueuedQueries caps the requests waiting for a slot once the limit
+	// is reached; further requests are refused with HTTP 429. Defaults to
+	// 20; -1 refuses every request beyond the limit.
+	MaxQueuedQueries int `yaml:"max_queued_queries" json:"max_queued_queries"`
 }
 
 type CORSConfig struct {
 	AllowedOrigins []string `yaml:"allowed_origins" json:"allowed_origins"`
 }
 
+// CSPConfig controls the Content-Security-Policy header
+type CSPConfig struct {
+	// Policy replaces the default policy entirely when set
+	Policy string `yaml:"policy" json:"policy"`
+	// ReportOnly sends the policy as Content-Security-Policy-Report-Only
+	ReportOnly bool `yaml:"report_only" json:"report_only"`
+}
+
 type ServerConfig struct {
 	Port int        `yaml:"port" json:"port"`
 	CORS CORSConfig `yaml:"cors" json:"cors"`
+	CSP  CSPConfig  `yaml:"csp" json:"csp"`
+	// FrameAncestors lists origins allowed to embed the UI in an iframe.
+	// When empty, framing is denied entirely.
+	FrameAncestors []string `yaml:"frame_ancestors" json:"frame_ancestors"`
 }
 
 type OllamaConfig struct {
 	URL   string `yaml:"url" json:"url"`
 	Model string `yaml:"model" json:"model"`
+	// AutoPull downloads the model via Ollama's pull API if the server
+	// doesn't have it
+	AutoPull bool `yaml:"auto_pull" json:"auto_pull"`
+	// NumPredict caps the number of tokens generated per title; 0 uses
+	// the server's default
+	NumPredict int `yaml:"num_predict" json:"num_predict"`
+	// TitleWords asks the model for titles of at most this many words
+	TitleWords int `yaml:"title_words" json:"title_words"`
+	// Temperature and TopP tune sampling; unset uses 0.7 and 0.9
+	Temperature *float64 `yaml:"temperature" json:"temperature,omitempty"`
+	TopP        *float64 `yaml:"top_p" json:"top_p,omitempty"`
+	// NumCtx sets the model's context window in tokens; 0 uses the
+	// model's default
+	NumCtx int `yaml:"num_ctx" json:"num_ctx"`
+	// KeepAlive is how long Ollama keeps the model loaded after a request,
+	// as a duration ("10m") or seconds; negative keeps it loaded
+	// indefinitely. Empty uses the server's default.
+	KeepAlive string `yaml:"keep_alive" json:"keep_alive"`
 }
 
 type OpenAIConfig struct {
 	URL    string `yaml:"url" json:"url"`
 	APIKey string `yaml:"api_key" json:"api_key"`
 	Model  string `yaml:"model" json:"model"`
+	// MaxTokens caps the number of tokens generated per title; 0 uses
+	// the backend's default of 50
+	MaxTokens int `yaml:"max_tokens" json:"max_tokens"`
+	// TitleWords asks the model for titles of at most this many words
+	TitleWords int `yaml:"title_words" json:"title_words"`
+	// StructuredOutput selects how replies are requested: "auto" (the
+	// default) asks for JSON matching a schema via response_format and
+	// falls back to free text if the server rejects it; "json_schema" and
+	// "json_object" always ask for JSON; "off" asks for free text
+	StructuredOutput string `yaml:"structured_output" json:"structured_output"`
+}
+
+// OpenAICompatibleConfig configures a local server mimicking OpenAI's API,
+// such as llama.cpp's llama-server or LM Studio
+type OpenAICompatibleConfig struct {
+	// URL is the server's base URL, e.g. http://localhost:1234/v1
+	URL string `yaml:"url" json:"url"`
+	// Path is the chat completions endpoint below URL; defaults to
+	// /chat/completions
+	Path string `yaml:"path" json:"path"`
+	// APIKey is sent as a bearer token if set; local servers usually
+	// don't need one
+	APIKey string `yaml:"api_key" json:"api_key"`
+	// Model selects the model; empty uses the one the server has loaded
+	Model string `yaml:"model" json:"model"`
+	// MaxTokens caps the number of tokens generated per title; 0 uses
+	// the backend's default of 50
+	MaxTokens int `yaml:"max_tokens" json:"max_tokens"`
+	// TitleWords asks the model for titles of at most this many words
+	TitleWords int `yaml:"title_words" json:"title_words"`
+	// StructuredOutput selects how replies are requested, as for openai
+	StructuredOutput string `yaml:"structured_output" json:"structured_output"`
+}
+
+// ClaudeConfig configures Anthropic's Claude vision models
+type ClaudeConfig struct {
+	APIKey string `yaml:"api_key" json:"api_key"`
+	Model  string `yaml:"model" json:"model"`
+	// URL overrides the Messages API endpoint, e.g. for a proxy
+	URL string `yaml:"url" json:"url"`
+	// MaxTokens caps the number of tokens generated per title; 0 uses
+	// the backend's default of 50
+	MaxTokens int `yaml:"max_tokens" json:"max_tokens"`
+	// TitleWords asks the model for titles of at most this many words
+	TitleWords int `yaml:"title_words" json:"title_words"`
+}
+
+// BedrockConfig configures vision models on AWS Bedrock, such as Claude or
+// Nova. Region and credentials not set here are read from the standard AWS
+// environment variables (AWS_REGION, AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN and AWS_BEARER_TOKEN_BEDROCK).
+type BedrockConfig struct {
+	Region string `yaml:"region" json:"region"`
+	// Model is a model or inference profile ID; defaults to
+	// amazon.nova-lite-v1:0
+	Model           string `yaml:"model" json:"model"`
+	AccessKeyID     string `yaml:"access_key_id" json:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key" json:"secret_access_key"`
+	// SessionToken accompanies temporary credentials
+	SessionToken string `yaml:"session_token" json:"session_token"`
+	// APIKey is a Bedrock API key, used instead of signing requests with
+	// access keys
+	APIKey string `yaml:"api_key" json:"api_key"`
+	// URL overrides the regional Bedrock runtime endpoint, e.g. for a VPC
+	// endpoint
+	URL string `yaml:"url" json:"url"`
+	// MaxTokens caps the number of tokens generated per title; 0 uses
+	// the backend's default of 50
+	MaxTokens int `yaml:"max_tokens" json:"max_tokens"`
+	// TitleWords asks the model for titles of at most this many words
+	TitleWords int `yaml:"title_words" json:"title_words"`
+}
+
+// HTTPCaptionerConfig configures an external image captioning service,
+// such as BLIP or LLaVA behind a small web app of your own, that takes an
+// image and replies with a caption
+type HTTPCaptionerConfig struct {
+	// URL is the endpoint images are POSTed to
+	URL string `yaml:"url" json:"url"`
+	// APIKey is sent as a bearer token if set
+	APIKey string `yaml:"api_key" json:"api_key"`
+	// RequestFormat is how the image is sent: "raw" (the default) as the
+	// request body, "multipart" as the form field "image", or "json" as a
+	// base64-encoded "image" field. The latter two include a "prompt".
+	RequestFormat string `yaml:"request_format" json:"request_format"`
+	// CaptionField is the field of the JSON reply holding the caption;
+	// defaults to the first of caption, generated_text, text and title
+	CaptionField string `yaml:"caption_field" json:"caption_field"`
+}
+
+// AIConfig selects a registered AI backend by name and passes it
+// backend-specific settings. The ollama, openai, openai_compatible, claude,
+// bedrock and http_captioner sections remain supported as shorthand for the built-in backends.
+type AIConfig struct {
+	Backend  string            `yaml:"backend" json:"backend"`
+	Settings map[string]string `yaml:"settings" json:"settings"`
+	// TitleCachePath is a file in which generated titles are cached by
+	// photo checksum. When unset, the cache is kept in memory only.
+	TitleCachePath string `yaml:"title_cache_path" json:"title_cache_path"`
+	// TitleLanguage is the language titles and tags are written in, e.g.
+	// "German", unless an album or request asks for another. When unset,
+	// the model's default (usually English) is used.
+	TitleLanguage string `yaml:"title_language" json:"title_language"`
+	// Pricing sets what models cost, keyed by model name, for the usage
+	// report's cost estimates. It adds to or overrides the built-in prices
+	// of the hosted backends' default models.
+	Pricing map[string]ModelPriceConfig `yaml:"pricing" json:"pricing"`
+	// MaxConcurrentGenerations caps the AI title and tag generations
+	// running at once; 0 leaves them unlimited
+	MaxConcurrentGenerations int `yaml:"max_concurrent_generations" json:"max_concurrent_generations"`
+	// MaxQueuedGenerations caps the generations waiting for a slot once
+	// the limit is reached; further requests are refused with HTTP 429.
+	// Defaults to 10; -1 refuses every request beyond the limit.
+	MaxQueuedGenerations int `yaml:"max_queued_generations" json:"max_queued_generations"`
+}
+
+// ModelPriceConfig is what a model costs in US dollars per million tokens
+type ModelPriceConfig struct {
+	InputPerMillion  float64 `yaml:"input_per_million" json:"input_per_million"`
+	OutputPerMillion float64 `yaml:"output_per_million" json:"output_per_million"`
+}
+
+// APITokenConfig defines a named API token and the scope it grants
+type APITokenConfig struct {
+	Name  string `yaml:"name" json:"name"`
+	Token string `yaml:"token" json:"token"`
+	Scope string `yaml:"scope" json:"scope"`
+	// LycheeUser optionally binds the token to a Lychee user, limiting it
+	// to the photos that user may edit in Lychee
+	LycheeUser string `yaml:"lychee_user" json:"lychee_user"`
+}
+
+// AuthConfig configures API token authentication. When no tokens are
+// defined, the API is unauthenticated.
+type AuthConfig struct {
+	Tokens      []APITokenConfig  `yaml:"tokens" json:"tokens"`
+	Sessions    SessionConfig     `yaml:"sessions" json:"sessions"`
+	ForwardAuth ForwardAuthConfig `yaml:"forward_auth" json:"forward_auth"`
+}
+
+// SessionConfig limits the sessions clients open with an API token, which
+// let the web UI keep a short-lived session secret instead of the token
+// itself
+type SessionConfig struct {
+	// IdleTimeoutMinutes ends a session that hasn't been used for this
+	// long; each request renews it. Defaults to 60.
+	IdleTimeoutMinutes int `yaml:"idle_timeout_minutes" json:"idle_timeout_minutes"`
+	// AbsoluteTimeoutHours ends a session this long after it was opened,
+	// however recently it was used. Defaults to 24.
+	AbsoluteTimeoutHours int `yaml:"absolute_timeout_hours" json:"absolute_timeout_hours"`
+}
+
+// ForwardAuthConfig trusts the identity headers of an upstream auth proxy,
+// such as Cloudflare Access, to tell who is making each request. The
+// identity is used for auditing and per-user preferences. It only grants
+// access to users given a role; once any are, other requests need an API
+// token, and are refused if none are configured.
+type ForwardAuthConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Headers are checked in order for the user's identity; defaults to
+	// Cf-Access-Authenticated-User-Email and X-Forwarded-User
+	Headers []string `yaml:"headers" json:"headers"`
+	// TrustedProxies are the addresses or CIDR ranges requests from the
+	// proxy come from. Identity headers on other requests are ignored,
+	// since any client can set them.
+	TrustedProxies []string `yaml:"trusted_proxies" json:"trusted_proxies"`
+	// Roles gives identities a role: viewer, editor or admin (or,
+	// equivalently, the token scopes read, edit or admin)
+	Roles map[string]string `yaml:"roles" json:"roles"`
+	// DefaultRole is the role of identities not listed in Roles; by
+	// default they have none
+	DefaultRole string `yaml:"default_role" json:"default_role"`
+}
+
+// EditingConfig controls how edits are written back to Lychee
+type EditingConfig struct {
+	// ChangeNotes appends a provenance note (e.g. "Title set via
+	// lychee-meta-tool on 2006-01-02 (AI)") to the description when a
+	// title is saved
+	ChangeNotes bool `yaml:"change_notes" json:"change_notes"`
+	// CacheBustImages adds a version parameter, based on the photo's
+	// updated_at, to image URLs so that caches in front of Lychee can't
+	// serve a stale image for an edited photo
+	CacheBustImages bool `yaml:"cache_bust_images" json:"cache_bust_images"`
+	// AllowDestructive enables operations that can't be undone, such as
+	// deleting photos from Lychee
+	AllowDestructive bool `yaml:"allow_destructive" json:"allow_destructive"`
+}
+
+// QueueConfig sets how many photos the photo queue endpoints return per
+// page. Smaller pages make the first load faster on large libraries and
+// slow devices.
+type QueueConfig struct {
+	// PageSize is the number of photos returned when a request doesn't
+	// set a limit
+	PageSize int `yaml:"page_size" json:"page_size"`
+	// MaxPageSize is the most photos a request may ask for at once
+	MaxPageSize int `yaml:"max_page_size" json:"max_page_size"`
+}
+
+// SidecarConfig locates the tool's own state file (e.g. metadata provenance).
+// When Path is empty, that state is kept in memory and lost on restart.
+type SidecarConfig struct {
+	Path string `yaml:"path" json:"path"`
+	// ReconcileMinutes is how often to check for photos edited directly in
+	// Lychee since the tool saved them. Negative disables the check.
+	ReconcileMinutes int `yaml:"reconcile_minutes" json:"reconcile_minutes"`
+}
+
+// MaintenanceConfig schedules pruning of the tool's own state: sidecar
+// entries for photos and albums deleted from Lychee, and stale cached titles
+type MaintenanceConfig struct {
+	// IntervalHours is how often to prune. Negative disables scheduled
+	// pruning; POST /api/admin/maintenance still runs it.
+	IntervalHours int `yaml:"interval_hours" json:"interval_hours"`
+	// TitleCacheMaxAgeDays expires cached AI titles older than this; 0
+	// keeps them until their image leaves Lychee
+	TitleCacheMaxAgeDays int `yaml:"title_cache_max_age_days" json:"title_cache_max_age_days"`
+}
+
+// MetricsConfig configures pushing metrics to a Prometheus Pushgateway, for
+// setups where Prometheus can't scrape GET /api/metrics
+type MetricsConfig struct {
+	Push MetricsPushConfig `yaml:"push" json:"push"`
+}
+
+// MetricsPushConfig configures the Pushgateway metrics are pushed to.
+// Pushing is disabled unless URL is set.
+type MetricsPushConfig struct {
+	// URL is the Pushgateway's base URL, e.g. "http://pushgateway:9091"
+	URL string `yaml:"url" json:"url"`
+	// Job and Instance are the grouping labels metrics are pushed under;
+	// Job defaults to "lychee-meta-tool"
+	Job      string `yaml:"job" json:"job"`
+	Instance string `yaml:"instance" json:"instance"`
+	// IntervalSeconds is how often to push; defaults to 60
+	IntervalSeconds int `yaml:"interval_seconds" json:"interval_seconds"`
+	// Username and Password authenticate with HTTP basic auth (optional)
+	Username string `yaml:"username" json:"username"`
+	Password string `yaml:"password" json:"password"`
+}
+
+// GeocodingConfig configures reverse geocoding of photos' GPS coordinates
+// into place names
+type GeocodingConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// URL is a Nominatim-compatible /reverse endpoint; defaults to the
+	// public Nominatim service
+	URL string `yaml:"url" json:"url"`
+	// UserAgent identifies the tool to the service, as Nominatim's usage
+	// policy requires; include contact details when using the public service
+	UserAgent string `yaml:"user_agent" json:"user_agent"`
+	// Language is the preferred language of place names, e.g. "en"
+	Language string `yaml:"language" json:"language"`
+	// IncludeInPrompt tells the AI backend where a photo was taken when
+	// generating its title
+	IncludeInPrompt bool `yaml:"include_in_prompt" json:"include_in_prompt"`
+}
+
+// LocaleConfig sets the library owner's conventions for dates and place
+// names, used in photo responses, geocoded locations and AI prompts
+type LocaleConfig struct {
+	// Tag is a BCP 47 language tag, e.g. "en-GB" or "de"; defaults to
+	// en-US. It also sets geocoding.language when that is unset.
+	Tag string `yaml:"tag" json:"tag"`
+	// DateFormat overrides the language's date format, in Go's reference
+	// time notation, e.g. "Jan 2, 2006"
+	DateFormat string `yaml:"date_format" json:"date_format"`
+	// Timezone is the IANA name of the zone dates are shown in, e.g.
+	// "Europe/Berlin"; defaults to the server's local time
+	Timezone string `yaml:"timezone" json:"timezone"`
+	// PlaceOrder is "small_first" ("Ann Arbor, Michigan") or "large_first"
+	// ("Michigan, Ann Arbor"); defaults to the language's convention
+	PlaceOrder string `yaml:"place_order" json:"place_order"`
+	// HomeCountry is left out of place names in that country, e.g.
+	// "United States"
+	HomeCountry string `yaml:"home_country" json:"home_country"`
+	// IncludeDateInPrompt tells the AI backend when a photo was taken when
+	// generating its title
+	IncludeDateInPrompt bool `yaml:"include_date_in_prompt" json:"include_date_in_prompt"`
+}
+
+// SummaryEmailConfig configures a weekly email summarizing review activity:
+// photos titled, the remaining backlog, and AI usage
+type SummaryEmailConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// From is the sender address; To lists the recipients
+	From string   `yaml:"from" json:"from"`
+	To   []string `yaml:"to" json:"to"`
+	// Weekday (e.g. "monday") and Hour (0-23) set when the summary is
+	// sent, in Timezone (an IANA name such as "America/New_York"; defaults
+	// to the server's local time)
+	Weekday  string     `yaml:"weekday" json:"weekday"`
+	Hour     int        `yaml:"hour" json:"hour"`
+	Timezone string     `yaml:"timezone" json:"timezone"`
+	SMTP     SMTPConfig `yaml:"smtp" json:"smtp"`
+}
+
+// SMTPConfig locates the mail server summaries are sent through
+type SMTPConfig struct {
+	Host string `yaml:"host" json:"host"`
+	// Port defaults to 587, or 465 with ImplicitTLS
+	Port     int    `yaml:"port" json:"port"`
+	Username string `yaml:"username" json:"username"`
+	Password string `yaml:"password" json:"password"`
+	// ImplicitTLS connects with TLS from the start rather than upgrading
+	// with STARTTLS
+	ImplicitTLS bool `yaml:"implicit_tls" json:"implicit_tls"`
+}
+
+// OutboundConfig sets headers sent with image downloads and AI backend
+// requests, e.g. for a zero-trust proxy such as Cloudflare Access in front
+// of Lychee
+type OutboundConfig struct {
+	// UserAgent replaces the default lychee-meta-tool/<version>
+	UserAgent string `yaml:"user_agent" json:"user_agent"`
+	// Headers are added to every request, unless it sets them itself
+	Headers map[string]string `yaml:"headers" json:"headers"`
+}
+
+// StorageConfig selects how images are read from each Lychee storage disk
+// (the storage_disk of their size variants). Images on disks not listed
+// here are downloaded from their URLs.
+type StorageConfig struct {
+	Disks map[string]StorageDiskConfig `yaml:"disks" json:"disks"`
+}
+
+// StorageDiskConfig configures reading images from one storage disk
+type StorageDiskConfig struct {
+	// Type is "http" (download from image URLs), "local" (read from Path),
+	// "s3" (read from Bucket) or "lychee" (download from Lychee's uploads
+	// directory)
+	Type string `yaml:"type" json:"type"`
+	// Path is the local directory short paths are relative to
+	Path string `yaml:"path" json:"path"`
+	// Headers are added to http downloads, e.g. for authentication
+	Headers map[string]string `yaml:"headers" json:"headers"`
+	// Bucket, Region and Prefix locate s3 objects; Endpoint overrides AWS
+	// for S3-compatible storage. Region and credentials not set here are
+	// read from the standard AWS environment variables.
+	Bucket          string `yaml:"bucket" json:"bucket"`
+	Region          string `yaml:"region" json:"region"`
+	Endpoint        string `yaml:"endpoint" json:"endpoint"`
+	Prefix          string `yaml:"prefix" json:"prefix"`
+	AccessKeyID     string `yaml:"access_key_id" json:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key" json:"secret_access_key"`
+	SessionToken    string `yaml:"session_token" json:"session_token"`
+	// URL is the Lychee instance to download from; defaults to
+	// lychee_base_url
+	URL string `yaml:"url" json:"url"`
+	// APIToken is sent to Lychee in the Authorization header
+	APIToken string `yaml:"api_token" json:"api_token"`
 }
 
 type Config struct {
 	Database      DatabaseConfig `yaml:"database" json:"database"`
 	Server        ServerConfig   `yaml:"server" json:"server"`
 	LycheeBaseURL string         `yaml:"lychee_base_url" json:"lychee_base_url"`
-	Ollama        OllamaConfig   `yaml:"ollama" json:"ollama"`
-	OpenAI        OpenAIConfig   `yaml:"openai" json:"openai"`
+	// ImageURLTemplate builds image URLs from {base_url} and a size
+	// variant's {short_path}, for deployments serving media elsewhere
+	ImageURLTemplate string `yaml:"image_url_template" json:"image_url_template"`
+	// LycheeUploadsPath is Lychee's uploads directory (public/uploads),
+	// from which images are read directly rather than downloaded when set
+	LycheeUploadsPath string                 `yaml:"lychee_uploads_path" json:"lychee_uploads_path"`
+	Storage           StorageConfig          `yaml:"storage" json:"storage"`
+	Outbound          OutboundConfig         `yaml:"outbound" json:"outbound"`
+	Ollama            OllamaConfig           `yaml:"ollama" json:"ollama"`
+	OpenAI            OpenAIConfig           `yaml:"openai" json:"openai"`
+	OpenAICompatible  OpenAICompatibleConfig `yaml:"openai_compatible" json:"openai_compatible"`
+	Claude            ClaudeConfig           `yaml:"claude" json:"claude"`
+	Bedrock           BedrockConfig          `yaml:"bedrock" json:"bedrock"`
+	HTTPCaptioner     HTTPCaptionerConfig    `yaml:"http_captioner" json:"http_captioner"`
+	AI                AIConfig               `yaml:"ai" json:"ai"`
+	Auth              AuthConfig             `yaml:"auth" json:"auth"`
+	Editing           EditingConfig          `yaml:"editing" json:"editing"`
+	Sidecar           SidecarConfig          `yaml:"sidecar" json:"sidecar"`
+	Geocoding         GeocodingConfig        `yaml:"geocoding" json:"geocoding"`
+	Locale            LocaleConfig           `yaml:"locale" json:"locale"`
+	Queue             QueueConfig            `yaml:"queue" json:"queue"`
+	SummaryEmail      SummaryEmailConfig     `yaml:"summary_email" json:"summary_email"`
+	Maintenance       MaintenanceConfig      `yaml:"maintenance" json:"maintenance"`
+	Metrics           MetricsConfig          `yaml:"metrics" json:"metrics"`
 }
 
 func Load(configPath string) (*Config, error) {
@@ -76,6 +547,58 @@ func Load(configPath string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	config, err := parse(configPath, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	return config, nil
+}
+
+// LoadDemo builds the configuration for demo mode, serving the sample
+// library at dbPath and uploadsPath from this server. The config file at
+// configPath is optional; settings such as the AI backend are taken from it
+// if it exists, but its database and Lychee settings are replaced.
+func LoadDemo(configPath, dbPath, uploadsPath string) (*Config, error) {
+	config := &Config{}
+	data, err := os.ReadFile(configPath)
+	switch {
+	case err == nil:
+		if config, err = parse(configPath, data); err != nil {
+			return nil, err
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if config.Server.Port == 0 {
+		config.Server.Port = DefaultServerPort
+	}
+	config.Database = DatabaseConfig{
+		Type:                 DatabaseSQLite,
+		Path:                 dbPath,
+		QueryTimeoutSeconds:  config.Database.QueryTimeoutSeconds,
+		SlowQueryMs:          config.Database.SlowQueryMs,
+		MaxConcurrentQueries: config.Database.MaxConcurrentQueries,
+		MaxQueuedQueries:     config.Database.MaxQueuedQueries,
+	}
+	config.LycheeBaseURL = fmt.Sprintf("http://localhost:%d", config.Server.Port)
+	config.ImageURLTemplate = constants.DefaultImageURLTemplate
+	config.LycheeUploadsPath = uploadsPath
+
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	return config, nil
+}
+
+// parse decodes a YAML or JSON config file, chosen by its extension
+func parse(configPath string, data []byte) (*Config, error) {
 	var config Config
 	ext := filepath.Ext(configPath)
 
@@ -92,10 +615,6 @@ func Load(configPath string) (*Config, error) {
 		return nil, fmt.Errorf("unsupported config file format: %s", ext)
 	}
 
-	if err := config.validate(); err != nil {
-		return nil, fmt.Errorf("config validation failed: %w", err)
-	}
-
 	return &config, nil
 }
 
@@ -119,6 +638,26 @@ func (c *Config) validate() error {
 		return fmt.Errorf("lychee_base_url configuration error: %w", err)
 	}
 
+	// Validate image URL template
+	if err := c.validateImageURLTemplate(); err != nil {
+		return fmt.Errorf("image_url_template configuration error: %w", err)
+	}
+
+	// Validate Lychee uploads directory (optional)
+	if err := c.validateLycheeUploadsPath(); err != nil {
+		return fmt.Errorf("lychee_uploads_path configuration error: %w", err)
+	}
+
+	// Validate storage disks (optional)
+	if err := c.validateStorage(); err != nil {
+		return fmt.Errorf("storage configuration error: %w", err)
+	}
+
+	// Validate outbound request headers (optional)
+	if err := c.validateOutbound(); err != nil {
+		return fmt.Errorf("outbound configuration error: %w", err)
+	}
+
 	// Validate Ollama configuration (optional)
 	if err := c.validateOllama(); err != nil {
 		return fmt.Errorf("ollama configuration error: %w", err)
@@ -129,6 +668,70 @@ func (c *Config) validate() error {
 		return fmt.Errorf("openai configuration error: %w", err)
 	}
 
+	// Validate OpenAI-compatible server configuration (optional)
+	if err := c.validateOpenAICompatible(); err != nil {
+		return fmt.Errorf("openai_compatible configuration error: %w", err)
+	}
+
+	// Validate Claude configuration (optional)
+	if err := c.validateClaude(); err != nil {
+		return fmt.Errorf("claude configuration error: %w", err)
+	}
+
+	// Validate Bedrock configuration (optional)
+	if err := c.validateBedrock(); err != nil {
+		return fmt.Errorf("bedrock configuration error: %w", err)
+	}
+
+	// Validate captioning service configuration (optional)
+	if err := c.validateHTTPCaptioner(); err != nil {
+		return fmt.Errorf("http_captioner configuration error: %w", err)
+	}
+
+	// Validate API tokens (optional)
+	if err := c.validateAuth(); err != nil {
+		return fmt.Errorf("auth configuration error: %w", err)
+	}
+
+	// Validate sidecar store location (optional)
+	if err := c.validateSidecar(); err != nil {
+		return fmt.Errorf("sidecar configuration error: %w", err)
+	}
+
+	// Validate generic AI backend configuration (optional)
+	if err := c.validateAI(); err != nil {
+		return fmt.Errorf("ai configuration error: %w", err)
+	}
+
+	// Validate queue page sizes
+	if err := c.validateQueue(); err != nil {
+		return fmt.Errorf("queue configuration error: %w", err)
+	}
+
+	// Validate geocoding configuration (optional)
+	if err := c.validateGeocoding(); err != nil {
+		return fmt.Errorf("geocoding configuration error: %w", err)
+	}
+
+	// Validate locale configuration (optional)
+	if _, err := locale.New(c.LocaleOptions()); err != nil {
+		return fmt.Errorf("locale configuration error: %w", err)
+	}
+
+	// Validate summary email configuration (optional)
+	if err := c.validateSummaryEmail(); err != nil {
+		return fmt.Errorf("summary_email configuration error: %w", err)
+	}
+
+	if c.Maintenance.TitleCacheMaxAgeDays < 0 {
+		return fmt.Errorf("maintenance.title_cache_max_age_days cannot be negative, got %d", c.Maintenance.TitleCacheMaxAgeDays)
+	}
+
+	// Validate metrics push configuration (optional)
+	if err := c.validateMetrics(); err != nil {
+		return fmt.Errorf("metrics configuration error: %w", err)
+	}
+
 	// Ensure only one AI backend is configured
 	if err := c.validateAIBackendExclusivity(); err != nil {
 		return fmt.Errorf("AI backend configuration error: %w", err)
@@ -144,6 +747,18 @@ func (c *Config) applyDefaults() {
 		c.Server.Port = DefaultServerPort
 	}
 
+	// Log queries slower than a second
+	if c.Database.SlowQueryMs == 0 {
+		c.Database.SlowQueryMs = DefaultSlowQueryMs
+	}
+
+	if c.Database.MaxConcurrentQueries == 0 {
+		c.Database.MaxConcurrentQueries = DefaultMaxConcurrentQueries
+	}
+	if c.Database.MaxQueuedQueries == 0 {
+		c.Database.MaxQueuedQueries = DefaultMaxQueuedQueries
+	}
+
 	// Set default database ports
 	if c.Database.Port == 0 {
 		switch c.Database.Type {
@@ -158,6 +773,79 @@ func (c *Config) applyDefaults() {
 	if c.Server.CORS.AllowedOrigins == nil {
 		c.Server.CORS.AllowedOrigins = []string{}
 	}
+
+	// Ensure frame ancestors is not nil
+	if c.Server.FrameAncestors == nil {
+		c.Server.FrameAncestors = []string{}
+	}
+
+	// Serve images from Lychee's uploads directory by default
+	if c.ImageURLTemplate == "" {
+		c.ImageURLTemplate = constants.DefaultImageURLTemplate
+	}
+
+	// Set default reconciliation interval
+	if c.Sidecar.ReconcileMinutes == 0 {
+		c.Sidecar.ReconcileMinutes = DefaultReconcileMinutes
+	}
+
+	// Set default maintenance interval
+	if c.Maintenance.IntervalHours == 0 {
+		c.Maintenance.IntervalHours = DefaultMaintenanceHours
+	}
+
+	// Set default session timeouts
+	if c.Auth.Sessions.IdleTimeoutMinutes == 0 {
+		c.Auth.Sessions.IdleTimeoutMinutes = DefaultSessionIdleMinutes
+	}
+	if c.Auth.Sessions.AbsoluteTimeoutHours == 0 {
+		c.Auth.Sessions.AbsoluteTimeoutHours = DefaultSessionAbsoluteHours
+	}
+
+	// Set default metrics push job and interval
+	if c.Metrics.Push.Job == "" {
+		c.Metrics.Push.Job = DefaultMetricsJob
+	}
+	if c.Metrics.Push.IntervalSeconds == 0 {
+		c.Metrics.Push.IntervalSeconds = DefaultMetricsPushSeconds
+	}
+
+	// Set default queue page sizes, allowing pages up to the configured
+	// default size
+	if c.Queue.PageSize == 0 {
+		c.Queue.PageSize = constants.DefaultPhotoLimit
+	}
+	if c.Queue.MaxPageSize == 0 {
+		c.Queue.MaxPageSize = max(constants.MaxPhotoLimit, c.Queue.PageSize)
+	}
+
+	// Queue up to 10 AI generations beyond the concurrency limit
+	if c.AI.MaxQueuedGenerations == 0 {
+		c.AI.MaxQueuedGenerations = constants.DefaultMaxQueuedGenerations
+	}
+
+	// Send summary emails on Monday mornings through the submission port
+	if c.SummaryEmail.Weekday == "" {
+		c.SummaryEmail.Weekday = DefaultSummaryWeekday
+	}
+	if c.SummaryEmail.SMTP.Port == 0 {
+		c.SummaryEmail.SMTP.Port = DefaultSMTPPort
+		if c.SummaryEmail.SMTP.ImplicitTLS {
+			c.SummaryEmail.SMTP.Port = DefaultSMTPImplicitTLSPort
+		}
+	}
+
+	// Use the public Nominatim service by default
+	if c.Geocoding.URL == "" {
+		c.Geocoding.URL = constants.DefaultGeocodeURL
+	}
+	if c.Geocoding.UserAgent == "" {
+		c.Geocoding.UserAgent = constants.AppName + "/" + constants.AppVersion
+	}
+	// Look up place names in the owner's language
+	if c.Geocoding.Language == "" {
+		c.Geocoding.Language = c.Locale.Tag
+	}
 }
 
 // validateDatabase validates database configuration
@@ -197,6 +885,19 @@ func (c *Config) validateDatabase() error {
 		return fmt.Errorf("unsupported database type: %s (supported: mysql, postgres, sqlite)", c.Database.Type)
 	}
 
+	if c.Database.QueryTimeoutSeconds < 0 {
+		return fmt.Errorf("query_timeout_seconds cannot be negative, got %d", c.Database.QueryTimeoutSeconds)
+	}
+	if c.Database.SlowQueryMs < -1 {
+		return fmt.Errorf("slow_query_ms must be positive, or -1 to disable slow query logging, got %d", c.Database.SlowQueryMs)
+	}
+	if c.Database.MaxConcurrentQueries < -1 {
+		return fmt.Errorf("max_concurrent_queries must be positive, or -1 for no limit, got %d", c.Database.MaxConcurrentQueries)
+	}
+	if c.Database.MaxQueuedQueries < -1 {
+		return fmt.Errorf("max_queued_queries must be positive, or -1 to refuse every request beyond the limit, got %d", c.Database.MaxQueuedQueries)
+	}
+
 	return nil
 }
 
@@ -217,6 +918,25 @@ func (c *Config) validateServer() error {
 		}
 	}
 
+	// Validate frame ancestors; these are emitted verbatim into a CSP header,
+	// so they must be plain origins
+	for i, origin := range c.Server.FrameAncestors {
+		parsedURL, err := url.Parse(origin)
+		if err != nil {
+			return fmt.Errorf("frame_ancestors[%d] has invalid URL format %q: %w", i, origin, err)
+		}
+		if !strings.HasPrefix(parsedURL.Scheme, "http") || parsedURL.Host == "" {
+			return fmt.Errorf("frame_ancestors[%d] must be an http or https origin: %q", i, origin)
+		}
+		if strings.ContainsAny(origin, " ;,'\"") {
+			return fmt.Errorf("frame_ancestors[%d] contains invalid characters: %q", i, origin)
+		}
+	}
+
+	if strings.ContainsAny(c.Server.CSP.Policy, "\r\n") {
+		return fmt.Errorf("csp policy must be a single line")
+	}
+
 	return nil
 }
 
@@ -246,6 +966,152 @@ func (c *Config) validateLycheeBaseURL() error {
 	return nil
 }
 
+// validateImageURLTemplate validates the image URL template's placeholders
+// and that it expands to an http(s) URL
+func (c *Config) validateImageURLTemplate() error {
+	if !strings.Contains(c.ImageURLTemplate, constants.ImageURLShortPath) {
+		return fmt.Errorf("image_url_template must include %s: %q", constants.ImageURLShortPath, c.ImageURLTemplate)
+	}
+
+	for _, placeholder := range imageURLPlaceholderPattern.FindAllString(c.ImageURLTemplate, -1) {
+		if placeholder != constants.ImageURLBaseURL && placeholder != constants.ImageURLShortPath {
+			return fmt.Errorf("unknown placeholder %s in image_url_template (supported: %s, %s)",
+				placeholder, constants.ImageURLBaseURL, constants.ImageURLShortPath)
+		}
+	}
+
+	sample := strings.ReplaceAll(c.ImageURLPattern(), constants.ImageURLShortPath, "thumb/ab/cd/sample.jpg")
+	parsedURL, err := url.Parse(sample)
+	if err != nil {
+		return fmt.Errorf("image_url_template does not produce a valid URL (e.g. %q): %w", sample, err)
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" || parsedURL.Host == "" {
+		return fmt.Errorf("image_url_template must produce an absolute http or https URL, got: %q", sample)
+	}
+
+	return nil
+}
+
+// validateLycheeUploadsPath checks that the uploads directory exists and
+// that image URLs can be mapped back to files in it
+func (c *Config) validateLycheeUploadsPath() error {
+	if c.LycheeUploadsPath == "" {
+		return nil
+	}
+
+	info, err := os.Stat(c.LycheeUploadsPath)
+	if err != nil {
+		return fmt.Errorf("cannot access uploads directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("not a directory: %s", c.LycheeUploadsPath)
+	}
+
+	if strings.Count(c.ImageURLTemplate, constants.ImageURLShortPath) != 1 {
+		return fmt.Errorf("image_url_template must include %s exactly once to read images from disk", constants.ImageURLShortPath)
+	}
+
+	if _, ok := c.Storage.Disks[constants.DefaultStorageDisk]; ok {
+		return fmt.Errorf("cannot be used with storage.disks.%s; set its path there instead", constants.DefaultStorageDisk)
+	}
+
+	return nil
+}
+
+// headerNamePattern matches valid HTTP header field names
+var headerNamePattern = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+// validateOutbound validates the outbound User-Agent and headers
+func (c *Config) validateOutbound() error {
+	if strings.ContainsAny(c.Outbound.UserAgent, "\r\n") {
+		return fmt.Errorf("user_agent cannot contain line breaks")
+	}
+	for name, value := range c.Outbound.Headers {
+		if !headerNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid header name: %q", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("header %s cannot contain line breaks", name)
+		}
+		if strings.EqualFold(name, "Host") || strings.EqualFold(name, "User-Agent") {
+			return fmt.Errorf("header %s cannot be set here; use user_agent for the User-Agent", name)
+		}
+	}
+	return nil
+}
+
+// validateStorage validates each configured storage disk
+func (c *Config) validateStorage() error {
+	for name, disk := range c.Storage.Disks {
+		if err := c.validateStorageDisk(disk); err != nil {
+			return fmt.Errorf("disk %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// validateStorageDisk validates one storage disk's settings for its type
+func (c *Config) validateStorageDisk(disk StorageDiskConfig) error {
+	switch disk.Type {
+	case "http":
+		return nil
+	case "local":
+		if disk.Path == "" {
+			return fmt.Errorf("path is required for local disks")
+		}
+		info, err := os.Stat(disk.Path)
+		if err != nil {
+			return fmt.Errorf("cannot access directory: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("not a directory: %s", disk.Path)
+		}
+	case "s3":
+		if disk.Bucket == "" {
+			return fmt.Errorf("bucket is required for s3 disks")
+		}
+		if disk.Region != "" && !bedrockRegionPattern.MatchString(disk.Region) {
+			return fmt.Errorf("region is not a valid AWS region name: %q", disk.Region)
+		}
+		if (disk.AccessKeyID == "") != (disk.SecretAccessKey == "") {
+			return fmt.Errorf("access_key_id and secret_access_key must be set together")
+		}
+		if disk.Endpoint != "" {
+			parsedURL, err := url.Parse(disk.Endpoint)
+			if err != nil {
+				return fmt.Errorf("invalid endpoint format %q: %w", disk.Endpoint, err)
+			}
+			if parsedURL.Host == "" || !strings.HasPrefix(parsedURL.Scheme, "http") {
+				return fmt.Errorf("endpoint must be an http or https URL: %q", disk.Endpoint)
+			}
+		}
+	case "lychee":
+		if disk.URL != "" {
+			parsedURL, err := url.Parse(disk.URL)
+			if err != nil {
+				return fmt.Errorf("invalid URL format %q: %w", disk.URL, err)
+			}
+			if parsedURL.Host == "" || !strings.HasPrefix(parsedURL.Scheme, "http") {
+				return fmt.Errorf("url must be an http or https URL: %q", disk.URL)
+			}
+		}
+	default:
+		return fmt.Errorf("type must be http, local, s3 or lychee, got: %q", disk.Type)
+	}
+
+	// The other types find images by the short paths in their URLs
+	if strings.Count(c.ImageURLTemplate, constants.ImageURLShortPath) != 1 {
+		return fmt.Errorf("image_url_template must include %s exactly once to read images by their paths", constants.ImageURLShortPath)
+	}
+	return nil
+}
+
+// ImageURLPattern returns image_url_template with {base_url} filled in,
+// leaving {short_path} for each image
+func (c *Config) ImageURLPattern() string {
+	return strings.ReplaceAll(c.ImageURLTemplate, constants.ImageURLBaseURL, strings.TrimSuffix(c.LycheeBaseURL, "/"))
+}
+
 // validateOllama validates Ollama configuration (optional)
 func (c *Config) validateOllama() error {
 	// Ollama configuration is optional - if URL is empty, skip validation
@@ -283,6 +1149,29 @@ func (c *Config) validateOllama() error {
 		return fmt.Errorf("model name contains invalid characters (allowed: alphanumeric, dots, colons, hyphens, slashes): %q", c.Ollama.Model)
 	}
 
+	if c.Ollama.NumPredict < 0 {
+		return fmt.Errorf("num_predict cannot be negative, got: %d", c.Ollama.NumPredict)
+	}
+	if c.Ollama.TitleWords < 0 {
+		return fmt.Errorf("title_words cannot be negative, got: %d", c.Ollama.TitleWords)
+	}
+	if t := c.Ollama.Temperature; t != nil && (*t < 0 || *t > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2, got: %g", *t)
+	}
+	if p := c.Ollama.TopP; p != nil && (*p <= 0 || *p > 1) {
+		return fmt.Errorf("top_p must be greater than 0 and at most 1, got: %g", *p)
+	}
+	if c.Ollama.NumCtx < 0 {
+		return fmt.Errorf("num_ctx cannot be negative, got: %d", c.Ollama.NumCtx)
+	}
+	if c.Ollama.KeepAlive != "" {
+		if _, err := strconv.ParseFloat(c.Ollama.KeepAlive, 64); err != nil {
+			if _, err := time.ParseDuration(c.Ollama.KeepAlive); err != nil {
+				return fmt.Errorf("keep_alive must be a duration like 10m or a number of seconds, got: %q", c.Ollama.KeepAlive)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -302,6 +1191,46 @@ func (c *Config) GetDSN() string {
 	}
 }
 
+// ContentSecurityPolicy returns the Content-Security-Policy header value.
+// Unless overridden by server.csp.policy, the default allows only same-origin
+// resources, plus images from the Lychee instance and framing by any
+// configured frame_ancestors.
+func (c *Config) ContentSecurityPolicy() string {
+	if c.Server.CSP.Policy != "" {
+		return c.Server.CSP.Policy
+	}
+
+	imgSrc := "'self' data:"
+	for _, source := range []string{c.LycheeBaseURL, c.ImageURLPattern()} {
+		u, err := url.Parse(source)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			continue
+		}
+		if origin := u.Scheme + "://" + u.Host; !strings.Contains(imgSrc, " "+origin) {
+			imgSrc += " " + origin
+		}
+	}
+
+	frameAncestors := "'none'"
+	if len(c.Server.FrameAncestors) > 0 {
+		frameAncestors = "'self' " + strings.Join(c.Server.FrameAncestors, " ")
+	}
+
+	directives := []string{
+		"default-src 'self'",
+		"script-src 'self'",
+		"style-src 'self' 'unsafe-inline'",
+		"img-src " + imgSrc,
+		"connect-src 'self'",
+		"font-src 'self' data:",
+		"object-src 'none'",
+		"base-uri 'self'",
+		"form-action 'self'",
+		"frame-ancestors " + frameAncestors,
+	}
+	return strings.Join(directives, "; ")
+}
+
 // validateOpenAI validates OpenAI configuration (optional)
 func (c *Config) validateOpenAI() error {
 	// OpenAI configuration is optional - if URL is empty, skip validation
@@ -339,21 +1268,532 @@ func (c *Config) validateOpenAI() error {
 		return fmt.Errorf("model name contains invalid characters (allowed: alphanumeric, dots, colons, hyphens, slashes): %q", c.OpenAI.Model)
 	}
 
+	if c.OpenAI.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens cannot be negative, got: %d", c.OpenAI.MaxTokens)
+	}
+	if c.OpenAI.TitleWords < 0 {
+		return fmt.Errorf("title_words cannot be negative, got: %d", c.OpenAI.TitleWords)
+	}
+	switch c.OpenAI.StructuredOutput {
+	case "", "auto", "json_schema", "json_object", "off":
+	default:
+		return fmt.Errorf("structured_output must be auto, json_schema, json_object or off, got: %q", c.OpenAI.StructuredOutput)
+	}
+
+	return nil
+}
+
+// validateOpenAICompatible validates OpenAI-compatible server
+// configuration (optional)
+func (c *Config) validateOpenAICompatible() error {
+	// The server is optional - if nothing is set, skip validation
+	if c.OpenAICompatible == (OpenAICompatibleConfig{}) {
+		return nil
+	}
+
+	if c.OpenAICompatible.URL == "" {
+		return fmt.Errorf("url is required when openai_compatible is configured")
+	}
+	parsedURL, err := url.Parse(c.OpenAICompatible.URL)
+	if err != nil {
+		return fmt.Errorf("invalid URL format %q: %w", c.OpenAICompatible.URL, err)
+	}
+	if parsedURL.Host == "" || !strings.HasPrefix(parsedURL.Scheme, "http") {
+		return fmt.Errorf("url must be an http or https URL: %q", c.OpenAICompatible.URL)
+	}
+
+	// LM Studio model identifiers may name a quantization after an @
+	if c.OpenAICompatible.Model != "" && !modelNamePattern.MatchString(strings.Replace(c.OpenAICompatible.Model, "@", ":", 1)) {
+		return fmt.Errorf("model name contains invalid characters (allowed: alphanumeric, dots, colons, hyphens, slashes): %q", c.OpenAICompatible.Model)
+	}
+
+	if c.OpenAICompatible.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens cannot be negative, got: %d", c.OpenAICompatible.MaxTokens)
+	}
+	if c.OpenAICompatible.TitleWords < 0 {
+		return fmt.Errorf("title_words cannot be negative, got: %d", c.OpenAICompatible.TitleWords)
+	}
+	switch c.OpenAICompatible.StructuredOutput {
+	case "", "auto", "json_schema", "json_object", "off":
+	default:
+		return fmt.Errorf("structured_output must be auto, json_schema, json_object or off, got: %q", c.OpenAICompatible.StructuredOutput)
+	}
+
+	return nil
+}
+
+// validateClaude validates Claude configuration (optional)
+func (c *Config) validateClaude() error {
+	// Claude configuration is optional - if nothing is set, skip validation
+	if c.Claude == (ClaudeConfig{}) {
+		return nil
+	}
+
+	if c.Claude.APIKey == "" {
+		return fmt.Errorf("api_key is required when claude is configured")
+	}
+
+	if c.Claude.URL != "" {
+		parsedURL, err := url.Parse(c.Claude.URL)
+		if err != nil {
+			return fmt.Errorf("invalid URL format %q: %w", c.Claude.URL, err)
+		}
+		if parsedURL.Host == "" || !strings.HasPrefix(parsedURL.Scheme, "http") {
+			return fmt.Errorf("url must be an http or https URL: %q", c.Claude.URL)
+		}
+	}
+
+	if c.Claude.Model != "" && !modelNamePattern.MatchString(c.Claude.Model) {
+		return fmt.Errorf("model name contains invalid characters (allowed: alphanumeric, dots, colons, hyphens, slashes): %q", c.Claude.Model)
+	}
+
+	if c.Claude.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens cannot be negative, got: %d", c.Claude.MaxTokens)
+	}
+	if c.Claude.TitleWords < 0 {
+		return fmt.Errorf("title_words cannot be negative, got: %d", c.Claude.TitleWords)
+	}
+
+	return nil
+}
+
+// bedrockRegionPattern matches AWS region names, e.g. us-east-1
+var bedrockRegionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-\d+$`)
+
+// validateBedrock validates AWS Bedrock configuration. Credentials may come
+// from the environment, so they're checked when the client is created.
+func (c *Config) validateBedrock() error {
+	// Bedrock configuration is optional - if nothing is set, skip validation
+	if c.Bedrock == (BedrockConfig{}) {
+		return nil
+	}
+
+	if c.Bedrock.Region != "" && !bedrockRegionPattern.MatchString(c.Bedrock.Region) {
+		return fmt.Errorf("region is not a valid AWS region name: %q", c.Bedrock.Region)
+	}
+
+	if (c.Bedrock.AccessKeyID == "") != (c.Bedrock.SecretAccessKey == "") {
+		return fmt.Errorf("access_key_id and secret_access_key must be set together")
+	}
+
+	if c.Bedrock.URL != "" {
+		parsedURL, err := url.Parse(c.Bedrock.URL)
+		if err != nil {
+			return fmt.Errorf("invalid URL format %q: %w", c.Bedrock.URL, err)
+		}
+		if parsedURL.Host == "" || !strings.HasPrefix(parsedURL.Scheme, "http") {
+			return fmt.Errorf("url must be an http or https URL: %q", c.Bedrock.URL)
+		}
+	}
+
+	if c.Bedrock.Model != "" && !modelNamePattern.MatchString(c.Bedrock.Model) {
+		return fmt.Errorf("model name contains invalid characters (allowed: alphanumeric, dots, colons, hyphens, slashes): %q", c.Bedrock.Model)
+	}
+
+	if c.Bedrock.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens cannot be negative, got: %d", c.Bedrock.MaxTokens)
+	}
+	if c.Bedrock.TitleWords < 0 {
+		return fmt.Errorf("title_words cannot be negative, got: %d", c.Bedrock.TitleWords)
+	}
+
+	return nil
+}
+
+// validateHTTPCaptioner validates captioning service configuration
+func (c *Config) validateHTTPCaptioner() error {
+	// Captioner configuration is optional - if nothing is set, skip validation
+	if c.HTTPCaptioner == (HTTPCaptionerConfig{}) {
+		return nil
+	}
+
+	if c.HTTPCaptioner.URL == "" {
+		return fmt.Errorf("url is required when http_captioner is configured")
+	}
+	parsedURL, err := url.Parse(c.HTTPCaptioner.URL)
+	if err != nil {
+		return fmt.Errorf("invalid URL format %q: %w", c.HTTPCaptioner.URL, err)
+	}
+	if parsedURL.Host == "" || !strings.HasPrefix(parsedURL.Scheme, "http") {
+		return fmt.Errorf("url must be an http or https URL: %q", c.HTTPCaptioner.URL)
+	}
+
+	switch c.HTTPCaptioner.RequestFormat {
+	case "", "raw", "multipart", "json":
+	default:
+		return fmt.Errorf("request_format must be raw, multipart or json, got: %q", c.HTTPCaptioner.RequestFormat)
+	}
+
+	return nil
+}
+
+// validateSidecar validates the sidecar store path
+func (c *Config) validateSidecar() error {
+	if c.Sidecar.Path == "" {
+		return nil
+	}
+
+	dir := filepath.Dir(c.Sidecar.Path)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return fmt.Errorf("directory for sidecar store does not exist: %s", dir)
+	}
+
+	return nil
+}
+
+// validateQueue validates the queue page sizes
+func (c *Config) validateQueue() error {
+	if c.Queue.MaxPageSize < 1 || c.Queue.MaxPageSize > constants.PhotoLimitCeiling {
+		return fmt.Errorf("max_page_size must be between 1 and %d, got %d", constants.PhotoLimitCeiling, c.Queue.MaxPageSize)
+	}
+	if c.Queue.PageSize < 1 || c.Queue.PageSize > c.Queue.MaxPageSize {
+		return fmt.Errorf("page_size must be between 1 and max_page_size (%d), got %d", c.Queue.MaxPageSize, c.Queue.PageSize)
+	}
+	return nil
+}
+
+// validateGeocoding validates the reverse geocoding service URL
+func (c *Config) validateGeocoding() error {
+	if !c.Geocoding.Enabled {
+		return nil
+	}
+
+	parsedURL, err := url.Parse(c.Geocoding.URL)
+	if err != nil {
+		return fmt.Errorf("invalid URL format %q: %w", c.Geocoding.URL, err)
+	}
+
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return fmt.Errorf("url must use http or https scheme, got: %q", parsedURL.Scheme)
+	}
+
+	if parsedURL.Host == "" {
+		return fmt.Errorf("url must include host: %q", c.Geocoding.URL)
+	}
+
+	return nil
+}
+
+// validateMetrics validates the Pushgateway URL and push interval
+func (c *Config) validateMetrics() error {
+	p := c.Metrics.Push
+	if p.URL == "" {
+		return nil
+	}
+
+	parsedURL, err := url.Parse(p.URL)
+	if err != nil {
+		return fmt.Errorf("invalid push URL format %q: %w", p.URL, err)
+	}
+
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return fmt.Errorf("push url must use http or https scheme, got: %q", parsedURL.Scheme)
+	}
+
+	if parsedURL.Host == "" {
+		return fmt.Errorf("push url must include host: %q", p.URL)
+	}
+
+	if p.IntervalSeconds < 0 {
+		return fmt.Errorf("push interval_seconds cannot be negative, got %d", p.IntervalSeconds)
+	}
+
+	return nil
+}
+
+// validateSummaryEmail validates the summary email schedule, addresses and
+// mail server
+func (c *Config) validateSummaryEmail() error {
+	s := c.SummaryEmail
+	if !s.Enabled {
+		return nil
+	}
+
+	if _, err := mail.ParseAddress(s.From); err != nil {
+		return fmt.Errorf("invalid from address %q: %w", s.From, err)
+	}
+	if len(s.To) == 0 {
+		return fmt.Errorf("to must list at least one address")
+	}
+	for i, to := range s.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return fmt.Errorf("invalid to[%d] address %q: %w", i, to, err)
+		}
+	}
+
+	if _, ok := ParseWeekday(s.Weekday); !ok {
+		return fmt.Errorf("weekday must be a day of the week, got %q", s.Weekday)
+	}
+	if s.Hour < 0 || s.Hour > 23 {
+		return fmt.Errorf("hour must be between 0 and 23, got %d", s.Hour)
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q: %w", s.Timezone, err)
+	}
+
+	if s.SMTP.Host == "" {
+		return fmt.Errorf("smtp.host is required")
+	}
+	if s.SMTP.Port < MinPort || s.SMTP.Port > MaxPort {
+		return fmt.Errorf("smtp.port must be between %d and %d, got %d", MinPort, MaxPort, s.SMTP.Port)
+	}
+	return nil
+}
+
+// LocaleOptions returns the options for the configured locale
+func (c *Config) LocaleOptions() locale.Options {
+	return locale.Options{
+		Tag:         c.Locale.Tag,
+		DateFormat:  c.Locale.DateFormat,
+		TimeZone:    c.Locale.Timezone,
+		PlaceOrder:  locale.PlaceOrder(c.Locale.PlaceOrder),
+		HomeCountry: c.Locale.HomeCountry,
+	}
+}
+
+// ParseWeekday parses an English day name, such as "monday" or "Mon"
+func ParseWeekday(s string) (time.Weekday, bool) {
+	s = strings.ToLower(s)
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// validateAuth validates configured API tokens
+func (c *Config) validateAuth() error {
+	names := make(map[string]bool, len(c.Auth.Tokens))
+	secrets := make(map[string]bool, len(c.Auth.Tokens))
+
+	for i, t := range c.Auth.Tokens {
+		if !tokenNamePattern.MatchString(t.Name) {
+			return fmt.Errorf("tokens[%d] name must be 1-64 characters, alphanumeric with underscores and hyphens: %q", i, t.Name)
+		}
+		if names[t.Name] {
+			return fmt.Errorf("tokens[%d] name %q is used more than once", i, t.Name)
+		}
+		names[t.Name] = true
+
+		if len(t.Token) < MinAPITokenLength {
+			return fmt.Errorf("tokens[%d] (%s) token must be at least %d characters", i, t.Name, MinAPITokenLength)
+		}
+		if secrets[t.Token] {
+			return fmt.Errorf("tokens[%d] (%s) token is used by more than one entry", i, t.Name)
+		}
+		secrets[t.Token] = true
+
+		validScope := false
+		for _, scope := range validTokenScopes {
+			if t.Scope == scope {
+				validScope = true
+				break
+			}
+		}
+		if !validScope {
+			return fmt.Errorf("tokens[%d] (%s) has unsupported scope %q (supported: %s)", i, t.Name, t.Scope, strings.Join(validTokenScopes, ", "))
+		}
+	}
+
+	sessions := c.Auth.Sessions
+	if sessions.IdleTimeoutMinutes < 0 {
+		return fmt.Errorf("sessions.idle_timeout_minutes cannot be negative, got %d", sessions.IdleTimeoutMinutes)
+	}
+	if sessions.AbsoluteTimeoutHours < 0 {
+		return fmt.Errorf("sessions.absolute_timeout_hours cannot be negative, got %d", sessions.AbsoluteTimeoutHours)
+	}
+
+	return c.validateForwardAuth()
+}
+
+// validateForwardAuth validates the forward-auth identity headers and
+// trusted proxies
+func (c *Config) validateForwardAuth() error {
+	fa := c.Auth.ForwardAuth
+	if !fa.Enabled {
+		return nil
+	}
+
+	for _, header := range fa.Headers {
+		if !headerNamePattern.MatchString(header) {
+			return fmt.Errorf("forward_auth has an invalid header name: %q", header)
+		}
+	}
+
+	if len(fa.TrustedProxies) == 0 {
+		return fmt.Errorf("forward_auth requires trusted_proxies, the addresses requests from the auth proxy come from")
+	}
+	for _, proxy := range fa.TrustedProxies {
+		var err error
+		if strings.Contains(proxy, "/") {
+			_, err = netip.ParsePrefix(proxy)
+		} else {
+			_, err = netip.ParseAddr(proxy)
+		}
+		if err != nil {
+			return fmt.Errorf("forward_auth trusted_proxies entry %q is not an IP address or CIDR range", proxy)
+		}
+	}
+
+	for identity, role := range fa.Roles {
+		if !slices.Contains(validTokenScopes, role) {
+			return fmt.Errorf("forward_auth roles entry %q has unsupported role %q (supported: %s)", identity, role, strings.Join(validTokenScopes, ", "))
+		}
+	}
+	if fa.DefaultRole != "" && !slices.Contains(validTokenScopes, fa.DefaultRole) {
+		return fmt.Errorf("forward_auth default_role %q is not supported (supported: %s)", fa.DefaultRole, strings.Join(validTokenScopes, ", "))
+	}
+
+	return nil
+}
+
+// validateAI validates the generic AI backend configuration (optional).
+// Backend-specific settings are validated by the backend's constructor.
+func (c *Config) validateAI() error {
+	c.AI.TitleLanguage = strings.TrimSpace(c.AI.TitleLanguage)
+
+	if c.AI.TitleCachePath != "" {
+		dir := filepath.Dir(c.AI.TitleCachePath)
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			return fmt.Errorf("directory for title cache does not exist: %s", dir)
+		}
+	}
+
+	// The language is inserted into AI prompts
+	if strings.ContainsAny(c.AI.TitleLanguage, "\r\n") {
+		return fmt.Errorf("title_language must be a single line")
+	}
+	if len([]rune(c.AI.TitleLanguage)) > constants.MaxTitleLanguageLength {
+		return fmt.Errorf("title_language is too long (max %d characters)", constants.MaxTitleLanguageLength)
+	}
+
+	if c.AI.MaxConcurrentGenerations < 0 {
+		return fmt.Errorf("max_concurrent_generations cannot be negative, got: %d", c.AI.MaxConcurrentGenerations)
+	}
+	if c.AI.MaxQueuedGenerations < -1 {
+		return fmt.Errorf("max_queued_generations must be -1 or more, got: %d", c.AI.MaxQueuedGenerations)
+	}
+
+	for model, price := range c.AI.Pricing {
+		if price.InputPerMillion < 0 || price.OutputPerMillion < 0 {
+			return fmt.Errorf("pricing for %s cannot be negative", model)
+		}
+	}
+
+	if c.AI.Backend == "" {
+		if len(c.AI.Settings) > 0 {
+			return fmt.Errorf("backend is required when settings are specified")
+		}
+		return nil
+	}
+
+	if !backendNamePattern.MatchString(c.AI.Backend) {
+		return fmt.Errorf("backend name contains invalid characters (allowed: lowercase alphanumeric, underscores, hyphens): %q", c.AI.Backend)
+	}
+
+	if model := c.AI.Settings["model"]; model != "" && !modelNamePattern.MatchString(model) {
+		return fmt.Errorf("model name contains invalid characters (allowed: alphanumeric, dots, colons, hyphens, slashes): %q", model)
+	}
+
 	return nil
 }
 
 // validateAIBackendExclusivity ensures only one AI backend is configured
 func (c *Config) validateAIBackendExclusivity() error {
-	ollamaEnabled := c.Ollama.URL != "" && c.Ollama.Model != ""
-	openAIEnabled := c.OpenAI.URL != "" && c.OpenAI.APIKey != ""
+	configured := 0
+	for _, enabled := range []bool{c.IsOllamaEnabled(), c.IsOpenAIEnabled(), c.IsOpenAICompatibleEnabled(), c.IsClaudeEnabled(), c.IsBedrockEnabled(), c.IsHTTPCaptionerEnabled(), c.AI.Backend != ""} {
+		if enabled {
+			configured++
+		}
+	}
 
-	if ollamaEnabled && openAIEnabled {
-		return fmt.Errorf("cannot configure both Ollama and OpenAI backends simultaneously. Please choose one")
+	if configured > 1 {
+		return fmt.Errorf("cannot configure more than one AI backend (ollama, openai, openai_compatible, claude, bedrock, http_captioner, ai) simultaneously. Please choose one")
 	}
 
 	return nil
 }
 
+// AIBackend returns the name and settings of the configured AI backend,
+// or an empty name if AI title generation is not configured.
+func (c *Config) AIBackend() (string, map[string]string) {
+	switch {
+	case c.IsOllamaEnabled():
+		return "ollama", map[string]string{
+			"url":         c.Ollama.URL,
+			"model":       c.Ollama.Model,
+			"auto_pull":   strconv.FormatBool(c.Ollama.AutoPull),
+			"num_predict": strconv.Itoa(c.Ollama.NumPredict),
+			"title_words": strconv.Itoa(c.Ollama.TitleWords),
+			"temperature": formatOptionalFloat(c.Ollama.Temperature),
+			"top_p":       formatOptionalFloat(c.Ollama.TopP),
+			"num_ctx":     strconv.Itoa(c.Ollama.NumCtx),
+			"keep_alive":  c.Ollama.KeepAlive,
+		}
+	case c.IsOpenAIEnabled():
+		return "openai", map[string]string{
+			"url":               c.OpenAI.URL,
+			"api_key":           c.OpenAI.APIKey,
+			"model":             c.OpenAI.Model,
+			"max_tokens":        strconv.Itoa(c.OpenAI.MaxTokens),
+			"title_words":       strconv.Itoa(c.OpenAI.TitleWords),
+			"structured_output": c.OpenAI.StructuredOutput,
+		}
+	case c.IsOpenAICompatibleEnabled():
+		return "openai_compatible", map[string]string{
+			"url":               c.OpenAICompatible.URL,
+			"path":              c.OpenAICompatible.Path,
+			"api_key":           c.OpenAICompatible.APIKey,
+			"model":             c.OpenAICompatible.Model,
+			"max_tokens":        strconv.Itoa(c.OpenAICompatible.MaxTokens),
+			"title_words":       strconv.Itoa(c.OpenAICompatible.TitleWords),
+			"structured_output": c.OpenAICompatible.StructuredOutput,
+		}
+	case c.IsClaudeEnabled():
+		return "claude", map[string]string{
+			"url":         c.Claude.URL,
+			"api_key":     c.Claude.APIKey,
+			"model":       c.Claude.Model,
+			"max_tokens":  strconv.Itoa(c.Claude.MaxTokens),
+			"title_words": strconv.Itoa(c.Claude.TitleWords),
+		}
+	case c.IsBedrockEnabled():
+		return "bedrock", map[string]string{
+			"region":            c.Bedrock.Region,
+			"model":             c.Bedrock.Model,
+			"access_key_id":     c.Bedrock.AccessKeyID,
+			"secret_access_key": c.Bedrock.SecretAccessKey,
+			"session_token":     c.Bedrock.SessionToken,
+			"api_key":           c.Bedrock.APIKey,
+			"url":               c.Bedrock.URL,
+			"max_tokens":        strconv.Itoa(c.Bedrock.MaxTokens),
+			"title_words":       strconv.Itoa(c.Bedrock.TitleWords),
+		}
+	case c.IsHTTPCaptionerEnabled():
+		return "http_captioner", map[string]string{
+			"url":            c.HTTPCaptioner.URL,
+			"api_key":        c.HTTPCaptioner.APIKey,
+			"request_format": c.HTTPCaptioner.RequestFormat,
+			"caption_field":  c.HTTPCaptioner.CaptionField,
+		}
+	case c.AI.Backend != "":
+		return c.AI.Backend, c.AI.Settings
+	default:
+		return "", nil
+	}
+}
+
+// formatOptionalFloat formats f as a setting value, or returns an empty
+// string if it is unset
+func formatOptionalFloat(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f, 'g', -1, 64)
+}
+
 // IsOllamaEnabled returns true if Ollama configuration is provided and valid
 func (c *Config) IsOllamaEnabled() bool {
 	return c.Ollama.URL != "" && c.Ollama.Model != ""
@@ -363,3 +1803,25 @@ func (c *Config) IsOllamaEnabled() bool {
 func (c *Config) IsOpenAIEnabled() bool {
 	return c.OpenAI.URL != "" && c.OpenAI.APIKey != ""
 }
+
+// IsOpenAICompatibleEnabled returns true if an OpenAI-compatible server is
+// configured
+func (c *Config) IsOpenAICompatibleEnabled() bool {
+	return c.OpenAICompatible.URL != ""
+}
+
+// IsClaudeEnabled returns true if Claude configuration is provided and valid
+func (c *Config) IsClaudeEnabled() bool {
+	return c.Claude.APIKey != ""
+}
+
+// IsBedrockEnabled returns true if the bedrock section is configured. The
+// region and credentials may instead come from the environment.
+func (c *Config) IsBedrockEnabled() bool {
+	return c.Bedrock != (BedrockConfig{})
+}
+
+// IsHTTPCaptionerEnabled returns true if a captioning service is configured
+func (c *Config) IsHTTPCaptionerEnabled() bool {
+	return c.HTTPCaptioner.URL != ""
+}
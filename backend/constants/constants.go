@@ -5,7 +5,7 @@
 // The constants are organized into logical groups:
 //   - HTTP-related constants (content types, methods)
 //   - API path constants and patterns
-//   - Database query limits and constraints  
+//   - Database query limits and constraints
 //   - Timeout and duration settings
 //   - File format and validation patterns
 //   - Application metadata and defaults
@@ -16,9 +16,16 @@ import "time"
 // HTTP Constants
 const (
 	// Content types
-	ContentTypeJSON = "application/json"
-	ContentTypeHTML = "text/html"
-	ContentTypeText = "text/plain"
+	ContentTypeJSON        = "application/json"
+	ContentTypeHTML        = "text/html"
+	ContentTypeText        = "text/plain"
+	ContentTypeJSONL       = "application/x-ndjson"
+	ContentTypeEventStream = "text/event-stream"
+	ContentTypeProblemJSON = "application/problem+json"
+
+	// ProblemTypeBaseURL prefixes error codes to form the type URIs of
+	// problem details responses
+	ProblemTypeBaseURL = "https://github.com/cdzombak/lychee-meta-tool/blob/main/docs/errors.md#"
 
 	// HTTP methods (for documentation/consistency)
 	MethodGET    = "GET"
@@ -30,15 +37,16 @@ const (
 // API Constants
 const (
 	// API path prefixes
-	APIPrefix     = "/api"
-	PhotosPrefix  = "/api/photos"
-	AlbumsPrefix  = "/api/albums"
-	HealthPrefix  = "/health"
+	APIPrefix    = "/api"
+	APIv1Prefix  = "/api/v1"
+	PhotosPrefix = "/api/photos"
+	AlbumsPrefix = "/api/albums"
+	HealthPrefix = "/health"
 
 	// API path suffixes
-	GenerateTitleSuffix = "/generate-title"
+	GenerateTitleSuffix   = "/generate-title"
 	WithPhotoCountsSuffix = "/withphotocounts"
-	NeedsMetadataSuffix = "/needsmetadata"
+	NeedsMetadataSuffix   = "/needsmetadata"
 )
 
 // Database Constants
@@ -47,6 +55,8 @@ const (
 	DefaultPhotoLimit = 1000
 	MaxPhotoLimit     = 1000
 	MinPhotoOffset    = 0
+	// PhotoLimitCeiling caps the configurable maximum page size
+	PhotoLimitCeiling = 10000
 
 	// ID constraints
 	MinIDLength = 1
@@ -55,21 +65,134 @@ const (
 	// Text field limits
 	MaxPhotoTitleLength       = 255
 	MaxPhotoDescriptionLength = 2000
+
+	// MinTakenAtYear is the earliest year a photo can be taken, that of the
+	// oldest surviving photograph
+	MinTakenAtYear = 1826
+
+	// MinAltitude and MaxAltitude bound a photo's altitude in meters, from
+	// the deepest ocean trench to the edge of space
+	MinAltitude = -11000
+	MaxAltitude = 100000
+
+	// MaxTitleLanguageLength limits title languages, which are inserted
+	// into AI prompts
+	MaxTitleLanguageLength = 50
 )
 
 // Timeout Constants
 const (
 	// HTTP timeouts
-	DefaultHTTPTimeout = 30 * time.Second
+	DefaultHTTPTimeout   = 30 * time.Second
 	ImageDownloadTimeout = 30 * time.Second
 
 	// AI generation timeouts
 	AIGenerationTimeout = 2 * time.Minute
 	OllamaClientTimeout = 5 * time.Minute
 
+	// AI backend retries: attempts per request, and the first and longest
+	// delay between them (doubling in between)
+	AIRetryAttempts  = 3
+	AIRetryBaseDelay = 1 * time.Second
+	AIRetryMaxDelay  = 10 * time.Second
+
+	// AI backend circuit breaker: consecutive failed requests before
+	// requests are refused, and how long until one is tried again
+	AIBreakerThreshold = 3
+	AIBreakerCooldown  = 30 * time.Second
+
 	// Database timeouts
 	DatabaseConnectionTimeout = 10 * time.Second
-	DatabaseQueryTimeout     = 30 * time.Second
+	DatabaseQueryTimeout      = 30 * time.Second
+
+	// SkipUndoWindow is how long a bulk skip can be undone
+	SkipUndoWindow = 10 * time.Minute
+
+	// Change feed: how long a long poll waits for events by default and at
+	// most, how often a stream sends a keep-alive comment, and how often
+	// Lychee is checked for imported photos
+	ChangesPollTimeout       = 25 * time.Second
+	MaxChangesPollTimeout    = 60 * time.Second
+	ChangesHeartbeatInterval = 30 * time.Second
+	ChangesImportInterval    = 30 * time.Second
+
+	// SMTPTimeout limits sending a summary email
+	SMTPTimeout = 30 * time.Second
+	// SummaryPeriod is how much activity a summary email covers
+	SummaryPeriod = 7 * 24 * time.Hour
+
+	// SlowQueryLogSize is how many recent slow queries are kept for the
+	// admin API, and SlowQueryArgLength how much of a string argument is
+	// shown
+	SlowQueryLogSize   = 100
+	SlowQueryArgLength = 40
+
+	// QueryQueueTimeout is how long an expensive read request waits for a
+	// slot before giving up with HTTP 503, and QueryRetryAfter the
+	// Retry-After sent when it is refused
+	QueryQueueTimeout = 10 * time.Second
+	QueryRetryAfter   = 5 * time.Second
+
+	// AlbumCacheSize is how many pages of the album list are cached
+	AlbumCacheSize = 50
+	// RecentAgeCacheTTL is how long Lychee's recent_age setting is cached
+	RecentAgeCacheTTL = 5 * time.Minute
+
+	// MetricsPushTimeout limits pushing metrics to a Pushgateway
+	MetricsPushTimeout = 10 * time.Second
+
+	// EditLockTTL is how long an edit lock on a photo lasts unless its
+	// client renews it
+	EditLockTTL = 2 * time.Minute
+
+	// MaxSessionsPerToken caps the API sessions open with one token; the
+	// least recently used is ended to make room for a new one
+	MaxSessionsPerToken = 100
+
+	// MaxTakenAtShift bounds how far a bulk time zone correction may move
+	// photos' capture times, a day beyond the 26 hours between the
+	// furthest-apart time zones
+	MaxTakenAtShift = 50 * time.Hour
+	// MaxTakenAtShiftPhotos caps the photos one correction may change
+	MaxTakenAtShiftPhotos = 1000
+
+	// BurstWindow is the longest gap between consecutive shots from the
+	// same camera for them to be grouped as a burst
+	BurstWindow = time.Minute
+
+	// Lychee media probe: how long to wait for a sample image, and how
+	// long health checks reuse a probe result
+	MediaProbeTimeout  = 10 * time.Second
+	MediaProbeInterval = 5 * time.Minute
+
+	// AIHealthTimeout is how long the AI health check waits for the backend
+	AIHealthTimeout = 10 * time.Second
+
+	// AI generation limits: how many generations may wait for a slot when
+	// ai.max_queued_generations is unset, and the Retry-After sent when
+	// the queue is full
+	DefaultMaxQueuedGenerations = 10
+	AIQueueRetryAfter           = 5 * time.Second
+
+	// AIUsageRecentRequests is how many recent AI requests the usage report
+	// lists individually
+	AIUsageRecentRequests = 100
+
+	// Reverse geocoding: how long to wait for the service, and the least
+	// time between requests (Nominatim's usage policy allows one a second)
+	GeocodeTimeout     = 10 * time.Second
+	GeocodeMinInterval = 1 * time.Second
+
+	// ImageConvertTimeout bounds converting a HEIC or AVIF image to JPEG
+	ImageConvertTimeout = 30 * time.Second
+	// VideoFrameTimeout bounds extracting a frame from a video, which
+	// ffmpeg streams from Lychee
+	VideoFrameTimeout = 1 * time.Minute
+
+	// SidecarSaveDelay is how long the sidecar store waits to persist
+	// recomputable state, such as perceptual hashes, so that a run of
+	// updates is written once
+	SidecarSaveDelay = 5 * time.Second
 )
 
 // File and Image Constants
@@ -88,9 +211,24 @@ const (
 	MimePNG  = "image/png"
 	MimeGIF  = "image/gif"
 	MimeWEBP = "image/webp"
+	MimeHEIC = "image/heic"
+	MimeHEIF = "image/heif"
+	MimeAVIF = "image/avif"
 
 	// File size limits
 	MaxImageSize = 5 * 1024 * 1024 // 5MB
+
+	// Image URL template placeholders, for the lychee_base_url and a size
+	// variant's short_path
+	ImageURLBaseURL   = "{base_url}"
+	ImageURLShortPath = "{short_path}"
+
+	// DefaultImageURLTemplate is where Lychee serves its uploads by default
+	DefaultImageURLTemplate = ImageURLBaseURL + "/uploads/" + ImageURLShortPath
+
+	// DefaultStorageDisk is Lychee's local storage disk, assumed for images
+	// whose disk isn't known
+	DefaultStorageDisk = "images"
 )
 
 // Application Constants
@@ -99,6 +237,9 @@ const (
 	AppName    = "lychee-meta-tool"
 	AppVersion = "1.0.0"
 
+	// DefaultGeocodeURL is the public Nominatim reverse geocoding endpoint
+	DefaultGeocodeURL = "https://nominatim.openstreetmap.org/reverse"
+
 	// Environment variables
 	EnvConfigPath = "CONFIG_PATH"
 	EnvLogLevel   = "LOG_LEVEL"
@@ -112,32 +253,32 @@ const (
 	AlbumIDPattern = `^[a-zA-Z0-9_-]+$`
 
 	// Validation error templates
-	ErrInvalidIDFormat     = "invalid %s format (must be %d-%d characters, alphanumeric with underscores and hyphens)"
-	ErrTextTooLong         = "%s too long (max %d characters, got %d)"
-	ErrInvalidUTF8         = "%s contains invalid UTF-8 characters"
-	ErrDangerousContent    = "%s contains potentially dangerous content"
-	ErrRequiredField       = "%s is required"
-	ErrInvalidRange        = "%s must be between %d and %d, got %d"
+	ErrInvalidIDFormat  = "invalid %s format (must be %d-%d characters, alphanumeric with underscores and hyphens)"
+	ErrTextTooLong      = "%s too long (max %d characters, got %d)"
+	ErrInvalidUTF8      = "%s contains invalid UTF-8 characters"
+	ErrDangerousContent = "%s contains potentially dangerous content"
+	ErrRequiredField    = "%s is required"
+	ErrInvalidRange     = "%s must be between %d and %d, got %d"
 )
 
 // Log Message Templates
 const (
-	LogPhotoUpdate          = "Updated photo %s with fields: %+v"
-	LogAITitleGeneration    = "Generated AI title for photo %s: %s"
-	LogImageDownload        = "Downloaded image: Content-Type=%s, Status=%d, URL=%s"
-	LogDatabaseOperation    = "Database operation %s completed in %v"
-	LogValidationFailed     = "Validation failed for %s: %v"
-	LogOllamaClientCreated  = "Ollama client configured with URL: %s, Model: %s"
-	LogServerStarted        = "Server started on port %d"
-	LogConfigLoaded         = "Configuration loaded from %s"
+	LogPhotoUpdate         = "Updated photo %s with fields: %+v"
+	LogAITitleGeneration   = "Generated AI title for photo %s: %s"
+	LogImageDownload       = "Downloaded image: Content-Type=%s, Status=%d, URL=%s"
+	LogDatabaseOperation   = "Database operation %s completed in %v"
+	LogValidationFailed    = "Validation failed for %s: %v"
+	LogOllamaClientCreated = "Ollama client configured with URL: %s, Model: %s"
+	LogServerStarted       = "Server started on port %d"
+	LogConfigLoaded        = "Configuration loaded from %s"
 )
 
 // Configuration Defaults
 const (
-	DefaultServerPort    = 8080
-	DefaultDatabasePort  = 3306
-	DefaultPostgresPort  = 5432
-	DefaultOllamaPort    = 11434
-	DefaultLogLevel      = "info"
-	DefaultConfigPath    = "config.yaml"
+	DefaultServerPort   = 8080
+	DefaultDatabasePort = 3306
+	DefaultPostgresPort = 5432
+	DefaultOllamaPort   = 11434
+	DefaultLogLevel     = "info"
+	DefaultConfigPath   = "config.yaml"
 )
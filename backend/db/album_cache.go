@@ -0,0 +1,105 @@
+package db
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/models"
+)
+
+// albumPageKey identifies a page of the album list
+type albumPageKey struct {
+	title         string
+	limit, offset int
+}
+
+// albumPage is a cached page of the album list
+type albumPage struct {
+	albums []models.Album
+	total  int
+}
+
+// albumCache keeps pages of the album list, which the UI's album picker
+// requests constantly, until the albums change. Pages are stored with the
+// albums' version (see albumsVersion), and all are dropped once it moves on.
+type albumCache struct {
+	mu      sync.Mutex
+	version string
+	pages   map[albumPageKey]albumPage
+}
+
+// get returns the page for key, if it is cached at version
+func (c *albumCache) get(version string, key albumPageKey) (albumPage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.version != version {
+		return albumPage{}, false
+	}
+	page, ok := c.pages[key]
+	return page, ok
+}
+
+// put caches the page for key at version, dropping pages of older versions.
+// Once constants.AlbumCacheSize pages are cached, they are all dropped
+// rather than tracking which were used least recently.
+func (c *albumCache) put(version string, key albumPageKey, page albumPage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.version != version || len(c.pages) >= constants.AlbumCacheSize {
+		c.version = version
+		c.pages = make(map[albumPageKey]albumPage)
+	}
+	c.pages[key] = page
+}
+
+// albumsVersion returns a value that changes whenever a normal album is
+// added, updated or deleted: the number of albums and when one was last
+// updated
+func (db *DB) albumsVersion() (string, error) {
+	condition, args, err := db.albumFilterCondition(AlbumFilter{})
+	if err != nil {
+		return "", err
+	}
+
+	var count int
+	// The type of MAX(updated_at) varies by driver, so it is only printed
+	var updated interface{}
+	if err := db.QueryRow("SELECT COUNT(*), MAX(a.updated_at) FROM base_albums a"+condition, args...).Scan(&count, &updated); err != nil {
+		return "", fmt.Errorf("failed to check albums for changes: %w", err)
+	}
+	if b, ok := updated.([]byte); ok {
+		updated = string(b)
+	}
+	return fmt.Sprintf("%d/%v", count, updated), nil
+}
+
+// GetAlbums returns up to limit normal albums matching filter, ordered by
+// title and skipping the first offset, and how many albums match in total.
+// A limit of 0 returns every matching album. Results are cached until the
+// albums change.
+func (db *DB) GetAlbums(filter AlbumFilter, limit, offset int) ([]models.Album, int, error) {
+	// Which albums have pending photos depends on the photos, and which
+	// albums a user may edit on access_permissions, neither of which the
+	// albums' version covers
+	if filter.WithPending || filter.EditableBy != nil {
+		return db.queryAlbums(filter, limit, offset)
+	}
+
+	version, err := db.albumsVersion()
+	if err != nil {
+		return nil, 0, err
+	}
+	key := albumPageKey{title: filter.Title, limit: limit, offset: offset}
+	if page, ok := db.albums.get(version, key); ok {
+		return slices.Clone(page.albums), page.total, nil
+	}
+
+	albums, total, err := db.queryAlbums(filter, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	db.albums.put(version, key, albumPage{albums: slices.Clone(albums), total: total})
+	return albums, total, nil
+}
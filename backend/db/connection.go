@@ -1,31 +1,77 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/cdzombak/lychee-meta-tool/backend/config"
 
 	_ "github.com/go-sql-driver/mysql"
-	_ "github.com/lib/pq"
+	_ "github.com/jackc/pgx/v5/stdlib"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 type DB struct {
-	*sql.DB
+	mu     sync.RWMutex
+	conn   *pool
 	driver string
+
+	// queryTimeout bounds queries, and queries slower than slowQuery are
+	// logged; zero disables either. Both are guarded by mu, as they change
+	// on reconnect.
+	queryTimeout time.Duration
+	slowQuery    time.Duration
+	slow         slowLog
+
+	// albums caches pages of the album list, and recent Lychee's
+	// recent_age setting
+	albums albumCache
+	recent recentAgeCache
+
+	// columns caches hasColumn's probes of optional schema columns
+	schemaMu sync.Mutex
+	columns  map[string]bool
 }
 
 func Connect(cfg *config.Config) (*DB, error) {
+	conn, err := open(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db := &DB{
+		conn:   &pool{DB: conn},
+		driver: cfg.Database.Type,
+	}
+	db.queryTimeout, db.slowQuery = queryLimits(cfg)
+	return db, nil
+}
+
+// queryLimits returns the configured query timeout and slow query threshold
+func queryLimits(cfg *config.Config) (timeout, slow time.Duration) {
+	timeout = time.Duration(cfg.Database.QueryTimeoutSeconds) * time.Second
+	if cfg.Database.SlowQueryMs > 0 {
+		slow = time.Duration(cfg.Database.SlowQueryMs) * time.Millisecond
+	}
+	return timeout, slow
+}
+
+// open creates and verifies a new connection pool for the configured database
+func open(cfg *config.Config) (*sql.DB, error) {
 	var driverName string
 	switch cfg.Database.Type {
 	case "mysql":
 		driverName = "mysql"
 	case "postgres":
-		driverName = "postgres"
+		driverName = "pgx"
 	case "sqlite":
-		driverName = "sqlite3"
+		driverName = sqliteDriver
 	default:
 		return nil, fmt.Errorf("unsupported database type: %s", cfg.Database.Type)
 	}
@@ -44,10 +90,209 @@ func Connect(cfg *config.Config) (*DB, error) {
 	db.SetMaxIdleConns(5)
 	db.SetConnMaxLifetime(time.Hour)
 
-	return &DB{
-		DB:     db,
-		driver: cfg.Database.Type,
-	}, nil
+	return db, nil
+}
+
+// pool is a connection pool and the queries and transactions using it
+type pool struct {
+	*sql.DB
+	inflight sync.WaitGroup
+}
+
+// Reconnect opens a new connection pool using cfg and, once it is verified,
+// swaps it in place of the current pool. The old pool is closed once the
+// queries and transactions already using it finish. On failure the
+// existing pool is left untouched.
+func (db *DB) Reconnect(cfg *config.Config) error {
+	if cfg.Database.Type != db.driver {
+		return fmt.Errorf("cannot change database type from %s to %s without restarting", db.driver, cfg.Database.Type)
+	}
+
+	conn, err := open(cfg)
+	if err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	old := db.conn
+	db.conn = &pool{DB: conn}
+	db.queryTimeout, db.slowQuery = queryLimits(cfg)
+	db.mu.Unlock()
+
+	// The new database may be a different Lychee version
+	db.schemaMu.Lock()
+	db.columns = nil
+	db.schemaMu.Unlock()
+
+	// No new work can start on the old pool now that it has been swapped
+	// out, so once the work in flight drains it can be closed
+	go func() {
+		old.inflight.Wait()
+		if err := old.Close(); err != nil {
+			log.Printf("Failed to close the previous database connection pool: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+// acquire returns the current connection pool and a function releasing it,
+// to be called once the pool is no longer used. Reconnect doesn't close a
+// pool until every acquired use of it is released.
+func (db *DB) acquire() (*sql.DB, func()) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	p := db.conn
+	p.inflight.Add(1)
+	var once sync.Once
+	return p.DB, func() { once.Do(p.inflight.Done) }
+}
+
+// pool returns the current connection pool, for statistics and shutdown
+func (db *DB) pool() *sql.DB {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.conn.DB
+}
+
+// limits returns the query timeout and slow query threshold
+func (db *DB) limits() (timeout, slow time.Duration) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.queryTimeout, db.slowQuery
+}
+
+// queryContext returns a context bounding a query by timeout, if set, and
+// the function releasing it. Rows keep using the context, and the pool,
+// until they are closed, so both are released by Rows.Close and Row.Scan
+// rather than when the query returns.
+func queryContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.Background(), func() {}
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// Rows are the results of Query. Closing them releases the query's timeout
+// and connection pool.
+type Rows struct {
+	*sql.Rows
+	done func()
+}
+
+// Close closes the rows and releases the query's timeout and connection
+// pool
+func (r *Rows) Close() error {
+	err := r.Rows.Close()
+	r.done()
+	return err
+}
+
+// Row is the result of QueryRow. Scanning it releases the query's timeout
+// and connection pool.
+type Row struct {
+	*sql.Row
+	done func()
+}
+
+// Scan copies the row's columns into dest and releases the query's timeout
+// and connection pool
+func (r *Row) Scan(dest ...interface{}) error {
+	defer r.done()
+	return r.Row.Scan(dest...)
+}
+
+// Query, QueryRow and Exec take queries written with ? placeholders and
+// rewrite them for the database driver. They are bounded by the query
+// timeout, and logged if slow. A query's duration is the time until its
+// first results are ready; SQLite, which evaluates queries as rows are
+// read, reports only the time to start them.
+
+func (db *DB) Query(query string, args ...interface{}) (*Rows, error) {
+	timeout, slow := db.limits()
+	ctx, cancel := queryContext(timeout)
+	conn, release := db.acquire()
+	done := func() { cancel(); release() }
+	start := time.Now()
+	rows, err := conn.QueryContext(ctx, db.rebind(query), args...)
+	db.observe(slow, query, args, time.Since(start), err)
+	if err != nil {
+		done()
+		return nil, err
+	}
+	return &Rows{Rows: rows, done: done}, nil
+}
+
+func (db *DB) QueryRow(query string, args ...interface{}) *Row {
+	timeout, slow := db.limits()
+	ctx, cancel := queryContext(timeout)
+	conn, release := db.acquire()
+	start := time.Now()
+	row := conn.QueryRowContext(ctx, db.rebind(query), args...)
+	db.observe(slow, query, args, time.Since(start), row.Err())
+	return &Row{Row: row, done: func() { cancel(); release() }}
+}
+
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	timeout, slow := db.limits()
+	ctx, cancel := queryContext(timeout)
+	defer cancel()
+	conn, release := db.acquire()
+	defer release()
+	start := time.Now()
+	result, err := conn.ExecContext(ctx, db.rebind(query), args...)
+	db.observe(slow, query, args, time.Since(start), err)
+	return result, err
+}
+
+// rebind rewrites the ? placeholders in query as $1, $2, ... for
+// PostgreSQL, where pgx only accepts numbered placeholders. Question marks
+// in quoted strings and identifiers, such as regular expressions, are left
+// alone. Queries for other drivers are returned unchanged.
+func (db *DB) rebind(query string) string {
+	if db.driver != "postgres" || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			// A doubled quote inside a quoted string is an escaped quote,
+			// which this handles as closing and reopening the string
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '?':
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+func (db *DB) Ping() error {
+	conn, release := db.acquire()
+	defer release()
+	return conn.Ping()
+}
+
+func (db *DB) Close() error {
+	return db.pool().Close()
+}
+
+// Stats returns connection pool statistics for the current pool
+func (db *DB) Stats() sql.DBStats {
+	return db.pool().Stats()
 }
 
 func (db *DB) Driver() string {
@@ -56,4 +301,4 @@ func (db *DB) Driver() string {
 
 func (db *DB) Health() error {
 	return db.Ping()
-}
\ No newline at end of file
+}
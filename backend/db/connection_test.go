@@ -0,0 +1,150 @@
+package db
+
+import (
+	"testing"
+)
+
+func TestRebind(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "no placeholders",
+			query: "SELECT COUNT(*) FROM photos",
+			want:  "SELECT COUNT(*) FROM photos",
+		},
+		{
+			name:  "placeholders are numbered in order",
+			query: "SELECT id FROM photos WHERE album_id = ? AND title = ? LIMIT ? OFFSET ?",
+			want:  "SELECT id FROM photos WHERE album_id = $1 AND title = $2 LIMIT $3 OFFSET $4",
+		},
+		{
+			name:  "question mark in a string",
+			query: "SELECT id FROM photos WHERE title = 'Why?' AND id = ?",
+			want:  "SELECT id FROM photos WHERE title = 'Why?' AND id = $1",
+		},
+		{
+			name:  "regular expression",
+			query: `SELECT id FROM photos WHERE title ~ '^IMG_?\d+(\.jpe?g)?$' AND album_id = ?`,
+			want:  `SELECT id FROM photos WHERE title ~ '^IMG_?\d+(\.jpe?g)?$' AND album_id = $1`,
+		},
+		{
+			name:  "escaped quote in a string",
+			query: "SELECT id FROM photos WHERE title = 'it''s ?' AND id = ?",
+			want:  "SELECT id FROM photos WHERE title = 'it''s ?' AND id = $1",
+		},
+		{
+			name:  "string of an escaped quote",
+			query: "SELECT ? || '''' || ?",
+			want:  "SELECT $1 || '''' || $2",
+		},
+		{
+			name:  "empty string",
+			query: "SELECT id FROM photos WHERE title = '' AND id = ?",
+			want:  "SELECT id FROM photos WHERE title = '' AND id = $1",
+		},
+		{
+			name:  "double-quoted identifier",
+			query: `SELECT "what?" FROM photos WHERE id = ?`,
+			want:  `SELECT "what?" FROM photos WHERE id = $1`,
+		},
+		{
+			name:  "escaped quote in an identifier",
+			query: `SELECT "a""?" FROM photos WHERE id = ?`,
+			want:  `SELECT "a""?" FROM photos WHERE id = $1`,
+		},
+		{
+			name:  "backtick identifier",
+			query: "SELECT `why?` FROM photos WHERE id = ?",
+			want:  "SELECT `why?` FROM photos WHERE id = $1",
+		},
+		{
+			name:  "quotes of one kind inside another",
+			query: "SELECT 'say \"hi?\"', \"it's?\" FROM photos WHERE id = ?",
+			want:  "SELECT 'say \"hi?\"', \"it's?\" FROM photos WHERE id = $1",
+		},
+		{
+			name:  "adjacent placeholders",
+			query: "SELECT ?,?",
+			want:  "SELECT $1,$2",
+		},
+		{
+			name:  "double-digit placeholders",
+			query: "IN (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			want:  "IN ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)",
+		},
+	}
+
+	postgres := &DB{driver: "postgres"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := postgres.rebind(tt.query); got != tt.want {
+				t.Errorf("rebind(%q) =\n\t%q, want\n\t%q", tt.query, got, tt.want)
+			}
+		})
+	}
+
+	for _, driver := range []string{"mysql", "sqlite"} {
+		db := &DB{driver: driver}
+		for _, tt := range tests {
+			if got := db.rebind(tt.query); got != tt.query {
+				t.Errorf("%s: rebind(%q) = %q, want it unchanged", driver, tt.query, got)
+			}
+		}
+	}
+}
+
+// TestPlaceholders runs queries with placeholders, and question marks and
+// quotes that aren't placeholders, against each test database
+func TestPlaceholders(t *testing.T) {
+	for _, tdb := range testDatabases(t) {
+		t.Run(tdb.name, func(t *testing.T) {
+			db := tdb.db
+			if _, err := db.Exec("CREATE TABLE placeholder_test (id INTEGER NOT NULL, note VARCHAR(100))"); err != nil {
+				t.Fatalf("failed to create table: %v", err)
+			}
+			t.Cleanup(func() {
+				if _, err := db.Exec("DROP TABLE placeholder_test"); err != nil {
+					t.Errorf("failed to drop table: %v", err)
+				}
+			})
+
+			notes := []string{"plain", "why?", "it's", "'?'"}
+			for i, note := range notes {
+				if _, err := db.Exec("INSERT INTO placeholder_test (id, note) VALUES (?, ?)", i+1, note); err != nil {
+					t.Fatalf("failed to insert %q: %v", note, err)
+				}
+			}
+
+			var id int
+			if err := db.QueryRow("SELECT id FROM placeholder_test WHERE note = 'why?' AND id = ?", 2).Scan(&id); err != nil {
+				t.Fatalf("question mark in a string: %v", err)
+			}
+			if err := db.QueryRow("SELECT id FROM placeholder_test WHERE note = 'it''s' AND id > ?", 0).Scan(&id); err != nil || id != 3 {
+				t.Fatalf("escaped quote: got %d, %v; want 3", id, err)
+			}
+
+			rows, err := db.Query("SELECT note FROM placeholder_test WHERE id IN (?, ?) OR note = ? ORDER BY id", 1, 2, "'?'")
+			if err != nil {
+				t.Fatalf("Query: %v", err)
+			}
+			defer rows.Close()
+			var got []string
+			for rows.Next() {
+				var note string
+				if err := rows.Scan(&note); err != nil {
+					t.Fatalf("Scan: %v", err)
+				}
+				got = append(got, note)
+			}
+			if err := rows.Err(); err != nil {
+				t.Fatalf("rows: %v", err)
+			}
+			if want := []string{"plain", "why?", "'?'"}; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+				t.Errorf("notes = %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/models"
+)
+
+// editableCondition matches photos a Lychee user may edit: photos they own,
+// and photos in albums shared with them with edit rights. It takes the
+// user's ID twice, followed by true for the grants_edit flag.
+const editableCondition = `(p.owner_id = ? OR p.old_album_id IN (
+	SELECT base_album_id FROM access_permissions WHERE user_id = ? AND grants_edit = ?
+))`
+
+// editableAlbumCondition matches albums, aliased as a, a Lychee user may
+// edit: albums they own, and albums shared with them with edit rights. It
+// takes the same arguments as editableCondition.
+const editableAlbumCondition = `(a.owner_id = ? OR a.id IN (
+	SELECT base_album_id FROM access_permissions WHERE user_id = ? AND grants_edit = ?
+))`
+
+// GetLycheeUser returns the Lychee user with the given username, or nil if
+// there is no such user
+func (db *DB) GetLycheeUser(username string) (*models.LycheeUser, error) {
+	var user models.LycheeUser
+	err := db.QueryRow(
+		"SELECT id, username, may_administrate FROM users WHERE username = ?", username,
+	).Scan(&user.ID, &user.Username, &user.MayAdministrate)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get Lychee user: %w", err)
+	}
+
+	return &user, nil
+}
+
+// CanEditPhoto reports whether the Lychee user with the given ID may edit a photo
+func (db *DB) CanEditPhoto(userID int, photoID string) (bool, error) {
+	query := "SELECT COUNT(*) FROM photos p WHERE p.id = ? AND " + editableCondition
+
+	var count int
+	if err := db.QueryRow(query, photoID, userID, userID, true).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check photo permissions: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+// CanEditAlbum reports whether the Lychee user with the given ID may edit an
+// album, i.e. owns it or has been granted edit rights to it
+func (db *DB) CanEditAlbum(userID int, albumID string) (bool, error) {
+	query := "SELECT COUNT(*) FROM base_albums a WHERE a.id = ? AND " + editableAlbumCondition
+
+	var count int
+	if err := db.QueryRow(query, albumID, userID, userID, true).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check album permissions: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+// EditablePhotoIDs returns which of the given photos the Lychee user with
+// the given ID may edit
+func (db *DB) EditablePhotoIDs(userID int, ids []string) (map[string]bool, error) {
+	editable := make(map[string]bool, len(ids))
+
+	for start := 0; start < len(ids); start += updateTimesBatchSize {
+		batch := ids[start:min(start+updateTimesBatchSize, len(ids))]
+
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(batch)), ", ")
+		args := make([]interface{}, 0, len(batch)+3)
+		for _, id := range batch {
+			args = append(args, id)
+		}
+		args = append(args, userID, userID, true)
+
+		rows, err := db.Query("SELECT p.id FROM photos p WHERE p.id IN ("+placeholders+") AND "+editableCondition, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check photo permissions: %w", err)
+		}
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan photo ID: %w", err)
+			}
+			editable[id] = true
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate photo permissions: %w", err)
+		}
+	}
+
+	return editable, nil
+}
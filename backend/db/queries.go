@@ -4,12 +4,54 @@ import (
 	"database/sql"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/cdzombak/lychee-meta-tool/backend/models"
 )
 
-func (db *DB) GetPhotosNeedingMetadata(albumID *string, limit, offset int) ([]models.PhotoWithSizeVariants, error) {
-	query := `
+// needsTitleCondition matches photos whose title is empty or a generic
+// camera/app-generated name. Its patterns are written as MySQL string
+// literals; convertToPostgreSQL and convertToSQLite unescape them.
+const needsTitleCondition = `(
+	p.title = '' OR p.title IS NULL OR
+	p.title REGEXP '^[A-Za-z0-9]{3}_[0-9]+(\\.\\w+)?$' OR
+	p.title REGEXP '^P[0-9]{7}(\\.\\w+)?$' OR
+	p.title REGEXP '^[0-9]{8}_[0-9]{6}(\\.\\w+)?$' OR
+	p.title REGEXP '^IMG-[0-9]{8}-WA[0-9]{4}(\\.\\w+)?$' OR
+	p.title REGEXP '^Screenshot.*(\\.\\w+)?$' OR
+	p.title REGEXP '^[0-9a-fA-F]{8}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{12}(\\.\\w+)?$'
+)`
+
+// needsDescriptionCondition matches photos without a description
+const needsDescriptionCondition = `(p.description IS NULL OR p.description = '')`
+
+// What the photos in the needs-metadata queue lack
+const (
+	NeedsTitle       = "title"
+	NeedsDescription = "description"
+	// NeedsBoth matches photos lacking either a title or a description
+	NeedsBoth = "both"
+)
+
+// NeedsModes are the values of PhotoFilter.Needs
+var NeedsModes = []string{NeedsTitle, NeedsDescription, NeedsBoth}
+
+// needsCondition returns the condition matching photos that lack what
+// needs names; empty means NeedsTitle
+func needsCondition(needs string) string {
+	switch needs {
+	case NeedsDescription:
+		return needsDescriptionCondition
+	case NeedsBoth:
+		return "(" + needsTitleCondition + " OR " + needsDescriptionCondition + ")"
+	default:
+		return needsTitleCondition
+	}
+}
+
+// photoSelect selects the columns scanned by scanPhoto, joining each photo's
+// album title and size variant paths
+const photoSelect = `
 		SELECT
 			p.id, p.created_at, p.updated_at, p.owner_id, p.old_album_id,
 			p.title, p.description, p.license, p.is_starred,
@@ -19,35 +61,334 @@ func (db *DB) GetPhotosNeedingMetadata(albumID *string, limit, offset int) ([]mo
 			a.title as album_title,
 			sv_thumb.short_path as thumbnail_path,
 			sv_large.short_path as large_path,
+			sv_medium.short_path as medium_path,
 			sv_original.short_path as original_path
 		FROM photos p
 		LEFT JOIN base_albums a ON p.old_album_id = a.id
 		LEFT JOIN size_variants sv_thumb ON p.id = sv_thumb.photo_id AND sv_thumb.type = 6
 		LEFT JOIN size_variants sv_large ON p.id = sv_large.photo_id AND sv_large.type = 3
+		LEFT JOIN size_variants sv_medium ON p.id = sv_medium.photo_id AND sv_medium.type = 4
 		LEFT JOIN size_variants sv_original ON p.id = sv_original.photo_id AND sv_original.type = 0
-		WHERE (
-			p.title = '' OR p.title IS NULL OR
-			p.title REGEXP '^[A-Za-z0-9]{3}_[0-9]+(\\.\\w+)?$' OR
-			p.title REGEXP '^P[0-9]{7}(\\.\\w+)?$' OR
-			p.title REGEXP '^[0-9]{8}_[0-9]{6}(\\.\\w+)?$' OR
-			p.title REGEXP '^IMG-[0-9]{8}-WA[0-9]{4}(\\.\\w+)?$' OR
-			p.title REGEXP '^Screenshot.*(\\.\\w+)?$' OR
-			p.title REGEXP '^[0-9a-fA-F]{8}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{12}(\\.\\w+)?$'
-		)`
+`
 
-	args := []interface{}{}
-	
-	if albumID != nil {
-		query += " AND p.old_album_id = ?"
-		args = append(args, *albumID)
+// PhotoFilter restricts which photos a query returns. The zero value
+// matches all photos.
+type PhotoFilter struct {
+	// AlbumID restricts photos to a single album or smart album
+	AlbumID *string
+	// PublicOnly restricts photos to those publicly visible in Lychee
+	PublicOnly bool
+	// EditableBy restricts photos to those the Lychee user with this ID
+	// may edit
+	EditableBy *int
+	// IDs, if not nil, restricts photos to those with these IDs; an empty
+	// non-nil slice matches no photos
+	IDs []string
+	// ExcludeIDs leaves out the photos with these IDs
+	ExcludeIDs []string
+	// ExcludeAlbumIDs leaves out photos in these albums
+	ExcludeAlbumIDs []string
+	// Sort orders listed photos; counts ignore it
+	Sort PhotoSort
+	// Search restricts photos to those whose title, description or album
+	// title contains each of its words, ignoring case
+	Search string
+	// TakenAfter and TakenBefore restrict photos to those taken at or after
+	// and before these times; photos without a capture time match neither
+	TakenAfter  *time.Time
+	TakenBefore *time.Time
+	// Needs is what photos in the needs-metadata queue lack, one of
+	// NeedsModes; empty means NeedsTitle. Only GetPhotosNeedingMetadata and
+	// CountPhotosNeedingMetadata honor it.
+	Needs string
+}
+
+// Fields photos can be sorted by
+const (
+	SortCreatedAt = "created_at"
+	SortTakenAt   = "taken_at"
+	SortFilesize  = "filesize"
+	// SortAlbum sorts by album title, then newest first
+	SortAlbum = "album"
+	// SortRandom shuffles photos in an order picked by PhotoSort.Seed
+	SortRandom = "random"
+)
+
+// SortFields are the fields photos can be sorted by
+var SortFields = []string{SortCreatedAt, SortTakenAt, SortFilesize, SortAlbum, SortRandom}
+
+// Sort directions
+const (
+	SortAscending  = "asc"
+	SortDescending = "desc"
+)
+
+// PhotoSort orders a list of photos. The zero value lists the newest
+// uploads first.
+type PhotoSort struct {
+	// Field is one of SortFields; empty means SortCreatedAt
+	Field string
+	// Direction is SortAscending or SortDescending; empty means ascending
+	// for SortAlbum and descending otherwise. SortRandom ignores it.
+	Direction string
+	// Seed picks the SortRandom order, so that pages of one shuffle don't
+	// overlap. It must be between 1 and MaxSortSeed.
+	Seed int64
+	// After, if set, starts the list after the photo with this ID, which
+	// pages through photos without skipping or repeating any when photos
+	// before the page leave the list. Only GetPhotosNeedingMetadata
+	// honors it.
+	After string
+}
+
+// MaxSortSeed is the largest PhotoSort.Seed, one less than the prime
+// modulus of the random order
+const MaxSortSeed = 2147483646
+
+// sortKey is a term of a photo ordering: an expression over photos p and
+// their albums a
+type sortKey struct {
+	expr string
+	args []interface{}
+	desc bool
+	// nullsLast puts photos for which expr is NULL last, whatever the
+	// direction
+	nullsLast bool
+}
+
+// sortKeys returns the terms implementing sort, before the final tie
+// break by photo ID
+func sortKeys(sort PhotoSort) []sortKey {
+	desc := !(sort.Direction == SortAscending || (sort.Direction == "" && sort.Field == SortAlbum))
+
+	switch sort.Field {
+	case SortTakenAt:
+		// Photos without a capture time go last either way
+		return []sortKey{{expr: "p.taken_at", desc: desc, nullsLast: true}}
+	case SortFilesize:
+		return []sortKey{{expr: "p.filesize", desc: desc}}
+	case SortAlbum:
+		// Unsorted photos go last either way
+		return []sortKey{
+			{expr: "a.title", desc: desc, nullsLast: true},
+			{expr: "p.created_at", desc: true},
+		}
+	case SortRandom:
+		// Multiplying by the seed modulo a prime permutes file sizes, which
+		// every driver can do without a random function that takes a seed
+		return []sortKey{{expr: "(p.filesize % 2147483647) * ? % 2147483647", args: []interface{}{sort.Seed}}}
+	default:
+		return []sortKey{{expr: "p.created_at", desc: desc}}
 	}
+}
+
+// orderBy returns the ORDER BY clause and arguments implementing sort. Ties
+// are broken by photo ID so that pages don't overlap.
+func orderBy(sort PhotoSort) (string, []interface{}) {
+	var terms []string
+	var args []interface{}
+	for _, k := range sortKeys(sort) {
+		if k.nullsLast {
+			terms = append(terms, k.expr+" IS NULL")
+			args = append(args, k.args...)
+		}
+		term := k.expr
+		if k.desc {
+			term += " DESC"
+		} else if k.nullsLast {
+			term += " ASC"
+		}
+		terms = append(terms, term)
+		args = append(args, k.args...)
+	}
+	terms = append(terms, "p.id")
+	return " ORDER BY " + strings.Join(terms, ", "), args
+}
+
+// afterCondition returns the WHERE clause fragment (with a leading AND) and
+// arguments selecting the photos after sort.After in sort's order, or
+// nothing if it is unset. The cursor photo's sort values are read with
+// subqueries, so that they compare exactly whatever the driver's time
+// handling, and so it works even once the photo has left the list.
+func afterCondition(sort PhotoSort) (string, []interface{}) {
+	if sort.After == "" {
+		return "", nil
+	}
+
+	// Build the condition from the last term outwards: a photo is after
+	// the cursor if it is after it on a term, or tied on it and after it
+	// on the remaining terms
+	condition := "p.id > ?"
+	args := []interface{}{sort.After}
+	keys := sortKeys(sort)
+	for i := len(keys) - 1; i >= 0; i-- {
+		k := keys[i]
+		cursor := "(SELECT " + strings.NewReplacer("p.", "c.", "a.", "ca.").Replace(k.expr) + `
+			FROM photos c LEFT JOIN base_albums ca ON c.old_album_id = ca.id WHERE c.id = ?)`
+		cursorArgs := append(append([]interface{}{}, k.args...), sort.After)
+		op := " > "
+		if k.desc {
+			op = " < "
+		}
+
+		var next string
+		var nextArgs []interface{}
+		add := func(s string, a []interface{}) {
+			next += s
+			nextArgs = append(nextArgs, a...)
+		}
+		if k.nullsLast {
+			// NULLs sort last: after the cursor if only the photo's value
+			// is NULL, and tied if both are
+			add("(("+k.expr+" IS NULL AND "+cursor+" IS NOT NULL)", append(append([]interface{}{}, k.args...), cursorArgs...))
+			add(" OR "+k.expr+op+cursor, append(append([]interface{}{}, k.args...), cursorArgs...))
+			add(" OR (("+k.expr+" = "+cursor, append(append([]interface{}{}, k.args...), cursorArgs...))
+			add(" OR ("+k.expr+" IS NULL AND "+cursor+" IS NULL))", append(append([]interface{}{}, k.args...), cursorArgs...))
+		} else {
+			add("("+k.expr+op+cursor, append(append([]interface{}{}, k.args...), cursorArgs...))
+			add(" OR ("+k.expr+" = "+cursor, append(append([]interface{}{}, k.args...), cursorArgs...))
+		}
+		add(" AND "+condition+"))", args)
+		condition, args = next, nextArgs
+	}
+	return " AND " + condition, args
+}
+
+// filterCondition returns the WHERE clause fragments (each with a leading
+// AND) and arguments implementing filter
+func (db *DB) filterCondition(filter PhotoFilter) (string, []interface{}) {
+	var query string
+	var args []interface{}
+
+	if filter.AlbumID != nil {
+		condition, conditionArgs := db.albumCondition(*filter.AlbumID)
+		query += " AND " + condition
+		args = append(args, conditionArgs...)
+	}
+
+	if filter.PublicOnly {
+		query += " AND p.old_album_id IN " + publicAlbumIDs
+	}
+
+	if filter.EditableBy != nil {
+		query += " AND " + editableCondition
+		args = append(args, *filter.EditableBy, *filter.EditableBy, true)
+	}
+
+	if filter.IDs != nil {
+		if len(filter.IDs) == 0 {
+			query += " AND 1 = 0"
+		} else {
+			query += " AND p.id IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(filter.IDs)), ", ") + ")"
+			for _, id := range filter.IDs {
+				args = append(args, id)
+			}
+		}
+	}
+
+	if len(filter.ExcludeIDs) > 0 {
+		query += " AND p.id NOT IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(filter.ExcludeIDs)), ", ") + ")"
+		for _, id := range filter.ExcludeIDs {
+			args = append(args, id)
+		}
+	}
+
+	if len(filter.ExcludeAlbumIDs) > 0 {
+		query += " AND (p.old_album_id IS NULL OR p.old_album_id NOT IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(filter.ExcludeAlbumIDs)), ", ") + "))"
+		for _, id := range filter.ExcludeAlbumIDs {
+			args = append(args, id)
+		}
+	}
+
+	if filter.TakenAfter != nil {
+		query += " AND p.taken_at >= ?"
+		args = append(args, db.timeValue(*filter.TakenAfter))
+	}
+	if filter.TakenBefore != nil {
+		query += " AND p.taken_at < ?"
+		args = append(args, db.timeValue(*filter.TakenBefore))
+	}
+
+	for _, word := range strings.Fields(filter.Search) {
+		// The album title is matched with a subquery, since not every query
+		// using the filter joins base_albums
+		query += ` AND (LOWER(p.title) LIKE LOWER(?) ESCAPE '!'
+			OR LOWER(COALESCE(p.description, '')) LIKE LOWER(?) ESCAPE '!'
+			OR p.old_album_id IN (SELECT id FROM base_albums WHERE LOWER(title) LIKE LOWER(?) ESCAPE '!'))`
+		pattern := containsPattern(word)
+		args = append(args, pattern, pattern, pattern)
+	}
+
+	return query, args
+}
+
+// containsPattern returns a LIKE pattern, with ESCAPE '!', matching text
+// containing s. '!' escapes LIKE wildcards the same way in every supported
+// database.
+func containsPattern(s string) string {
+	return "%" + strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s) + "%"
+}
+
+// rowScanner is implemented by *Row and *Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanPhoto scans a row selected with photoSelect
+func scanPhoto(row rowScanner) (*models.PhotoWithSizeVariants, error) {
+	var photo models.PhotoWithSizeVariants
+	err := row.Scan(
+		&photo.ID, &photo.CreatedAt, &photo.UpdatedAt, &photo.OwnerID, &photo.AlbumID,
+		&photo.Title, &photo.Description, &photo.License, &photo.IsStarred,
+		&photo.ISO, &photo.Make, &photo.Model, &photo.Lens, &photo.Aperture, &photo.Shutter, &photo.Focal,
+		&photo.Latitude, &photo.Longitude, &photo.Altitude, &photo.ImgDirection, &photo.Location,
+		&photo.TakenAt, &photo.Type, &photo.Filesize, &photo.Checksum,
+		&photo.AlbumTitle, &photo.ThumbnailPath, &photo.LargePath, &photo.MediumPath, &photo.OriginalPath,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &photo, nil
+}
+
+// scanPhotos scans all rows selected with photoSelect
+func scanPhotos(rows *Rows) ([]models.PhotoWithSizeVariants, error) {
+	var photos []models.PhotoWithSizeVariants
+	for rows.Next() {
+		photo, err := scanPhoto(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan photo: %w", err)
+		}
+		photos = append(photos, *photo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate photos: %w", err)
+	}
+	return photos, nil
+}
+
+func (db *DB) GetPhotosNeedingMetadata(filter PhotoFilter, limit, offset int) ([]models.PhotoWithSizeVariants, error) {
+	query := photoSelect + `
+		WHERE ` + needsCondition(filter.Needs)
+
+	args := []interface{}{}
+
+	condition, conditionArgs := db.filterCondition(filter)
+	query += condition
+	args = append(args, conditionArgs...)
+
+	after, afterArgs := afterCondition(filter.Sort)
+	query += after
+	args = append(args, afterArgs...)
+
+	order, orderArgs := orderBy(filter.Sort)
+	query += order
+	args = append(args, orderArgs...)
 
-	query += " ORDER BY p.created_at DESC"
-	
 	if limit > 0 {
 		query += " LIMIT ?"
 		args = append(args, limit)
-		
+
 		if offset > 0 {
 			query += " OFFSET ?"
 			args = append(args, offset)
@@ -68,54 +409,226 @@ func (db *DB) GetPhotosNeedingMetadata(albumID *string, limit, offset int) ([]mo
 	}
 	defer rows.Close()
 
-	var photos []models.PhotoWithSizeVariants
-	for rows.Next() {
-		var photo models.PhotoWithSizeVariants
-		err := rows.Scan(
-			&photo.ID, &photo.CreatedAt, &photo.UpdatedAt, &photo.OwnerID, &photo.AlbumID,
-			&photo.Title, &photo.Description, &photo.License, &photo.IsStarred,
-			&photo.ISO, &photo.Make, &photo.Model, &photo.Lens, &photo.Aperture, &photo.Shutter, &photo.Focal,
-			&photo.Latitude, &photo.Longitude, &photo.Altitude, &photo.ImgDirection, &photo.Location,
-			&photo.TakenAt, &photo.Type, &photo.Filesize, &photo.Checksum,
-			&photo.AlbumTitle, &photo.ThumbnailPath, &photo.LargePath, &photo.OriginalPath,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan photo: %w", err)
+	return db.withSizeVariants(scanPhotos(rows))
+}
+
+// GetTitledPhotos returns up to limit photos, newest first, that match
+// filter and already have a meaningful title
+func (db *DB) GetTitledPhotos(filter PhotoFilter, limit int) ([]models.PhotoWithSizeVariants, error) {
+	query := photoSelect + `
+		WHERE NOT ` + needsTitleCondition
+
+	condition, args := db.filterCondition(filter)
+	query += condition
+
+	query += " ORDER BY p.created_at DESC"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	switch db.driver {
+	case "postgres":
+		query = db.convertToPostgreSQL(query)
+	case "sqlite":
+		query = db.convertToSQLite(query)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query titled photos: %w", err)
+	}
+	defer rows.Close()
+
+	return db.withSizeVariants(scanPhotos(rows))
+}
+
+// GetPhotos returns photos matching filter, newest first. A limit of 0
+// returns all of them.
+func (db *DB) GetPhotos(filter PhotoFilter, limit, offset int) ([]models.PhotoWithSizeVariants, error) {
+	query := photoSelect + `
+		WHERE 1 = 1`
+
+	condition, args := db.filterCondition(filter)
+	query += condition
+
+	query += " ORDER BY p.created_at DESC"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+
+		if offset > 0 {
+			query += " OFFSET ?"
+			args = append(args, offset)
 		}
-		photos = append(photos, photo)
 	}
 
-	return photos, nil
+	switch db.driver {
+	case "postgres":
+		query = db.convertToPostgreSQL(query)
+	case "sqlite":
+		query = db.convertToSQLite(query)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query photos: %w", err)
+	}
+	defer rows.Close()
+
+	return db.withSizeVariants(scanPhotos(rows))
 }
 
-func (db *DB) GetPhotoByID(id string) (*models.PhotoWithSizeVariants, error) {
+// CountPhotos returns the number of photos matching filter
+func (db *DB) CountPhotos(filter PhotoFilter) (int, error) {
+	condition, args := db.filterCondition(filter)
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM photos p WHERE 1 = 1"+condition, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count photos: %w", err)
+	}
+	return count, nil
+}
+
+// CountPhotosNeedingMetadata returns the number of photos that need metadata
+// and match filter
+func (db *DB) CountPhotosNeedingMetadata(filter PhotoFilter) (int, error) {
+	query := "SELECT COUNT(*) FROM photos p WHERE " + needsCondition(filter.Needs)
+
+	args := []interface{}{}
+	condition, conditionArgs := db.filterCondition(filter)
+	query += condition
+	args = append(args, conditionArgs...)
+
+	switch db.driver {
+	case "postgres":
+		query = db.convertToPostgreSQL(query)
+	case "sqlite":
+		query = db.convertToSQLite(query)
+	}
+
+	var count int
+	if err := db.QueryRow(query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count photos: %w", err)
+	}
+
+	return count, nil
+}
+
+// MissingFieldCounts counts photos missing each kind of metadata
+type MissingFieldCounts struct {
+	Title       int
+	Description int
+}
+
+// Any reports whether any of the photos counted need what needs, one of
+// NeedsModes or empty for a title, describes
+func (c MissingFieldCounts) Any(needs string) bool {
+	switch needs {
+	case NeedsDescription:
+		return c.Description > 0
+	case NeedsBoth:
+		return c.Title > 0 || c.Description > 0
+	default:
+		return c.Title > 0
+	}
+}
+
+// CountMissingFields returns how many photos matching filter need a title
+// and how many lack a description
+func (db *DB) CountMissingFields(filter PhotoFilter) (MissingFieldCounts, error) {
 	query := `
 		SELECT
-			p.id, p.created_at, p.updated_at, p.owner_id, p.old_album_id,
-			p.title, p.description, p.license, p.is_starred,
-			p.iso, p.make, p.model, p.lens, p.aperture, p.shutter, p.focal,
-			p.latitude, p.longitude, p.altitude, p.img_direction, p.location,
-			p.taken_at, p.type, p.filesize, p.checksum,
-			a.title as album_title,
-			sv_thumb.short_path as thumbnail_path,
-			sv_large.short_path as large_path,
-			sv_original.short_path as original_path
+			COALESCE(SUM(CASE WHEN ` + needsTitleCondition + ` THEN 1 ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN ` + needsDescriptionCondition + ` THEN 1 ELSE 0 END), 0)
 		FROM photos p
-		LEFT JOIN base_albums a ON p.old_album_id = a.id
-		LEFT JOIN size_variants sv_thumb ON p.id = sv_thumb.photo_id AND sv_thumb.type = 6
-		LEFT JOIN size_variants sv_large ON p.id = sv_large.photo_id AND sv_large.type = 3
-		LEFT JOIN size_variants sv_original ON p.id = sv_original.photo_id AND sv_original.type = 0
+		WHERE 1=1`
+
+	condition, args := db.filterCondition(filter)
+	query += condition
+
+	switch db.driver {
+	case "postgres":
+		query = db.convertToPostgreSQL(query)
+	case "sqlite":
+		query = db.convertToSQLite(query)
+	}
+
+	var counts MissingFieldCounts
+	if err := db.QueryRow(query, args...).Scan(&counts.Title, &counts.Description); err != nil {
+		return MissingFieldCounts{}, fmt.Errorf("failed to count photos missing metadata: %w", err)
+	}
+
+	return counts, nil
+}
+
+// GetLastPhotoUpdate returns the most recent photo updated_at timestamp,
+// or nil if there are no photos
+func (db *DB) GetLastPhotoUpdate() (*time.Time, error) {
+	var updatedAt time.Time
+	err := db.QueryRow("SELECT updated_at FROM photos ORDER BY updated_at DESC LIMIT 1").Scan(&updatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get last photo update: %w", err)
+	}
+
+	return &updatedAt, nil
+}
+
+// GetLatestPhotoCreatedAt returns when the newest photo was created, or nil
+// if there are no photos
+func (db *DB) GetLatestPhotoCreatedAt() (*time.Time, error) {
+	var createdAt time.Time
+	err := db.QueryRow("SELECT created_at FROM photos ORDER BY created_at DESC LIMIT 1").Scan(&createdAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get newest photo: %w", err)
+	}
+
+	return &createdAt, nil
+}
+
+// GetPhotosCreatedSince returns the IDs of up to limit photos created after
+// since, oldest first, and the creation time of the newest of them (since,
+// if there are none)
+func (db *DB) GetPhotosCreatedSince(since time.Time, limit int) ([]string, time.Time, error) {
+	rows, err := db.Query("SELECT id, created_at FROM photos WHERE created_at > ? ORDER BY created_at LIMIT ?", db.timeValue(since), limit)
+	if err != nil {
+		return nil, since, fmt.Errorf("failed to get new photos: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	latest := since
+	for rows.Next() {
+		var id string
+		var createdAt time.Time
+		if err := rows.Scan(&id, &createdAt); err != nil {
+			return nil, since, fmt.Errorf("failed to scan new photo: %w", err)
+		}
+		// The comparison is to the second, so the photos created at since
+		// may be returned again
+		if !createdAt.After(since) {
+			continue
+		}
+		ids = append(ids, id)
+		latest = createdAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, since, fmt.Errorf("failed to get new photos: %w", err)
+	}
+	return ids, latest, nil
+}
+
+func (db *DB) GetPhotoByID(id string) (*models.PhotoWithSizeVariants, error) {
+	query := photoSelect + `
 		WHERE p.id = ?`
 
-	var photo models.PhotoWithSizeVariants
-	err := db.QueryRow(query, id).Scan(
-		&photo.ID, &photo.CreatedAt, &photo.UpdatedAt, &photo.OwnerID, &photo.AlbumID,
-		&photo.Title, &photo.Description, &photo.License, &photo.IsStarred,
-		&photo.ISO, &photo.Make, &photo.Model, &photo.Lens, &photo.Aperture, &photo.Shutter, &photo.Focal,
-		&photo.Latitude, &photo.Longitude, &photo.Altitude, &photo.ImgDirection, &photo.Location,
-		&photo.TakenAt, &photo.Type, &photo.Filesize, &photo.Checksum,
-		&photo.AlbumTitle, &photo.ThumbnailPath, &photo.LargePath, &photo.OriginalPath,
-	)
+	photo, err := scanPhoto(db.QueryRow(query, id))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -123,53 +636,318 @@ func (db *DB) GetPhotoByID(id string) (*models.PhotoWithSizeVariants, error) {
 		return nil, fmt.Errorf("failed to get photo: %w", err)
 	}
 
-	return &photo, nil
+	photos, err := db.withSizeVariants([]models.PhotoWithSizeVariants{*photo}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &photos[0], nil
 }
 
-func (db *DB) UpdatePhoto(id string, update models.PhotoUpdate) error {
-	// Build update query with explicit field handling to prevent SQL injection
-	var query string
-	var args []interface{}
-	
-	// Determine which fields to update
-	updateTitle := update.Title != nil
-	updateDescription := update.Description != nil
-	
-	if !updateTitle && !updateDescription {
-		// No photo metadata to update, just handle album change if needed
-		if update.AlbumID != nil {
-			if err := db.UpdatePhotoAlbum(id, *update.AlbumID); err != nil {
-				return fmt.Errorf("failed to update photo album: %w", err)
+// GetSamplePhoto returns the newest photo with a thumbnail, or nil if the
+// library has none. It is used to check that Lychee serves media.
+func (db *DB) GetSamplePhoto() (*models.PhotoWithSizeVariants, error) {
+	query := photoSelect + `
+		WHERE sv_thumb.short_path IS NOT NULL AND sv_thumb.short_path != ''
+		ORDER BY p.created_at DESC
+		LIMIT 1`
+
+	photo, err := scanPhoto(db.QueryRow(query))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get sample photo: %w", err)
+	}
+
+	return photo, nil
+}
+
+// GetPhotosByIDs returns the photos with the given IDs, newest first,
+// applying limit and offset as in GetPhotosNeedingMetadata
+func (db *DB) GetPhotosByIDs(ids []string, filter PhotoFilter, limit, offset int) ([]models.PhotoWithSizeVariants, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	query := photoSelect + `
+		WHERE p.id IN (` + placeholders + `)`
+
+	args := make([]interface{}, 0, len(ids)+3)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	condition, conditionArgs := db.filterCondition(filter)
+	query += condition
+	args = append(args, conditionArgs...)
+
+	query += " ORDER BY p.created_at DESC"
+
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+
+		if offset > 0 {
+			query += " OFFSET ?"
+			args = append(args, offset)
+		}
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query photos by ID: %w", err)
+	}
+	defer rows.Close()
+
+	return db.withSizeVariants(scanPhotos(rows))
+}
+
+// CountPhotosByIDs returns the number of the given photos that match
+// filter, as listed by GetPhotosByIDs
+func (db *DB) CountPhotosByIDs(ids []string, filter PhotoFilter) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	query := "SELECT COUNT(*) FROM photos p WHERE p.id IN (" + placeholders + ")"
+
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+
+	condition, conditionArgs := db.filterCondition(filter)
+	query += condition
+	args = append(args, conditionArgs...)
+
+	var count int
+	if err := db.QueryRow(query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count photos by ID: %w", err)
+	}
+	return count, nil
+}
+
+// updateTimesBatchSize bounds the number of IDs per query in GetPhotoUpdateTimes
+// and existingValues
+const updateTimesBatchSize = 500
+
+// GetPhotoUpdateTimes returns the updated_at timestamp of each of the given
+// photos, keyed by photo ID. Photos that no longer exist are omitted.
+func (db *DB) GetPhotoUpdateTimes(ids []string) (map[string]time.Time, error) {
+	times := make(map[string]time.Time, len(ids))
+
+	for start := 0; start < len(ids); start += updateTimesBatchSize {
+		batch := ids[start:min(start+updateTimesBatchSize, len(ids))]
+
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(batch)), ", ")
+		args := make([]interface{}, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+
+		rows, err := db.Query("SELECT id, updated_at FROM photos WHERE id IN ("+placeholders+")", args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query photo update times: %w", err)
+		}
+
+		for rows.Next() {
+			var id string
+			var updatedAt time.Time
+			if err := rows.Scan(&id, &updatedAt); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan photo update time: %w", err)
+			}
+			times[id] = updatedAt
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate photo update times: %w", err)
+		}
+	}
+
+	return times, nil
+}
+
+// GetExistingChecksums returns which of the given checksums belong to photos
+// in Lychee
+func (db *DB) GetExistingChecksums(checksums []string) (map[string]bool, error) {
+	return db.existingValues("photos", "checksum", checksums)
+}
+
+// GetExistingAlbumIDs returns which of the given IDs are albums in Lychee,
+// including albums Lychee has soft-deleted, which may yet be restored
+func (db *DB) GetExistingAlbumIDs(ids []string) (map[string]bool, error) {
+	return db.existingValues("base_albums", "id", ids)
+}
+
+// existingValues returns which of values appear in column of table
+func (db *DB) existingValues(table, column string, values []string) (map[string]bool, error) {
+	existing := make(map[string]bool, len(values))
+
+	for start := 0; start < len(values); start += updateTimesBatchSize {
+		batch := values[start:min(start+updateTimesBatchSize, len(values))]
+
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(batch)), ", ")
+		args := make([]interface{}, len(batch))
+		for i, v := range batch {
+			args[i] = v
+		}
+
+		rows, err := db.Query("SELECT DISTINCT "+column+" FROM "+table+" WHERE "+column+" IN ("+placeholders+")", args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s.%s: %w", table, column, err)
+		}
+
+		for rows.Next() {
+			var v string
+			if err := rows.Scan(&v); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan %s.%s: %w", table, column, err)
+			}
+			existing[v] = true
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate %s.%s: %w", table, column, err)
+		}
+	}
+
+	return existing, nil
+}
+
+// withSizeVariants loads all size variants of the photos returned by a
+// query, so responses can offer every available image size. It takes the
+// query's results directly: `return db.withSizeVariants(scanPhotos(rows))`.
+func (db *DB) withSizeVariants(photos []models.PhotoWithSizeVariants, err error) ([]models.PhotoWithSizeVariants, error) {
+	if err != nil || len(photos) == 0 {
+		return photos, err
+	}
+
+	index := make(map[string]int, len(photos))
+	args := make([]interface{}, len(photos))
+	for i := range photos {
+		index[photos[i].ID] = i
+		args[i] = photos[i].ID
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(photos)), ", ")
+
+	rows, err := db.Query(`
+		SELECT photo_id, type, short_path, width, height, storage_disk
+		FROM size_variants
+		WHERE photo_id IN (`+placeholders+`) AND short_path IS NOT NULL AND short_path <> ''
+		ORDER BY width`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query size variants: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v models.SizeVariant
+		if err := rows.Scan(&v.PhotoID, &v.Type, &v.ShortPath, &v.Width, &v.Height, &v.StorageDisk); err != nil {
+			return nil, fmt.Errorf("failed to scan size variant: %w", err)
+		}
+		if i, ok := index[v.PhotoID]; ok {
+			photos[i].SizeVariants = append(photos[i].SizeVariants, v)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate size variants: %w", err)
+	}
+
+	return photos, nil
+}
+
+// GetPhotoTags returns the tags of each of the given photos, keyed by photo
+// ID, from Lychee's comma-separated photos.tags column. Photos without tags
+// are omitted.
+func (db *DB) GetPhotoTags(ids []string) (map[string][]string, error) {
+	tags := make(map[string][]string, len(ids))
+	if len(ids) == 0 {
+		return tags, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	rows, err := db.Query("SELECT id, tags FROM photos WHERE id IN ("+placeholders+") AND tags IS NOT NULL AND tags <> ''", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query photo tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, value string
+		if err := rows.Scan(&id, &value); err != nil {
+			return nil, fmt.Errorf("failed to scan photo tags: %w", err)
+		}
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags[id] = append(tags[id], tag)
 			}
 		}
-		return nil
 	}
-	
-	// Build query with explicit field combinations to avoid string concatenation
-	if updateTitle && updateDescription {
-		query = "UPDATE photos SET title = ?, description = ?, updated_at = NOW() WHERE id = ?"
-		args = []interface{}{*update.Title, *update.Description, id}
-	} else if updateTitle {
-		query = "UPDATE photos SET title = ?, updated_at = NOW() WHERE id = ?"
-		args = []interface{}{*update.Title, id}
-	} else if updateDescription {
-		query = "UPDATE photos SET description = ?, updated_at = NOW() WHERE id = ?"
-		args = []interface{}{*update.Description, id}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate photo tags: %w", err)
 	}
 
-	// Adjust for SQLite's datetime function
-	if db.driver == "sqlite" {
-		query = strings.Replace(query, "NOW()", "datetime('now')", 1)
+	return tags, nil
+}
+
+// SetPhotoTags replaces a photo's tags, stored comma-separated in the
+// photos.tags column read by GetPhotoTags
+func (db *DB) SetPhotoTags(id string, tags []string) error {
+	query, args, err := db.newPhotoUpdate().Set("tags", strings.Join(tags, ",")).Build(id)
+	if err != nil {
+		return err
 	}
 
-	_, err := db.Exec(query, args...)
+	if _, err := db.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to update photo tags: %w", err)
+	}
+
+	return nil
+}
+
+// SetPhotoLocation sets the place name in a photo's location column
+func (db *DB) SetPhotoLocation(id, location string) error {
+	query, args, err := db.newPhotoUpdate().Set("location", location).Build(id)
 	if err != nil {
-		return fmt.Errorf("failed to update photo: %w", err)
+		return err
 	}
 
-	// Handle album change separately
-	if update.AlbumID != nil {
-		if err := db.UpdatePhotoAlbum(id, *update.AlbumID); err != nil {
+	if _, err := db.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to update photo location: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DB) UpdatePhoto(id string, update models.PhotoUpdate) error {
+	query, args, err := db.photoUpdateQuery(id, update)
+	if err != nil {
+		return err
+	}
+	if query != "" {
+		if _, err := db.Exec(query, args...); err != nil {
+			return fmt.Errorf("failed to update photo: %w", err)
+		}
+	}
+
+	// Handle album change separately; clearing the album moves the photo
+	// to Unsorted
+	if update.ChangesAlbum() {
+		var albumID string
+		if update.AlbumID != nil {
+			albumID = *update.AlbumID
+		}
+		if err := db.UpdatePhotoAlbum(id, albumID); err != nil {
 			return fmt.Errorf("failed to update photo album: %w", err)
 		}
 	}
@@ -177,16 +955,126 @@ func (db *DB) UpdatePhoto(id string, update models.PhotoUpdate) error {
 	return nil
 }
 
+// UpdatePhotos sets the metadata of several photos, keyed by ID, in a
+// single transaction: either every photo is updated or none is.
+// Album changes are not supported.
+func (db *DB) UpdatePhotos(updates map[string]models.PhotoUpdate) error {
+	conn, release := db.acquire()
+	defer release()
+	tx, err := conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for id, update := range updates {
+		if update.ChangesAlbum() {
+			return fmt.Errorf("album changes are not supported for photo %s", id)
+		}
+		query, args, err := db.photoUpdateQuery(id, update)
+		if err != nil {
+			return err
+		}
+		if query == "" {
+			continue
+		}
+		if _, err := tx.Exec(db.rebind(query), args...); err != nil {
+			return fmt.Errorf("failed to update photo %s: %w", id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit photo updates: %w", err)
+	}
+	return nil
+}
+
+// DeletePhoto removes a photo's row along with its size variants and album
+// links, in a single transaction. The image files are left in Lychee's
+// storage. It returns false if there was no such photo.
+func (db *DB) DeletePhoto(id string) (bool, error) {
+	conn, release := db.acquire()
+	defer release()
+	tx, err := conn.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, table := range []string{"photo_album", "size_variants"} {
+		if _, err := tx.Exec(db.rebind("DELETE FROM "+table+" WHERE photo_id = ?"), id); err != nil {
+			return false, fmt.Errorf("failed to delete photo %s from %s: %w", id, table, err)
+		}
+	}
+	result, err := tx.Exec(db.rebind("DELETE FROM photos WHERE id = ?"), id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete photo %s: %w", id, err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete photo %s: %w", id, err)
+	}
+	if deleted == 0 {
+		return false, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit photo deletion: %w", err)
+	}
+	return true, nil
+}
+
+// photoUpdateQuery returns the statement setting a photo's title,
+// description, starred flag, license, taken_at and geotag from update, or
+// an empty query if it changes none of them
+func (db *DB) photoUpdateQuery(id string, update models.PhotoUpdate) (string, []interface{}, error) {
+	b := db.newPhotoUpdate()
+	if update.Title != nil {
+		b.Set("title", *update.Title)
+	}
+	if update.Description != nil {
+		b.Set("description", *update.Description)
+	} else if update.ClearDescription {
+		b.Set("description", nil)
+	}
+	if update.Starred != nil {
+		b.Set("is_starred", *update.Starred)
+	}
+	if update.License != nil {
+		b.Set("license", *update.License)
+	}
+	if update.TakenAt != nil {
+		b.Set("taken_at", db.timeValue(*update.TakenAt))
+	} else if update.ClearTakenAt {
+		b.Set("taken_at", nil)
+	}
+	if update.ClearCoordinates {
+		b.Set("latitude", nil).Set("longitude", nil)
+	} else if update.Latitude != nil && update.Longitude != nil {
+		b.Set("latitude", *update.Latitude).Set("longitude", *update.Longitude)
+	}
+	if update.Altitude != nil {
+		b.Set("altitude", *update.Altitude)
+	} else if update.ClearAltitude || update.ClearCoordinates {
+		b.Set("altitude", nil)
+	}
+	return b.Build(id)
+}
+
+// UpdatePhotoAlbum moves a photo to the album albumID, or to Unsorted (no
+// album) if albumID is empty
 func (db *DB) UpdatePhotoAlbum(photoID, albumID string) error {
 	// First update the old_album_id in photos table
-	query := "UPDATE photos SET old_album_id = ?, updated_at = NOW() WHERE id = ?"
-	args := []interface{}{albumID, photoID}
-
-	if db.driver == "sqlite" {
-		query = strings.Replace(query, "NOW()", "datetime('now')", 1)
+	var album interface{}
+	if albumID != "" {
+		album = albumID
+	}
+	query, args, err := db.newPhotoUpdate().Set("old_album_id", album).Build(photoID)
+	if err != nil {
+		return err
 	}
 
-	_, err := db.Exec(query, args...)
+	_, err = db.Exec(query, args...)
 	if err != nil {
 		return fmt.Errorf("failed to update photo album_id: %w", err)
 	}
@@ -196,6 +1084,9 @@ func (db *DB) UpdatePhotoAlbum(photoID, albumID string) error {
 	if err != nil {
 		return fmt.Errorf("failed to delete old photo_album relationships: %w", err)
 	}
+	if albumID == "" {
+		return nil
+	}
 
 	// Add new photo_album relationship
 	_, err = db.Exec("INSERT INTO photo_album (photo_id, album_id) VALUES (?, ?)", photoID, albumID)
@@ -206,19 +1097,86 @@ func (db *DB) UpdatePhotoAlbum(photoID, albumID string) error {
 	return nil
 }
 
-func (db *DB) GetAlbums() ([]models.Album, error) {
+// AlbumFilter restricts which albums GetAlbums returns. The zero value
+// matches all normal albums.
+type AlbumFilter struct {
+	// Title, if set, matches albums whose title contains it, ignoring case
+	Title string
+	// WithPending restricts albums to those containing photos that need a
+	// title
+	WithPending bool
+	// EditableBy restricts albums to those the Lychee user with this ID
+	// owns or may edit
+	EditableBy *int
+}
+
+// albumFilterCondition returns the WHERE clause matching filter, for a
+// query on base_albums aliased as a, and its arguments
+func (db *DB) albumFilterCondition(filter AlbumFilter) (string, []interface{}, error) {
+	live, err := db.liveAlbumCondition("a")
+	if err != nil {
+		return "", nil, err
+	}
+	condition := " WHERE a.id NOT IN (SELECT id FROM tag_albums)" + live
+	var args []interface{}
+
+	if filter.Title != "" {
+		condition += " AND LOWER(a.title) LIKE LOWER(?) ESCAPE '!'"
+		args = append(args, containsPattern(filter.Title))
+	}
+
+	if filter.WithPending {
+		condition += " AND EXISTS (SELECT 1 FROM photos p WHERE p.old_album_id = a.id AND " + needsTitleCondition + ")"
+	}
+
+	if filter.EditableBy != nil {
+		condition += " AND " + editableAlbumCondition
+		args = append(args, *filter.EditableBy, *filter.EditableBy, true)
+	}
+
+	switch db.driver {
+	case "postgres":
+		condition = db.convertToPostgreSQL(condition)
+	case "sqlite":
+		condition = db.convertToSQLite(condition)
+	}
+	return condition, args, nil
+}
+
+// queryAlbums reads a page of albums for GetAlbums from the database
+func (db *DB) queryAlbums(filter AlbumFilter, limit, offset int) ([]models.Album, int, error) {
+	condition, args, err := db.albumFilterCondition(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM base_albums a"+condition, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count albums: %w", err)
+	}
+
 	query := `
 		SELECT 
-			id, created_at, updated_at, published_at, title, description,
-			owner_id, is_nsfw, is_pinned, sorting_col, sorting_order,
-			copyright, photo_layout, photo_timeline
-		FROM base_albums 
-		WHERE id NOT IN (SELECT id FROM tag_albums)
-		ORDER BY title ASC`
-
-	rows, err := db.Query(query)
+			a.id, a.created_at, a.updated_at, a.published_at, a.title, a.description,
+			a.owner_id, a.is_nsfw, a.is_pinned, a.sorting_col, a.sorting_order,
+			a.copyright, a.photo_layout, a.photo_timeline, al.parent_id
+		FROM base_albums a
+		LEFT JOIN albums al ON al.id = a.id` + condition + `
+		ORDER BY a.title ASC, a.id ASC`
+
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+
+		if offset > 0 {
+			query += " OFFSET ?"
+			args = append(args, offset)
+		}
+	}
+
+	rows, err := db.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query albums: %w", err)
+		return nil, 0, fmt.Errorf("failed to query albums: %w", err)
 	}
 	defer rows.Close()
 
@@ -229,39 +1187,46 @@ func (db *DB) GetAlbums() ([]models.Album, error) {
 			&album.ID, &album.CreatedAt, &album.UpdatedAt, &album.PublishedAt,
 			&album.Title, &album.Description, &album.OwnerID, &album.IsNSFW,
 			&album.IsPinned, &album.SortingCol, &album.SortingOrder,
-			&album.Copyright, &album.PhotoLayout, &album.PhotoTimeline,
+			&album.Copyright, &album.PhotoLayout, &album.PhotoTimeline, &album.ParentID,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan album: %w", err)
+			return nil, 0, fmt.Errorf("failed to scan album: %w", err)
 		}
 		albums = append(albums, album)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to iterate albums: %w", err)
+	}
 
-	return albums, nil
+	return albums, total, nil
 }
 
-func (db *DB) GetAlbumsWithPhotoCounts() ([]models.AlbumWithPhotoCount, error) {
+// GetAlbumsWithPhotoCounts returns albums containing photos that match
+// filter and need what its Needs describes. The filter's AlbumID is
+// ignored.
+func (db *DB) GetAlbumsWithPhotoCounts(filter PhotoFilter) ([]models.AlbumWithPhotoCount, error) {
+	filter.AlbumID = nil
+	condition, args := db.filterCondition(filter)
+	live, err := db.liveAlbumCondition("a")
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
 		SELECT 
 			a.id, a.created_at, a.updated_at, a.published_at, a.title, a.description,
 			a.owner_id, a.is_nsfw, a.is_pinned, a.sorting_col, a.sorting_order,
-			a.copyright, a.photo_layout, a.photo_timeline,
-			COUNT(p.id) as photo_count
+			a.copyright, a.photo_layout, a.photo_timeline, al.parent_id,
+			SUM(CASE WHEN ` + needsTitleCondition + ` THEN 1 ELSE 0 END) as photo_count,
+			SUM(CASE WHEN ` + needsDescriptionCondition + ` THEN 1 ELSE 0 END) as missing_description_count
 		FROM base_albums a
-		LEFT JOIN photos p ON a.id = p.old_album_id AND (
-			p.title = '' OR p.title IS NULL OR
-			p.title REGEXP '^[A-Za-z0-9]{3}_[0-9]+(\\.\\w+)?$' OR
-			p.title REGEXP '^P[0-9]{7}(\\.\\w+)?$' OR
-			p.title REGEXP '^[0-9]{8}_[0-9]{6}(\\.\\w+)?$' OR
-			p.title REGEXP '^IMG-[0-9]{8}-WA[0-9]{4}(\\.\\w+)?$' OR
-			p.title REGEXP '^Screenshot.*(\\.\\w+)?$' OR
-			p.title REGEXP '^[0-9a-fA-F]{8}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{12}(\\.\\w+)?$'
-		)
-		WHERE a.id NOT IN (SELECT id FROM tag_albums)
+		LEFT JOIN albums al ON al.id = a.id
+		JOIN photos p ON a.id = p.old_album_id` + condition + `
+		WHERE a.id NOT IN (SELECT id FROM tag_albums)` + live + `
 		GROUP BY a.id, a.created_at, a.updated_at, a.published_at, a.title, a.description,
 				 a.owner_id, a.is_nsfw, a.is_pinned, a.sorting_col, a.sorting_order,
-				 a.copyright, a.photo_layout, a.photo_timeline
-		HAVING COUNT(p.id) > 0
+				 a.copyright, a.photo_layout, a.photo_timeline, al.parent_id
+		HAVING SUM(CASE WHEN ` + needsCondition(filter.Needs) + ` THEN 1 ELSE 0 END) > 0
 		ORDER BY a.title ASC`
 
 	// Adjust query for different databases
@@ -269,10 +1234,10 @@ func (db *DB) GetAlbumsWithPhotoCounts() ([]models.AlbumWithPhotoCount, error) {
 	case "postgres":
 		query = db.convertToPostgreSQL(query)
 	case "sqlite":
-		query = db.convertToSQLiteWithPhotoCounts(query)
+		query = db.convertToSQLite(query)
 	}
 
-	rows, err := db.Query(query)
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query albums with photo counts: %w", err)
 	}
@@ -285,14 +1250,17 @@ func (db *DB) GetAlbumsWithPhotoCounts() ([]models.AlbumWithPhotoCount, error) {
 			&album.ID, &album.CreatedAt, &album.UpdatedAt, &album.PublishedAt,
 			&album.Title, &album.Description, &album.OwnerID, &album.IsNSFW,
 			&album.IsPinned, &album.SortingCol, &album.SortingOrder,
-			&album.Copyright, &album.PhotoLayout, &album.PhotoTimeline,
-			&album.PhotoCount,
+			&album.Copyright, &album.PhotoLayout, &album.PhotoTimeline, &album.ParentID,
+			&album.PhotoCount, &album.MissingDescriptionCount,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan album with photo count: %w", err)
 		}
 		albums = append(albums, album)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate albums with photo counts: %w", err)
+	}
 
 	return albums, nil
 }
@@ -302,31 +1270,18 @@ func (db *DB) convertToPostgreSQL(query string) string {
 	query = strings.ReplaceAll(query, "REGEXP", "~")
 	// Convert MySQL backticks to PostgreSQL double quotes (if any)
 	query = strings.ReplaceAll(query, "`", "\"")
-	return query
+	return unescapeBackslashes.Replace(query)
 }
 
+// convertToSQLite adjusts a query for SQLite, whose REGEXP is provided by
+// sqliteDriver
 func (db *DB) convertToSQLite(query string) string {
-	// SQLite doesn't support REGEXP by default, we'll use LIKE patterns instead
-	// This is a simplified conversion - in production, you might want to enable REGEXP extension
-	query = strings.ReplaceAll(query, "p.title REGEXP '^[A-Za-z0-9]{3}_[0-9]+(\\.\\w+)?$'", "(p.title GLOB '???_*' AND LENGTH(p.title) >= 5)")
-	query = strings.ReplaceAll(query, "p.title REGEXP '^P[0-9]{7}(\\.\\w+)?$'", "p.title GLOB 'P*'")
-	query = strings.ReplaceAll(query, "p.title REGEXP '^[0-9]{8}_[0-9]{6}(\\.\\w+)?$'", "p.title GLOB '*_*'")
-	query = strings.ReplaceAll(query, "p.title REGEXP '^IMG-[0-9]{8}-WA[0-9]{4}(\\.\\w+)?$'", "p.title GLOB 'IMG-*-WA*'")
-	query = strings.ReplaceAll(query, "p.title REGEXP '^Screenshot.*(\\.\\w+)?$'", "p.title GLOB 'Screenshot*'")
-	// UUID pattern is complex, we'll use a simpler check
-	query = strings.ReplaceAll(query, "p.title REGEXP '^[0-9a-fA-F]{8}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{12}(\\.\\w+)?$'", "(LENGTH(p.title) = 32 OR LENGTH(p.title) = 36)")
-	return query
-}
-
-func (db *DB) convertToSQLiteWithPhotoCounts(query string) string {
-	// SQLite doesn't support REGEXP by default, we'll use LIKE patterns instead
-	// This is a simplified conversion - in production, you might want to enable REGEXP extension
-	query = strings.ReplaceAll(query, "p.title REGEXP '^[A-Za-z0-9]{3}_[0-9]+(\\.\\w+)?$'", "(p.title GLOB '???_*' AND LENGTH(p.title) >= 5)")
-	query = strings.ReplaceAll(query, "p.title REGEXP '^P[0-9]{7}(\\.\\w+)?$'", "p.title GLOB 'P*'")
-	query = strings.ReplaceAll(query, "p.title REGEXP '^[0-9]{8}_[0-9]{6}(\\.\\w+)?$'", "p.title GLOB '*_*'")
-	query = strings.ReplaceAll(query, "p.title REGEXP '^IMG-[0-9]{8}-WA[0-9]{4}(\\.\\w+)?$'", "p.title GLOB 'IMG-*-WA*'")
-	query = strings.ReplaceAll(query, "p.title REGEXP '^Screenshot.*(\\.\\w+)?$'", "p.title GLOB 'Screenshot*'")
-	// UUID pattern is complex, we'll use a simpler check
-	query = strings.ReplaceAll(query, "p.title REGEXP '^[0-9a-fA-F]{8}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{4}-?[0-9a-fA-F]{12}(\\.\\w+)?$'", "(LENGTH(p.title) = 32 OR LENGTH(p.title) = 36)")
-	return query
-}
\ No newline at end of file
+	return unescapeBackslashes.Replace(query)
+}
+
+// unescapeBackslashes undoes the escaping of backslashes in the string
+// literals of REGEXP patterns, which MySQL requires. PostgreSQL (with the
+// default standard_conforming_strings) and SQLite take backslashes in
+// string literals literally, so '\\.' would otherwise reach their regular
+// expression engines as an escaped backslash followed by any character.
+var unescapeBackslashes = strings.NewReplacer(`\\`, `\`)
@@ -0,0 +1,586 @@
+package db
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/models"
+)
+
+// Fixture IDs from testdata/library.sql
+const (
+	albumAlps   = "album000000000000000alps"
+	albumCity   = "album000000000000000city"
+	albumShared = "album00000000000000share"
+
+	photo1 = "photo0000000000000000001"
+	photo2 = "photo0000000000000000002"
+	photo3 = "photo0000000000000000003"
+	photo4 = "photo0000000000000000004"
+	photo5 = "photo0000000000000000005"
+	photo6 = "photo0000000000000000006"
+	photo7 = "photo0000000000000000007"
+	photo8 = "photo0000000000000000008"
+
+	userAlice = 2
+	userBob   = 3
+)
+
+// photoIDs returns the IDs of photos, in order
+func photoIDs(photos []models.PhotoWithSizeVariants) []string {
+	ids := make([]string, len(photos))
+	for i, p := range photos {
+		ids[i] = p.ID
+	}
+	return ids
+}
+
+// albumTitles returns the titles of albums, in order
+func albumTitles[A any](albums []A, title func(A) string) []string {
+	titles := make([]string, len(albums))
+	for i, a := range albums {
+		titles[i] = title(a)
+	}
+	return titles
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// TestReadQueries runs the query layer's read queries against the fixture
+// library on each test database
+func TestReadQueries(t *testing.T) {
+	for _, tdb := range testDatabases(t) {
+		t.Run(tdb.name, func(t *testing.T) {
+			db := tdb.db
+			loadLibrary(t, db)
+
+			t.Run("count needing metadata", func(t *testing.T) {
+				tests := []struct {
+					filter PhotoFilter
+					want   int
+				}{
+					{PhotoFilter{}, 4},
+					{PhotoFilter{Needs: NeedsDescription}, 3},
+					{PhotoFilter{Needs: NeedsBoth}, 5},
+					{PhotoFilter{AlbumID: ptr(albumAlps)}, 2},
+					{PhotoFilter{PublicOnly: true}, 2},
+					{PhotoFilter{EditableBy: ptr(userAlice)}, 2},
+					{PhotoFilter{EditableBy: ptr(userBob)}, 0},
+					{PhotoFilter{AlbumID: ptr(SmartAlbumStarred)}, 1},
+					{PhotoFilter{ExcludeAlbumIDs: []string{albumCity}}, 3},
+					{PhotoFilter{IDs: []string{}}, 0},
+				}
+				for _, tt := range tests {
+					got, err := db.CountPhotosNeedingMetadata(tt.filter)
+					if err != nil {
+						t.Fatalf("CountPhotosNeedingMetadata(%+v): %v", tt.filter, err)
+					}
+					if got != tt.want {
+						t.Errorf("CountPhotosNeedingMetadata(%+v) = %d, want %d", tt.filter, got, tt.want)
+					}
+				}
+			})
+
+			t.Run("count missing fields", func(t *testing.T) {
+				counts, err := db.CountMissingFields(PhotoFilter{})
+				if err != nil {
+					t.Fatalf("CountMissingFields: %v", err)
+				}
+				if want := (MissingFieldCounts{Title: 4, Description: 3}); counts != want {
+					t.Errorf("CountMissingFields = %+v, want %+v", counts, want)
+				}
+			})
+
+			t.Run("count photos", func(t *testing.T) {
+				tests := []struct {
+					filter PhotoFilter
+					want   int
+				}{
+					{PhotoFilter{}, 8},
+					{PhotoFilter{PublicOnly: true}, 3},
+					{PhotoFilter{EditableBy: ptr(userAlice)}, 4},
+					{PhotoFilter{Search: "MATTERHORN"}, 1},
+					// Album titles are searched too
+					{PhotoFilter{Search: "shared"}, 2},
+					// LIKE wildcards in the search match only themselves
+					{PhotoFilter{Search: "100%"}, 1},
+					{PhotoFilter{Search: "_"}, 4},
+					{PhotoFilter{TakenAfter: ptr(time.Date(2023, 6, 30, 0, 0, 0, 0, time.UTC))}, 1},
+					{PhotoFilter{TakenBefore: ptr(time.Date(2023, 6, 30, 0, 0, 0, 0, time.UTC))}, 0},
+					{PhotoFilter{AlbumID: ptr(SmartAlbumPublic)}, 3},
+					{PhotoFilter{ExcludeIDs: []string{photo1, photo2}}, 6},
+				}
+				for _, tt := range tests {
+					got, err := db.CountPhotos(tt.filter)
+					if err != nil {
+						t.Fatalf("CountPhotos(%+v): %v", tt.filter, err)
+					}
+					if got != tt.want {
+						t.Errorf("CountPhotos(%+v) = %d, want %d", tt.filter, got, tt.want)
+					}
+				}
+			})
+
+			t.Run("list needing metadata", func(t *testing.T) {
+				tests := []struct {
+					name   string
+					filter PhotoFilter
+					limit  int
+					offset int
+					want   []string
+				}{
+					{"newest first", PhotoFilter{}, 0, 0, []string{photo6, photo5, photo3, photo1}},
+					{"page", PhotoFilter{}, 2, 1, []string{photo5, photo3}},
+					{"oldest first", PhotoFilter{Sort: PhotoSort{Direction: SortAscending}}, 0, 0, []string{photo1, photo3, photo5, photo6}},
+					{"after cursor", PhotoFilter{Sort: PhotoSort{After: photo5}}, 0, 0, []string{photo3, photo1}},
+					{"largest first", PhotoFilter{Sort: PhotoSort{Field: SortFilesize}}, 0, 0, []string{photo6, photo5, photo3, photo1}},
+					{"by album", PhotoFilter{Sort: PhotoSort{Field: SortAlbum}}, 0, 0, []string{photo3, photo1, photo5, photo6}},
+					{"by album after cursor", PhotoFilter{Sort: PhotoSort{Field: SortAlbum, After: photo1}}, 0, 0, []string{photo5, photo6}},
+					{"descriptions", PhotoFilter{Needs: NeedsDescription}, 0, 0, []string{photo5, photo4, photo1}},
+				}
+				for _, tt := range tests {
+					t.Run(tt.name, func(t *testing.T) {
+						photos, err := db.GetPhotosNeedingMetadata(tt.filter, tt.limit, tt.offset)
+						if err != nil {
+							t.Fatalf("GetPhotosNeedingMetadata: %v", err)
+						}
+						if got := photoIDs(photos); !slices.Equal(got, tt.want) {
+							t.Errorf("GetPhotosNeedingMetadata = %v, want %v", got, tt.want)
+						}
+					})
+				}
+
+				// A random order is a stable permutation for its seed
+				random := PhotoFilter{Sort: PhotoSort{Field: SortRandom, Seed: 12345}}
+				first, err := db.GetPhotosNeedingMetadata(random, 0, 0)
+				if err != nil {
+					t.Fatalf("GetPhotosNeedingMetadata(random): %v", err)
+				}
+				again, err := db.GetPhotosNeedingMetadata(random, 0, 0)
+				if err != nil {
+					t.Fatalf("GetPhotosNeedingMetadata(random): %v", err)
+				}
+				if got := photoIDs(first); len(got) != 4 || !slices.Equal(got, photoIDs(again)) {
+					t.Errorf("random orders %v and %v differ", got, photoIDs(again))
+				}
+			})
+
+			t.Run("photo by ID", func(t *testing.T) {
+				photo, err := db.GetPhotoByID(photo1)
+				if err != nil {
+					t.Fatalf("GetPhotoByID: %v", err)
+				}
+				if photo == nil {
+					t.Fatal("GetPhotoByID returned no photo")
+				}
+				if photo.Title != "IMG_1234.jpg" || photo.Description != nil || photo.AlbumTitle == nil || *photo.AlbumTitle != "Alps" {
+					t.Errorf("GetPhotoByID = title %q, description %v, album %v", photo.Title, photo.Description, photo.AlbumTitle)
+				}
+				if want := time.Date(2024, 5, 1, 10, 0, 1, 0, time.UTC); !photo.CreatedAt.Equal(want) {
+					t.Errorf("CreatedAt = %v, want %v", photo.CreatedAt, want)
+				}
+				if photo.ThumbnailPath == nil || *photo.ThumbnailPath != "thumb/1.jpg" {
+					t.Errorf("ThumbnailPath = %v, want thumb/1.jpg", photo.ThumbnailPath)
+				}
+				if len(photo.SizeVariants) != 3 || photo.SizeVariants[0].Width != 200 {
+					t.Errorf("SizeVariants = %+v, want 3, smallest first", photo.SizeVariants)
+				}
+
+				missing, err := db.GetPhotoByID("photo0000000000000000099")
+				if err != nil || missing != nil {
+					t.Errorf("GetPhotoByID(missing) = %v, %v; want nil, nil", missing, err)
+				}
+			})
+
+			t.Run("titled photos", func(t *testing.T) {
+				photos, err := db.GetTitledPhotos(PhotoFilter{}, 0)
+				if err != nil {
+					t.Fatalf("GetTitledPhotos: %v", err)
+				}
+				if got, want := photoIDs(photos), []string{photo8, photo7, photo4, photo2}; !slices.Equal(got, want) {
+					t.Errorf("GetTitledPhotos = %v, want %v", got, want)
+				}
+			})
+
+			t.Run("photos by ID", func(t *testing.T) {
+				photos, err := db.GetPhotosByIDs([]string{photo2, photo4, photo7}, PhotoFilter{PublicOnly: true}, 0, 0)
+				if err != nil {
+					t.Fatalf("GetPhotosByIDs: %v", err)
+				}
+				if got, want := photoIDs(photos), []string{photo2}; !slices.Equal(got, want) {
+					t.Errorf("GetPhotosByIDs = %v, want %v", got, want)
+				}
+				count, err := db.CountPhotosByIDs([]string{photo2, photo4, photo7}, PhotoFilter{})
+				if err != nil || count != 3 {
+					t.Errorf("CountPhotosByIDs = %d, %v; want 3", count, err)
+				}
+			})
+
+			t.Run("sample photo", func(t *testing.T) {
+				photo, err := db.GetSamplePhoto()
+				if err != nil {
+					t.Fatalf("GetSamplePhoto: %v", err)
+				}
+				if photo == nil || photo.ID != photo2 {
+					t.Errorf("GetSamplePhoto = %v, want %s", photo, photo2)
+				}
+			})
+
+			t.Run("existing values", func(t *testing.T) {
+				checksums, err := db.GetExistingChecksums([]string{"checksum1", "checksum9"})
+				if err != nil {
+					t.Fatalf("GetExistingChecksums: %v", err)
+				}
+				if !checksums["checksum1"] || checksums["checksum9"] {
+					t.Errorf("GetExistingChecksums = %v, want only checksum1", checksums)
+				}
+				albums, err := db.GetExistingAlbumIDs([]string{albumCity, "album0000000000000000gone"})
+				if err != nil {
+					t.Fatalf("GetExistingAlbumIDs: %v", err)
+				}
+				if !albums[albumCity] || len(albums) != 1 {
+					t.Errorf("GetExistingAlbumIDs = %v, want only %s", albums, albumCity)
+				}
+			})
+
+			t.Run("latest photo times", func(t *testing.T) {
+				created, err := db.GetLatestPhotoCreatedAt()
+				if err != nil {
+					t.Fatalf("GetLatestPhotoCreatedAt: %v", err)
+				}
+				if want := time.Date(2024, 5, 1, 10, 0, 8, 0, time.UTC); created == nil || !created.Equal(want) {
+					t.Errorf("GetLatestPhotoCreatedAt = %v, want %v", created, want)
+				}
+
+				ids, latest, err := db.GetPhotosCreatedSince(time.Date(2024, 5, 1, 10, 0, 6, 0, time.UTC), 10)
+				if err != nil {
+					t.Fatalf("GetPhotosCreatedSince: %v", err)
+				}
+				if want := []string{photo7, photo8}; !slices.Equal(ids, want) || !latest.Equal(*created) {
+					t.Errorf("GetPhotosCreatedSince = %v, %v; want %v, %v", ids, latest, want, created)
+				}
+			})
+
+			t.Run("tags", func(t *testing.T) {
+				tags, err := db.GetPhotoTags([]string{photo1, photo8})
+				if err != nil {
+					t.Fatalf("GetPhotoTags: %v", err)
+				}
+				if len(tags) != 1 || !slices.Equal(tags[photo8], []string{"street", "night"}) {
+					t.Errorf("GetPhotoTags = %v, want only %s's street and night", tags, photo8)
+				}
+			})
+
+			t.Run("albums", func(t *testing.T) {
+				title := func(a models.Album) string { return a.Title }
+				tests := []struct {
+					filter AlbumFilter
+					want   []string
+				}{
+					// Tag albums are left out
+					{AlbumFilter{}, []string{"Alps", "City", "Shared"}},
+					{AlbumFilter{Title: "IT"}, []string{"City"}},
+					{AlbumFilter{WithPending: true}, []string{"Alps", "City", "Shared"}},
+					// Owned, and shared with edit rights, but not public
+					{AlbumFilter{EditableBy: ptr(userAlice)}, []string{"City", "Shared"}},
+					{AlbumFilter{EditableBy: ptr(userAlice), WithPending: true}, []string{"City", "Shared"}},
+					{AlbumFilter{EditableBy: ptr(userBob)}, []string{}},
+				}
+				for _, tt := range tests {
+					albums, total, err := db.GetAlbums(tt.filter, 0, 0)
+					if err != nil {
+						t.Fatalf("GetAlbums(%+v): %v", tt.filter, err)
+					}
+					if got := albumTitles(albums, title); !slices.Equal(got, tt.want) || total != len(tt.want) {
+						t.Errorf("GetAlbums(%+v) = %v (total %d), want %v", tt.filter, got, total, tt.want)
+					}
+				}
+
+				albums, _, err := db.GetAlbums(AlbumFilter{}, 1, 2)
+				if err != nil {
+					t.Fatalf("GetAlbums: %v", err)
+				}
+				if len(albums) != 1 || albums[0].ParentID == nil || *albums[0].ParentID != albumAlps {
+					t.Errorf("GetAlbums page = %+v, want Shared inside Alps", albums)
+				}
+			})
+
+			t.Run("albums with photo counts", func(t *testing.T) {
+				title := func(a models.AlbumWithPhotoCount) string { return a.Title }
+				albums, err := db.GetAlbumsWithPhotoCounts(PhotoFilter{})
+				if err != nil {
+					t.Fatalf("GetAlbumsWithPhotoCounts: %v", err)
+				}
+				if got, want := albumTitles(albums, title), []string{"Alps", "City", "Shared"}; !slices.Equal(got, want) {
+					t.Fatalf("GetAlbumsWithPhotoCounts = %v, want %v", got, want)
+				}
+				for i, want := range [][2]int{{2, 1}, {1, 2}, {1, 0}} {
+					if got := [2]int{albums[i].PhotoCount, albums[i].MissingDescriptionCount}; got != want {
+						t.Errorf("%s counts = %v, want %v", albums[i].Title, got, want)
+					}
+				}
+
+				albums, err = db.GetAlbumsWithPhotoCounts(PhotoFilter{Needs: NeedsDescription})
+				if err != nil {
+					t.Fatalf("GetAlbumsWithPhotoCounts: %v", err)
+				}
+				if got, want := albumTitles(albums, title), []string{"Alps", "City"}; !slices.Equal(got, want) {
+					t.Errorf("GetAlbumsWithPhotoCounts(description) = %v, want %v", got, want)
+				}
+			})
+
+			t.Run("permissions", func(t *testing.T) {
+				user, err := db.GetLycheeUser("admin")
+				if err != nil || user == nil || !user.MayAdministrate {
+					t.Errorf("GetLycheeUser(admin) = %+v, %v; want an administrator", user, err)
+				}
+				user, err = db.GetLycheeUser("alice")
+				if err != nil || user == nil || user.ID != userAlice || user.MayAdministrate {
+					t.Errorf("GetLycheeUser(alice) = %+v, %v", user, err)
+				}
+				if user, err := db.GetLycheeUser("mallory"); err != nil || user != nil {
+					t.Errorf("GetLycheeUser(mallory) = %+v, %v; want nil", user, err)
+				}
+
+				tests := []struct {
+					userID  int
+					photoID string
+					want    bool
+				}{
+					{userAlice, photo4, true},  // owned
+					{userAlice, photo6, true},  // shared with edit rights
+					{userAlice, photo1, false}, // public, but not editable
+					{userBob, photo6, false},
+				}
+				for _, tt := range tests {
+					got, err := db.CanEditPhoto(tt.userID, tt.photoID)
+					if err != nil || got != tt.want {
+						t.Errorf("CanEditPhoto(%d, %s) = %t, %v; want %t", tt.userID, tt.photoID, got, err, tt.want)
+					}
+				}
+				if ok, err := db.CanEditAlbum(userAlice, albumShared); err != nil || !ok {
+					t.Errorf("CanEditAlbum(alice, Shared) = %t, %v; want true", ok, err)
+				}
+				if ok, err := db.CanEditAlbum(userBob, albumAlps); err != nil || ok {
+					t.Errorf("CanEditAlbum(bob, Alps) = %t, %v; want false", ok, err)
+				}
+
+				editable, err := db.EditablePhotoIDs(userAlice, []string{photo1, photo4, photo6, "photo0000000000000000099"})
+				if err != nil {
+					t.Fatalf("EditablePhotoIDs: %v", err)
+				}
+				if len(editable) != 2 || !editable[photo4] || !editable[photo6] {
+					t.Errorf("EditablePhotoIDs(alice) = %v, want %s and %s", editable, photo4, photo6)
+				}
+			})
+		})
+	}
+}
+
+// TestWriteQueries runs the query layer's updates against the fixture
+// library on each test database
+func TestWriteQueries(t *testing.T) {
+	for _, tdb := range testDatabases(t) {
+		t.Run(tdb.name, func(t *testing.T) {
+			db := tdb.db
+			loadLibrary(t, db)
+
+			t.Run("update photo", func(t *testing.T) {
+				takenAt := time.Date(2024, 4, 30, 18, 45, 10, 0, time.FixedZone("CEST", 2*60*60))
+				err := db.UpdatePhoto(photo1, models.PhotoUpdate{
+					Title:       ptr("Lake Geneva"),
+					Description: ptr("Evening at the lake"),
+					Starred:     ptr(true),
+					TakenAt:     &takenAt,
+					Latitude:    ptr(46.45),
+					Longitude:   ptr(6.6),
+					AlbumID:     ptr(albumCity),
+				})
+				if err != nil {
+					t.Fatalf("UpdatePhoto: %v", err)
+				}
+
+				photo, err := db.GetPhotoByID(photo1)
+				if err != nil || photo == nil {
+					t.Fatalf("GetPhotoByID = %v, %v", photo, err)
+				}
+				if photo.Title != "Lake Geneva" || photo.Description == nil || *photo.Description != "Evening at the lake" || !photo.IsStarred {
+					t.Errorf("updated photo = title %q, description %v, starred %t", photo.Title, photo.Description, photo.IsStarred)
+				}
+				if photo.TakenAt == nil || !photo.TakenAt.Equal(takenAt) {
+					t.Errorf("TakenAt = %v, want %v", photo.TakenAt, takenAt)
+				}
+				if photo.Latitude == nil || *photo.Latitude != 46.45 {
+					t.Errorf("Latitude = %v, want 46.45", photo.Latitude)
+				}
+				if photo.AlbumID == nil || *photo.AlbumID != albumCity {
+					t.Errorf("AlbumID = %v, want %s", photo.AlbumID, albumCity)
+				}
+				// updated_at is set to the database's current time
+				if !photo.UpdatedAt.After(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) {
+					t.Errorf("UpdatedAt = %v, want now", photo.UpdatedAt)
+				}
+
+				count, err := db.CountPhotosNeedingMetadata(PhotoFilter{AlbumID: ptr(albumCity)})
+				if err != nil || count != 1 {
+					t.Errorf("City needs %d titles (%v), want 1", count, err)
+				}
+				var links int
+				if err := db.QueryRow("SELECT COUNT(*) FROM photo_album WHERE photo_id = ? AND album_id = ?", photo1, albumCity).Scan(&links); err != nil || links != 1 {
+					t.Errorf("photo_album links = %d (%v), want 1", links, err)
+				}
+			})
+
+			t.Run("clear fields", func(t *testing.T) {
+				err := db.UpdatePhoto(photo2, models.PhotoUpdate{ClearDescription: true, ClearTakenAt: true, ClearAlbum: true})
+				if err != nil {
+					t.Fatalf("UpdatePhoto: %v", err)
+				}
+				photo, err := db.GetPhotoByID(photo2)
+				if err != nil || photo == nil {
+					t.Fatalf("GetPhotoByID = %v, %v", photo, err)
+				}
+				if photo.Description != nil || photo.TakenAt != nil || photo.AlbumID != nil {
+					t.Errorf("cleared photo = description %v, taken_at %v, album %v; want all nil", photo.Description, photo.TakenAt, photo.AlbumID)
+				}
+			})
+
+			t.Run("update photos", func(t *testing.T) {
+				err := db.UpdatePhotos(map[string]models.PhotoUpdate{
+					photo3: {Title: ptr("Aletsch Glacier")},
+					photo6: {Title: ptr("Night market")},
+				})
+				if err != nil {
+					t.Fatalf("UpdatePhotos: %v", err)
+				}
+				count, err := db.CountPhotosNeedingMetadata(PhotoFilter{})
+				if err != nil || count != 1 {
+					t.Errorf("%d photos need titles (%v), want 1", count, err)
+				}
+
+				// A failing update rolls back the others
+				err = db.UpdatePhotos(map[string]models.PhotoUpdate{
+					photo5: {Title: ptr("Eiffel Tower")},
+					photo7: {AlbumID: ptr(albumCity)},
+				})
+				if err == nil {
+					t.Fatal("UpdatePhotos with an album change succeeded")
+				}
+				photo, err := db.GetPhotoByID(photo5)
+				if err != nil || photo == nil || photo.Title != "20240501_101500.jpg" {
+					t.Errorf("photo5 = %v, %v; want its title unchanged", photo, err)
+				}
+			})
+
+			t.Run("tags and location", func(t *testing.T) {
+				if err := db.SetPhotoTags(photo4, []string{"paris", "eiffel tower"}); err != nil {
+					t.Fatalf("SetPhotoTags: %v", err)
+				}
+				if err := db.SetPhotoLocation(photo4, "Paris, France"); err != nil {
+					t.Fatalf("SetPhotoLocation: %v", err)
+				}
+				tags, err := db.GetPhotoTags([]string{photo4})
+				if err != nil || !slices.Equal(tags[photo4], []string{"paris", "eiffel tower"}) {
+					t.Errorf("GetPhotoTags = %v, %v", tags, err)
+				}
+				photo, err := db.GetPhotoByID(photo4)
+				if err != nil || photo == nil || photo.Location == nil || *photo.Location != "Paris, France" {
+					t.Errorf("Location = %v, %v", photo, err)
+				}
+			})
+
+			t.Run("update times", func(t *testing.T) {
+				times, err := db.GetPhotoUpdateTimes([]string{photo4, photo7, "photo0000000000000000099"})
+				if err != nil {
+					t.Fatalf("GetPhotoUpdateTimes: %v", err)
+				}
+				if len(times) != 2 || !times[photo7].Equal(time.Date(2024, 5, 1, 10, 0, 7, 0, time.UTC)) || !times[photo4].After(times[photo7]) {
+					t.Errorf("GetPhotoUpdateTimes = %v", times)
+				}
+				last, err := db.GetLastPhotoUpdate()
+				if err != nil || last == nil || last.Year() < 2025 {
+					t.Errorf("GetLastPhotoUpdate = %v, %v; want now", last, err)
+				}
+			})
+
+			t.Run("recent", func(t *testing.T) {
+				recent := PhotoFilter{AlbumID: ptr(SmartAlbumRecent)}
+				if count, err := db.CountPhotos(recent); err != nil || count != 0 {
+					t.Errorf("%d recent photos (%v), want 0", count, err)
+				}
+				// Within the library's recent_age of 7 days
+				if _, err := db.Exec("UPDATE photos SET created_at = ? WHERE id = ?", db.timeValue(time.Now().Add(-6*24*time.Hour)), photo7); err != nil {
+					t.Fatalf("failed to backdate photo: %v", err)
+				}
+				if count, err := db.CountPhotos(recent); err != nil || count != 1 {
+					t.Errorf("%d recent photos (%v), want 1", count, err)
+				}
+			})
+
+			t.Run("soft-deleted albums", func(t *testing.T) {
+				if ok, err := db.hasColumn("base_albums", "deleted_at"); err != nil || ok {
+					t.Fatalf("hasColumn(deleted_at) = %t, %v; want false", ok, err)
+				}
+				if ok, err := db.hasColumn("photos", "title"); err != nil || !ok {
+					t.Fatalf("hasColumn(title) = %t, %v; want true", ok, err)
+				}
+
+				column := "ALTER TABLE base_albums ADD COLUMN deleted_at DATETIME"
+				if db.driver == "postgres" {
+					column = "ALTER TABLE base_albums ADD COLUMN deleted_at TIMESTAMP(0)"
+				}
+				if _, err := db.Exec(column); err != nil {
+					t.Fatalf("failed to add deleted_at: %v", err)
+				}
+				if _, err := db.Exec("UPDATE base_albums SET deleted_at = ? WHERE id = ?", db.timeValue(time.Now()), albumShared); err != nil {
+					t.Fatalf("failed to delete album: %v", err)
+				}
+				// A Lychee upgrade would come with a reconnect
+				db.schemaMu.Lock()
+				db.columns = nil
+				db.schemaMu.Unlock()
+
+				albums, total, err := db.GetAlbums(AlbumFilter{}, 0, 0)
+				if err != nil {
+					t.Fatalf("GetAlbums: %v", err)
+				}
+				if got, want := albumTitles(albums, func(a models.Album) string { return a.Title }), []string{"Alps", "City"}; !slices.Equal(got, want) || total != 2 {
+					t.Errorf("GetAlbums = %v (total %d), want %v", got, total, want)
+				}
+				withCounts, err := db.GetAlbumsWithPhotoCounts(PhotoFilter{Needs: NeedsBoth})
+				if err != nil {
+					t.Fatalf("GetAlbumsWithPhotoCounts: %v", err)
+				}
+				for _, a := range withCounts {
+					if a.ID == albumShared {
+						t.Errorf("GetAlbumsWithPhotoCounts listed the deleted album")
+					}
+				}
+				// Deleted albums may yet be restored
+				if existing, err := db.GetExistingAlbumIDs([]string{albumShared}); err != nil || !existing[albumShared] {
+					t.Errorf("GetExistingAlbumIDs = %v, %v; want the deleted album", existing, err)
+				}
+			})
+
+			t.Run("delete photo", func(t *testing.T) {
+				deleted, err := db.DeletePhoto(photo8)
+				if err != nil || !deleted {
+					t.Fatalf("DeletePhoto = %t, %v; want true", deleted, err)
+				}
+				if deleted, err := db.DeletePhoto(photo8); err != nil || deleted {
+					t.Errorf("DeletePhoto again = %t, %v; want false", deleted, err)
+				}
+				var links int
+				if err := db.QueryRow("SELECT COUNT(*) FROM photo_album WHERE photo_id = ?", photo8).Scan(&links); err != nil || links != 0 {
+					t.Errorf("photo_album links = %d (%v), want 0", links, err)
+				}
+				if count, err := db.CountPhotos(PhotoFilter{}); err != nil || count != 7 {
+					t.Errorf("%d photos (%v), want 7", count, err)
+				}
+			})
+		})
+	}
+}
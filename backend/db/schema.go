@@ -0,0 +1,59 @@
+package db
+
+import (
+	"fmt"
+	"log"
+)
+
+// hasColumn reports whether table has column. Lychee's schema varies
+// between versions, so optional columns are looked up in the database's
+// schema once and the answer is cached until the database is reconnected.
+// A failed lookup is returned rather than cached, so that a transient
+// error isn't taken for a missing column.
+func (db *DB) hasColumn(table, column string) (bool, error) {
+	key := table + "." + column
+
+	db.schemaMu.Lock()
+	defer db.schemaMu.Unlock()
+	if present, ok := db.columns[key]; ok {
+		return present, nil
+	}
+
+	var query string
+	switch db.driver {
+	case "mysql":
+		query = "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?"
+	case "postgres":
+		query = "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?"
+	case "sqlite":
+		query = "SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?"
+	default:
+		return false, fmt.Errorf("unsupported database type: %s", db.driver)
+	}
+
+	var count int
+	if err := db.QueryRow(query, table, column).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check the schema for %s: %w", key, err)
+	}
+	present := count > 0
+	if !present {
+		log.Printf("Lychee schema has no %s column; assuming this Lychee version doesn't use it", key)
+	}
+
+	if db.columns == nil {
+		db.columns = make(map[string]bool)
+	}
+	db.columns[key] = present
+	return present, nil
+}
+
+// liveAlbumCondition returns a condition, starting with AND, excluding
+// albums Lychee has soft-deleted from base_albums aliased as alias, or an
+// empty string if this Lychee version doesn't soft-delete albums
+func (db *DB) liveAlbumCondition(alias string) (string, error) {
+	present, err := db.hasColumn("base_albums", "deleted_at")
+	if err != nil || !present {
+		return "", err
+	}
+	return " AND " + alias + ".deleted_at IS NULL", nil
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+)
+
+// SlowQuery is a query that took longer than the slow query threshold
+type SlowQuery struct {
+	Query string `json:"query"`
+	// Args are the query's arguments, sanitized as for the log
+	Args       []string  `json:"args"`
+	DurationMs int64     `json:"duration_ms"`
+	At         time.Time `json:"at"`
+	// Error is set if the query failed, e.g. by timing out
+	Error string `json:"error,omitempty"`
+}
+
+// slowLog keeps the most recent slow queries
+type slowLog struct {
+	mu      sync.Mutex
+	entries []SlowQuery
+	// next is the index of the oldest entry once the log is full
+	next int
+}
+
+// add records q, replacing the oldest entry if the log is full
+func (l *slowLog) add(q SlowQuery) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.entries) < constants.SlowQueryLogSize {
+		l.entries = append(l.entries, q)
+		return
+	}
+	l.entries[l.next] = q
+	l.next = (l.next + 1) % len(l.entries)
+}
+
+// SlowQueries returns up to limit of the recent slow queries, slowest first
+func (db *DB) SlowQueries(limit int) []SlowQuery {
+	db.slow.mu.Lock()
+	queries := append([]SlowQuery{}, db.slow.entries...)
+	db.slow.mu.Unlock()
+
+	slices.SortFunc(queries, func(a, b SlowQuery) int {
+		return cmp.Compare(b.DurationMs, a.DurationMs)
+	})
+	if len(queries) > limit {
+		queries = queries[:limit]
+	}
+	return queries
+}
+
+// SlowQueryThreshold returns how long a query takes before it is logged as
+// slow, or 0 if slow queries aren't logged
+func (db *DB) SlowQueryThreshold() time.Duration {
+	_, slow := db.limits()
+	return slow
+}
+
+// observe logs and records a query that took longer than slow, or that
+// timed out
+func (db *DB) observe(slow time.Duration, query string, args []interface{}, elapsed time.Duration, err error) {
+	timedOut := errors.Is(err, context.DeadlineExceeded)
+	if !timedOut && (slow <= 0 || elapsed < slow) {
+		return
+	}
+
+	q := SlowQuery{
+		Query:      strings.Join(strings.Fields(query), " "),
+		Args:       make([]string, len(args)),
+		DurationMs: elapsed.Milliseconds(),
+		At:         time.Now().UTC(),
+	}
+	for i, arg := range args {
+		q.Args[i] = sanitizeArg(arg)
+	}
+	if err != nil {
+		q.Error = err.Error()
+	}
+
+	if timedOut {
+		log.Printf("Query timed out after %s: %s [args: %s]", elapsed.Round(time.Millisecond), q.Query, strings.Join(q.Args, ", "))
+	} else {
+		log.Printf("Slow query (%s): %s [args: %s]", elapsed.Round(time.Millisecond), q.Query, strings.Join(q.Args, ", "))
+	}
+	db.slow.add(q)
+}
+
+// sanitizeArg describes a query argument for the log, shortening long
+// strings, which may hold descriptions or other personal text, and leaving
+// out binary data
+func sanitizeArg(arg interface{}) string {
+	switch v := arg.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		if n := utf8.RuneCountInString(v); n > constants.SlowQueryArgLength {
+			return fmt.Sprintf("%q... (%d characters)", string([]rune(v)[:constants.SlowQueryArgLength]), n)
+		}
+		return fmt.Sprintf("%q", v)
+	case []byte:
+		return fmt.Sprintf("<%d bytes>", len(v))
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case *string:
+		if v == nil {
+			return "NULL"
+		}
+		return sanitizeArg(*v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
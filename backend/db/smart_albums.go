@@ -0,0 +1,107 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+)
+
+// Lychee's built-in smart albums. Their IDs match the ones Lychee itself
+// uses, and can be passed anywhere a regular album ID filters the photo
+// queue. Regular Lychee album IDs are 24-character random strings, so they
+// never collide with these.
+const (
+	SmartAlbumStarred = "starred"
+	SmartAlbumRecent  = "recent"
+	SmartAlbumPublic  = "public"
+)
+
+// SmartAlbum describes one of Lychee's built-in smart albums
+type SmartAlbum struct {
+	ID    string
+	Title string
+}
+
+// SmartAlbums lists the supported smart albums in display order
+var SmartAlbums = []SmartAlbum{
+	{ID: SmartAlbumStarred, Title: "Starred"},
+	{ID: SmartAlbumRecent, Title: "Recent"},
+	{ID: SmartAlbumPublic, Title: "Public"},
+}
+
+// publicAlbumIDs is a subquery selecting the IDs of albums that are shared
+// publicly in Lychee, i.e. that have an access permission not tied to any user
+const publicAlbumIDs = "(SELECT base_album_id FROM access_permissions WHERE user_id IS NULL)"
+
+// defaultRecentAge matches Lychee's default recent_age setting of one day
+const defaultRecentAge = 24 * time.Hour
+
+// recentAgeCache holds Lychee's recent_age setting, so the Recent smart
+// album doesn't read it on every query
+type recentAgeCache struct {
+	mu      sync.Mutex
+	age     time.Duration
+	expires time.Time
+}
+
+// IsSmartAlbum reports whether id names one of Lychee's smart albums
+func IsSmartAlbum(id string) bool {
+	for _, a := range SmartAlbums {
+		if a.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// albumCondition returns the WHERE clause fragment (without a leading AND)
+// and arguments restricting photos to albumID, which may be a smart album
+func (db *DB) albumCondition(albumID string) (string, []interface{}) {
+	switch albumID {
+	case SmartAlbumStarred:
+		return "p.is_starred = ?", []interface{}{true}
+	case SmartAlbumRecent:
+		return "p.created_at >= ?", []interface{}{db.timeValue(time.Now().Add(-db.recentAge()))}
+	case SmartAlbumPublic:
+		return "p.old_album_id IN " + publicAlbumIDs, nil
+	default:
+		return "p.old_album_id = ?", []interface{}{albumID}
+	}
+}
+
+// recentAge returns how far back Lychee's Recent smart album reaches, read
+// from Lychee's recent_age setting (in days) at most once per
+// constants.RecentAgeCacheTTL. If the setting can't be read, Lychee's
+// default is used without caching it, so the setting is read again next
+// time.
+func (db *DB) recentAge() time.Duration {
+	c := &db.recent
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Now().Before(c.expires) {
+		return c.age
+	}
+
+	query := "SELECT value FROM configs WHERE `key` = ?"
+	if db.driver == "postgres" {
+		query = db.convertToPostgreSQL(query)
+	}
+
+	age := defaultRecentAge
+	var value string
+	if err := db.QueryRow(query, "recent_age").Scan(&value); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return defaultRecentAge
+		}
+	} else if days, err := strconv.Atoi(value); err == nil && days > 0 {
+		age = time.Duration(days) * 24 * time.Hour
+	}
+
+	c.age = age
+	c.expires = time.Now().Add(constants.RecentAgeCacheTTL)
+	return age
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteDriver is the sqlite3 driver with a REGEXP function, which SQLite
+// leaves to the application to provide, so that needsTitleCondition's
+// patterns work on SQLite as they do on MySQL and PostgreSQL
+const sqliteDriver = "sqlite3_regexp"
+
+func init() {
+	sql.Register(sqliteDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("regexp", sqliteRegexp, true)
+		},
+	})
+}
+
+// sqliteRegexps caches compiled patterns; queries only ever use the few
+// constant patterns in this package
+var sqliteRegexps sync.Map
+
+// sqliteRegexp implements SQLite's "value REGEXP pattern", which it calls as
+// regexp(pattern, value). NULL never matches.
+func sqliteRegexp(pattern string, value interface{}) (bool, error) {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		return false, nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		s = fmt.Sprint(v)
+	}
+
+	re, ok := sqliteRegexps.Load(pattern)
+	if !ok {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return false, err
+		}
+		re, _ = sqliteRegexps.LoadOrStore(pattern, compiled)
+	}
+	return re.(*regexp.Regexp).MatchString(s), nil
+}
@@ -0,0 +1,52 @@
+-- A small Lychee library for the query layer tests. Photo IDs are numbered
+-- in upload order; the comments say what each photo lacks.
+
+INSERT INTO users (id, username, may_administrate) VALUES (1, 'admin', TRUE);
+INSERT INTO users (id, username, may_administrate) VALUES (2, 'alice', FALSE);
+INSERT INTO users (id, username, may_administrate) VALUES (3, 'bob', FALSE);
+
+INSERT INTO configs (id, `key`, value) VALUES (1, 'recent_age', '7');
+
+INSERT INTO base_albums (id, created_at, updated_at, title, owner_id) VALUES ('album000000000000000alps', '2024-01-01 09:00:00', '2024-01-01 09:00:00', 'Alps', 1);
+INSERT INTO base_albums (id, created_at, updated_at, title, owner_id) VALUES ('album000000000000000city', '2024-01-02 09:00:00', '2024-01-02 09:00:00', 'City', 2);
+INSERT INTO base_albums (id, created_at, updated_at, title, owner_id) VALUES ('album00000000000000share', '2024-01-03 09:00:00', '2024-01-03 09:00:00', 'Shared', 1);
+INSERT INTO base_albums (id, created_at, updated_at, title, owner_id) VALUES ('album000000000000000tags', '2024-01-04 09:00:00', '2024-01-04 09:00:00', 'Tagged', 1);
+
+INSERT INTO albums (id, parent_id) VALUES ('album000000000000000alps', NULL);
+INSERT INTO albums (id, parent_id) VALUES ('album000000000000000city', NULL);
+INSERT INTO albums (id, parent_id) VALUES ('album00000000000000share', 'album000000000000000alps');
+INSERT INTO tag_albums (id, show_tags) VALUES ('album000000000000000tags', 'mountains');
+
+-- Alps is public; Shared is shared with alice, who may edit it
+INSERT INTO access_permissions (id, base_album_id, user_id, grants_edit) VALUES (1, 'album000000000000000alps', NULL, FALSE);
+INSERT INTO access_permissions (id, base_album_id, user_id, grants_edit) VALUES (2, 'album00000000000000share', 2, TRUE);
+
+-- Title and description
+INSERT INTO photos (id, created_at, updated_at, owner_id, old_album_id, title, description, type, filesize, checksum) VALUES ('photo0000000000000000001', '2024-05-01 10:00:01', '2024-05-01 10:00:01', 1, 'album000000000000000alps', 'IMG_1234.jpg', NULL, 'image/jpeg', 1000, 'checksum1');
+-- Nothing
+INSERT INTO photos (id, created_at, updated_at, owner_id, old_album_id, title, description, taken_at, type, filesize, checksum) VALUES ('photo0000000000000000002', '2024-05-01 10:00:02', '2024-05-01 10:00:02', 1, 'album000000000000000alps', 'Matterhorn at dawn', 'Sunrise over the peak', '2023-07-01 05:30:00', 'image/jpeg', 2000, 'checksum2');
+-- Title
+INSERT INTO photos (id, created_at, updated_at, owner_id, old_album_id, title, description, type, filesize, checksum) VALUES ('photo0000000000000000003', '2024-05-01 10:00:03', '2024-05-01 10:00:03', 1, 'album000000000000000alps', '', 'A glacier', 'image/jpeg', 3000, 'checksum3');
+-- Description
+INSERT INTO photos (id, created_at, updated_at, owner_id, old_album_id, title, description, type, filesize, checksum) VALUES ('photo0000000000000000004', '2024-05-01 10:00:04', '2024-05-01 10:00:04', 2, 'album000000000000000city', 'Paris', NULL, 'image/jpeg', 4000, 'checksum4');
+-- Title and description; starred
+INSERT INTO photos (id, created_at, updated_at, owner_id, old_album_id, title, description, is_starred, type, filesize, checksum) VALUES ('photo0000000000000000005', '2024-05-01 10:00:05', '2024-05-01 10:00:05', 2, 'album000000000000000city', '20240501_101500.jpg', '', TRUE, 'image/jpeg', 5000, 'checksum5');
+-- Title
+INSERT INTO photos (id, created_at, updated_at, owner_id, old_album_id, title, description, type, filesize, checksum) VALUES ('photo0000000000000000006', '2024-05-01 10:00:06', '2024-05-01 10:00:06', 1, 'album00000000000000share', 'DSC_0001', 'A street', 'image/jpeg', 6000, 'checksum6');
+-- Nothing; unsorted, and not a generic name despite its underscores
+INSERT INTO photos (id, created_at, updated_at, owner_id, old_album_id, title, description, type, filesize, checksum) VALUES ('photo0000000000000000007', '2024-05-01 10:00:07', '2024-05-01 10:00:07', 1, NULL, 'my_trip_to_rome', 'The Colosseum', 'image/jpeg', 7000, 'checksum7');
+-- Nothing; a title with LIKE wildcards
+INSERT INTO photos (id, created_at, updated_at, owner_id, old_album_id, title, description, tags, type, filesize, checksum) VALUES ('photo0000000000000000008', '2024-05-01 10:00:08', '2024-05-01 10:00:08', 1, 'album00000000000000share', 'Why? 100% done', 'Finished', 'street, night', 'image/jpeg', 8000, 'checksum8');
+
+INSERT INTO photo_album (photo_id, album_id) VALUES ('photo0000000000000000001', 'album000000000000000alps');
+INSERT INTO photo_album (photo_id, album_id) VALUES ('photo0000000000000000002', 'album000000000000000alps');
+INSERT INTO photo_album (photo_id, album_id) VALUES ('photo0000000000000000003', 'album000000000000000alps');
+INSERT INTO photo_album (photo_id, album_id) VALUES ('photo0000000000000000004', 'album000000000000000city');
+INSERT INTO photo_album (photo_id, album_id) VALUES ('photo0000000000000000005', 'album000000000000000city');
+INSERT INTO photo_album (photo_id, album_id) VALUES ('photo0000000000000000006', 'album00000000000000share');
+INSERT INTO photo_album (photo_id, album_id) VALUES ('photo0000000000000000008', 'album00000000000000share');
+
+INSERT INTO size_variants (id, photo_id, type, short_path, width, height) VALUES (1, 'photo0000000000000000001', 0, 'original/1.jpg', 4000, 3000);
+INSERT INTO size_variants (id, photo_id, type, short_path, width, height) VALUES (2, 'photo0000000000000000001', 3, 'large/1.jpg', 1920, 1440);
+INSERT INTO size_variants (id, photo_id, type, short_path, width, height) VALUES (3, 'photo0000000000000000001', 6, 'thumb/1.jpg', 200, 200);
+INSERT INTO size_variants (id, photo_id, type, short_path, width, height) VALUES (4, 'photo0000000000000000002', 6, 'thumb/2.jpg', 200, 200);
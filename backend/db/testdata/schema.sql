@@ -0,0 +1,98 @@
+-- The subset of Lychee's schema the query layer uses, written for MySQL.
+-- testDatabases' callers load it with loadFixture, which adjusts it for
+-- PostgreSQL and SQLite.
+
+CREATE TABLE users (
+	id INTEGER PRIMARY KEY,
+	username VARCHAR(100) NOT NULL UNIQUE,
+	may_administrate BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE configs (
+	id INTEGER PRIMARY KEY,
+	`key` VARCHAR(50) NOT NULL UNIQUE,
+	value VARCHAR(200)
+);
+
+CREATE TABLE base_albums (
+	id CHAR(24) PRIMARY KEY,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	published_at DATETIME,
+	title VARCHAR(100) NOT NULL,
+	description TEXT,
+	owner_id INTEGER NOT NULL,
+	is_nsfw BOOLEAN NOT NULL DEFAULT FALSE,
+	is_pinned BOOLEAN NOT NULL DEFAULT FALSE,
+	sorting_col VARCHAR(30),
+	sorting_order VARCHAR(4),
+	copyright VARCHAR(300),
+	photo_layout VARCHAR(20),
+	photo_timeline VARCHAR(20)
+);
+
+CREATE TABLE albums (
+	id CHAR(24) PRIMARY KEY,
+	parent_id CHAR(24)
+);
+
+CREATE TABLE tag_albums (
+	id CHAR(24) PRIMARY KEY,
+	show_tags TEXT
+);
+
+CREATE TABLE access_permissions (
+	id INTEGER PRIMARY KEY,
+	base_album_id CHAR(24),
+	user_id INTEGER,
+	grants_edit BOOLEAN NOT NULL DEFAULT FALSE,
+	is_link_required BOOLEAN NOT NULL DEFAULT FALSE,
+	password VARCHAR(100)
+);
+
+CREATE TABLE photos (
+	id CHAR(24) PRIMARY KEY,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	owner_id INTEGER NOT NULL,
+	old_album_id CHAR(24),
+	title VARCHAR(100) NOT NULL DEFAULT '',
+	description TEXT,
+	tags TEXT,
+	license VARCHAR(20) NOT NULL DEFAULT 'none',
+	is_starred BOOLEAN NOT NULL DEFAULT FALSE,
+	iso VARCHAR(20),
+	make VARCHAR(50),
+	model VARCHAR(50),
+	lens VARCHAR(100),
+	aperture VARCHAR(20),
+	shutter VARCHAR(20),
+	focal VARCHAR(20),
+	latitude DECIMAL(10, 8),
+	longitude DECIMAL(11, 8),
+	altitude DECIMAL(10, 4),
+	img_direction DECIMAL(10, 4),
+	location VARCHAR(255),
+	taken_at DATETIME,
+	type VARCHAR(30) NOT NULL,
+	filesize BIGINT NOT NULL DEFAULT 0,
+	checksum VARCHAR(40) NOT NULL
+);
+
+CREATE TABLE photo_album (
+	photo_id CHAR(24) NOT NULL,
+	album_id CHAR(24) NOT NULL,
+	PRIMARY KEY (photo_id, album_id)
+);
+
+CREATE TABLE size_variants (
+	id INTEGER PRIMARY KEY,
+	photo_id CHAR(24) NOT NULL,
+	type INTEGER NOT NULL,
+	short_path VARCHAR(255) NOT NULL,
+	width INTEGER NOT NULL,
+	height INTEGER NOT NULL,
+	ratio REAL NOT NULL DEFAULT 1,
+	filesize BIGINT NOT NULL DEFAULT 0,
+	storage_disk VARCHAR(255) NOT NULL DEFAULT 'images'
+);
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// photoUpdateColumns are the photos columns an updateBuilder may set.
+// Column names are written into the SQL as-is, so only these are accepted.
+var photoUpdateColumns = map[string]bool{
+	"title":        true,
+	"description":  true,
+	"old_album_id": true,
+	"location":     true,
+	"tags":         true,
+	"taken_at":     true,
+	"license":      true,
+	"is_starred":   true,
+	"latitude":     true,
+	"longitude":    true,
+	"altitude":     true,
+}
+
+// updateBuilder builds an UPDATE statement for one row from the columns
+// set on it, in the order they were set. Values are always passed as
+// arguments; a nil value writes NULL. updated_at is set to the current
+// time whenever anything else changes.
+type updateBuilder struct {
+	driver  string
+	table   string
+	allowed map[string]bool
+	columns []string
+	args    []interface{}
+	err     error
+}
+
+// newPhotoUpdate starts an UPDATE of the photos table
+func (db *DB) newPhotoUpdate() *updateBuilder {
+	return &updateBuilder{driver: db.driver, table: "photos", allowed: photoUpdateColumns}
+}
+
+// Set adds column = value to the statement. Setting a column that isn't
+// allowed, or the same column twice, makes Build fail.
+func (b *updateBuilder) Set(column string, value interface{}) *updateBuilder {
+	switch {
+	case b.err != nil:
+	case !b.allowed[column]:
+		b.err = fmt.Errorf("column %q of %s cannot be updated", column, b.table)
+	case slices.Contains(b.columns, column):
+		b.err = fmt.Errorf("column %q of %s is set more than once", column, b.table)
+	default:
+		b.columns = append(b.columns, column)
+		b.args = append(b.args, value)
+	}
+	return b
+}
+
+// Empty reports whether no columns have been set
+func (b *updateBuilder) Empty() bool {
+	return len(b.columns) == 0
+}
+
+// Build returns the statement updating the row with the given id, using
+// the database driver's current time function, and its arguments. The
+// query is empty if no columns were set.
+func (b *updateBuilder) Build(id string) (string, []interface{}, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+	if b.Empty() {
+		return "", nil, nil
+	}
+
+	now := "NOW()"
+	if b.driver == "sqlite" {
+		now = "datetime('now')"
+	}
+
+	var query strings.Builder
+	query.WriteString("UPDATE ")
+	query.WriteString(b.table)
+	query.WriteString(" SET ")
+	for _, column := range b.columns {
+		query.WriteString(column)
+		query.WriteString(" = ?, ")
+	}
+	query.WriteString("updated_at = ")
+	query.WriteString(now)
+	query.WriteString(" WHERE id = ?")
+
+	args := make([]interface{}, 0, len(b.args)+1)
+	args = append(args, b.args...)
+	args = append(args, id)
+	return query.String(), args, nil
+}
+
+// lycheeTimeFormat is how Lychee writes timestamps to MySQL and SQLite
+const lycheeTimeFormat = "2006-01-02 15:04:05"
+
+// timeValue converts t to the value for a timestamp column, which Lychee
+// stores in UTC. The MySQL and SQLite drivers would otherwise write
+// fractional seconds and, for SQLite, a zone offset, which Lychee doesn't
+// parse; PostgreSQL's timestamp columns take a time.Time as is.
+func (db *DB) timeValue(t time.Time) interface{} {
+	t = t.UTC().Truncate(time.Second)
+	if db.driver == "postgres" {
+		return t
+	}
+	return t.Format(lycheeTimeFormat)
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUpdateBuilderBuild(t *testing.T) {
+	tests := []struct {
+		driver string
+		want   string
+	}{
+		{"mysql", "UPDATE photos SET title = ?, description = ?, updated_at = NOW() WHERE id = ?"},
+		{"postgres", "UPDATE photos SET title = ?, description = ?, updated_at = NOW() WHERE id = ?"},
+		{"sqlite", "UPDATE photos SET title = ?, description = ?, updated_at = datetime('now') WHERE id = ?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.driver, func(t *testing.T) {
+			db := &DB{driver: tt.driver}
+			query, args, err := db.newPhotoUpdate().
+				Set("title", "Sunset").
+				Set("description", nil).
+				Build("photo1")
+			if err != nil {
+				t.Fatalf("Build: %v", err)
+			}
+			if query != tt.want {
+				t.Errorf("query = %q, want %q", query, tt.want)
+			}
+			wantArgs := []interface{}{"Sunset", nil, "photo1"}
+			if !reflect.DeepEqual(args, wantArgs) {
+				t.Errorf("args = %#v, want %#v", args, wantArgs)
+			}
+		})
+	}
+}
+
+func TestUpdateBuilderRejectsColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		build   func(b *updateBuilder) *updateBuilder
+		wantErr string
+	}{
+		{
+			name:    "unknown column",
+			build:   func(b *updateBuilder) *updateBuilder { return b.Set("owner_id", 1) },
+			wantErr: `column "owner_id" of photos cannot be updated`,
+		},
+		{
+			name:    "injected column name",
+			build:   func(b *updateBuilder) *updateBuilder { return b.Set("title = 'x', owner_id", 1) },
+			wantErr: "cannot be updated",
+		},
+		{
+			name:    "updated_at is set by Build",
+			build:   func(b *updateBuilder) *updateBuilder { return b.Set("updated_at", time.Now()) },
+			wantErr: `column "updated_at" of photos cannot be updated`,
+		},
+		{
+			name:    "column set twice",
+			build:   func(b *updateBuilder) *updateBuilder { return b.Set("title", "a").Set("title", "b") },
+			wantErr: `column "title" of photos is set more than once`,
+		},
+		{
+			name: "first error is kept",
+			build: func(b *updateBuilder) *updateBuilder {
+				return b.Set("owner_id", 1).Set("title", "a").Set("album_id", 2)
+			},
+			wantErr: `column "owner_id" of photos cannot be updated`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &DB{driver: "mysql"}
+			query, args, err := tt.build(db.newPhotoUpdate()).Build("photo1")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Build error = %v, want one containing %q", err, tt.wantErr)
+			}
+			if query != "" || args != nil {
+				t.Errorf("Build returned %q, %v along with an error", query, args)
+			}
+		})
+	}
+}
+
+func TestUpdateBuilderEmpty(t *testing.T) {
+	db := &DB{driver: "sqlite"}
+	b := db.newPhotoUpdate()
+	if !b.Empty() {
+		t.Fatal("new builder is not Empty")
+	}
+
+	query, args, err := b.Build("photo1")
+	if err != nil || query != "" || args != nil {
+		t.Errorf("Build() = %q, %v, %v; want an empty query and no error", query, args, err)
+	}
+
+	if b.Set("title", "Sunset").Empty() {
+		t.Error("builder with a column set is Empty")
+	}
+}
+
+func TestTimeValue(t *testing.T) {
+	// 14:04:05.5 in UTC-5
+	in := time.Date(2024, 3, 9, 9, 4, 5, 500_000_000, time.FixedZone("EST", -5*60*60))
+	utc := time.Date(2024, 3, 9, 14, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		driver string
+		want   interface{}
+	}{
+		{"mysql", "2024-03-09 14:04:05"},
+		{"sqlite", "2024-03-09 14:04:05"},
+		{"postgres", utc},
+	}
+	for _, tt := range tests {
+		t.Run(tt.driver, func(t *testing.T) {
+			db := &DB{driver: tt.driver}
+			got := db.timeValue(in)
+			if want, ok := tt.want.(time.Time); ok {
+				gotTime, ok := got.(time.Time)
+				if !ok || !gotTime.Equal(want) || gotTime.Location() != time.UTC {
+					t.Errorf("timeValue() = %#v, want %v in UTC", got, want)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("timeValue() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
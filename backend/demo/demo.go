@@ -0,0 +1,347 @@
+// Package demo creates a sample Lychee library, so lychee-meta-tool can be
+// tried (and its frontend developed) without a Lychee install
+package demo
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"database/sql"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/models"
+)
+
+// schema creates the tables lychee-meta-tool reads and writes
+//
+//go:embed schema.sql
+var schema string
+
+// Sizes of the generated size variants
+const (
+	originalWidth, originalHeight = 1200, 800
+	mediumWidth, mediumHeight     = 600, 400
+	thumbSize                     = 200
+	jpegQuality                   = 85
+)
+
+// ownerID is the Lychee user owning the sample albums and photos
+const ownerID = 1
+
+// Library is a sample library created by Create
+type Library struct {
+	// DBPath is the SQLite database
+	DBPath string
+	// UploadsPath holds the photos' images, like Lychee's public/uploads
+	UploadsPath string
+}
+
+type album struct {
+	id, parentID string
+	title        string
+	description  string
+	public       bool
+}
+
+type gps struct {
+	latitude, longitude, altitude float64
+}
+
+type photo struct {
+	id, albumID string
+	title       string
+	description string
+	tags        string
+	starred     bool
+	make, model string
+	takenAt     time.Time
+	gps         *gps
+	scene       scene
+}
+
+var albums = []album{
+	{id: "demoalbumsummertrip00001", title: "Summer Trip 2024", description: "Two weeks out west", public: true},
+	{id: "demoalbumwinter000000001", title: "Winter"},
+	{id: "demoalbumalps00000000001", parentID: "demoalbumwinter000000001", title: "Alps"},
+}
+
+// photos mixes the camera-generated titles lychee-meta-tool looks for with
+// photos that are already titled, and includes a burst and photos with and
+// without GPS coordinates
+var photos = []photo{
+	{
+		id: "demophotolaketahoe000001", albumID: "demoalbumsummertrip00001",
+		title: "IMG_4821.jpg", make: "Apple", model: "iPhone 13",
+		takenAt: date(2024, 7, 14, 20, 12, 5), gps: &gps{39.0968, -120.0324, 1897},
+		scene: scene{
+			skyTop: rgb(44, 62, 120), skyHorizon: rgb(250, 160, 90), sun: rgb(255, 214, 140),
+			sunX: 0.62, sunY: 0.5, sunR: 0.05, ridge: rgb(52, 48, 70), ground: rgb(30, 40, 70),
+			horizon: 0.58, peaks: 0.14, water: true, seed: 11,
+		},
+	},
+	{
+		id: "demophotolaketahoe000002", albumID: "demoalbumsummertrip00001",
+		title: "IMG_4822.jpg", make: "Apple", model: "iPhone 13",
+		takenAt: date(2024, 7, 14, 20, 12, 41), gps: &gps{39.0968, -120.0324, 1897},
+		scene: scene{
+			skyTop: rgb(42, 58, 116), skyHorizon: rgb(248, 150, 84), sun: rgb(255, 208, 132),
+			sunX: 0.6, sunY: 0.52, sunR: 0.05, ridge: rgb(50, 46, 68), ground: rgb(28, 38, 66),
+			horizon: 0.58, peaks: 0.14, water: true, seed: 11,
+		},
+	},
+	{
+		id: "demophotoyosemite0000001", albumID: "demoalbumsummertrip00001",
+		title: "DSC_0042", make: "NIKON CORPORATION", model: "NIKON D750",
+		takenAt: date(2024, 7, 17, 11, 3, 52), gps: &gps{37.7459, -119.5332, 1220},
+		scene: scene{
+			skyTop: rgb(40, 110, 200), skyHorizon: rgb(170, 210, 240), sun: rgb(255, 255, 230),
+			sunX: 0.2, sunY: 0.12, sunR: 0.04, ridge: rgb(110, 110, 120), ground: rgb(60, 100, 50),
+			horizon: 0.7, peaks: 0.45, seed: 23,
+		},
+	},
+	{
+		id: "demophotosantacruz000001", albumID: "demoalbumsummertrip00001",
+		title: "20240720_183015", make: "samsung", model: "SM-G991U",
+		takenAt: date(2024, 7, 20, 18, 30, 15), gps: &gps{36.9622, -122.0238, 4},
+		scene: scene{
+			skyTop: rgb(90, 150, 210), skyHorizon: rgb(235, 215, 180), sun: rgb(255, 240, 200),
+			sunX: 0.8, sunY: 0.38, sunR: 0.04, ridge: rgb(120, 130, 140), ground: rgb(40, 90, 120),
+			horizon: 0.55, peaks: 0.03, water: true, seed: 37,
+		},
+	},
+	{
+		id: "demophotolighthouse00001", albumID: "demoalbumsummertrip00001",
+		title: "Lighthouse at dusk", description: "The last light over the headland",
+		tags: "coast,dusk", starred: true, make: "FUJIFILM", model: "X-T3",
+		takenAt: date(2024, 7, 21, 20, 41, 0),
+		scene: scene{
+			skyTop: rgb(30, 30, 80), skyHorizon: rgb(200, 110, 120), sun: rgb(255, 190, 150),
+			sunX: 0.3, sunY: 0.56, sunR: 0.03, ridge: rgb(35, 30, 45), ground: rgb(25, 30, 55),
+			horizon: 0.6, peaks: 0.08, water: true, seed: 41,
+		},
+	},
+	{
+		id: "demophotosnowfield000001", albumID: "demoalbumwinter000000001",
+		title: "P1000312", make: "Panasonic", model: "DMC-GX85",
+		takenAt: date(2024, 1, 6, 13, 22, 9),
+		scene: scene{
+			skyTop: rgb(150, 170, 190), skyHorizon: rgb(220, 225, 230), sun: rgb(240, 240, 240),
+			sunX: 0.7, sunY: 0.2, sunR: 0.06, ridge: rgb(170, 180, 195), ground: rgb(235, 238, 242),
+			horizon: 0.62, peaks: 0.2, seed: 53,
+		},
+	},
+	{
+		id: "demophotonightsky0000001", albumID: "demoalbumwinter000000001",
+		title: "", make: "Canon", model: "Canon EOS R6",
+		takenAt: date(2024, 1, 8, 23, 47, 30),
+		scene: scene{
+			skyTop: rgb(5, 8, 25), skyHorizon: rgb(25, 40, 80), sun: rgb(235, 235, 220),
+			sunX: 0.75, sunY: 0.18, sunR: 0.025, ridge: rgb(10, 12, 20), ground: rgb(20, 25, 35),
+			horizon: 0.75, peaks: 0.25, stars: true, seed: 67,
+		},
+	},
+	{
+		id: "demophotofrozenpond00001", albumID: "demoalbumwinter000000001",
+		title: "Frozen pond", tags: "winter,ice", make: "Canon", model: "Canon EOS R6",
+		takenAt: date(2024, 1, 9, 9, 15, 0),
+		scene: scene{
+			skyTop: rgb(120, 160, 200), skyHorizon: rgb(210, 220, 235), sun: rgb(255, 250, 235),
+			sunX: 0.15, sunY: 0.3, sunR: 0.04, ridge: rgb(80, 95, 90), ground: rgb(190, 210, 225),
+			horizon: 0.5, peaks: 0.1, water: true, seed: 71,
+		},
+	},
+	{
+		id: "demophotozermatt00000001", albumID: "demoalbumalps00000000001",
+		title: "DSC_1187.JPG", make: "NIKON CORPORATION", model: "NIKON Z 6",
+		takenAt: date(2024, 2, 3, 10, 5, 44), gps: &gps{46.0207, 7.7491, 1608},
+		scene: scene{
+			skyTop: rgb(20, 80, 180), skyHorizon: rgb(150, 190, 230), sun: rgb(255, 255, 240),
+			sunX: 0.85, sunY: 0.1, sunR: 0.035, ridge: rgb(225, 230, 240), ground: rgb(90, 100, 110),
+			horizon: 0.72, peaks: 0.6, seed: 83,
+		},
+	},
+	{
+		id:    "demophotojoshuatree00001",
+		title: "IMG_0007", make: "Google", model: "Pixel 7",
+		takenAt: date(2024, 4, 12, 7, 2, 18), gps: &gps{33.8734, -115.9010, 900},
+		scene: scene{
+			skyTop: rgb(100, 150, 210), skyHorizon: rgb(250, 200, 150), sun: rgb(255, 230, 170),
+			sunX: 0.1, sunY: 0.55, sunR: 0.05, ridge: rgb(150, 100, 90), ground: rgb(200, 160, 110),
+			horizon: 0.64, peaks: 0.1, seed: 97,
+		},
+	},
+	{
+		id:    "demophotoforest000000001",
+		title: "a3f1c2e4-9b7d-4e2a-8c11-5d6f7e8a9b0c", make: "FUJIFILM", model: "X-T3",
+		takenAt: date(2024, 5, 2, 16, 40, 3),
+		scene: scene{
+			skyTop: rgb(160, 190, 170), skyHorizon: rgb(220, 230, 200), sun: rgb(250, 250, 220),
+			sunX: 0.5, sunY: 0.15, sunR: 0.03, ridge: rgb(30, 70, 40), ground: rgb(40, 60, 30),
+			horizon: 0.8, peaks: 0.55, seed: 101,
+		},
+	},
+	{
+		id:      "demophotoscreenshot00001",
+		title:   "Screenshot 2024-03-02 at 10.14.22",
+		takenAt: date(2024, 3, 2, 10, 14, 22),
+		scene:   scene{window: true},
+	},
+}
+
+// Create writes a sample library to dir: a SQLite database with a subset of
+// Lychee's schema, and generated images for its photos. Images are generated
+// rather than bundled to keep the binary small.
+func Create(dir string) (*Library, error) {
+	lib := &Library{
+		DBPath:      filepath.Join(dir, "lychee.db"),
+		UploadsPath: filepath.Join(dir, "uploads"),
+	}
+
+	conn, err := sql.Open("sqlite3", lib.DBPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create demo database: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Exec(schema); err != nil {
+		return nil, fmt.Errorf("failed to create demo schema: %w", err)
+	}
+
+	// Spread upload times over the past few days, so some photos appear in
+	// the Recent smart album
+	uploaded := time.Now().UTC().Add(-time.Duration(len(photos)) * 12 * time.Hour)
+
+	for _, a := range albums {
+		if err := insertAlbum(conn, a, uploaded); err != nil {
+			return nil, fmt.Errorf("failed to add demo album %s: %w", a.title, err)
+		}
+	}
+	for i, p := range photos {
+		createdAt := uploaded.Add(time.Duration(i) * 12 * time.Hour)
+		if err := insertPhoto(conn, lib.UploadsPath, p, createdAt); err != nil {
+			return nil, fmt.Errorf("failed to add demo photo %s: %w", p.id, err)
+		}
+	}
+
+	return lib, nil
+}
+
+// insertAlbum adds an album, and makes it public if it should be
+func insertAlbum(conn *sql.DB, a album, createdAt time.Time) error {
+	_, err := conn.Exec(`
+		INSERT INTO base_albums (id, created_at, updated_at, title, description, owner_id)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		a.id, createdAt, createdAt, a.title, nullString(a.description), ownerID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := conn.Exec("INSERT INTO albums (id, parent_id) VALUES (?, ?)", a.id, nullString(a.parentID)); err != nil {
+		return err
+	}
+
+	if a.public {
+		_, err = conn.Exec("INSERT INTO access_permissions (base_album_id, user_id) VALUES (?, NULL)", a.id)
+	}
+	return err
+}
+
+// insertPhoto generates a photo's size variants in uploadsDir and adds the
+// photo to the database
+func insertPhoto(conn *sql.DB, uploadsDir string, p photo, createdAt time.Time) error {
+	original := p.scene.render(originalWidth, originalHeight)
+	variants := []struct {
+		kind models.SizeVariantType
+		img  *image.RGBA
+	}{
+		{models.SizeVariantOriginal, original},
+		{models.SizeVariantMedium, scale(original, original.Bounds(), mediumWidth, mediumHeight)},
+		{models.SizeVariantThumb, scale(original, centerSquare(original.Bounds()), thumbSize, thumbSize)},
+	}
+
+	// Lychee stores size variants under a directory per variant, in
+	// subdirectories named from a hash
+	sum := sha1.Sum([]byte(p.id))
+	name := hex.EncodeToString(sum[:])
+
+	var checksum string
+	var filesize int64
+	for _, v := range variants {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, v.img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return fmt.Errorf("failed to encode %s: %w", v.kind, err)
+		}
+
+		shortPath := path.Join(v.kind.String(), name[0:2], name[2:4], name[4:]+constants.ExtJPG)
+		file := filepath.Join(uploadsDir, filepath.FromSlash(shortPath))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, buf.Bytes(), 0o644); err != nil {
+			return err
+		}
+
+		b := v.img.Bounds()
+		_, err := conn.Exec(`
+			INSERT INTO size_variants (photo_id, type, short_path, width, height, ratio, filesize)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			p.id, int(v.kind), shortPath, b.Dx(), b.Dy(), float64(b.Dx())/float64(b.Dy()), buf.Len())
+		if err != nil {
+			return err
+		}
+
+		if v.kind == models.SizeVariantOriginal {
+			fileSum := sha1.Sum(buf.Bytes())
+			checksum = hex.EncodeToString(fileSum[:])
+			filesize = int64(buf.Len())
+		}
+	}
+
+	var latitude, longitude, altitude interface{}
+	if p.gps != nil {
+		latitude, longitude, altitude = p.gps.latitude, p.gps.longitude, p.gps.altitude
+	}
+	_, err := conn.Exec(`
+		INSERT INTO photos (
+			id, created_at, updated_at, owner_id, old_album_id, title, description, tags,
+			is_starred, make, model, latitude, longitude, altitude, taken_at, type, filesize, checksum
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.id, createdAt, createdAt, ownerID, nullString(p.albumID), p.title, nullString(p.description),
+		nullString(p.tags), p.starred, nullString(p.make), nullString(p.model),
+		latitude, longitude, altitude, p.takenAt, constants.MimeJPEG, filesize, checksum)
+	if err != nil {
+		return err
+	}
+
+	if p.albumID != "" {
+		_, err = conn.Exec("INSERT INTO photo_album (photo_id, album_id) VALUES (?, ?)", p.id, p.albumID)
+	}
+	return err
+}
+
+// nullString returns nil for an empty string, so it's stored as NULL
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func rgb(r, g, b uint8) color.RGBA {
+	return color.RGBA{r, g, b, 255}
+}
+
+func date(year int, month time.Month, day, hour, min, sec int) time.Time {
+	return time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+}
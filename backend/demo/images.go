@@ -0,0 +1,182 @@
+package demo
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// scene describes a generated landscape: a sky gradient with a sun or moon,
+// a distant ridge and either land or water in the foreground
+type scene struct {
+	skyTop, skyHorizon color.RGBA
+	sun                color.RGBA
+	// sunX and sunY place the sun as fractions of the width and height;
+	// sunR is its radius as a fraction of the height
+	sunX, sunY, sunR float64
+	ridge            color.RGBA
+	ground           color.RGBA
+	// horizon is the height of the ridge's base as a fraction of the height
+	horizon float64
+	// peaks scales the ridge's height and roughness
+	peaks float64
+	// water reflects the sky in the foreground instead of drawing ground
+	water bool
+	stars bool
+	// window draws a desktop application window instead of a landscape,
+	// for screenshots
+	window bool
+	seed   uint32
+}
+
+// render draws the scene at the given size
+func (s scene) render(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	if s.window {
+		drawWindow(img)
+		return img
+	}
+
+	w, h := float64(width), float64(height)
+	horizon := s.horizon * h
+	for x := 0; x < width; x++ {
+		fx := float64(x) / w
+		ridgeTop := horizon - s.peaks*h*s.ridgeHeight(fx)
+		for y := 0; y < height; y++ {
+			fy := float64(y)
+			var c color.RGBA
+			switch {
+			case fy < ridgeTop:
+				c = s.sky(float64(x), fy, w, h)
+			case fy < horizon:
+				// Haze the ridge toward the sky near its base
+				c = lerp(s.ridge, s.skyHorizon, 0.35*(fy-ridgeTop)/math.Max(horizon-ridgeTop, 1))
+			case s.water:
+				// Mirror the sky, darkened and broken up by ripples
+				ripple := 3 * math.Sin(fy*0.9+fx*40+float64(s.seed))
+				mirrored := math.Max(0, 2*horizon-fy+ripple)
+				c = lerp(s.sky(float64(x), mirrored, w, h), s.ground, 0.3+0.4*(fy-horizon)/(h-horizon))
+			default:
+				c = lerp(s.ground, color.RGBA{0, 0, 0, 255}, 0.5*(fy-horizon)/(h-horizon))
+			}
+			img.SetRGBA(x, y, grain(c, s.seed, x, y))
+		}
+	}
+	return img
+}
+
+// ridgeHeight returns the height of the ridge at fx across the image, from
+// 0 to 1, as a sum of sine waves varied by the scene's seed
+func (s scene) ridgeHeight(fx float64) float64 {
+	phase := float64(s.seed%97) / 97 * 2 * math.Pi
+	v := 0.5 + 0.3*math.Sin(fx*2*math.Pi*1.3+phase) +
+		0.15*math.Sin(fx*2*math.Pi*4.7+2*phase) +
+		0.05*math.Sin(fx*2*math.Pi*13+3*phase)
+	return math.Max(0, math.Min(1, v))
+}
+
+// sky returns the sky's color at (x, y) in an image of size w by h
+func (s scene) sky(x, y, w, h float64) color.RGBA {
+	c := lerp(s.skyTop, s.skyHorizon, y/(s.horizon*h))
+
+	if s.stars && hash(s.seed, int(x), int(y))%900 == 0 {
+		return color.RGBA{255, 255, 240, 255}
+	}
+
+	// The sun's disc, surrounded by a glow that fades with distance
+	d := math.Hypot(x-s.sunX*w, y-s.sunY*h) / h
+	if d < s.sunR {
+		return s.sun
+	}
+	return lerp(c, s.sun, 0.6*math.Exp(-(d-s.sunR)*12))
+}
+
+// drawWindow draws a light-themed application window on a desktop
+func drawWindow(img *image.RGBA) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	fill(img, b, color.RGBA{58, 110, 165, 255})
+
+	win := image.Rect(w/10, h/10, w*9/10, h*9/10)
+	fill(img, win, color.RGBA{246, 246, 246, 255})
+	titleBar := image.Rect(win.Min.X, win.Min.Y, win.Max.X, win.Min.Y+h/18)
+	fill(img, titleBar, color.RGBA{222, 222, 222, 255})
+	for i, c := range []color.RGBA{{255, 95, 87, 255}, {254, 188, 46, 255}, {40, 200, 64, 255}} {
+		x := titleBar.Min.X + h/40 + i*h/30
+		fill(img, image.Rect(x, titleBar.Min.Y+h/60, x+h/50, titleBar.Min.Y+h/60+h/50), c)
+	}
+
+	// A sidebar and lines of text
+	sidebar := image.Rect(win.Min.X, titleBar.Max.Y, win.Min.X+w/6, win.Max.Y)
+	fill(img, sidebar, color.RGBA{232, 236, 241, 255})
+	for line := 0; titleBar.Max.Y+(line+2)*h/25 < win.Max.Y; line++ {
+		y := titleBar.Max.Y + (line+1)*h/25
+		length := (win.Dx() - w/6) * (50 + int(hash(7, line, 0)%45)) / 100
+		fill(img, image.Rect(sidebar.Max.X+w/40, y, sidebar.Max.X+w/40+length, y+h/80), color.RGBA{120, 120, 125, 255})
+	}
+}
+
+// fill paints r with c
+func fill(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	r = r.Intersect(img.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// scale resizes the part of src within crop to width by height, averaging
+// the source pixels covered by each output pixel
+func scale(src *image.RGBA, crop image.Rectangle, width, height int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := crop.Min.Y + y*crop.Dy()/height
+		y1 := max(y0+1, crop.Min.Y+(y+1)*crop.Dy()/height)
+		for x := 0; x < width; x++ {
+			x0 := crop.Min.X + x*crop.Dx()/width
+			x1 := max(x0+1, crop.Min.X+(x+1)*crop.Dx()/width)
+
+			var r, g, b, n int
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := src.RGBAAt(sx, sy)
+					r, g, b, n = r+int(c.R), g+int(c.G), b+int(c.B), n+1
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), 255})
+		}
+	}
+	return dst
+}
+
+// centerSquare returns the largest square centered in r
+func centerSquare(r image.Rectangle) image.Rectangle {
+	side := min(r.Dx(), r.Dy())
+	x := r.Min.X + (r.Dx()-side)/2
+	y := r.Min.Y + (r.Dy()-side)/2
+	return image.Rect(x, y, x+side, y+side)
+}
+
+// lerp blends from a toward b by t, clamped to [0, 1]
+func lerp(a, b color.RGBA, t float64) color.RGBA {
+	t = math.Max(0, math.Min(1, t))
+	mix := func(x, y uint8) uint8 { return uint8(float64(x) + (float64(y)-float64(x))*t) }
+	return color.RGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), 255}
+}
+
+// grain adds a little deterministic noise to c so scenes look less flat
+func grain(c color.RGBA, seed uint32, x, y int) color.RGBA {
+	n := int(hash(seed, x, y)%9) - 4
+	adjust := func(v uint8) uint8 { return uint8(max(0, min(255, int(v)+n))) }
+	return color.RGBA{adjust(c.R), adjust(c.G), adjust(c.B), 255}
+}
+
+// hash returns a pseudo-random number for a pixel
+func hash(seed uint32, x, y int) uint32 {
+	h := seed*0x9E3779B1 ^ uint32(x)*0x85EBCA77 ^ uint32(y)*0xC2B2AE3D
+	h ^= h >> 15
+	h *= 0x2C1B3C6D
+	h ^= h >> 12
+	return h
+}
@@ -0,0 +1,109 @@
+// Package eval scores generated titles against human-written reference
+// titles, for comparing AI models and prompts offline.
+package eval
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Score holds the similarity of a generated title to a reference title.
+// Both measures range from 0 (nothing in common) to 1 (identical after
+// normalization).
+type Score struct {
+	// Words is the Jaccard similarity of the titles' word sets
+	Words float64 `json:"words"`
+	// Characters is 1 minus the normalized edit distance between the titles
+	Characters float64 `json:"characters"`
+}
+
+// Compare scores generated against reference, ignoring case and punctuation
+func Compare(generated, reference string) Score {
+	a := normalize(generated)
+	b := normalize(reference)
+	return Score{
+		Words:      jaccard(strings.Fields(a), strings.Fields(b)),
+		Characters: editSimilarity([]rune(a), []rune(b)),
+	}
+}
+
+// Summary aggregates scores over an evaluation run
+type Summary struct {
+	Count          int     `json:"count"`
+	MeanWords      float64 `json:"mean_words"`
+	MeanCharacters float64 `json:"mean_characters"`
+	// ExactMatches counts titles identical to the reference after normalization
+	ExactMatches int `json:"exact_matches"`
+}
+
+// Add includes a score in the summary
+func (s *Summary) Add(score Score) {
+	s.MeanWords = (s.MeanWords*float64(s.Count) + score.Words) / float64(s.Count+1)
+	s.MeanCharacters = (s.MeanCharacters*float64(s.Count) + score.Characters) / float64(s.Count+1)
+	s.Count++
+	if score.Characters == 1 {
+		s.ExactMatches++
+	}
+}
+
+// normalize lowercases s, replaces punctuation with spaces and collapses whitespace
+func normalize(s string) string {
+	mapped := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			return unicode.ToLower(r)
+		}
+		return ' '
+	}, s)
+	return strings.Join(strings.Fields(mapped), " ")
+}
+
+// jaccard returns the Jaccard similarity of two word lists treated as sets
+func jaccard(a, b []string) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+
+	set := make(map[string]int, len(a)+len(b))
+	for _, w := range a {
+		set[w] |= 1
+	}
+	for _, w := range b {
+		set[w] |= 2
+	}
+
+	both := 0
+	for _, v := range set {
+		if v == 3 {
+			both++
+		}
+	}
+	return float64(both) / float64(len(set))
+}
+
+// editSimilarity returns 1 minus the Levenshtein distance between a and b
+// divided by the length of the longer one
+func editSimilarity(a, b []rune) float64 {
+	longest := max(len(a), len(b))
+	if longest == 0 {
+		return 1
+	}
+
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return 1 - float64(prev[len(b)])/float64(longest)
+}
@@ -0,0 +1,143 @@
+// Package feed broadcasts changes to photos, such as saved titles and
+// skips, so that people reviewing the queue at the same time see each
+// other's progress.
+package feed
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType is the kind of change an Event reports
+type EventType string
+
+const (
+	// EventTitled means the photos' titles were saved
+	EventTitled EventType = "titled"
+	// EventUpdated means other metadata of the photos changed, such as
+	// their description or album
+	EventUpdated EventType = "updated"
+	// EventApproved means the photos' AI titles were approved unchanged
+	EventApproved EventType = "approved"
+	// EventSkipped means the photos were put on the ignore list
+	EventSkipped EventType = "skipped"
+	// EventUnskipped means a skip of the photos was undone
+	EventUnskipped EventType = "unskipped"
+	// EventDeleted means the photos were deleted
+	EventDeleted EventType = "deleted"
+	// EventAdded means the photos were newly imported into Lychee
+	EventAdded EventType = "added"
+	// EventLocked means someone started editing the photos
+	EventLocked EventType = "locked"
+	// EventUnlocked means someone stopped editing the photos
+	EventUnlocked EventType = "unlocked"
+)
+
+const (
+	// maxRecent is how many events are kept for clients catching up
+	maxRecent = 256
+	// subscriberBuffer is how many events a subscriber may fall behind
+	// before it is dropped
+	subscriberBuffer = 64
+)
+
+// Event is a change to one or more photos
+type Event struct {
+	// ID increases with each event, so clients can resume after the last
+	// one they saw
+	ID       uint64    `json:"id"`
+	Type     EventType `json:"type"`
+	PhotoIDs []string  `json:"photo_ids"`
+	// Actor is who made the change (see auth.Actor); empty for imports
+	// and anonymous requests
+	Actor string    `json:"actor,omitempty"`
+	At    time.Time `json:"at"`
+}
+
+// Hub delivers events to subscribers and keeps the most recent ones for
+// clients that reconnect. A nil *Hub discards events.
+type Hub struct {
+	mu          sync.Mutex
+	lastID      uint64
+	recent      []Event
+	subscribers map[chan Event]struct{}
+}
+
+// NewHub creates a Hub with no subscribers
+func NewHub() *Hub {
+	return &Hub{subscribers: map[chan Event]struct{}{}}
+}
+
+// Publish sends an event about photoIDs to all subscribers. Subscribers
+// that have fallen too far behind are dropped; their channel is closed so
+// they can reconnect and catch up with Since.
+func (h *Hub) Publish(eventType EventType, actor string, photoIDs ...string) {
+	if h == nil || len(photoIDs) == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	event := Event{ID: h.lastID, Type: eventType, PhotoIDs: photoIDs, Actor: actor, At: time.Now().UTC()}
+	h.recent = append(h.recent, event)
+	if len(h.recent) > maxRecent {
+		h.recent = h.recent[len(h.recent)-maxRecent:]
+	}
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Since returns the kept events after the one with ID id, and the ID of
+// the latest event. complete is false if some events after id are no
+// longer kept, in which case the client should reload everything.
+func (h *Hub) Since(id uint64) (events []Event, lastID uint64, complete bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if id >= h.lastID {
+		return nil, h.lastID, true
+	}
+	complete = len(h.recent) > 0 && h.recent[0].ID <= id+1
+	for _, event := range h.recent {
+		if event.ID > id {
+			events = append(events, event)
+		}
+	}
+	return events, h.lastID, complete
+}
+
+// Subscribe returns a channel receiving every event published from now on,
+// and a function that unsubscribes. The channel is closed if the
+// subscriber falls too far behind.
+func (h *Hub) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	h.mu.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subscribers[ch]; ok {
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribers returns the number of connected subscribers
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers)
+}
@@ -0,0 +1,52 @@
+package feed
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/db"
+)
+
+// maxImportBatch limits how many new photos one added event reports
+const maxImportBatch = 500
+
+// WatchImports polls Lychee every interval for photos created since the
+// watch began, publishing an added event for them, until ctx is cancelled.
+// Polls are skipped while nobody is subscribed; photos imported meanwhile
+// are reported by the next poll.
+func (h *Hub) WatchImports(ctx context.Context, database *db.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	since := time.Now().UTC()
+	if latest, err := database.GetLatestPhotoCreatedAt(); err != nil {
+		log.Printf("Failed to get the newest photo for the change feed: %v", err)
+	} else if latest != nil {
+		since = *latest
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if h.Subscribers() == 0 {
+			continue
+		}
+
+		for {
+			ids, latest, err := database.GetPhotosCreatedSince(since, maxImportBatch)
+			if err != nil {
+				log.Printf("Failed to check for imported photos: %v", err)
+				break
+			}
+			since = latest
+			h.Publish(EventAdded, "", ids...)
+			if len(ids) < maxImportBatch {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,181 @@
+// Package geocode turns photos' GPS coordinates into place names using a
+// Nominatim-compatible reverse geocoding service.
+package geocode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/locale"
+)
+
+// ErrNoResult is returned by Reverse when the service knows of no place at
+// the coordinates, e.g. in the open ocean
+var ErrNoResult = errors.New("no place found at these coordinates")
+
+// Client looks up place names for coordinates. Requests are spaced at least
+// constants.GeocodeMinInterval apart, as the public Nominatim service
+// requires, and results are cached for the life of the process. It is safe
+// for concurrent use.
+type Client struct {
+	url        string
+	userAgent  string
+	language   string
+	locale     *locale.Locale
+	httpClient *http.Client
+
+	// mu serializes requests so they can be rate limited
+	mu   sync.Mutex
+	last time.Time
+
+	cacheMu sync.RWMutex
+	cache   map[string]string
+}
+
+// New creates a Client for the reverse geocoding endpoint at serviceURL.
+// userAgent identifies the application to the service; language, if set,
+// is the preferred language of place names (e.g. "en" or "de,en"), and
+// place names are written following loc's conventions.
+func New(serviceURL, userAgent, language string, loc *locale.Locale) *Client {
+	return &Client{
+		url:        serviceURL,
+		userAgent:  userAgent,
+		language:   language,
+		locale:     loc,
+		httpClient: &http.Client{Timeout: constants.GeocodeTimeout},
+		cache:      make(map[string]string),
+	}
+}
+
+// nominatimResponse is the part of a Nominatim jsonv2 reverse response the
+// client uses
+type nominatimResponse struct {
+	Error       string            `json:"error"`
+	DisplayName string            `json:"display_name"`
+	Address     map[string]string `json:"address"`
+}
+
+// Reverse returns a short name, such as "Ann Arbor, Michigan, United
+// States", for the place at the given coordinates
+func (c *Client) Reverse(ctx context.Context, lat, lon float64) (string, error) {
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return "", fmt.Errorf("invalid coordinates %f, %f", lat, lon)
+	}
+
+	// Four decimal places is about 10 meters, well below the resolution of
+	// a place name
+	key := fmt.Sprintf("%.4f,%.4f", lat, lon)
+	c.cacheMu.RLock()
+	name, ok := c.cache[key]
+	c.cacheMu.RUnlock()
+	if ok {
+		return name, nil
+	}
+
+	name, err := c.lookup(ctx, lat, lon)
+	if err != nil {
+		return "", err
+	}
+
+	c.cacheMu.Lock()
+	c.cache[key] = name
+	c.cacheMu.Unlock()
+	return name, nil
+}
+
+// lookup queries the service, waiting as needed to respect its rate limit
+func (c *Client) lookup(ctx context.Context, lat, lon float64) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if wait := constants.GeocodeMinInterval - time.Since(c.last); wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", ctx.Err()
+		case <-timer.C:
+		}
+	}
+	defer func() { c.last = time.Now() }()
+
+	u, err := url.Parse(c.url)
+	if err != nil {
+		return "", fmt.Errorf("invalid geocoding URL: %w", err)
+	}
+	q := u.Query()
+	q.Set("format", "jsonv2")
+	q.Set("lat", strconv.FormatFloat(lat, 'f', -1, 64))
+	q.Set("lon", strconv.FormatFloat(lon, 'f', -1, 64))
+	// City level; street addresses make poor locations and titles
+	q.Set("zoom", "10")
+	q.Set("addressdetails", "1")
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create geocoding request: %w", err)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept", constants.ContentTypeJSON)
+	if c.language != "" {
+		req.Header.Set("Accept-Language", c.language)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("geocoding request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("geocoding service returned HTTP %d", resp.StatusCode)
+	}
+
+	var result nominatimResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode geocoding response: %w", err)
+	}
+	if result.Error != "" {
+		return "", ErrNoResult
+	}
+
+	if parts := placeParts(result.Address); len(parts) > 0 {
+		return c.locale.FormatPlace(parts), nil
+	}
+	if result.DisplayName != "" {
+		return result.DisplayName, nil
+	}
+	return "", ErrNoResult
+}
+
+// placeParts returns the locality, region and country of a Nominatim
+// address, using the most specific of each that is present
+func placeParts(address map[string]string) []string {
+	var parts []string
+	for _, keys := range [][]string{
+		{"city", "town", "village", "hamlet", "municipality", "suburb", "county"},
+		{"state", "province", "region", "state_district"},
+		{"country"},
+	} {
+		for _, k := range keys {
+			if v := strings.TrimSpace(address[k]); v != "" {
+				// Skip repeats, e.g. city-states
+				if len(parts) == 0 || parts[len(parts)-1] != v {
+					parts = append(parts, v)
+				}
+				break
+			}
+		}
+	}
+	return parts
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/config"
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/db"
+)
+
+// AdminHandler handles operational HTTP requests such as database pool
+// inspection and reconnection
+type AdminHandler struct {
+	db           *db.DB
+	queryLimiter *QueryLimiter
+	configPath   string
+}
+
+// NewAdminHandler creates a new AdminHandler. configPath is re-read on
+// reconnect so that rotated database credentials are picked up.
+func NewAdminHandler(database *db.DB, queryLimiter *QueryLimiter, configPath string) *AdminHandler {
+	return &AdminHandler{
+		db:           database,
+		queryLimiter: queryLimiter,
+		configPath:   configPath,
+	}
+}
+
+// DBStatsResponse represents database connection pool statistics
+type DBStatsResponse struct {
+	Driver             string `json:"driver"`
+	MaxOpenConnections int    `json:"max_open_connections"`
+	OpenConnections    int    `json:"open_connections"`
+	InUse              int    `json:"in_use"`
+	Idle               int    `json:"idle"`
+	WaitCount          int64  `json:"wait_count"`
+	WaitDurationMs     int64  `json:"wait_duration_ms"`
+	MaxIdleClosed      int64  `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64  `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64  `json:"max_lifetime_closed"`
+	// Queries reports the expensive read requests running and waiting,
+	// if they are limited
+	Queries *QueryLimiterStats `json:"queries,omitempty"`
+}
+
+// SlowQueriesResponse lists the slowest recent queries
+type SlowQueriesResponse struct {
+	// ThresholdMs is how long a query takes before it is recorded; 0 if
+	// slow queries aren't recorded, though timeouts still are
+	ThresholdMs int64          `json:"threshold_ms"`
+	Queries     []db.SlowQuery `json:"queries"`
+}
+
+// GetSlowQueries handles GET requests for the slowest of the recent queries
+// that exceeded the slow query threshold or timed out. ?limit= caps how
+// many are returned.
+func (h *AdminHandler) GetSlowQueries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w)
+		return
+	}
+
+	limit, _, ok := parsePageParams(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	if err := json.NewEncoder(w).Encode(SlowQueriesResponse{
+		ThresholdMs: h.db.SlowQueryThreshold().Milliseconds(),
+		Queries:     h.db.SlowQueries(limit),
+	}); err != nil {
+		log.Printf("Failed to encode slow queries response: %v", err)
+	}
+}
+
+// GetDBStats handles GET requests for database connection pool statistics
+func (h *AdminHandler) GetDBStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w)
+		return
+	}
+
+	h.writeDBStats(w)
+}
+
+// ReconnectDB handles POST requests to re-read the database configuration
+// and replace the connection pool
+func (h *AdminHandler) ReconnectDB(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w)
+		return
+	}
+
+	cfg, err := config.Load(h.configPath)
+	if err != nil {
+		log.Printf("Failed to reload config for database reconnect: %v", err)
+		InternalServerError(w, "Failed to reload configuration.")
+		return
+	}
+
+	if err := h.db.Reconnect(cfg); err != nil {
+		log.Printf("Database reconnect failed: %v", err)
+		ServiceUnavailable(w, "Failed to reconnect to the database. The existing connection pool is still in use.")
+		return
+	}
+
+	log.Printf("Reconnected to %s database", h.db.Driver())
+	h.writeDBStats(w)
+}
+
+func (h *AdminHandler) writeDBStats(w http.ResponseWriter) {
+	stats := h.db.Stats()
+	response := DBStatsResponse{
+		Driver:             h.db.Driver(),
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMs:     stats.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+		Queries:            h.queryLimiter.Stats(),
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode database stats response: %v", err)
+	}
+}
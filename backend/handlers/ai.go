@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/ai"
+	"github.com/cdzombak/lychee-meta-tool/backend/config"
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/titlecache"
+)
+
+// AIHandler handles HTTP requests about the configured AI backend
+type AIHandler struct {
+	backend *ai.Holder
+	opts    AIHandlerOptions
+}
+
+// AIHandlerOptions holds the AI request machinery shared with
+// PhotoHandler, which AIHandler reports on and resets when the backend is
+// reloaded. Each may be nil if unused.
+type AIHandlerOptions struct {
+	Guard      *ai.Guard
+	Usage      *ai.UsageTracker
+	Limiter    *ai.Limiter
+	TitleCache *titlecache.Cache
+	// ConfigPath is re-read when the AI backend is reloaded
+	ConfigPath string
+}
+
+// NewAIHandler creates a new AIHandler for the AI client held by backend
+func NewAIHandler(backend *ai.Holder, opts AIHandlerOptions) *AIHandler {
+	return &AIHandler{
+		backend: backend,
+		opts:    opts,
+	}
+}
+
+// AIStatusResponse describes the AI backend and, where the backend reports
+// it, whether its model is ready or still being downloaded
+type AIStatusResponse struct {
+	Backend string          `json:"backend,omitempty"`
+	Status  string          `json:"status"`
+	Model   *ai.ModelStatus `json:"model,omitempty"`
+	// Circuit is the state of the circuit breaker guarding AI requests:
+	// "open" while requests are refused after repeated failures
+	Circuit string `json:"circuit,omitempty"`
+	// Generations reports the generations running and queued when
+	// ai.max_concurrent_generations limits them
+	Generations *ai.LimiterStats `json:"generations,omitempty"`
+}
+
+// GetStatus handles GET requests for the AI backend's status
+func (h *AIHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w)
+		return
+	}
+
+	h.writeStatus(w)
+}
+
+// writeStatus writes the AI backend's status
+func (h *AIHandler) writeStatus(w http.ResponseWriter) {
+	client := h.backend.Client()
+	status := aiStatusOf(h.backend)
+	response := AIStatusResponse{
+		Backend: status.Backend,
+		Status:  status.Status(),
+	}
+	if reporter, ok := client.(ai.ModelStatusReporter); ok {
+		model := reporter.ModelStatus()
+		response.Model = &model
+	}
+	if client != nil && h.opts.Guard != nil {
+		response.Circuit = h.opts.Guard.State()
+	}
+	if client != nil && h.opts.Limiter != nil {
+		stats := h.opts.Limiter.Stats()
+		response.Generations = &stats
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode AI status response: %v", err)
+	}
+}
+
+// AIModelsResponse lists the models offered by the AI backend's server
+type AIModelsResponse struct {
+	Backend string `json:"backend"`
+	ai.ModelList
+}
+
+// ListModels handles GET requests for the models available on the AI
+// backend's server, and whether the configured model is among them
+func (h *AIHandler) ListModels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w)
+		return
+	}
+
+	client, backend := h.backend.Client(), h.backend.Backend()
+	if client == nil {
+		AINotConfigured(w)
+		return
+	}
+	lister, ok := client.(ai.ModelLister)
+	if !ok {
+		BadRequest(w, fmt.Sprintf("The %s AI backend does not support listing models.", backend), nil)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), constants.DefaultHTTPTimeout)
+	defer cancel()
+
+	list, err := lister.ListModels(ctx)
+	if err != nil {
+		log.Printf("Failed to list %s models: %v", backend, err)
+		ServiceUnavailable(w, "Failed to list models from the AI backend. Please check that it is running.")
+		return
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	if err := json.NewEncoder(w).Encode(AIModelsResponse{Backend: backend, ModelList: list}); err != nil {
+		log.Printf("Failed to encode AI models response: %v", err)
+	}
+}
+
+// AIHealthResponse reports whether the AI backend is reachable and its model
+// available, i.e. whether title generation should work
+type AIHealthResponse struct {
+	Backend string `json:"backend,omitempty"`
+	// Status is "ok", "unhealthy", "disabled" if no backend is configured,
+	// or "unknown" if the backend can't be checked without generating a title
+	Status  string `json:"status"`
+	Version string `json:"version,omitempty"`
+	Model   string `json:"model,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// CheckHealth handles GET requests to check the AI backend: that its server
+// responds and offers the configured model, and that AI requests aren't
+// being refused after repeated failures. It responds 503 if title
+// generation won't work.
+func (h *AIHandler) CheckHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w)
+		return
+	}
+
+	client := h.backend.Client()
+	response := AIHealthResponse{Backend: h.backend.Backend(), Status: "ok"}
+	if client == nil {
+		response.Status = "disabled"
+		response.Error = "AI title generation is not configured"
+	} else {
+		ctx, cancel := context.WithTimeout(r.Context(), constants.AIHealthTimeout)
+		defer cancel()
+		if err := h.checkBackend(ctx, client, &response); err != nil {
+			response.Status = "unhealthy"
+			response.Error = err.Error()
+		}
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	if response.Status == "unhealthy" || response.Status == "disabled" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode AI health response: %v", err)
+	}
+}
+
+// GetUsage handles GET requests for the tokens, latency and estimated cost
+// of the AI requests made since the server started
+func (h *AIHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w)
+		return
+	}
+	if h.opts.Usage == nil {
+		AINotConfigured(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	if err := json.NewEncoder(w).Encode(h.opts.Usage.Report()); err != nil {
+		log.Printf("Failed to encode AI usage response: %v", err)
+	}
+}
+
+// checkBackend pings the AI backend's server and looks for the configured
+// model, recording what it learns in response. Backends supporting neither
+// check are reported as unknown.
+func (h *AIHandler) checkBackend(ctx context.Context, client ai.Client, response *AIHealthResponse) error {
+	if h.opts.Guard != nil && h.opts.Guard.State() == ai.CircuitOpen {
+		return fmt.Errorf("AI requests are paused after repeated failures")
+	}
+
+	pinger, canPing := client.(ai.Pinger)
+	lister, canList := client.(ai.ModelLister)
+	if !canPing && !canList {
+		response.Status = "unknown"
+		return nil
+	}
+
+	if canPing {
+		version, err := pinger.Ping(ctx)
+		if err != nil {
+			return err
+		}
+		response.Version = version
+	}
+
+	if canList {
+		list, err := lister.ListModels(ctx)
+		if err != nil {
+			return err
+		}
+		response.Model = list.Configured
+		if !list.Installed {
+			if reporter, ok := client.(ai.ModelStatusReporter); ok && reporter.ModelStatus().State == ai.ModelPulling {
+				return fmt.Errorf("model %q is still being downloaded", list.Configured)
+			}
+			return fmt.Errorf("model %q is not available on the server", list.Configured)
+		}
+	}
+
+	// A model without vision support is reachable but useless for titles
+	if reporter, ok := client.(ai.ModelStatusReporter); ok {
+		if model := reporter.ModelStatus(); model.State == ai.ModelNoVision {
+			return errors.New(model.Error)
+		}
+	}
+
+	return nil
+}
+
+// ReloadBackend handles POST requests to re-read the AI section of the
+// configuration and replace the AI client, e.g. to switch from Ollama to
+// OpenAI or change the model without restarting. Generations already
+// running finish with the old client. Concurrency limits and the title
+// cache path still require a restart.
+func (h *AIHandler) ReloadBackend(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w)
+		return
+	}
+
+	cfg, err := config.Load(h.opts.ConfigPath)
+	if err != nil {
+		log.Printf("Failed to reload config for AI backend reload: %v", err)
+		InternalServerError(w, "Failed to reload configuration.")
+		return
+	}
+
+	// Removing the AI configuration disables title generation
+	var client ai.Client
+	backend, settings := cfg.AIBackend()
+	if backend != "" {
+		if client, err = ai.New(backend, settings); err != nil {
+			log.Printf("AI backend reload failed: failed to initialize %s AI backend: %v", backend, err)
+			ServiceUnavailable(w, fmt.Sprintf("Failed to initialize the %s AI backend. The existing backend is still in use.", backend))
+			return
+		}
+	}
+
+	model := settings["model"]
+	h.backend.Swap(backend, model, client)
+	// Failures of the old backend say nothing about the new one
+	h.opts.Guard.Reset()
+	h.opts.Usage.SetModel(backend, model)
+	if h.opts.TitleCache != nil {
+		if err := h.opts.TitleCache.SetScope(backend + "/" + model); err != nil {
+			log.Printf("Failed to save title cache after AI backend reload: %v", err)
+		}
+	}
+
+	if backend == "" {
+		log.Printf("AI backend reloaded: AI title generation is disabled")
+	} else {
+		log.Printf("AI backend reloaded: using %s", backend)
+	}
+	h.writeStatus(w)
+}
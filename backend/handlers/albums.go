@@ -2,32 +2,75 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"sort"
+	"strings"
 
+	"github.com/cdzombak/lychee-meta-tool/backend/auth"
 	"github.com/cdzombak/lychee-meta-tool/backend/constants"
 	"github.com/cdzombak/lychee-meta-tool/backend/db"
 	"github.com/cdzombak/lychee-meta-tool/backend/models"
+	"github.com/cdzombak/lychee-meta-tool/backend/sidecar"
 )
 
 // AlbumHandler handles HTTP requests related to photo albums
 type AlbumHandler struct {
-	db *db.DB
+	db      *db.DB
+	sidecar *sidecar.Store
 }
 
-// NewAlbumHandler creates a new AlbumHandler with the provided database connection
-func NewAlbumHandler(database *db.DB) *AlbumHandler {
-	return &AlbumHandler{db: database}
+// NewAlbumHandler creates a new AlbumHandler with the provided dependencies
+func NewAlbumHandler(database *db.DB, sidecarStore *sidecar.Store) *AlbumHandler {
+	return &AlbumHandler{
+		db:      database,
+		sidecar: sidecarStore,
+	}
+}
+
+// AlbumListResponse is a page of the albums listed by GetAlbums
+type AlbumListResponse struct {
+	Albums []models.AlbumResponse `json:"albums"`
+	// Total is the number of matching albums across all pages
+	Total int `json:"total"`
 }
 
-// GetAlbums handles GET requests to retrieve all albums
+// GetAlbums handles GET requests to list normal albums by title. ?q=
+// searches titles, ?only_with_pending=true lists only albums containing
+// photos that need a title, and limit and offset page through the results.
+// Requests bound to a Lychee user only see the albums that user may edit.
 func (h *AlbumHandler) GetAlbums(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		MethodNotAllowed(w)
 		return
 	}
 
-	albums, err := h.db.GetAlbums()
+	query := r.URL.Query()
+	filter := db.AlbumFilter{Title: strings.TrimSpace(query.Get("q"))}
+	if textLength(filter.Title) > MaxTitleLength {
+		BadRequest(w, fmt.Sprintf("Search query is too long. Maximum %d characters.", MaxTitleLength), nil)
+		return
+	}
+
+	withPending, valid := parseBoolParam(query.Get("only_with_pending"))
+	if !valid {
+		BadRequest(w, "Invalid only_with_pending parameter. Must be true or false.", nil)
+		return
+	}
+	filter.WithPending = withPending
+
+	limit, offset, ok := parsePageParams(w, r)
+	if !ok {
+		return
+	}
+
+	if filter.EditableBy, ok = lycheeUserID(w, r, h.db); !ok {
+		return
+	}
+
+	albums, total, err := h.db.GetAlbums(filter, limit, offset)
 	if err != nil {
 		DatabaseError(w, "get albums", err)
 		return
@@ -35,43 +78,86 @@ func (h *AlbumHandler) GetAlbums(w http.ResponseWriter, r *http.Request) {
 
 	// Convert to response format
 	albumResponses := make([]models.AlbumResponse, len(albums))
-	for i, album := range albums {
-		albumResponses[i] = models.AlbumResponse{
-			ID:    album.ID,
-			Title: album.Title,
-		}
+	for i := range albums {
+		albumResponses[i] = albums[i].ToAlbumResponse()
 	}
 
-	response := models.AlbumsResponse{
+	response := AlbumListResponse{
 		Albums: albumResponses,
+		Total:  total,
 	}
 
+	page := Pagination{Limit: limit, Offset: offset, Total: &total}
+	if next := offset + len(albums); next < total {
+		page.HasMore = true
+		page.NextOffset = &next
+	}
+	setPagination(w, page)
 	w.Header().Set("Content-Type", constants.ContentTypeJSON)
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		log.Printf("Failed to encode albums response: %v", err)
 	}
 }
 
-// GetAlbumsWithPhotoCounts handles GET requests to retrieve albums containing photos that need metadata
+// GetAlbumsWithPhotoCounts handles GET requests to retrieve albums containing photos that need metadata.
+// Lychee's smart albums that contain such photos are listed first. Each album reports how many of
+// its photos need a title and how many lack a description. With ?public=true, only publicly
+// visible albums and photos are included; ?needs= lists albums with photos lacking a title (the
+// default), a description, or either, as for the needs-metadata queue.
 func (h *AlbumHandler) GetAlbumsWithPhotoCounts(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		MethodNotAllowed(w)
 		return
 	}
 
-	albums, err := h.db.GetAlbumsWithPhotoCounts()
+	publicOnly, valid := parseBoolParam(r.URL.Query().Get("public"))
+	if !valid {
+		BadRequest(w, "Invalid public parameter. Must be true or false.", nil)
+		return
+	}
+
+	filter := db.PhotoFilter{PublicOnly: publicOnly}
+	if !parseNeedsParam(w, r, &filter) {
+		return
+	}
+	if !restrictFilter(w, r, h.db, &filter) {
+		return
+	}
+	applyExclusions(h.sidecar, &filter)
+
+	albums, err := h.db.GetAlbumsWithPhotoCounts(filter)
 	if err != nil {
 		DatabaseError(w, "get albums with photo counts", err)
 		return
 	}
 
 	// Convert to response format - only include albums with photos needing metadata
-	albumResponses := make([]models.AlbumResponse, len(albums))
-	for i, album := range albums {
-		albumResponses[i] = models.AlbumResponse{
-			ID:    album.ID,
-			Title: album.Title,
+	albumResponses := make([]models.AlbumResponse, 0, len(db.SmartAlbums)+len(albums))
+	for _, smart := range db.SmartAlbums {
+		smartFilter := filter
+		smartFilter.AlbumID = &smart.ID
+		counts, err := h.db.CountMissingFields(smartFilter)
+		if err != nil {
+			// Smart albums depend on parts of Lychee's schema that vary
+			// between versions; don't fail the whole list over one
+			log.Printf("Failed to count photos in smart album %s: %v", smart.ID, err)
+			continue
 		}
+		if counts.Any(filter.Needs) {
+			albumResponses = append(albumResponses, models.AlbumResponse{
+				ID:                      smart.ID,
+				Title:                   smart.Title,
+				Smart:                   true,
+				MissingTitleCount:       counts.Title,
+				MissingDescriptionCount: counts.Description,
+			})
+		}
+	}
+	for i := range albums {
+		response := albums[i].ToAlbumResponse()
+		response.MissingTitleCount = albums[i].PhotoCount
+		response.MissingDescriptionCount = albums[i].MissingDescriptionCount
+		albumResponses = append(albumResponses, response)
 	}
 
 	response := models.AlbumsResponse{
@@ -82,4 +168,148 @@ func (h *AlbumHandler) GetAlbumsWithPhotoCounts(w http.ResponseWriter, r *http.R
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		log.Printf("Failed to encode albums with photo counts response: %v", err)
 	}
-}
\ No newline at end of file
+}
+
+// AlbumSettingsResponse represents an album's AI generation settings
+type AlbumSettingsResponse struct {
+	AlbumID string `json:"album_id"`
+	sidecar.AlbumSettings
+}
+
+// AllAlbumSettingsResponse lists every album with AI setting overrides
+type AllAlbumSettingsResponse struct {
+	Albums []AlbumSettingsResponse `json:"albums"`
+}
+
+// GetAllAlbumSettings handles GET requests listing albums with AI setting overrides
+func (h *AlbumHandler) GetAllAlbumSettings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w)
+		return
+	}
+
+	overrides := h.sidecar.Albums()
+	albums := make([]AlbumSettingsResponse, 0, len(overrides))
+	for id, settings := range overrides {
+		albums = append(albums, AlbumSettingsResponse{AlbumID: id, AlbumSettings: settings})
+	}
+	sort.Slice(albums, func(i, j int) bool { return albums[i].AlbumID < albums[j].AlbumID })
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	if err := json.NewEncoder(w).Encode(AllAlbumSettingsResponse{Albums: albums}); err != nil {
+		log.Printf("Failed to encode album settings response: %v", err)
+	}
+}
+
+// HandleAlbumSettings handles GET and PUT requests for a single album's AI
+// settings at /api/albums/{id}/settings, and POST requests excluding it
+// from the queues at /api/albums/{id}/exclude
+func (h *AlbumHandler) HandleAlbumSettings(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, constants.AlbumsPrefix+"/")
+	albumID, suffix, found := strings.Cut(rest, "/")
+	if !found || (suffix != "settings" && suffix != "exclude") {
+		NotFound(w, "")
+		return
+	}
+	if albumID == "" || !validateAlbumID(albumID) {
+		InvalidID(w, "album ID")
+		return
+	}
+	if db.IsSmartAlbum(albumID) {
+		BadRequest(w, "Smart albums cannot have AI settings.", nil)
+		return
+	}
+
+	if suffix == "exclude" {
+		h.excludeAlbum(w, r, albumID)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.writeAlbumSettings(w, albumID)
+	case http.MethodPut:
+		if !checkAlbumAccess(w, r, h.db, albumID) {
+			return
+		}
+
+		var settings sidecar.AlbumSettings
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			InvalidJSON(w, err)
+			return
+		}
+
+		if validationErrors := ValidateAlbumSettings(&settings); len(validationErrors) > 0 {
+			ValidationFailed(w, validationErrors)
+			return
+		}
+		// Auto-apply saves every title generated in the album, which is a
+		// bulk operation; changing the album's other settings is ordinary
+		// editing
+		if settings.AutoApply && !h.sidecar.Album(albumID).AutoApply && !requireScope(w, r, auth.ScopeAdmin) {
+			return
+		}
+
+		if err := h.sidecar.SetAlbum(albumID, settings); err != nil {
+			log.Printf("Failed to save settings for album %s: %v", albumID, err)
+			InternalServerError(w, "Failed to save album settings. Please try again.")
+			return
+		}
+
+		h.writeAlbumSettings(w, albumID)
+	default:
+		MethodNotAllowed(w)
+	}
+}
+
+// ExcludeAlbumRequest sets whether an album is excluded from the queues
+type ExcludeAlbumRequest struct {
+	Excluded *bool `json:"excluded"`
+}
+
+// excludeAlbum handles POST /api/albums/{id}/exclude, which leaves the
+// album's photos out of the queues. With an empty body it toggles the
+// exclusion; {"excluded": false} includes the album again.
+func (h *AlbumHandler) excludeAlbum(w http.ResponseWriter, r *http.Request, albumID string) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w)
+		return
+	}
+	if !checkAlbumAccess(w, r, h.db, albumID) {
+		return
+	}
+
+	var req ExcludeAlbumRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		InvalidJSON(w, err)
+		return
+	}
+
+	settings := h.sidecar.Album(albumID)
+	if req.Excluded != nil {
+		settings.ExcludedFromQueue = *req.Excluded
+	} else {
+		settings.ExcludedFromQueue = !settings.ExcludedFromQueue
+	}
+
+	if err := h.sidecar.SetAlbum(albumID, settings); err != nil {
+		log.Printf("Failed to save queue exclusion for album %s: %v", albumID, err)
+		InternalServerError(w, "Failed to save album settings. Please try again.")
+		return
+	}
+	log.Printf("Album %s excluded from queues: %t", albumID, settings.ExcludedFromQueue)
+
+	h.writeAlbumSettings(w, albumID)
+}
+
+func (h *AlbumHandler) writeAlbumSettings(w http.ResponseWriter, albumID string) {
+	response := AlbumSettingsResponse{
+		AlbumID:       albumID,
+		AlbumSettings: h.sidecar.Album(albumID),
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode album settings response: %v", err)
+	}
+}
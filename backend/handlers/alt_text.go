@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/ai"
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/feed"
+	"github.com/cdzombak/lychee-meta-tool/backend/models"
+	"github.com/cdzombak/lychee-meta-tool/backend/sidecar"
+)
+
+// GenerateAltTextRequest adjusts AI alt text generation. The body is
+// optional.
+type GenerateAltTextRequest struct {
+	// Apply saves the alt text as the photo's description in Lychee,
+	// marked as AI-written
+	Apply bool `json:"apply"`
+	// Overwrite allows Apply to replace an existing description
+	Overwrite bool `json:"overwrite"`
+	// Language overrides the album's and the default language
+	Language string `json:"language"`
+}
+
+// GenerateAltTextResponse reports generated alt text for a photo
+type GenerateAltTextResponse struct {
+	Success bool   `json:"success"`
+	AltText string `json:"alt_text"`
+	Applied bool   `json:"applied"`
+	// ImageVariant is the size variant the alt text was generated from
+	ImageVariant string `json:"image_variant"`
+}
+
+// GenerateAltText handles POST requests asking the AI backend for
+// accessibility alt text for a photo: a factual description, longer than a
+// title. It is returned for review, or saved as the photo's description.
+func (h *PhotoHandler) GenerateAltText(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w)
+		return
+	}
+
+	describer, ok := h.aiClient().(ai.AltTextGenerator)
+	if !ok {
+		ServiceUnavailable(w, "AI alt text generation is not available. Please check your AI backend configuration.")
+		return
+	}
+
+	photoID, valid := extractPhotoIDFromPath(r.URL.Path)
+	if !valid {
+		InvalidID(w, "photo ID")
+		return
+	}
+
+	var req GenerateAltTextRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		InvalidJSON(w, err)
+		return
+	}
+	req.Language = strings.TrimSpace(req.Language)
+	if err := validatePromptText(req.Language, MaxAlbumLanguageLength); err != nil {
+		BadRequest(w, fmt.Sprintf("Invalid language: %s.", err), nil)
+		return
+	}
+
+	if !checkPhotoAccess(w, r, h.db, photoID) {
+		return
+	}
+
+	photo, err := h.db.GetPhotoByID(photoID)
+	if err != nil {
+		DatabaseError(w, "retrieve photo", err)
+		return
+	}
+	if photo == nil {
+		NotFound(w, fmt.Sprintf("Photo with ID '%s' not found.", photoID))
+		return
+	}
+	// Check before spending a generation on it
+	if req.Apply && !req.Overwrite && photo.Description != nil && strings.TrimSpace(*photo.Description) != "" {
+		Conflict(w, "Photo already has a description. Set overwrite to replace it.")
+		return
+	}
+
+	var albumSettings sidecar.AlbumSettings
+	if photo.AlbumID != nil {
+		albumSettings = h.sidecar.Album(*photo.AlbumID)
+		if albumSettings.Excluded {
+			Forbidden(w, "AI generation is disabled for this photo's album.")
+			return
+		}
+	}
+	opts := ai.AltTextOptions{Language: h.titleLanguage(r.Context(), albumSettings, req.Language)}
+
+	variants := aiImageVariants(photo.ToPhotoResponse(h.imageURLPattern))
+	if len(variants) == 0 {
+		InternalServerError(w, "Photo image URL is not available.")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), constants.AIGenerationTimeout)
+	defer cancel()
+
+	release, err := h.acquireGeneration(ctx, false, nil)
+	if errors.Is(err, ai.ErrQueueFull) {
+		aiQueueFull(w, err)
+		return
+	}
+	if err != nil {
+		log.Printf("Gave up waiting to generate AI alt text for photo %s: %v", photoID, err)
+		ServiceUnavailable(w, aiQueueFullMessage)
+		return
+	}
+	defer release()
+	ctx = ai.WithStorageDisk(ctx, photo.StorageDisk())
+
+	// Try each variant in turn while the image itself can't be fetched, as
+	// for titles
+	var altText, variant string
+	for _, v := range variants {
+		variant = v.name
+		log.Printf("Generating AI alt text for photo %s using %s image URL: %s", photoID, v.name, v.url)
+		err = h.opts.AIUsage.Track(ctx, "alt_text", func(ctx context.Context) error {
+			return h.opts.AIGuard.Do(ctx, func(ctx context.Context) error {
+				var err error
+				altText, err = describer.GenerateAltText(ctx, v.url, opts)
+				return err
+			})
+		})
+		if err == nil || !errors.Is(err, ai.ErrImageUnavailable) {
+			break
+		}
+		log.Printf("Failed to fetch %s variant for photo %s: %v", v.name, photoID, err)
+	}
+	if errors.Is(err, ai.ErrBackendUnavailable) {
+		AIUnavailable(w)
+		return
+	}
+	if errors.Is(err, ai.ErrNoVision) {
+		AINoVision(w, err)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to generate AI alt text for photo %s: %v", photoID, err)
+		InternalServerError(w, "Failed to generate AI alt text. Please check your network connection and try again.")
+		return
+	}
+	altText = sanitizeText(altText)
+
+	applied := false
+	if req.Apply {
+		if err := h.applyAIDescription(ctx, photoID, altText); err != nil {
+			DatabaseError(w, "apply photo alt text", err)
+			return
+		}
+		applied = true
+		log.Printf("Applied AI alt text to photo %s", photoID)
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	if err := json.NewEncoder(w).Encode(GenerateAltTextResponse{
+		Success:      true,
+		AltText:      altText,
+		Applied:      applied,
+		ImageVariant: variant,
+	}); err != nil {
+		log.Printf("Failed to encode alt text response: %v", err)
+	}
+}
+
+// applyAIDescription saves AI-generated text as a photo's description,
+// recording it as AI-written
+func (h *PhotoHandler) applyAIDescription(ctx context.Context, photoID, description string) error {
+	source := models.ProvenanceAI
+	update := models.PhotoUpdate{Description: &description, DescriptionSource: &source}
+	previous := h.previousMetadata(ctx, map[string]models.PhotoUpdate{photoID: update})
+	if err := h.db.UpdatePhoto(photoID, update); err != nil {
+		return err
+	}
+
+	if err := h.recordProvenance(ctx, photoID, update, previous[photoID]); err != nil {
+		log.Printf("Failed to record provenance for photo %s: %v", photoID, err)
+	}
+	h.publish(ctx, feed.EventUpdated, photoID)
+	return nil
+}
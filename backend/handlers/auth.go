@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/auth"
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/db"
+)
+
+const (
+	// TokensAPIPrefix is the path prefix for API token administration
+	TokensAPIPrefix = "/api/admin/tokens"
+	// SessionsAPIPath is where clients open and end their own sessions
+	SessionsAPIPath = "/api/sessions"
+	// SessionsAdminAPIPrefix is the path prefix for session administration
+	SessionsAdminAPIPrefix = "/api/admin/sessions"
+)
+
+var tokenNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// publicAPIPaths are API endpoints that never require a token, so that
+// health checks and embedded dashboard badges keep working
+var publicAPIPaths = map[string]bool{
+	"/api/health":        true,
+	"/api/health/ai":     true,
+	"/api/badge.svg":     true,
+	"/api/progress.json": true,
+}
+
+// destructiveAPIPaths are endpoints that change many photos at once, which
+// need the admin scope rather than the edit scope of ordinary editing
+var destructiveAPIPaths = map[string]bool{
+	"/api/photos/bulk-title":     true,
+	"/api/photos/shift-taken-at": true,
+	"/api/photos/skip":           true,
+	"/api/photos/skip/undo":      true,
+}
+
+// RequiredScope returns the token scope needed to serve r, or an empty scope
+// if the request is public
+func RequiredScope(r *http.Request) auth.Scope {
+	path := r.URL.Path
+	switch {
+	case !strings.HasPrefix(path, constants.APIPrefix+"/"):
+		// Frontend assets
+		return ""
+	case publicAPIPaths[path]:
+		return ""
+	case r.Method == http.MethodOptions:
+		return ""
+	case path == SessionsAPIPath:
+		// Any token may open a session with its own scope
+		return auth.ScopeRead
+	case strings.HasPrefix(path, "/api/admin/"):
+		return auth.ScopeAdmin
+	case destructiveAPIPaths[path]:
+		return auth.ScopeAdmin
+	case r.Method == http.MethodDelete && strings.HasPrefix(path, "/api/photos/") && !strings.HasSuffix(path, "/lock"):
+		// Deleting photos; releasing an edit lock is ordinary editing
+		return auth.ScopeAdmin
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		return auth.ScopeRead
+	default:
+		return auth.ScopeEdit
+	}
+}
+
+// AuthMiddleware enforces API token scopes when any tokens are configured,
+// and the roles of forward-auth users who have one. A bearer token, or the
+// secret of a session opened with one, takes precedence over the user's
+// role. Once forward-auth roles are configured, requests with neither a
+// role nor a token are refused, so that anonymous clients reaching the
+// server directly don't get more access than a viewer.
+func AuthMiddleware(next http.Handler, store *auth.TokenStore, sessions *auth.SessionStore, forwardAuth *auth.ForwardAuth) http.Handler {
+	rolesEnforced := forwardAuth.HasRoles()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		required := RequiredScope(r)
+		role, hasRole := auth.RoleFromContext(r.Context())
+		secret := auth.BearerToken(r)
+
+		switch {
+		case required == "":
+			next.ServeHTTP(w, r)
+		case store.Enabled() && (secret != "" || !hasRole):
+			ctx := r.Context()
+			token, ok := store.Lookup(secret)
+			if !ok {
+				var session auth.Session
+				if token, session, ok = sessions.Lookup(secret); !ok {
+					Unauthorized(w)
+					return
+				}
+				ctx = auth.WithSession(ctx, session)
+			}
+			if !token.Scope.Allows(required) {
+				Forbidden(w, fmt.Sprintf("API token %q has %s scope; this operation requires %s", token.Name, token.Scope, required))
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithToken(ctx, token)))
+		case hasRole:
+			if !role.Allows(required) {
+				Forbidden(w, fmt.Sprintf("User %q has %s access; this operation requires %s", auth.Actor(r.Context()), role, required))
+				return
+			}
+			next.ServeHTTP(w, r)
+		case rolesEnforced:
+			Unauthorized(w)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// requireScope checks that a request was granted required, for operations
+// whose scope depends on more than RequiredScope can see, such as the
+// request body. It reports whether the request may proceed, having written
+// a 403 if not. Unauthenticated requests, which AuthMiddleware only lets
+// through when neither tokens nor forward-auth roles are configured, may
+// always proceed.
+func requireScope(w http.ResponseWriter, r *http.Request, required auth.Scope) bool {
+	granted, ok := auth.Granted(r.Context())
+	if !ok || granted.Allows(required) {
+		return true
+	}
+	Forbidden(w, fmt.Sprintf("This operation requires %s access; the request has %s", required, granted))
+	return false
+}
+
+// ForwardAuthMiddleware records the identity an upstream auth proxy has
+// authenticated as the request's actor (see auth.Actor), along with their
+// role if they have one. It only identifies; access is governed by
+// AuthMiddleware. A nil forwardAuth passes requests through unchanged.
+func ForwardAuthMiddleware(next http.Handler, forwardAuth *auth.ForwardAuth) http.Handler {
+	if forwardAuth == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if identity, ok := forwardAuth.Identity(r); ok {
+			ctx := auth.WithActor(r.Context(), identity)
+			if role, ok := forwardAuth.Role(identity); ok {
+				ctx = auth.WithRole(ctx, role)
+			}
+			r = r.WithContext(ctx)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// TokenHandler handles HTTP requests for API token administration
+type TokenHandler struct {
+	store *auth.TokenStore
+	db    *db.DB
+}
+
+// NewTokenHandler creates a new TokenHandler backed by the given token store.
+// The database is used to check Lychee users that tokens are bound to.
+func NewTokenHandler(store *auth.TokenStore, database *db.DB) *TokenHandler {
+	return &TokenHandler{store: store, db: database}
+}
+
+// TokensResponse represents the list of API tokens (without secrets)
+type TokensResponse struct {
+	Tokens []auth.Token `json:"tokens"`
+}
+
+// CreateTokenRequest represents a request to create a runtime API token
+type CreateTokenRequest struct {
+	Name       string `json:"name"`
+	Scope      string `json:"scope"`
+	LycheeUser string `json:"lychee_user,omitempty"`
+}
+
+// CreateTokenResponse returns a newly created token's secret. The secret is
+// not retrievable afterward.
+type CreateTokenResponse struct {
+	Name       string     `json:"name"`
+	Scope      auth.Scope `json:"scope"`
+	LycheeUser string     `json:"lychee_user,omitempty"`
+	Token      string     `json:"token"`
+}
+
+// HandleTokens dispatches token administration requests:
+// GET lists tokens, POST creates a runtime token, and DELETE on
+// /api/admin/tokens/{name} revokes one
+func (h *TokenHandler) HandleTokens(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, TokensAPIPrefix), "/")
+
+	switch {
+	case name == "" && r.Method == http.MethodGet:
+		h.listTokens(w)
+	case name == "" && r.Method == http.MethodPost:
+		h.createToken(w, r)
+	case name != "" && r.Method == http.MethodDelete:
+		h.revokeToken(w, name)
+	default:
+		MethodNotAllowed(w)
+	}
+}
+
+func (h *TokenHandler) listTokens(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	if err := json.NewEncoder(w).Encode(TokensResponse{Tokens: h.store.List()}); err != nil {
+		log.Printf("Failed to encode tokens response: %v", err)
+	}
+}
+
+// createToken creates a runtime token. Only a request authenticated with an
+// admin token from the config file, rather than a session opened with one,
+// may create one: with no tokens configured the API is unauthenticated, and
+// the first token created would turn authentication on and lock out every
+// other client.
+func (h *TokenHandler) createToken(w http.ResponseWriter, r *http.Request) {
+	_, viaSession := auth.SessionFromContext(r.Context())
+	if caller, ok := auth.FromContext(r.Context()); !ok || viaSession || caller.Runtime || !caller.Scope.Allows(auth.ScopeAdmin) {
+		Forbidden(w, "Runtime API tokens can only be created with an admin token from auth.tokens in the config file.")
+		return
+	}
+
+	var req CreateTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		InvalidJSON(w, err)
+		return
+	}
+
+	if !tokenNamePattern.MatchString(req.Name) {
+		BadRequest(w, "Invalid token name. Must be 1-64 characters, alphanumeric with underscores and hyphens only.", nil)
+		return
+	}
+
+	scope, err := auth.ParseScope(req.Scope)
+	if err != nil {
+		BadRequest(w, err.Error(), nil)
+		return
+	}
+
+	if req.LycheeUser != "" {
+		user, err := h.db.GetLycheeUser(req.LycheeUser)
+		if err != nil {
+			DatabaseError(w, "get Lychee user", err)
+			return
+		}
+		if user == nil {
+			BadRequest(w, fmt.Sprintf("Lychee user %q not found", req.LycheeUser), nil)
+			return
+		}
+	}
+
+	secret, err := h.store.Generate(req.Name, scope, req.LycheeUser)
+	if err != nil {
+		Conflict(w, err.Error())
+		return
+	}
+
+	log.Printf("Created runtime API token %q with %s scope", req.Name, scope)
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(CreateTokenResponse{Name: req.Name, Scope: scope, LycheeUser: req.LycheeUser, Token: secret}); err != nil {
+		log.Printf("Failed to encode create token response: %v", err)
+	}
+}
+
+func (h *TokenHandler) revokeToken(w http.ResponseWriter, name string) {
+	if err := h.store.Revoke(name); err != nil {
+		BadRequest(w, err.Error(), nil)
+		return
+	}
+
+	log.Printf("Revoked runtime API token %q", name)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/auth"
+)
+
+const (
+	proxyAddr  = "192.0.2.1:40000"
+	directAddr = "203.0.113.9:40000"
+)
+
+// newTestForwardAuth trusts X-Forwarded-User from proxyAddr, giving roles
+func newTestForwardAuth(t *testing.T, roles map[string]auth.Scope, defaultRole auth.Scope) *auth.ForwardAuth {
+	t.Helper()
+	forwardAuth, err := auth.NewForwardAuth([]string{"X-Forwarded-User"}, []string{"192.0.2.1"})
+	if err != nil {
+		t.Fatalf("NewForwardAuth: %v", err)
+	}
+	forwardAuth.SetRoles(roles, defaultRole)
+	return forwardAuth
+}
+
+// authRequest is a request to the middleware chain main.go builds
+type authRequest struct {
+	name       string
+	method     string
+	path       string
+	remoteAddr string
+	user       string
+	token      string
+	want       int
+}
+
+// serveAuth runs each request through ForwardAuthMiddleware and
+// AuthMiddleware, checking its status
+func serveAuth(t *testing.T, store *auth.TokenStore, forwardAuth *auth.ForwardAuth, requests []authRequest) {
+	t.Helper()
+	sessions := auth.NewSessionStore(store, time.Hour, 24*time.Hour)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := ForwardAuthMiddleware(AuthMiddleware(ok, store, sessions, forwardAuth), forwardAuth)
+
+	for _, tt := range requests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.user != "" {
+				r.Header.Set("X-Forwarded-User", tt.user)
+			}
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.path, w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareForwardAuthRolesWithoutTokens(t *testing.T) {
+	forwardAuth := newTestForwardAuth(t, map[string]auth.Scope{"viewer@example.com": auth.ScopeRead}, "")
+	serveAuth(t, auth.NewTokenStore(), forwardAuth, []authRequest{
+		{"anonymous read", http.MethodGet, "/api/photos", directAddr, "", "", http.StatusUnauthorized},
+		{"anonymous admin", http.MethodPost, "/api/admin/tokens", directAddr, "", "", http.StatusUnauthorized},
+		{"anonymous delete", http.MethodDelete, "/api/photos/abc", directAddr, "", "", http.StatusUnauthorized},
+		{"anonymous bulk title", http.MethodPost, "/api/photos/bulk-title", directAddr, "", "", http.StatusUnauthorized},
+		{"anonymous skip", http.MethodPost, "/api/photos/skip", directAddr, "", "", http.StatusUnauthorized},
+		{"identity header from an untrusted address", http.MethodGet, "/api/photos", directAddr, "viewer@example.com", "", http.StatusUnauthorized},
+		{"proxied user without a role", http.MethodGet, "/api/photos", proxyAddr, "other@example.com", "", http.StatusUnauthorized},
+		{"proxied request without an identity", http.MethodGet, "/api/photos", proxyAddr, "", "", http.StatusUnauthorized},
+		{"viewer reads", http.MethodGet, "/api/photos", proxyAddr, "Viewer@example.com", "", http.StatusOK},
+		{"viewer edits", http.MethodPut, "/api/photos/abc", proxyAddr, "viewer@example.com", "", http.StatusForbidden},
+		{"public endpoint", http.MethodGet, "/api/health", directAddr, "", "", http.StatusOK},
+		{"frontend", http.MethodGet, "/", directAddr, "", "", http.StatusOK},
+	})
+}
+
+func TestAuthMiddlewareForwardAuthDefaultRole(t *testing.T) {
+	forwardAuth := newTestForwardAuth(t, nil, auth.ScopeEdit)
+	serveAuth(t, auth.NewTokenStore(), forwardAuth, []authRequest{
+		{"anonymous", http.MethodGet, "/api/photos", directAddr, "", "", http.StatusUnauthorized},
+		{"proxied user edits", http.MethodPut, "/api/photos/abc", proxyAddr, "anyone@example.com", "", http.StatusOK},
+		{"proxied user deletes", http.MethodDelete, "/api/photos/abc", proxyAddr, "anyone@example.com", "", http.StatusForbidden},
+	})
+}
+
+func TestAuthMiddlewareForwardAuthRolesWithTokens(t *testing.T) {
+	store := auth.NewTokenStore()
+	if err := store.Add(auth.Token{Name: "script", Scope: auth.ScopeAdmin}, "scriptsecret"); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	forwardAuth := newTestForwardAuth(t, map[string]auth.Scope{"viewer@example.com": auth.ScopeRead}, "")
+	serveAuth(t, store, forwardAuth, []authRequest{
+		{"anonymous", http.MethodGet, "/api/photos", directAddr, "", "", http.StatusUnauthorized},
+		{"wrong token", http.MethodGet, "/api/photos", directAddr, "", "nope", http.StatusUnauthorized},
+		{"token", http.MethodDelete, "/api/photos/abc", directAddr, "", "scriptsecret", http.StatusOK},
+		{"token over a role", http.MethodPost, "/api/photos/bulk-title", proxyAddr, "viewer@example.com", "scriptsecret", http.StatusOK},
+		{"viewer", http.MethodGet, "/api/photos", proxyAddr, "viewer@example.com", "", http.StatusOK},
+	})
+}
+
+func TestAuthMiddlewareWithoutAuthentication(t *testing.T) {
+	// Forward auth that only identifies users leaves access open, as it is
+	// without forward auth when no tokens are configured
+	for name, forwardAuth := range map[string]*auth.ForwardAuth{
+		"forward auth without roles": newTestForwardAuth(t, nil, ""),
+		"no forward auth":            nil,
+	} {
+		t.Run(name, func(t *testing.T) {
+			serveAuth(t, auth.NewTokenStore(), forwardAuth, []authRequest{
+				{"anonymous", http.MethodDelete, "/api/photos/abc", directAddr, "", "", http.StatusOK},
+				{"proxied user", http.MethodPut, "/api/photos/abc", proxyAddr, "anyone@example.com", "", http.StatusOK},
+			})
+		})
+	}
+}
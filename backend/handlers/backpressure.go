@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+)
+
+// QueryLimiterStats describes the requests a QueryLimiter is running and
+// holding back
+type QueryLimiterStats struct {
+	Active        int `json:"active"`
+	Queued        int `json:"queued"`
+	MaxConcurrent int `json:"max_concurrent"`
+	MaxQueued     int `json:"max_queued"`
+}
+
+// QueryLimiter caps the expensive read requests, such as listing the
+// needs-metadata queue or counting photos per album, that run at once, so
+// that many simultaneous clients can't saturate the shared Lychee database.
+// Requests beyond the cap wait their turn for up to
+// constants.QueryQueueTimeout; once too many are waiting, further requests
+// are refused with HTTP 429. It is safe for concurrent use; a nil
+// QueryLimiter doesn't limit requests.
+type QueryLimiter struct {
+	// slots holds a token for each request running
+	slots     chan struct{}
+	maxQueued int
+
+	mu     sync.Mutex
+	queued int
+}
+
+// NewQueryLimiter creates a QueryLimiter running at most maxConcurrent
+// requests at once, with at most maxQueued waiting. It returns nil, which
+// doesn't limit requests, if maxConcurrent isn't positive.
+func NewQueryLimiter(maxConcurrent, maxQueued int) *QueryLimiter {
+	if maxConcurrent <= 0 {
+		return nil
+	}
+	return &QueryLimiter{
+		slots:     make(chan struct{}, maxConcurrent),
+		maxQueued: max(maxQueued, 0),
+	}
+}
+
+// Limit wraps next so that it runs only once a slot is free
+func (l *QueryLimiter) Limit(next http.HandlerFunc) http.HandlerFunc {
+	if l == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !l.acquire(w, r) {
+			return
+		}
+		defer func() { <-l.slots }()
+		next(w, r)
+	}
+}
+
+// acquire waits for a slot. If none comes free, it sends an error response
+// and returns false.
+func (l *QueryLimiter) acquire(w http.ResponseWriter, r *http.Request) bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	l.mu.Lock()
+	if l.queued >= l.maxQueued {
+		stats := l.statsLocked()
+		l.mu.Unlock()
+		w.Header().Set("Retry-After", strconv.Itoa(int(constants.QueryRetryAfter.Seconds())))
+		TooManyRequests(w, "The server is busy with other requests. Please try again shortly.", stats)
+		return false
+	}
+	l.queued++
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		l.queued--
+		l.mu.Unlock()
+	}()
+
+	timer := time.NewTimer(constants.QueryQueueTimeout)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		w.Header().Set("Retry-After", strconv.Itoa(int(constants.QueryRetryAfter.Seconds())))
+		ServiceUnavailable(w, "The server is busy with other requests. Please try again shortly.")
+		return false
+	case <-r.Context().Done():
+		// The client went away; there is no one to respond to
+		return false
+	}
+}
+
+// Stats returns the number of requests running and waiting, or nil for a
+// nil QueryLimiter
+func (l *QueryLimiter) Stats() *QueryLimiterStats {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stats := l.statsLocked()
+	return &stats
+}
+
+func (l *QueryLimiter) statsLocked() QueryLimiterStats {
+	return QueryLimiterStats{
+		Active:        len(l.slots),
+		Queued:        l.queued,
+		MaxConcurrent: cap(l.slots),
+		MaxQueued:     l.maxQueued,
+	}
+}
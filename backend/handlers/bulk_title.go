@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/db"
+	"github.com/cdzombak/lychee-meta-tool/backend/feed"
+	"github.com/cdzombak/lychee-meta-tool/backend/models"
+)
+
+// BulkTitleRequest applies one title to several photos
+type BulkTitleRequest struct {
+	IDs   []string `json:"ids"`
+	Title string   `json:"title"`
+	// Number appends " (1)", " (2)", ... to the title, in the order of IDs
+	Number bool `json:"number"`
+	// TitleSource records how the title was produced, as for UpdatePhoto
+	TitleSource *models.Provenance `json:"title_source,omitempty"`
+}
+
+// BulkTitleResponse reports the title given to each photo
+type BulkTitleResponse struct {
+	Success bool              `json:"success"`
+	Updated int               `json:"updated"`
+	Titles  map[string]string `json:"titles"`
+}
+
+// BulkTitle handles POST requests applying a base title to the selected
+// photos, optionally numbered, e.g. for a burst of shots. The photos are
+// updated in a single transaction, so either all of them get the title or
+// none do.
+func (h *PhotoHandler) BulkTitle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w)
+		return
+	}
+
+	var req BulkTitleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		InvalidJSON(w, err)
+		return
+	}
+	if len(req.IDs) == 0 {
+		BadRequest(w, "ids must list at least one photo ID.", nil)
+		return
+	}
+	if len(req.IDs) > MaxLimit {
+		BadRequest(w, fmt.Sprintf("At most %d photos can be titled at once.", MaxLimit), nil)
+		return
+	}
+	seen := make(map[string]bool, len(req.IDs))
+	for _, id := range req.IDs {
+		if !validatePhotoID(id) {
+			InvalidID(w, "photo ID")
+			return
+		}
+		if seen[id] {
+			BadRequest(w, fmt.Sprintf("Photo %s is listed more than once.", id), nil)
+			return
+		}
+		seen[id] = true
+	}
+
+	// Validate every title up front; numbering makes later ones longer
+	updates := make(map[string]models.PhotoUpdate, len(req.IDs))
+	for i, id := range req.IDs {
+		title := req.Title
+		if req.Number {
+			title = fmt.Sprintf("%s (%d)", req.Title, i+1)
+		}
+		update := models.PhotoUpdate{Title: &title, TitleSource: req.TitleSource}
+		if validationErrors := ValidatePhotoUpdate(&update); len(validationErrors) > 0 {
+			errorMessages := make([]string, len(validationErrors))
+			for j, err := range validationErrors {
+				errorMessages[j] = err.Error()
+			}
+			BadRequest(w, "Validation failed", errorMessages)
+			return
+		}
+		updates[id] = update
+	}
+
+	if !checkPhotosAccess(w, r, h.db, req.IDs) {
+		return
+	}
+
+	photos, err := h.db.GetPhotosByIDs(req.IDs, db.PhotoFilter{}, len(req.IDs), 0)
+	if err != nil {
+		DatabaseError(w, "retrieve photos", err)
+		return
+	}
+	if len(photos) != len(req.IDs) {
+		found := make(map[string]bool, len(photos))
+		for _, p := range photos {
+			found[p.ID] = true
+		}
+		for _, id := range req.IDs {
+			if !found[id] {
+				NotFound(w, fmt.Sprintf("Photo with ID '%s' not found.", id))
+				return
+			}
+		}
+	}
+
+	// Provenance reflects the fields the client set, not the descriptions
+	// rewritten to carry a change note
+	provenanceUpdates := make(map[string]models.PhotoUpdate, len(updates))
+	for id, update := range updates {
+		provenanceUpdates[id] = update
+	}
+
+	if h.opts.ChangeNotes {
+		for _, photo := range photos {
+			update := updates[photo.ID]
+			description := ""
+			if photo.Description != nil {
+				description = *photo.Description
+			}
+			update.Description = &description
+			if err := h.addChangeNote(photo.ID, &update); err != nil {
+				log.Printf("Failed to add change note for photo %s: %v", photo.ID, err)
+				InternalServerError(w, "Failed to update photos. Please try again.")
+				return
+			}
+			updates[photo.ID] = update
+		}
+	}
+
+	previous := h.previousMetadata(r.Context(), updates)
+	if err := h.db.UpdatePhotos(updates); err != nil {
+		log.Printf("Failed to apply title to %d photos: %v", len(updates), err)
+		InternalServerError(w, "Failed to update photos. Please try again.")
+		return
+	}
+	log.Printf("Applied title %q to %d photos", req.Title, len(updates))
+
+	// Lychee has been updated at this point, so failures here are logged
+	// rather than reported
+	titles := make(map[string]string, len(updates))
+	photoIDs := make([]string, 0, len(updates))
+	for id, update := range provenanceUpdates {
+		titles[id] = *update.Title
+		photoIDs = append(photoIDs, id)
+		if err := h.recordProvenance(r.Context(), id, update, previous[id]); err != nil {
+			log.Printf("Failed to record provenance for photo %s: %v", id, err)
+		}
+	}
+	h.publish(r.Context(), feed.EventTitled, photoIDs...)
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	if err := json.NewEncoder(w).Encode(BulkTitleResponse{
+		Success: true,
+		Updated: len(titles),
+		Titles:  titles,
+	}); err != nil {
+		log.Printf("Failed to encode bulk title response: %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/models"
+)
+
+// changeNotePrefix starts every change note line, so that an earlier note
+// can be found and replaced rather than accumulating on repeated edits
+const changeNotePrefix = "Title set via lychee-meta-tool on "
+
+// formatChangeNote returns the provenance note for a title set from source
+func formatChangeNote(source models.Provenance, now time.Time) string {
+	label := "manual"
+	switch source {
+	case models.ProvenanceAI:
+		label = "AI"
+	case models.ProvenanceAIEdited:
+		label = "AI, edited"
+	}
+	return fmt.Sprintf("%s%s (%s)", changeNotePrefix, now.Format("2006-01-02"), label)
+}
+
+// withChangeNote returns description with any previous change note removed
+// and a new note for source appended on its own line
+func withChangeNote(description string, source models.Provenance, now time.Time) string {
+	base := withoutChangeNote(description)
+	note := formatChangeNote(source, now)
+	if base == "" {
+		return note
+	}
+	return base + "\n\n" + note
+}
+
+// withoutChangeNote returns description with any change note lines removed
+func withoutChangeNote(description string) string {
+	lines := strings.Split(description, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), changeNotePrefix) {
+			kept = append(kept, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/db"
+	"github.com/cdzombak/lychee-meta-tool/backend/feed"
+)
+
+// Server-sent event types sent by the change feed
+const (
+	// changesEventChange carries a feed.Event
+	changesEventChange = "change"
+	// changesEventReset carries a ChangesResponse without events when
+	// events since the client's last one are no longer kept, so it should
+	// reload its queue and counts
+	changesEventReset = "reset"
+)
+
+// ChangesHandler serves the feed of changes to photos made by other
+// reviewers and by imports into Lychee
+type ChangesHandler struct {
+	hub *feed.Hub
+	db  *db.DB
+}
+
+// NewChangesHandler creates a new ChangesHandler publishing the events of
+// hub. The database is used to limit events to photos the request's Lychee
+// user may edit.
+func NewChangesHandler(hub *feed.Hub, database *db.DB) *ChangesHandler {
+	return &ChangesHandler{hub: hub, db: database}
+}
+
+// ChangesResponse is the response to a long poll of the change feed
+type ChangesResponse struct {
+	Events []feed.Event `json:"events"`
+	// LastID is the since parameter for the next poll
+	LastID uint64 `json:"last_id"`
+	// Reset is true when events since the given one are no longer kept,
+	// so the client should reload its queue and counts
+	Reset bool `json:"reset,omitempty"`
+}
+
+// GetChanges handles GET requests for the change feed. Clients accepting
+// text/event-stream get a stream of change events; others long-poll,
+// getting the events after ?since= as soon as there are any, or none after
+// ?timeout= seconds. Without since, a poll returns at once with the ID to
+// poll from. Streams resume after the Last-Event-ID header.
+func (h *ChangesHandler) GetChanges(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w)
+		return
+	}
+
+	sinceParam := r.URL.Query().Get("since")
+	if sinceParam == "" {
+		sinceParam = r.Header.Get("Last-Event-ID")
+	}
+	var since uint64
+	if sinceParam != "" {
+		var err error
+		if since, err = strconv.ParseUint(sinceParam, 10, 64); err != nil {
+			BadRequest(w, "Invalid since parameter. Must be an event ID.", nil)
+			return
+		}
+	}
+
+	userID, ok := lycheeUserID(w, r, h.db)
+	if !ok {
+		return
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), constants.ContentTypeEventStream) {
+		h.stream(w, r, since, sinceParam != "", userID)
+		return
+	}
+
+	timeout := constants.ChangesPollTimeout
+	if timeoutParam := r.URL.Query().Get("timeout"); timeoutParam != "" {
+		seconds, err := strconv.Atoi(timeoutParam)
+		if err != nil || seconds < 0 || time.Duration(seconds)*time.Second > constants.MaxChangesPollTimeout {
+			BadRequest(w, fmt.Sprintf("Invalid timeout parameter. Must be a number of seconds between 0 and %d.", int(constants.MaxChangesPollTimeout.Seconds())), nil)
+			return
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+	h.poll(w, r, since, sinceParam != "", timeout, userID)
+}
+
+// poll sends the events after since, waiting up to timeout for one
+func (h *ChangesHandler) poll(w http.ResponseWriter, r *http.Request, since uint64, hasSince bool, timeout time.Duration, userID *int) {
+	events, lastID, complete := h.hub.Since(since)
+	if hasSince && complete && len(events) == 0 && timeout > 0 {
+		updates, unsubscribe := h.hub.Subscribe()
+		defer unsubscribe()
+
+		// An event may have been published before subscribing
+		if events, lastID, complete = h.hub.Since(since); len(events) == 0 {
+			// The wait can outlast the server's write timeout
+			if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + constants.DefaultHTTPTimeout)); err != nil {
+				log.Printf("Failed to extend write deadline for change feed poll: %v", err)
+			}
+
+			timer := time.NewTimer(timeout)
+			defer timer.Stop()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-updates:
+			case <-timer.C:
+			}
+			events, lastID, complete = h.hub.Since(since)
+		}
+	}
+
+	response := ChangesResponse{Events: []feed.Event{}, LastID: lastID}
+	if hasSince {
+		response.Events = h.visible(userID, events)
+		response.Reset = !complete
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode changes response: %v", err)
+	}
+}
+
+// stream sends change events as they happen, starting with those after
+// since if given, until the client disconnects. The stream ends if the
+// client falls too far behind; it can then reconnect to catch up.
+func (h *ChangesHandler) stream(w http.ResponseWriter, r *http.Request, since uint64, hasSince bool, userID *int) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		InternalServerError(w, "Streaming is not supported.")
+		return
+	}
+
+	// The stream outlasts the server's write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Failed to clear write deadline for change feed: %v", err)
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeEventStream)
+	w.Header().Set("Cache-Control", "no-cache")
+	// Ask reverse proxies such as nginx not to buffer the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	updates, unsubscribe := h.hub.Subscribe()
+	defer unsubscribe()
+
+	// Events published between subscribing and catching up arrive twice
+	sent := since
+	send := func(event feed.Event) bool {
+		if event.ID <= sent {
+			return true
+		}
+		sent = event.ID
+		if visible := h.visible(userID, []feed.Event{event}); len(visible) > 0 {
+			if err := writeFeedEvent(w, changesEventChange, event.ID, visible[0]); err != nil {
+				return false
+			}
+			flusher.Flush()
+		}
+		return true
+	}
+
+	events, lastID, complete := h.hub.Since(since)
+	if !hasSince {
+		sent = lastID
+	} else if !complete {
+		sent = lastID
+		if err := writeFeedEvent(w, changesEventReset, lastID, ChangesResponse{Events: []feed.Event{}, LastID: lastID, Reset: true}); err != nil {
+			return
+		}
+		flusher.Flush()
+	} else {
+		for _, event := range events {
+			if !send(event) {
+				return
+			}
+		}
+	}
+
+	heartbeat := time.NewTicker(constants.ChangesHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-updates:
+			if !ok || !send(event) {
+				return
+			}
+		case <-heartbeat.C:
+			// Comments keep idle connections from being closed by proxies
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// visible limits events to the photos the Lychee user userID may edit,
+// dropping events left without any. userID is nil for unrestricted
+// requests, which see every event.
+func (h *ChangesHandler) visible(userID *int, events []feed.Event) []feed.Event {
+	if userID == nil {
+		return events
+	}
+
+	visible := make([]feed.Event, 0, len(events))
+	for _, event := range events {
+		var photoIDs []string
+		for _, id := range event.PhotoIDs {
+			allowed, err := h.db.CanEditPhoto(*userID, id)
+			if err != nil {
+				log.Printf("Failed to check permissions on photo %s for the change feed: %v", id, err)
+				continue
+			}
+			if allowed {
+				photoIDs = append(photoIDs, id)
+			}
+		}
+		if len(photoIDs) > 0 {
+			event.PhotoIDs = photoIDs
+			visible = append(visible, event)
+		}
+	}
+	return visible
+}
+
+// writeFeedEvent writes a server-sent event with an ID, which clients send
+// back as Last-Event-ID when they reconnect
+func writeFeedEvent(w http.ResponseWriter, event string, id uint64, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, event, data)
+	return err
+}
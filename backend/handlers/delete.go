@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/feed"
+)
+
+// DeletePhoto handles DELETE requests removing a junk photo from Lychee:
+// its row, size variants and album links. The image files are left in
+// Lychee's storage. Deletion can't be undone, so it must be enabled with
+// editing.allow_destructive.
+func (h *PhotoHandler) DeletePhoto(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		MethodNotAllowed(w)
+		return
+	}
+	if !h.opts.AllowDestructive {
+		Forbidden(w, "Deleting photos is disabled. Set editing.allow_destructive in the configuration to enable it.")
+		return
+	}
+
+	photoID, valid := extractPhotoIDFromPath(r.URL.Path)
+	if !valid {
+		InvalidID(w, "photo ID")
+		return
+	}
+
+	if !checkPhotoAccess(w, r, h.db, photoID) {
+		return
+	}
+
+	deleted, err := h.db.DeletePhoto(photoID)
+	if err != nil {
+		DatabaseError(w, "delete photo", err)
+		return
+	}
+	if !deleted {
+		NotFound(w, fmt.Sprintf("Photo with ID '%s' not found.", photoID))
+		return
+	}
+
+	log.Printf("Deleted photo %s", photoID)
+	h.publish(r.Context(), feed.EventDeleted, photoID)
+	w.WriteHeader(http.StatusNoContent)
+}
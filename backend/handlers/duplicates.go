@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/db"
+	"github.com/cdzombak/lychee-meta-tool/backend/models"
+	"github.com/cdzombak/lychee-meta-tool/backend/phash"
+	"github.com/cdzombak/lychee-meta-tool/backend/sidecar"
+)
+
+// Limits on the distance parameter of GetDuplicates: the number of the 64
+// hash bits that may differ between duplicates
+const (
+	DefaultDuplicateDistance = 8
+	MaxDuplicateDistance     = 20
+)
+
+// DuplicateGroup is a set of visually near-identical photos
+type DuplicateGroup struct {
+	Photos []models.PhotoResponse `json:"photos"`
+}
+
+// DuplicatesResponse lists groups of duplicate photos
+type DuplicatesResponse struct {
+	Groups []DuplicateGroup `json:"groups"`
+	Total  int              `json:"total"`
+	// Hashed is the number of photos with a perceptual hash; photos not yet
+	// hashed by a compute-hashes job can't be matched
+	Hashed int `json:"hashed"`
+}
+
+// GetDuplicates handles GET requests listing groups of visually duplicate
+// photos across all albums, found by comparing perceptual hashes computed
+// by a compute-hashes job. Unlike checksums, these also match re-exports,
+// resized copies and lightly edited versions.
+func (h *PhotoHandler) GetDuplicates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w)
+		return
+	}
+
+	distance := DefaultDuplicateDistance
+	if d := sanitizeQueryParam(r.URL.Query().Get("distance")); d != "" {
+		parsed, err := strconv.Atoi(d)
+		if err != nil || parsed < 0 || parsed > MaxDuplicateDistance {
+			BadRequest(w, fmt.Sprintf("Invalid distance parameter. Must be a number between 0 and %d.", MaxDuplicateDistance), nil)
+			return
+		}
+		distance = parsed
+	}
+
+	var filter db.PhotoFilter
+	if !restrictFilter(w, r, h.db, &filter) {
+		return
+	}
+
+	states := h.sidecar.Photos(func(_ string, p sidecar.PhotoState) bool {
+		return p.PHash != ""
+	})
+	hashes := make(map[string]uint64, len(states))
+	for id, state := range states {
+		hash, err := phash.Parse(state.PHash)
+		if err != nil {
+			log.Printf("Ignoring perceptual hash of photo %s: %v", id, err)
+			continue
+		}
+		hashes[id] = hash
+	}
+
+	groups := phash.Group(hashes, distance)
+	var ids []string
+	for _, group := range groups {
+		ids = append(ids, group...)
+	}
+
+	// Fetch the grouped photos the user may see, leaving out any whose
+	// thumbnail has changed since it was hashed
+	byID := make(map[string]*models.PhotoWithSizeVariants, len(ids))
+	if len(ids) > 0 {
+		photos, err := h.db.GetPhotosByIDs(ids, filter, len(ids), 0)
+		if err != nil {
+			log.Printf("Failed to get duplicate photos: %v", err)
+			InternalServerError(w, "Failed to retrieve photos. Please try again.")
+			return
+		}
+		for i := range photos {
+			photo := &photos[i]
+			if photo.ThumbnailPath != nil && *photo.ThumbnailPath == states[photo.ID].PHashPath {
+				byID[photo.ID] = photo
+			}
+		}
+	}
+
+	response := DuplicatesResponse{Groups: []DuplicateGroup{}, Hashed: len(hashes)}
+	for _, group := range groups {
+		var photos []models.PhotoResponse
+		for _, id := range group {
+			if photo, ok := byID[id]; ok {
+				photos = append(photos, h.photoResponse(photo))
+			}
+		}
+		if len(photos) > 1 {
+			response.Groups = append(response.Groups, DuplicateGroup{Photos: photos})
+		}
+	}
+	response.Total = len(response.Groups)
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode duplicates response: %v", err)
+	}
+}
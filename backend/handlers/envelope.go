@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+)
+
+// APIVersion is the version of the enveloped API served under
+// constants.APIv1Prefix
+const APIVersion = "v1"
+
+// Envelope wraps every JSON response from the versioned API, so clients can
+// handle results generically: Data holds the endpoint's response, Error is
+// set for failed requests and Meta describes the response.
+type Envelope struct {
+	Data  json.RawMessage `json:"data"`
+	Error *EnvelopeError  `json:"error"`
+	Meta  EnvelopeMeta    `json:"meta"`
+}
+
+// EnvelopeError describes why a request failed. Code is one of the stable
+// error codes documented in docs/errors.md, when the handler set one.
+type EnvelopeError struct {
+	Status  int         `json:"status"`
+	Code    string      `json:"code,omitempty"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// EnvelopeMeta holds information about a response other than its data
+type EnvelopeMeta struct {
+	Version    string      `json:"version"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+// Pagination describes the page of results in a response
+type Pagination struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	// Total is the number of results across all pages, when known
+	Total      *int `json:"total,omitempty"`
+	HasMore    bool `json:"has_more"`
+	NextOffset *int `json:"next_offset,omitempty"`
+	// NextAfter is the cursor for the next page, for endpoints that take one
+	NextAfter string `json:"next_after,omitempty"`
+}
+
+// envelopeWriter records the path a request was made to, for problem
+// details' instance field. For the versioned API it also buffers a JSON
+// response so it can be wrapped in an Envelope; other responses, such as
+// event streams, images and exports, pass through unchanged.
+type envelopeWriter struct {
+	http.ResponseWriter
+	// path is the path the client requested, before any rewriting
+	path        string
+	enveloped   bool
+	status      int
+	wroteHeader bool
+	buffering   bool
+	body        bytes.Buffer
+	// err and pagination are recorded by handlers through setEnvelopeError
+	// and setPagination rather than parsed back out of the response
+	err        *EnvelopeError
+	pagination *Pagination
+}
+
+func (ew *envelopeWriter) WriteHeader(status int) {
+	if ew.wroteHeader {
+		return
+	}
+	ew.wroteHeader = true
+	ew.status = status
+
+	// Plain text errors, e.g. from http.Error or http.NotFound, are
+	// enveloped too
+	contentType := ew.Header().Get("Content-Type")
+	ew.buffering = ew.enveloped && (hasMediaType(contentType, constants.ContentTypeJSON) ||
+		hasMediaType(contentType, constants.ContentTypeProblemJSON) ||
+		(status >= http.StatusBadRequest && hasMediaType(contentType, constants.ContentTypeText)))
+	if !ew.buffering {
+		ew.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (ew *envelopeWriter) Write(b []byte) (int, error) {
+	if !ew.wroteHeader {
+		ew.WriteHeader(http.StatusOK)
+	}
+	if ew.buffering {
+		return ew.body.Write(b)
+	}
+	return ew.ResponseWriter.Write(b)
+}
+
+// Flush lets streaming handlers flush responses that aren't enveloped
+func (ew *envelopeWriter) Flush() {
+	if ew.buffering {
+		return
+	}
+	if f, ok := ew.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// extend a streaming response's write deadline
+func (ew *envelopeWriter) Unwrap() http.ResponseWriter {
+	return ew.ResponseWriter
+}
+
+// finish writes the buffered response wrapped in an Envelope
+func (ew *envelopeWriter) finish() {
+	if !ew.buffering {
+		return
+	}
+
+	env := Envelope{
+		Data:  json.RawMessage("null"),
+		Error: ew.err,
+		Meta:  EnvelopeMeta{Version: APIVersion, Pagination: ew.pagination},
+	}
+	body := bytes.TrimSpace(ew.body.Bytes())
+	contentType := ew.Header().Get("Content-Type")
+	isJSON := hasMediaType(contentType, constants.ContentTypeJSON) ||
+		hasMediaType(contentType, constants.ContentTypeProblemJSON)
+	switch {
+	case env.Error != nil:
+		// The error helpers' own body duplicates env.Error
+	case ew.status >= http.StatusBadRequest:
+		env.Error = &EnvelopeError{Status: ew.status, Message: http.StatusText(ew.status)}
+		if isJSON && len(body) > 0 {
+			// e.g. a health check reporting why it is unhealthy
+			env.Data = body
+		} else if len(body) > 0 {
+			env.Error.Message = string(body)
+		}
+	case len(body) > 0:
+		env.Data = body
+	}
+
+	ew.Header().Set("Content-Type", constants.ContentTypeJSON)
+	ew.Header().Del("Content-Length")
+	ew.ResponseWriter.WriteHeader(ew.status)
+	if err := json.NewEncoder(ew.ResponseWriter).Encode(env); err != nil {
+		log.Printf("Failed to encode response envelope: %v", err)
+	}
+}
+
+// hasMediaType reports whether the Content-Type header value is mediaType
+func hasMediaType(contentType, mediaType string) bool {
+	parsed, _, err := mime.ParseMediaType(contentType)
+	return err == nil && parsed == mediaType
+}
+
+// setEnvelopeError records a failed request's error for its envelope, if
+// the response is enveloped
+func setEnvelopeError(w http.ResponseWriter, status int, code, message string, details interface{}) {
+	if ew, ok := w.(*envelopeWriter); ok {
+		ew.err = &EnvelopeError{Status: status, Code: code, Message: message, Details: details}
+	}
+}
+
+// requestPath returns the path the client requested, as served by w, or ""
+// if w wasn't wrapped by APIVersionMiddleware
+func requestPath(w http.ResponseWriter) string {
+	if ew, ok := w.(*envelopeWriter); ok {
+		return ew.path
+	}
+	return ""
+}
+
+// setPagination records the page of results in a response for its
+// envelope, if the response is enveloped
+func setPagination(w http.ResponseWriter, p Pagination) {
+	if ew, ok := w.(*envelopeWriter); ok {
+		ew.pagination = &p
+	}
+}
+
+// APIVersionMiddleware serves the versioned API: requests under
+// constants.APIv1Prefix are handled by the matching unversioned route, with
+// JSON responses wrapped in an Envelope. It must wrap AuthMiddleware so
+// versioned paths require the same scopes as unversioned ones, and so
+// error responses from it can report the path requested.
+func APIVersionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ew := &envelopeWriter{ResponseWriter: w, path: r.URL.EscapedPath()}
+		rest, ok := strings.CutPrefix(r.URL.Path, constants.APIv1Prefix)
+		if !ok || (rest != "" && !strings.HasPrefix(rest, "/")) {
+			next.ServeHTTP(ew, r)
+			return
+		}
+
+		versioned := new(http.Request)
+		*versioned = *r
+		u := *r.URL
+		u.Path = constants.APIPrefix + rest
+		u.RawPath = ""
+		versioned.URL = &u
+
+		ew.enveloped = true
+		next.ServeHTTP(ew, versioned)
+		ew.finish()
+	})
+}
@@ -2,19 +2,28 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
-)
 
-// Standard error response structures
-type ErrorResponse struct {
-	Error   string      `json:"error"`
-	Details interface{} `json:"details,omitempty"`
-}
+	"github.com/cdzombak/lychee-meta-tool/backend/ai"
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+)
 
-type ValidationErrorResponse struct {
-	Error   string   `json:"error"`
-	Details []string `json:"details"`
+// Problem is an RFC 7807 problem details object, the body of every error
+// response. Code identifies the kind of error for automation; Error and
+// Details repeat Detail and any specifics for clients of the earlier
+// {"error", "details"} format.
+type Problem struct {
+	Type     string      `json:"type"`
+	Title    string      `json:"title"`
+	Status   int         `json:"status"`
+	Detail   string      `json:"detail,omitempty"`
+	Instance string      `json:"instance,omitempty"`
+	Code     string      `json:"code"`
+	Error    string      `json:"error"`
+	Details  interface{} `json:"details,omitempty"`
 }
 
 // HTTP status code constants
@@ -25,10 +34,35 @@ const (
 	StatusNotFound            = http.StatusNotFound
 	StatusMethodNotAllowed    = http.StatusMethodNotAllowed
 	StatusConflict            = http.StatusConflict
+	StatusGone                = http.StatusGone
+	StatusTooManyRequests     = http.StatusTooManyRequests
 	StatusInternalServerError = http.StatusInternalServerError
 	StatusServiceUnavailable  = http.StatusServiceUnavailable
 )
 
+// Stable, machine-readable error codes, documented in docs/errors.md
+const (
+	ErrorCodeBadRequest         = "bad_request"
+	ErrorCodeInvalidJSON        = "invalid_json"
+	ErrorCodeInvalidID          = "invalid_id"
+	ErrorCodeValidationFailed   = "validation_failed"
+	ErrorCodeUnauthorized       = "unauthorized"
+	ErrorCodeForbidden          = "forbidden"
+	ErrorCodeNotFound           = "not_found"
+	ErrorCodeMethodNotAllowed   = "method_not_allowed"
+	ErrorCodeConflict           = "conflict"
+	ErrorCodeGone               = "gone"
+	ErrorCodeTooManyRequests    = "too_many_requests"
+	ErrorCodeInternal           = "internal_error"
+	ErrorCodeDatabase           = "database_error"
+	ErrorCodeServiceUnavailable = "service_unavailable"
+	ErrorCodeAINotConfigured    = "ai_not_configured"
+	ErrorCodeAIDisabled         = "ai_disabled"
+	ErrorCodeAIUnavailable      = "ai_unavailable"
+	ErrorCodeAIQueueFull        = "ai_queue_full"
+	ErrorCodeAINoVision         = "ai_no_vision"
+)
+
 // Standard error messages
 const (
 	ErrorMethodNotAllowed   = "HTTP method not allowed for this endpoint"
@@ -39,40 +73,34 @@ const (
 	ErrorValidationFailed   = "Request validation failed"
 	ErrorInvalidID          = "Invalid ID format"
 	ErrorDatabaseConnection = "Database connection error. Please try again."
+	ErrorUnauthorized       = "A valid API token is required"
+	ErrorForbidden          = "API token does not have permission for this operation"
+	ErrorAINotConfigured    = "AI title generation is not configured. Please check your AI backend configuration."
 )
 
-// sendJSONError sends a standardized JSON error response
-func sendJSONError(w http.ResponseWriter, statusCode int, message string, details interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-
-	response := ErrorResponse{
-		Error:   message,
-		Details: details,
-	}
-
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Failed to encode error response: %v", err)
+// newProblem returns the problem details for an error response to the
+// request w is serving
+func newProblem(w http.ResponseWriter, statusCode int, code, message string, details interface{}) Problem {
+	return Problem{
+		Type:     constants.ProblemTypeBaseURL + code,
+		Title:    http.StatusText(statusCode),
+		Status:   statusCode,
+		Detail:   message,
+		Instance: requestPath(w),
+		Code:     code,
+		Error:    message,
+		Details:  details,
 	}
 }
 
-// sendValidationError sends a validation error response with multiple error details
-func sendValidationError(w http.ResponseWriter, errors []ValidationError) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(StatusBadRequest)
-
-	errorMessages := make([]string, len(errors))
-	for i, err := range errors {
-		errorMessages[i] = err.Error()
-	}
-
-	response := ValidationErrorResponse{
-		Error:   ErrorValidationFailed,
-		Details: errorMessages,
-	}
+// sendProblem sends a problem details error response
+func sendProblem(w http.ResponseWriter, statusCode int, code, message string, details interface{}) {
+	setEnvelopeError(w, statusCode, code, message, details)
+	w.Header().Set("Content-Type", constants.ContentTypeProblemJSON)
+	w.WriteHeader(statusCode)
 
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Failed to encode validation error response: %v", err)
+	if err := json.NewEncoder(w).Encode(newProblem(w, statusCode, code, message, details)); err != nil {
+		log.Printf("Failed to encode error response: %v", err)
 	}
 }
 
@@ -80,7 +108,7 @@ func sendValidationError(w http.ResponseWriter, errors []ValidationError) {
 
 // BadRequest sends a 400 Bad Request error
 func BadRequest(w http.ResponseWriter, message string, details interface{}) {
-	sendJSONError(w, StatusBadRequest, message, details)
+	sendProblem(w, StatusBadRequest, ErrorCodeBadRequest, message, details)
 }
 
 // NotFound sends a 404 Not Found error
@@ -88,12 +116,41 @@ func NotFound(w http.ResponseWriter, message string) {
 	if message == "" {
 		message = ErrorResourceNotFound
 	}
-	sendJSONError(w, StatusNotFound, message, nil)
+	sendProblem(w, StatusNotFound, ErrorCodeNotFound, message, nil)
+}
+
+// Unauthorized sends a 401 Unauthorized error
+func Unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="lychee-meta-tool"`)
+	sendProblem(w, StatusUnauthorized, ErrorCodeUnauthorized, ErrorUnauthorized, nil)
+}
+
+// Forbidden sends a 403 Forbidden error
+func Forbidden(w http.ResponseWriter, message string) {
+	if message == "" {
+		message = ErrorForbidden
+	}
+	sendProblem(w, StatusForbidden, ErrorCodeForbidden, message, nil)
 }
 
 // MethodNotAllowed sends a 405 Method Not Allowed error
 func MethodNotAllowed(w http.ResponseWriter) {
-	sendJSONError(w, StatusMethodNotAllowed, ErrorMethodNotAllowed, nil)
+	sendProblem(w, StatusMethodNotAllowed, ErrorCodeMethodNotAllowed, ErrorMethodNotAllowed, nil)
+}
+
+// Conflict sends a 409 Conflict error
+func Conflict(w http.ResponseWriter, message string) {
+	sendProblem(w, StatusConflict, ErrorCodeConflict, message, nil)
+}
+
+// Gone sends a 410 Gone error
+func Gone(w http.ResponseWriter, message string) {
+	sendProblem(w, StatusGone, ErrorCodeGone, message, nil)
+}
+
+// TooManyRequests sends a 429 Too Many Requests error
+func TooManyRequests(w http.ResponseWriter, message string, details interface{}) {
+	sendProblem(w, StatusTooManyRequests, ErrorCodeTooManyRequests, message, details)
 }
 
 // InternalServerError sends a 500 Internal Server Error
@@ -101,7 +158,7 @@ func InternalServerError(w http.ResponseWriter, message string) {
 	if message == "" {
 		message = ErrorInternalServer
 	}
-	sendJSONError(w, StatusInternalServerError, message, nil)
+	sendProblem(w, StatusInternalServerError, ErrorCodeInternal, message, nil)
 }
 
 // ServiceUnavailable sends a 503 Service Unavailable error
@@ -109,12 +166,39 @@ func ServiceUnavailable(w http.ResponseWriter, message string) {
 	if message == "" {
 		message = ErrorServiceUnavailable
 	}
-	sendJSONError(w, StatusServiceUnavailable, message, nil)
+	sendProblem(w, StatusServiceUnavailable, ErrorCodeServiceUnavailable, message, nil)
+}
+
+// AINotConfigured sends a 503 Service Unavailable error for AI requests
+// when no AI backend is configured
+func AINotConfigured(w http.ResponseWriter) {
+	sendProblem(w, StatusServiceUnavailable, ErrorCodeAINotConfigured, ErrorAINotConfigured, nil)
+}
+
+// AIUnavailable sends a 503 Service Unavailable error while the AI guard
+// refuses requests
+func AIUnavailable(w http.ResponseWriter) {
+	sendProblem(w, StatusServiceUnavailable, ErrorCodeAIUnavailable, aiUnavailableMessage, nil)
+}
+
+// AINoVision sends a 503 Service Unavailable error when the configured AI
+// model can't accept images
+func AINoVision(w http.ResponseWriter, err error) {
+	sendProblem(w, StatusServiceUnavailable, ErrorCodeAINoVision, noVisionMessage(err), nil)
+}
+
+// noVisionMessage describes an ai.ErrNoVision error to the user
+func noVisionMessage(err error) string {
+	var noVision ai.NoVisionError
+	if errors.As(err, &noVision) && noVision.Model != "" {
+		return fmt.Sprintf("The AI model %q does not support images. Configure a vision model.", noVision.Model)
+	}
+	return "The AI model does not support images. Configure a vision model."
 }
 
 // InvalidJSON sends a 400 Bad Request error for JSON parsing failures
 func InvalidJSON(w http.ResponseWriter, err error) {
-	BadRequest(w, ErrorInvalidJSON, err.Error())
+	sendProblem(w, StatusBadRequest, ErrorCodeInvalidJSON, ErrorInvalidJSON, err.Error())
 }
 
 // InvalidID sends a 400 Bad Request error for invalid ID format
@@ -123,16 +207,21 @@ func InvalidID(w http.ResponseWriter, idType string) {
 	if idType != "" {
 		message = "Invalid " + idType + " format"
 	}
-	BadRequest(w, message, nil)
+	sendProblem(w, StatusBadRequest, ErrorCodeInvalidID, message, nil)
 }
 
-// ValidationFailed sends a 400 Bad Request error for validation failures
+// ValidationFailed sends a 400 Bad Request error for validation failures,
+// listing each failure in details
 func ValidationFailed(w http.ResponseWriter, errors []ValidationError) {
-	sendValidationError(w, errors)
+	errorMessages := make([]string, len(errors))
+	for i, err := range errors {
+		errorMessages[i] = err.Error()
+	}
+	sendProblem(w, StatusBadRequest, ErrorCodeValidationFailed, ErrorValidationFailed, errorMessages)
 }
 
 // DatabaseError sends a 500 Internal Server Error for database issues
 func DatabaseError(w http.ResponseWriter, operation string, err error) {
 	log.Printf("Database error during %s: %v", operation, err)
-	InternalServerError(w, ErrorDatabaseConnection)
+	sendProblem(w, StatusInternalServerError, ErrorCodeDatabase, ErrorDatabaseConnection, nil)
 }
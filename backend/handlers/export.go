@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/db"
+	"github.com/cdzombak/lychee-meta-tool/backend/models"
+	"github.com/cdzombak/lychee-meta-tool/backend/sidecar"
+)
+
+// exportBatchSize is the number of photos loaded from Lychee at a time
+// while streaming an export
+const exportBatchSize = 500
+
+// ExportHandler handles HTTP requests exporting the tool's curation data
+type ExportHandler struct {
+	db              *db.DB
+	sidecar         *sidecar.Store
+	imageURLPattern string
+}
+
+// NewExportHandler creates a new ExportHandler
+func NewExportHandler(database *db.DB, sidecarStore *sidecar.Store, imageURLPattern string) *ExportHandler {
+	return &ExportHandler{
+		db:              database,
+		sidecar:         sidecarStore,
+		imageURLPattern: imageURLPattern,
+	}
+}
+
+// DecisionRecord is one line of the review decisions export: a photo's
+// image and the metadata that was settled on for it
+type DecisionRecord struct {
+	PhotoID               string            `json:"photo_id"`
+	ImageURL              string            `json:"image_url"`
+	Checksum              string            `json:"checksum"`
+	Title                 string            `json:"title"`
+	Description           string            `json:"description,omitempty"`
+	Tags                  []string          `json:"tags,omitempty"`
+	TitleProvenance       models.Provenance `json:"title_provenance,omitempty"`
+	DescriptionProvenance models.Provenance `json:"description_provenance,omitempty"`
+	// TitleReviewed is true when an AI-written title was approved unchanged
+	TitleReviewed bool `json:"title_reviewed,omitempty"`
+	// DecidedBy is who saved the metadata, when known
+	DecidedBy string    `json:"decided_by,omitempty"`
+	DecidedAt time.Time `json:"decided_at"`
+}
+
+// ExportDecisions handles GET requests streaming review decisions as JSONL,
+// one DecisionRecord per photo whose title was saved through the tool.
+// The optional title query parameter (ai, ai_edited or manual) filters on
+// title provenance. Photos since edited directly in Lychee are skipped, as
+// their recorded provenance may no longer apply.
+func (h *ExportHandler) ExportDecisions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w)
+		return
+	}
+
+	titleFilter, ok := parseProvenanceParam(w, "title", r.URL.Query().Get("title"))
+	if !ok {
+		return
+	}
+
+	var filter db.PhotoFilter
+	if !restrictFilter(w, r, h.db, &filter) {
+		return
+	}
+
+	states := h.sidecar.Photos(func(_ string, p sidecar.PhotoState) bool {
+		if p.TitleProvenance == "" || p.ExternalEditAt != nil {
+			return false
+		}
+		return titleFilter == "" || p.TitleProvenance == titleFilter
+	})
+	ids := make([]string, 0, len(states))
+	for id := range states {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSONL)
+	w.Header().Set("Content-Disposition", `attachment; filename="decisions.jsonl"`)
+
+	encoder := json.NewEncoder(w)
+	for start := 0; start < len(ids); start += exportBatchSize {
+		batch := ids[start:min(start+exportBatchSize, len(ids))]
+
+		photos, err := h.db.GetPhotosByIDs(batch, filter, 0, 0)
+		if err != nil {
+			// Part of the export may already have been sent, so the status
+			// can no longer be changed; stop and leave the file truncated
+			log.Printf("Failed to get photos for decisions export: %v", err)
+			return
+		}
+
+		// Tags live in a column that not every Lychee version has
+		tags, err := h.db.GetPhotoTags(batch)
+		if err != nil {
+			log.Printf("Exporting decisions without tags: %v", err)
+		}
+
+		for i := range photos {
+			photo := &photos[i]
+			state := states[photo.ID]
+			urls := photo.ToPhotoResponse(h.imageURLPattern)
+
+			record := DecisionRecord{
+				PhotoID:               photo.ID,
+				ImageURL:              urls.LargeURL,
+				Checksum:              photo.Checksum,
+				Title:                 photo.Title,
+				Tags:                  tags[photo.ID],
+				TitleProvenance:       state.TitleProvenance,
+				DescriptionProvenance: state.DescriptionProvenance,
+				TitleReviewed:         state.TitleReviewedAt != nil,
+				DecidedBy:             state.UpdatedBy,
+				DecidedAt:             state.UpdatedAt,
+			}
+			if record.ImageURL == "" {
+				record.ImageURL = urls.FullURL
+			}
+			if photo.Description != nil {
+				record.Description = withoutChangeNote(*photo.Description)
+			}
+
+			if err := encoder.Encode(record); err != nil {
+				log.Printf("Failed to write decisions export: %v", err)
+				return
+			}
+		}
+	}
+}
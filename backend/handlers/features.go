@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/ai"
+	"github.com/cdzombak/lychee-meta-tool/backend/auth"
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+)
+
+// FeaturesHandler reports which optional features the server supports, so
+// the frontend can hide controls for the ones it doesn't
+type FeaturesHandler struct {
+	aiBackend *ai.Holder
+	tokens    *auth.TokenStore
+	opts      FeatureOptions
+}
+
+// FeatureOptions are the features fixed by the configuration at startup
+type FeatureOptions struct {
+	// Geocoding is true when reverse geocoding is configured
+	Geocoding bool
+	// Delete is true when editing.allow_destructive enables deleting photos
+	Delete bool
+	// AuthProxy is true when identities are taken from a forward-auth proxy
+	AuthProxy bool
+}
+
+// NewFeaturesHandler creates a new FeaturesHandler. The AI backend and API
+// tokens are checked on each request, since both can change at runtime.
+func NewFeaturesHandler(aiBackend *ai.Holder, tokens *auth.TokenStore, opts FeatureOptions) *FeaturesHandler {
+	return &FeaturesHandler{aiBackend: aiBackend, tokens: tokens, opts: opts}
+}
+
+// FeaturesResponse maps feature names to whether they are available
+type FeaturesResponse struct {
+	Features map[string]bool `json:"features"`
+}
+
+// GetFeatures handles GET requests for the feature flags:
+//   - ai: AI title generation
+//   - tags, alt_text: AI tag and alt text generation, which not every AI
+//     backend supports
+//   - jobs: batch AI title jobs
+//   - geocoding: looking up place names for photos' coordinates
+//   - delete: deleting photos
+//   - auth: API tokens are required
+//   - auth_proxy: users are identified by a forward-auth proxy
+func (h *FeaturesHandler) GetFeatures(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w)
+		return
+	}
+
+	client := h.aiBackend.Client()
+	_, tags := client.(ai.TagGenerator)
+	_, altText := client.(ai.AltTextGenerator)
+
+	features := map[string]bool{
+		"ai":         client != nil,
+		"tags":       tags,
+		"alt_text":   altText,
+		"jobs":       client != nil,
+		"geocoding":  h.opts.Geocoding,
+		"delete":     h.opts.Delete,
+		"auth":       h.tokens.Enabled(),
+		"auth_proxy": h.opts.AuthProxy,
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	if err := json.NewEncoder(w).Encode(FeaturesResponse{Features: features}); err != nil {
+		log.Printf("Failed to encode features response: %v", err)
+	}
+}
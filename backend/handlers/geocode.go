@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/feed"
+	"github.com/cdzombak/lychee-meta-tool/backend/geocode"
+	"github.com/cdzombak/lychee-meta-tool/backend/models"
+)
+
+// MaxLocationLength is the longest place name saved to a photo's location
+const MaxLocationLength = 255
+
+// GeocodeResponse reports the place name saved for a photo
+type GeocodeResponse struct {
+	Success  bool   `json:"success"`
+	Location string `json:"location"`
+}
+
+// GeocodePhoto handles POST requests to look up the place name for a
+// photo's GPS coordinates and save it as the photo's location in Lychee
+func (h *PhotoHandler) GeocodePhoto(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w)
+		return
+	}
+
+	if h.opts.Geocoder == nil {
+		ServiceUnavailable(w, "Geocoding is not configured. Please enable it in the geocoding section of the configuration.")
+		return
+	}
+
+	photoID, valid := extractPhotoIDFromPath(r.URL.Path)
+	if !valid {
+		InvalidID(w, "photo ID")
+		return
+	}
+
+	if !checkPhotoAccess(w, r, h.db, photoID) {
+		return
+	}
+
+	photo, err := h.db.GetPhotoByID(photoID)
+	if err != nil {
+		DatabaseError(w, "retrieve photo", err)
+		return
+	}
+	if photo == nil {
+		NotFound(w, fmt.Sprintf("Photo with ID '%s' not found.", photoID))
+		return
+	}
+	if photo.Latitude == nil || photo.Longitude == nil {
+		BadRequest(w, "Photo has no GPS coordinates.", nil)
+		return
+	}
+
+	location, err := h.opts.Geocoder.Reverse(r.Context(), *photo.Latitude, *photo.Longitude)
+	if errors.Is(err, geocode.ErrNoResult) {
+		NotFound(w, "No place name was found for this photo's coordinates.")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to geocode photo %s: %v", photoID, err)
+		ServiceUnavailable(w, "The geocoding service could not be reached. Please try again later.")
+		return
+	}
+
+	location = sanitizeText(truncateTitle(location, MaxLocationLength))
+	if err := h.db.SetPhotoLocation(photoID, location); err != nil {
+		DatabaseError(w, "update photo location", err)
+		return
+	}
+	log.Printf("Set location of photo %s to %q", photoID, location)
+	h.publish(r.Context(), feed.EventUpdated, photoID)
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	if err := json.NewEncoder(w).Encode(GeocodeResponse{Success: true, Location: location}); err != nil {
+		log.Printf("Failed to encode geocode response: %v", err)
+	}
+}
+
+// photoLocation returns where photo was taken for use in an AI prompt: its
+// saved location, or else a place name looked up from its coordinates.
+// Lookup failures are logged and leave the location out.
+func (h *PhotoHandler) photoLocation(ctx context.Context, photo *models.PhotoWithSizeVariants) string {
+	if photo.Location != nil && *photo.Location != "" {
+		return html.UnescapeString(*photo.Location)
+	}
+	if h.opts.Geocoder == nil || photo.Latitude == nil || photo.Longitude == nil {
+		return ""
+	}
+
+	location, err := h.opts.Geocoder.Reverse(ctx, *photo.Latitude, *photo.Longitude)
+	if err != nil {
+		log.Printf("Failed to geocode photo %s for its AI title: %v", photo.ID, err)
+		return ""
+	}
+	return location
+}
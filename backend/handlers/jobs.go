@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/ai"
+	"github.com/cdzombak/lychee-meta-tool/backend/auth"
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/db"
+	"github.com/cdzombak/lychee-meta-tool/backend/jobs"
+	"github.com/cdzombak/lychee-meta-tool/backend/sidecar"
+)
+
+// JobsAPIPrefix is the path prefix for background job endpoints
+const JobsAPIPrefix = "/api/jobs"
+
+// JobHandler handles HTTP requests for background jobs
+type JobHandler struct {
+	jobs      *jobs.Manager
+	db        *db.DB
+	sidecar   *sidecar.Store
+	aiBackend *ai.Holder
+}
+
+// NewJobHandler creates a new JobHandler
+func NewJobHandler(manager *jobs.Manager, database *db.DB, sidecarStore *sidecar.Store, aiBackend *ai.Holder) *JobHandler {
+	return &JobHandler{
+		jobs:      manager,
+		db:        database,
+		sidecar:   sidecarStore,
+		aiBackend: aiBackend,
+	}
+}
+
+// GenerateTitlesRequest starts a batch title generation job
+type GenerateTitlesRequest struct {
+	// AlbumID restricts the job to an album or smart album
+	AlbumID string `json:"album_id"`
+	// PublicOnly restricts the job to publicly visible photos
+	PublicOnly bool `json:"public"`
+	// Limit caps the number of photos titled; defaults to MaxLimit
+	Limit int `json:"limit"`
+	// Apply saves each generated title to Lychee, marked as AI-written so
+	// that it appears in the re-review queue. Otherwise titles are only
+	// reported in the job's results, unless the album is set to auto-apply.
+	Apply bool `json:"apply"`
+}
+
+// JobsResponse lists background jobs
+type JobsResponse struct {
+	Jobs []jobs.Job `json:"jobs"`
+}
+
+// GenerateTitles handles POST requests starting a background job that
+// generates AI titles for photos needing metadata
+func (h *JobHandler) GenerateTitles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w)
+		return
+	}
+	if h.aiBackend.Client() == nil {
+		AINotConfigured(w)
+		return
+	}
+
+	var req GenerateTitlesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		InvalidJSON(w, err)
+		return
+	}
+	// Saving titles to many photos at once is a bulk operation; only
+	// suggesting them is ordinary editing
+	if req.Apply && !requireScope(w, r, auth.ScopeAdmin) {
+		return
+	}
+
+	filter := db.PhotoFilter{PublicOnly: req.PublicOnly}
+	if req.AlbumID != "" {
+		if !validateAlbumID(req.AlbumID) {
+			InvalidID(w, "album ID")
+			return
+		}
+		filter.AlbumID = &req.AlbumID
+	}
+	if req.Limit < 0 || req.Limit > MaxLimit {
+		BadRequest(w, fmt.Sprintf("Invalid limit. Must be a number between 1 and %d.", MaxLimit), nil)
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = MaxLimit
+	}
+
+	if !restrictFilter(w, r, h.db, &filter) {
+		return
+	}
+	applyExclusions(h.sidecar, &filter)
+	// Leave out albums with AI generation disabled rather than failing
+	// each of their photos
+	for id, settings := range h.sidecar.Albums() {
+		if settings.Excluded {
+			filter.ExcludeAlbumIDs = append(filter.ExcludeAlbumIDs, id)
+		}
+	}
+
+	job, err := h.jobs.SubmitTitles(r.Context(), jobs.TitleRequest{
+		Filter: filter,
+		Limit:  req.Limit,
+		Apply:  req.Apply,
+	})
+	if errors.Is(err, jobs.ErrQueueFull) {
+		ServiceUnavailable(w, "Too many jobs are queued. Please wait for some to finish.")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to submit title generation job: %v", err)
+		InternalServerError(w, "Failed to start job. Please try again.")
+		return
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		log.Printf("Failed to encode job response: %v", err)
+	}
+}
+
+// ComputeHashesRequest starts a job computing perceptual hashes
+type ComputeHashesRequest struct {
+	// AlbumID restricts the job to an album or smart album
+	AlbumID string `json:"album_id"`
+	// PublicOnly restricts the job to publicly visible photos
+	PublicOnly bool `json:"public"`
+}
+
+// ComputeHashes handles POST requests starting a background job that
+// computes perceptual hashes of photos' thumbnails, for finding duplicates.
+// Photos already hashed are skipped, so the job can be rerun cheaply after
+// new uploads.
+func (h *JobHandler) ComputeHashes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w)
+		return
+	}
+
+	var req ComputeHashesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		InvalidJSON(w, err)
+		return
+	}
+
+	filter := db.PhotoFilter{PublicOnly: req.PublicOnly}
+	if req.AlbumID != "" {
+		if !validateAlbumID(req.AlbumID) {
+			InvalidID(w, "album ID")
+			return
+		}
+		filter.AlbumID = &req.AlbumID
+	}
+	if !restrictFilter(w, r, h.db, &filter) {
+		return
+	}
+
+	job, err := h.jobs.SubmitHashes(r.Context(), jobs.HashRequest{Filter: filter})
+	if errors.Is(err, jobs.ErrQueueFull) {
+		ServiceUnavailable(w, "Too many jobs are queued. Please wait for some to finish.")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to submit hash job: %v", err)
+		InternalServerError(w, "Failed to start job. Please try again.")
+		return
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		log.Printf("Failed to encode job response: %v", err)
+	}
+}
+
+// CheckMediaRequest starts a job auditing photos' media
+type CheckMediaRequest struct {
+	// AlbumID restricts the job to an album or smart album
+	AlbumID string `json:"album_id"`
+	// PublicOnly restricts the job to publicly visible photos
+	PublicOnly bool `json:"public"`
+}
+
+// CheckMedia handles POST requests starting a background job that checks
+// every size variant of each photo can be retrieved, from its storage disk
+// or over HTTP. The job's results report the photos with broken media.
+func (h *JobHandler) CheckMedia(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w)
+		return
+	}
+
+	var req CheckMediaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		InvalidJSON(w, err)
+		return
+	}
+
+	filter := db.PhotoFilter{PublicOnly: req.PublicOnly}
+	if req.AlbumID != "" {
+		if !validateAlbumID(req.AlbumID) {
+			InvalidID(w, "album ID")
+			return
+		}
+		filter.AlbumID = &req.AlbumID
+	}
+	if !restrictFilter(w, r, h.db, &filter) {
+		return
+	}
+
+	job, err := h.jobs.SubmitMediaCheck(r.Context(), jobs.MediaRequest{Filter: filter})
+	if errors.Is(err, jobs.ErrQueueFull) {
+		ServiceUnavailable(w, "Too many jobs are queued. Please wait for some to finish.")
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to submit media check job: %v", err)
+		InternalServerError(w, "Failed to start job. Please try again.")
+		return
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		log.Printf("Failed to encode job response: %v", err)
+	}
+}
+
+// ListJobs handles GET requests listing background jobs, newest first
+func (h *JobHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	if err := json.NewEncoder(w).Encode(JobsResponse{Jobs: h.jobs.List()}); err != nil {
+		log.Printf("Failed to encode jobs response: %v", err)
+	}
+}
+
+// HandleJob handles GET /api/jobs/{id}, returning a job with its per-photo
+// results, and POST /api/jobs/{id}/cancel
+func (h *JobHandler) HandleJob(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, JobsAPIPrefix+"/")
+	id, action, _ := strings.Cut(rest, "/")
+	if id == "" {
+		NotFound(w, "")
+		return
+	}
+
+	var job jobs.Job
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		var ok bool
+		if job, ok = h.jobs.Get(id); !ok {
+			NotFound(w, "Job not found.")
+			return
+		}
+	case action == "cancel" && r.Method == http.MethodPost:
+		var err error
+		job, err = h.jobs.Cancel(id)
+		switch {
+		case errors.Is(err, jobs.ErrNotFound):
+			NotFound(w, "Job not found.")
+			return
+		case errors.Is(err, jobs.ErrFinished):
+			Conflict(w, fmt.Sprintf("Job has already %s.", job.State))
+			return
+		}
+	case action == "" || action == "cancel":
+		MethodNotAllowed(w)
+		return
+	default:
+		NotFound(w, "")
+		return
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	if err := json.NewEncoder(w).Encode(job); err != nil {
+		log.Printf("Failed to encode job response: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/auth"
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/feed"
+	"github.com/cdzombak/lychee-meta-tool/backend/models"
+)
+
+// LockRequest identifies the client session taking or releasing an edit
+// lock, so that one person's browser tabs don't share a lock
+type LockRequest struct {
+	Session string `json:"session"`
+}
+
+// LockResponse is the edit lock held on a photo
+type LockResponse struct {
+	Lock models.EditLock `json:"lock"`
+}
+
+// LockPhoto handles POST requests to take or renew the edit lock on a
+// photo. Clients renew it while the photo stays open, since it expires
+// after constants.EditLockTTL. If someone else holds the lock, it responds
+// 409 Conflict with their lock in the details.
+func (h *PhotoHandler) LockPhoto(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w)
+		return
+	}
+
+	photoID, owner, ok := h.parseLockRequest(w, r)
+	if !ok {
+		return
+	}
+
+	_, renewing := h.sidecar.PhotoLock(photoID)
+	holder := auth.Actor(r.Context())
+	lock, acquired := h.sidecar.LockPhoto(photoID, owner, holder, constants.EditLockTTL)
+	if !acquired {
+		message := fmt.Sprintf("Photo '%s' is being edited by someone else", photoID)
+		if lock.Holder != "" {
+			message = fmt.Sprintf("Photo '%s' is being edited by %s", photoID, lock.Holder)
+		}
+		sendProblem(w, StatusConflict, ErrorCodeConflict, message, LockResponse{Lock: lock})
+		return
+	}
+	if !renewing {
+		h.publish(r.Context(), feed.EventLocked, photoID)
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	_ = json.NewEncoder(w).Encode(LockResponse{Lock: lock})
+}
+
+// UnlockPhoto handles DELETE requests to release the edit lock on a photo.
+// Releasing a lock held by another session, or one that has expired, does
+// nothing.
+func (h *PhotoHandler) UnlockPhoto(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		MethodNotAllowed(w)
+		return
+	}
+
+	photoID, owner, ok := h.parseLockRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if h.sidecar.UnlockPhoto(photoID, owner) {
+		h.publish(r.Context(), feed.EventUnlocked, photoID)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseLockRequest extracts the photo ID from a lock request's path and the
+// lock owner from its body: the requester's identity and client session.
+// On failure it sends an error response and returns ok == false.
+func (h *PhotoHandler) parseLockRequest(w http.ResponseWriter, r *http.Request) (photoID, owner string, ok bool) {
+	photoID, valid := extractPhotoIDFromPath(r.URL.Path)
+	if !valid {
+		InvalidID(w, "photo ID")
+		return "", "", false
+	}
+
+	var req LockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		InvalidJSON(w, err)
+		return "", "", false
+	}
+	if len(req.Session) > constants.MaxIDLength || (req.Session != "" && !albumIDPattern.MatchString(req.Session)) {
+		BadRequest(w, "Invalid session. Must be alphanumeric with underscores and hyphens only.", nil)
+		return "", "", false
+	}
+
+	if !checkPhotoAccess(w, r, h.db, photoID) {
+		return "", "", false
+	}
+
+	// A newline can't occur in either part, so owners can't collide
+	return photoID, auth.Actor(r.Context()) + "\n" + req.Session, true
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/maintenance"
+)
+
+// MaintenanceHandler handles HTTP requests for pruning the tool's own state
+type MaintenanceHandler struct {
+	maintainer *maintenance.Maintainer
+}
+
+// NewMaintenanceHandler creates a new MaintenanceHandler
+func NewMaintenanceHandler(maintainer *maintenance.Maintainer) *MaintenanceHandler {
+	return &MaintenanceHandler{maintainer: maintainer}
+}
+
+// MaintenanceResponse reports the outcome of a maintenance pass
+type MaintenanceResponse struct {
+	Result *maintenance.Result `json:"result"`
+}
+
+// HandleMaintenance dispatches maintenance requests: GET returns the most
+// recent pass's result, and POST runs a pass immediately, only counting
+// what it would prune with ?dry_run=true
+func (h *MaintenanceHandler) HandleMaintenance(w http.ResponseWriter, r *http.Request) {
+	var response MaintenanceResponse
+
+	switch r.Method {
+	case http.MethodGet:
+		if result, ok := h.maintainer.Last(); ok {
+			response.Result = &result
+		}
+	case http.MethodPost:
+		dryRun, valid := parseBoolParam(r.URL.Query().Get("dry_run"))
+		if !valid {
+			BadRequest(w, "Invalid dry_run parameter. Must be true or false.", nil)
+			return
+		}
+		result, err := h.maintainer.Prune(dryRun)
+		if err != nil {
+			log.Printf("Maintenance failed: %v", err)
+			InternalServerError(w, "Maintenance failed. Please try again.")
+			return
+		}
+		response.Result = &result
+	default:
+		MethodNotAllowed(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode maintenance response: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/auth"
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/sidecar"
+)
+
+// MeHandler handles HTTP requests about the person making them: who the
+// tool takes them to be, and their preferences
+type MeHandler struct {
+	sidecar *sidecar.Store
+}
+
+// NewMeHandler creates a new MeHandler backed by the given sidecar store
+func NewMeHandler(sidecarStore *sidecar.Store) *MeHandler {
+	return &MeHandler{sidecar: sidecarStore}
+}
+
+// MeResponse reports the request's actor and their preferences
+type MeResponse struct {
+	// Actor is the forward-auth identity, or "token:" and the API token's
+	// name; empty for anonymous requests
+	Actor       string                  `json:"actor"`
+	Preferences sidecar.UserPreferences `json:"preferences"`
+}
+
+// HandleMe handles GET requests for the request's actor and preferences,
+// and PUT requests replacing the preferences. Preferences need an actor,
+// from a forward-auth proxy or an API token.
+func (h *MeHandler) HandleMe(w http.ResponseWriter, r *http.Request) {
+	actor := auth.Actor(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		if actor == "" {
+			Forbidden(w, "Preferences need a user identity. Enable auth.forward_auth or use an API token.")
+			return
+		}
+
+		var prefs sidecar.UserPreferences
+		if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+			InvalidJSON(w, err)
+			return
+		}
+		if validationErrors := ValidateUserPreferences(&prefs); len(validationErrors) > 0 {
+			ValidationFailed(w, validationErrors)
+			return
+		}
+
+		if err := h.sidecar.SetUser(actor, prefs); err != nil {
+			log.Printf("Failed to save preferences for %s: %v", actor, err)
+			InternalServerError(w, "Failed to save preferences. Please try again.")
+			return
+		}
+	default:
+		MethodNotAllowed(w)
+		return
+	}
+
+	response := MeResponse{Actor: actor}
+	if actor != "" {
+		response.Preferences = h.sidecar.User(actor)
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode me response: %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/metrics"
+)
+
+// MetricsHandler serves snapshots of the tool's metrics
+type MetricsHandler struct {
+	collector *metrics.Collector
+}
+
+// NewMetricsHandler creates a new MetricsHandler
+func NewMetricsHandler(collector *metrics.Collector) *MetricsHandler {
+	return &MetricsHandler{collector: collector}
+}
+
+// GetMetrics handles GET requests for a snapshot of the tool's metrics, such
+// as the review backlog and AI usage, in the Prometheus text exposition
+// format
+func (h *MetricsHandler) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w)
+		return
+	}
+
+	families, err := h.collector.Collect()
+	if err != nil {
+		DatabaseError(w, "collect metrics", err)
+		return
+	}
+
+	var body bytes.Buffer
+	if err := metrics.Write(&body, families); err != nil {
+		log.Printf("Failed to write metrics: %v", err)
+		InternalServerError(w, "Failed to write metrics")
+		return
+	}
+
+	w.Header().Set("Content-Type", metrics.ContentType)
+	_, _ = w.Write(body.Bytes())
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/auth"
+	"github.com/cdzombak/lychee-meta-tool/backend/db"
+)
+
+// lycheeUserID returns the ID of the Lychee user whose permissions limit r,
+// or nil if the request is unrestricted: it was not made with a token bound
+// to a Lychee user, or that user is a Lychee administrator. On failure it
+// sends an error response and returns ok == false.
+func lycheeUserID(w http.ResponseWriter, r *http.Request, database *db.DB) (userID *int, ok bool) {
+	token, found := auth.FromContext(r.Context())
+	if !found || token.LycheeUser == "" {
+		return nil, true
+	}
+
+	user, err := database.GetLycheeUser(token.LycheeUser)
+	if err != nil {
+		DatabaseError(w, "get Lychee user", err)
+		return nil, false
+	}
+	if user == nil {
+		log.Printf("API token %q is bound to Lychee user %q, which does not exist", token.Name, token.LycheeUser)
+		Forbidden(w, fmt.Sprintf("Lychee user %q for this API token no longer exists", token.LycheeUser))
+		return nil, false
+	}
+	if user.MayAdministrate {
+		return nil, true
+	}
+
+	return &user.ID, true
+}
+
+// restrictFilter limits filter to photos the request's Lychee user may edit.
+// On failure it sends an error response and returns false.
+func restrictFilter(w http.ResponseWriter, r *http.Request, database *db.DB, filter *db.PhotoFilter) bool {
+	userID, ok := lycheeUserID(w, r, database)
+	if !ok {
+		return false
+	}
+	filter.EditableBy = userID
+	return true
+}
+
+// checkPhotoAccess verifies that the request's Lychee user may edit a photo.
+// On failure it sends an error response and returns false.
+func checkPhotoAccess(w http.ResponseWriter, r *http.Request, database *db.DB, photoID string) bool {
+	userID, ok := lycheeUserID(w, r, database)
+	if !ok {
+		return false
+	}
+	if userID == nil {
+		return true
+	}
+
+	allowed, err := database.CanEditPhoto(*userID, photoID)
+	if err != nil {
+		DatabaseError(w, "check photo permissions", err)
+		return false
+	}
+	if !allowed {
+		Forbidden(w, "You do not have permission to edit this photo in Lychee.")
+		return false
+	}
+	return true
+}
+
+// checkPhotosAccess verifies that the request's Lychee user may edit every
+// listed photo. On failure it sends an error response and returns false.
+func checkPhotosAccess(w http.ResponseWriter, r *http.Request, database *db.DB, photoIDs []string) bool {
+	userID, ok := lycheeUserID(w, r, database)
+	if !ok {
+		return false
+	}
+	if userID == nil {
+		return true
+	}
+
+	for _, id := range photoIDs {
+		allowed, err := database.CanEditPhoto(*userID, id)
+		if err != nil {
+			DatabaseError(w, "check photo permissions", err)
+			return false
+		}
+		if !allowed {
+			Forbidden(w, fmt.Sprintf("You do not have permission to edit photo %s in Lychee.", id))
+			return false
+		}
+	}
+	return true
+}
+
+// checkAlbumAccess verifies that the request's Lychee user may edit an album.
+// On failure it sends an error response and returns false.
+func checkAlbumAccess(w http.ResponseWriter, r *http.Request, database *db.DB, albumID string) bool {
+	userID, ok := lycheeUserID(w, r, database)
+	if !ok {
+		return false
+	}
+	if userID == nil {
+		return true
+	}
+
+	allowed, err := database.CanEditAlbum(*userID, albumID)
+	if err != nil {
+		DatabaseError(w, "check album permissions", err)
+		return false
+	}
+	if !allowed {
+		Forbidden(w, "You do not have permission to edit this album in Lychee.")
+		return false
+	}
+	return true
+}
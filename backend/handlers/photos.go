@@ -3,98 +3,682 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand/v2"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cdzombak/lychee-meta-tool/backend/ai"
+	"github.com/cdzombak/lychee-meta-tool/backend/auth"
 	"github.com/cdzombak/lychee-meta-tool/backend/constants"
 	"github.com/cdzombak/lychee-meta-tool/backend/db"
+	"github.com/cdzombak/lychee-meta-tool/backend/feed"
+	"github.com/cdzombak/lychee-meta-tool/backend/geocode"
+	"github.com/cdzombak/lychee-meta-tool/backend/locale"
 	"github.com/cdzombak/lychee-meta-tool/backend/models"
+	"github.com/cdzombak/lychee-meta-tool/backend/placeholder"
+	"github.com/cdzombak/lychee-meta-tool/backend/sidecar"
+	"github.com/cdzombak/lychee-meta-tool/backend/titlecache"
 )
 
+// PhotoHandlerOptions holds optional PhotoHandler behavior
+type PhotoHandlerOptions struct {
+	// ChangeNotes appends a provenance note to the description when a title is set
+	ChangeNotes bool
+	// CacheBustImages versions image URLs by the photo's updated_at
+	CacheBustImages bool
+	// AllowDestructive enables deleting photos
+	AllowDestructive bool
+	// Placeholders supplies BlurHash and dominant color placeholders; nil
+	// leaves them out
+	Placeholders *placeholder.Worker
+	// TitleCache caches generated titles by photo checksum; nil disables
+	// caching
+	TitleCache *titlecache.Cache
+	// AIGuard retries failed AI requests and fails fast while the backend
+	// is down; nil calls the backend directly
+	AIGuard *ai.Guard
+	// AIUsage records the tokens, latency and cost of AI requests; nil
+	// disables tracking
+	AIUsage *ai.UsageTracker
+	// AILimiter caps concurrent AI generations; nil leaves them unlimited
+	AILimiter *ai.Limiter
+	// Geocoder looks up place names for photos' coordinates; nil disables
+	// geocoding
+	Geocoder *geocode.Client
+	// LocationInPrompt includes where a photo was taken in AI title prompts
+	LocationInPrompt bool
+	// Locale formats dates in responses and AI prompts; nil leaves them
+	// unformatted
+	Locale *locale.Locale
+	// DateInPrompt includes when a photo was taken in AI title prompts
+	DateInPrompt bool
+	// TitleLanguage is the language AI titles and tags are written in when
+	// neither the album nor the request sets one; empty leaves it to the
+	// model
+	TitleLanguage string
+	// Feed announces changes to photos to other reviewers; nil disables
+	// the change feed
+	Feed *feed.Hub
+}
+
 // PhotoHandler handles HTTP requests related to photos
 type PhotoHandler struct {
-	db            *db.DB
-	lycheeBaseURL string
-	aiClient      ai.Client
+	db              *db.DB
+	sidecar         *sidecar.Store
+	imageURLPattern string
+	aiBackend       *ai.Holder
+	opts            PhotoHandlerOptions
 }
 
-// NewPhotoHandler creates a new PhotoHandler with the provided dependencies
-func NewPhotoHandler(database *db.DB, lycheeBaseURL string, aiClient ai.Client) *PhotoHandler {
+// NewPhotoHandler creates a new PhotoHandler with the provided dependencies.
+// aiBackend holds the AI client, which may be replaced while the server
+// runs.
+func NewPhotoHandler(database *db.DB, sidecarStore *sidecar.Store, imageURLPattern string, aiBackend *ai.Holder, opts PhotoHandlerOptions) *PhotoHandler {
 	return &PhotoHandler{
-		db:            database,
-		lycheeBaseURL: lycheeBaseURL,
-		aiClient:      aiClient,
+		db:              database,
+		sidecar:         sidecarStore,
+		imageURLPattern: imageURLPattern,
+		aiBackend:       aiBackend,
+		opts:            opts,
+	}
+}
+
+// aiClient returns the AI client in use, or nil if AI title generation is
+// unavailable
+func (h *PhotoHandler) aiClient() ai.Client {
+	return h.aiBackend.Client()
+}
+
+// publish announces a change to photos on the change feed, attributed to
+// the request's actor
+func (h *PhotoHandler) publish(ctx context.Context, eventType feed.EventType, photoIDs ...string) {
+	h.opts.Feed.Publish(eventType, auth.Actor(ctx), photoIDs...)
+}
+
+// photoResponse converts a photo to its response format, including
+// provenance from the sidecar store
+func (h *PhotoHandler) photoResponse(photo *models.PhotoWithSizeVariants) models.PhotoResponse {
+	response := photo.ToPhotoResponse(h.imageURLPattern)
+	if h.opts.Placeholders != nil && photo.ThumbnailPath != nil {
+		p := h.opts.Placeholders.Lookup(photo.ID, *photo.ThumbnailPath, response.ThumbnailURL, photo.StorageDisk())
+		response.Blurhash = p.Blurhash
+		response.DominantColor = p.Color
+	}
+	if h.opts.CacheBustImages {
+		response.VersionImageURLs(photo.UpdatedAt)
+	}
+	if state, ok := h.sidecar.Photo(photo.ID); ok {
+		response.TitleProvenance = state.TitleProvenance
+		response.DescriptionProvenance = state.DescriptionProvenance
+		response.ExternalEditAt = state.ExternalEditAt
+	}
+	if lock, ok := h.sidecar.PhotoLock(photo.ID); ok {
+		response.EditLock = &lock
+	}
+	if h.opts.Locale != nil && photo.TakenAt != nil {
+		response.TakenAtDisplay = h.opts.Locale.FormatDate(*photo.TakenAt)
 	}
+	return response
 }
 
 // PhotosNeedingMetadataResponse represents the response for photos needing metadata
 type PhotosNeedingMetadataResponse struct {
 	Photos []models.PhotoResponse `json:"photos"`
-	Total  int                    `json:"total"`
+	// Total is the number of photos in the queue across all pages
+	Total int `json:"total"`
+	// Limit and Offset describe the page returned. HasMore is true when
+	// more photos follow it, starting at NextOffset; photos titled since
+	// this page was loaded leave the queue, shifting later pages forward.
+	Limit      int  `json:"limit"`
+	Offset     int  `json:"offset"`
+	HasMore    bool `json:"has_more"`
+	NextOffset int  `json:"next_offset,omitempty"`
+	// NextAfter is the cursor for the next page of the needs-metadata
+	// queue, to pass as the after parameter; unlike offsets, it stays
+	// valid as photos are titled
+	NextAfter string `json:"next_after,omitempty"`
+	// Seed is the seed of a random sort, to pass back when loading later
+	// pages
+	Seed int64 `json:"seed,omitempty"`
+	// Groups lists bursts of near-identical photos within this page, so
+	// they can be titled together
+	Groups []models.PhotoGroup `json:"groups,omitempty"`
 }
 
-// GetPhotosNeedingMetadata handles GET requests to retrieve photos that need metadata
+// GetPhotosNeedingMetadata handles GET requests to retrieve photos that need metadata:
+// by default those needing a title, or with ?needs=description or ?needs=both, those
+// lacking a description or either
 func (h *PhotoHandler) GetPhotosNeedingMetadata(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		MethodNotAllowed(w)
 		return
 	}
 
-	// Parse and validate query parameters
+	filter, limit, offset, ok := parseQueueParams(w, r)
+	if !ok || !parseNeedsParam(w, r, &filter) || !parseSortParams(w, r, &filter) || !h.parseAfterParam(w, r, &filter, offset) || !restrictFilter(w, r, h.db, &filter) || !applyColorFilter(w, r, h.sidecar, &filter) {
+		return
+	}
+	applyExclusions(h.sidecar, &filter)
+
+	// Fetch one more photo than requested to tell whether more follow
+	photos, err := h.db.GetPhotosNeedingMetadata(filter, limit+1, offset)
+	if err != nil {
+		log.Printf("Failed to get photos needing metadata (filter=%s, limit=%d, offset=%d): %v", formatFilter(filter), limit, offset, err)
+		InternalServerError(w, "Failed to retrieve photos. Please try again.")
+		return
+	}
+	hasMore := len(photos) > limit
+	if hasMore {
+		photos = photos[:limit]
+	}
+
+	total, err := h.db.CountPhotosNeedingMetadata(filter)
+	if err != nil {
+		log.Printf("Failed to count photos needing metadata (filter=%s): %v", formatFilter(filter), err)
+		InternalServerError(w, "Failed to retrieve photos. Please try again.")
+		return
+	}
+
+	// Convert to response format
+	photoResponses := make([]models.PhotoResponse, len(photos))
+	for i := range photos {
+		photoResponses[i] = h.photoResponse(&photos[i])
+	}
+
+	response := queuePage(photoResponses, total, limit, offset, hasMore)
+	if hasMore {
+		response.NextAfter = photos[len(photos)-1].ID
+	}
+	response.Seed = filter.Sort.Seed
+	response.Groups = models.GroupBursts(photos, constants.BurstWindow)
+
+	setPagination(w, response.pagination())
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// parseQueueParams parses and validates the album_id, public, taken_after,
+// taken_before, limit and offset query parameters shared by the photo queue
+// endpoints. On failure it sends a 400 response and returns ok == false.
+func parseQueueParams(w http.ResponseWriter, r *http.Request) (filter db.PhotoFilter, limit, offset int, ok bool) {
 	query := r.URL.Query()
-	var albumID *string
 	if aid := sanitizeQueryParam(query.Get("album_id")); aid != "" {
 		if !validateAlbumID(aid) {
 			BadRequest(w, "Invalid album_id format. Must be alphanumeric with underscores and hyphens only.", nil)
-			return
+			return filter, 0, 0, false
+		}
+		filter.AlbumID = &aid
+	}
+
+	publicOnly, valid := parseBoolParam(query.Get("public"))
+	if !valid {
+		BadRequest(w, "Invalid public parameter. Must be true or false.", nil)
+		return filter, 0, 0, false
+	}
+	filter.PublicOnly = publicOnly
+
+	for _, param := range []struct {
+		name string
+		dest **time.Time
+	}{
+		{"taken_after", &filter.TakenAfter},
+		{"taken_before", &filter.TakenBefore},
+	} {
+		if v := sanitizeQueryParam(query.Get(param.name)); v != "" {
+			t, valid := parseTimeParam(v)
+			if !valid {
+				BadRequest(w, fmt.Sprintf("Invalid %s parameter. Must be a date (YYYY-MM-DD) or an RFC 3339 timestamp.", param.name), nil)
+				return filter, 0, 0, false
+			}
+			*param.dest = &t
 		}
-		albumID = &aid
+	}
+	if filter.TakenAfter != nil && filter.TakenBefore != nil && !filter.TakenAfter.Before(*filter.TakenBefore) {
+		BadRequest(w, "taken_after must be before taken_before.", nil)
+		return filter, 0, 0, false
 	}
 
-	limit := DefaultLimit
+	limit, offset, ok = parsePageParams(w, r)
+	return filter, limit, offset, ok
+}
+
+// parseTimeParam parses a query parameter holding an RFC 3339 timestamp or
+// a date, which is taken as midnight UTC
+func parseTimeParam(s string) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// parseNeedsParam parses and validates the needs query parameter, which
+// picks what photos in the needs-metadata queue lack: a title (the
+// default), a description, or either. On failure it sends a 400 response
+// and returns false.
+func parseNeedsParam(w http.ResponseWriter, r *http.Request, filter *db.PhotoFilter) bool {
+	needs := strings.ToLower(sanitizeQueryParam(r.URL.Query().Get("needs")))
+	if needs != "" && !slices.Contains(db.NeedsModes, needs) {
+		BadRequest(w, fmt.Sprintf("Invalid needs parameter. Must be one of: %s.", strings.Join(db.NeedsModes, ", ")), nil)
+		return false
+	}
+	filter.Needs = needs
+	return true
+}
+
+// parseSortParams parses and validates the sort, direction and seed query
+// parameters ordering the needs-metadata queue. A random sort without a
+// seed gets a new one. On failure it sends a 400 response and returns
+// false.
+func parseSortParams(w http.ResponseWriter, r *http.Request, filter *db.PhotoFilter) bool {
+	query := r.URL.Query()
+
+	field := strings.ToLower(sanitizeQueryParam(query.Get("sort")))
+	if field != "" && !slices.Contains(db.SortFields, field) {
+		BadRequest(w, fmt.Sprintf("Invalid sort parameter. Must be one of: %s.", strings.Join(db.SortFields, ", ")), nil)
+		return false
+	}
+
+	direction := strings.ToLower(sanitizeQueryParam(query.Get("direction")))
+	if direction != "" && direction != db.SortAscending && direction != db.SortDescending {
+		BadRequest(w, fmt.Sprintf("Invalid direction parameter. Must be %q or %q.", db.SortAscending, db.SortDescending), nil)
+		return false
+	}
+
+	var seed int64
+	if s := sanitizeQueryParam(query.Get("seed")); s != "" {
+		parsed, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || parsed < 1 || parsed > db.MaxSortSeed {
+			BadRequest(w, fmt.Sprintf("Invalid seed parameter. Must be a number between 1 and %d.", db.MaxSortSeed), nil)
+			return false
+		}
+		seed = parsed
+	}
+	if field == db.SortRandom && seed == 0 {
+		seed = rand.Int64N(db.MaxSortSeed) + 1
+	}
+
+	filter.Sort = db.PhotoSort{Field: field, Direction: direction, Seed: seed}
+	return true
+}
+
+// parseAfterParam parses and validates the after query parameter, a photo
+// ID from which the needs-metadata queue continues, as an alternative to
+// offset. On failure it sends a 400 response and returns false.
+func (h *PhotoHandler) parseAfterParam(w http.ResponseWriter, r *http.Request, filter *db.PhotoFilter, offset int) bool {
+	after := sanitizeQueryParam(r.URL.Query().Get("after"))
+	if after == "" {
+		return true
+	}
+	if offset > 0 {
+		BadRequest(w, "The after and offset parameters cannot be combined.", nil)
+		return false
+	}
+	if !validatePhotoID(after) {
+		BadRequest(w, "Invalid after parameter. Must be a photo ID.", nil)
+		return false
+	}
+
+	// The cursor photo's sort values place the page, so it must still exist
+	photo, err := h.db.GetPhotoByID(after)
+	if err != nil {
+		DatabaseError(w, "get queue cursor photo", err)
+		return false
+	}
+	if photo == nil {
+		BadRequest(w, "The photo in the after parameter no longer exists. Reload the queue from the first page.", nil)
+		return false
+	}
+
+	filter.Sort.After = after
+	return true
+}
+
+// parsePageParams parses and validates the limit and offset query
+// parameters. On failure it sends a 400 response and returns ok == false.
+func parsePageParams(w http.ResponseWriter, r *http.Request) (limit, offset int, ok bool) {
+	query := r.URL.Query()
+
+	limit = DefaultLimit
 	if l := sanitizeQueryParam(query.Get("limit")); l != "" {
 		if parsed, err := strconv.Atoi(l); err == nil {
 			limit = validateLimit(parsed)
 		} else {
 			BadRequest(w, fmt.Sprintf("Invalid limit parameter. Must be a number between 1 and %d.", MaxLimit), nil)
-			return
+			return 0, 0, false
 		}
 	}
 
-	offset := 0
+	offset = 0
 	if o := sanitizeQueryParam(query.Get("offset")); o != "" {
 		if parsed, err := strconv.Atoi(o); err == nil {
 			offset = validateOffset(parsed)
 		} else {
 			BadRequest(w, "Invalid offset parameter. Must be a non-negative number.", nil)
-			return
+			return 0, 0, false
 		}
 	}
 
-	photos, err := h.db.GetPhotosNeedingMetadata(albumID, limit, offset)
+	return limit, offset, true
+}
+
+// formatFilter describes a photo filter for log messages
+func formatFilter(filter db.PhotoFilter) string {
+	albumID := "<none>"
+	if filter.AlbumID != nil {
+		albumID = *filter.AlbumID
+	}
+	s := fmt.Sprintf("album_id=%s public=%t", albumID, filter.PublicOnly)
+	if filter.IDs != nil {
+		s += fmt.Sprintf(" ids=%d", len(filter.IDs))
+	}
+	if filter.TakenAfter != nil {
+		s += " taken_after=" + filter.TakenAfter.Format(time.RFC3339)
+	}
+	if filter.TakenBefore != nil {
+		s += " taken_before=" + filter.TakenBefore.Format(time.RFC3339)
+	}
+	if filter.Needs != "" {
+		s += " needs=" + filter.Needs
+	}
+	return s
+}
+
+// applyColorFilter restricts filter to photos whose dominant color is in
+// the family named by the color query parameter, if given. Photos whose
+// color hasn't been computed yet don't match. On failure it sends a 400
+// response and returns false.
+func applyColorFilter(w http.ResponseWriter, r *http.Request, store *sidecar.Store, filter *db.PhotoFilter) bool {
+	family := strings.ToLower(sanitizeQueryParam(r.URL.Query().Get("color")))
+	if family == "" {
+		return true
+	}
+	if !slices.Contains(placeholder.Families, family) {
+		BadRequest(w, fmt.Sprintf("Invalid color parameter. Must be one of: %s.", strings.Join(placeholder.Families, ", ")), nil)
+		return false
+	}
+
+	filter.IDs = []string{}
+	for id := range store.Photos(func(_ string, p sidecar.PhotoState) bool {
+		c, err := placeholder.ParseHex(p.DominantColor)
+		return err == nil && c.Family() == family
+	}) {
+		filter.IDs = append(filter.IDs, id)
+	}
+	return true
+}
+
+// GetPhotosForAIReview handles GET requests for the re-review queue: photos
+// whose current title is an unedited AI suggestion that no one has approved
+func (h *PhotoHandler) GetPhotosForAIReview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w)
+		return
+	}
+
+	filter, limit, offset, ok := parseQueueParams(w, r)
+	if !ok || !restrictFilter(w, r, h.db, &filter) || !applyColorFilter(w, r, h.sidecar, &filter) {
+		return
+	}
+	applyExclusions(h.sidecar, &filter)
+
+	photoResponses, hasMore, err := h.aiReviewPhotos(filter, limit, offset)
 	if err != nil {
-		log.Printf("Failed to get photos needing metadata (album_id=%v, limit=%d, offset=%d): %v", albumID, limit, offset, err)
+		log.Printf("Failed to get photos for AI review (filter=%s, limit=%d, offset=%d): %v", formatFilter(filter), limit, offset, err)
 		InternalServerError(w, "Failed to retrieve photos. Please try again.")
 		return
 	}
 
-	// Convert to response format
+	total, err := h.db.CountPhotosByIDs(aiReviewIDs(h.sidecar), filter)
+	if err != nil {
+		log.Printf("Failed to count photos for AI review (filter=%s): %v", formatFilter(filter), err)
+		InternalServerError(w, "Failed to retrieve photos. Please try again.")
+		return
+	}
+
+	response := queuePage(photoResponses, total, limit, offset, hasMore)
+
+	setPagination(w, response.pagination())
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// SearchPhotos handles GET requests to find photos whose title, description
+// or album title contains every word of ?q=, ignoring case, whether or not
+// they need metadata. album_id, public, limit and offset work as for the
+// queues.
+func (h *PhotoHandler) SearchPhotos(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w)
+		return
+	}
+
+	search := strings.TrimSpace(r.URL.Query().Get("q"))
+	if search == "" {
+		BadRequest(w, "Missing q parameter.", nil)
+		return
+	}
+	if textLength(search) > MaxTitleLength {
+		BadRequest(w, fmt.Sprintf("Search query is too long. Maximum %d characters.", MaxTitleLength), nil)
+		return
+	}
+
+	filter, limit, offset, ok := parseQueueParams(w, r)
+	if !ok || !restrictFilter(w, r, h.db, &filter) {
+		return
+	}
+	filter.Search = search
+
+	// Fetch one more photo than requested to tell whether more follow
+	photos, err := h.db.GetPhotos(filter, limit+1, offset)
+	if err != nil {
+		log.Printf("Failed to search photos (q=%q, filter=%s, limit=%d, offset=%d): %v", search, formatFilter(filter), limit, offset, err)
+		InternalServerError(w, "Failed to search photos. Please try again.")
+		return
+	}
+	hasMore := len(photos) > limit
+	if hasMore {
+		photos = photos[:limit]
+	}
+
+	total, err := h.db.CountPhotos(filter)
+	if err != nil {
+		log.Printf("Failed to count photo search results (q=%q, filter=%s): %v", search, formatFilter(filter), err)
+		InternalServerError(w, "Failed to search photos. Please try again.")
+		return
+	}
+
 	photoResponses := make([]models.PhotoResponse, len(photos))
-	for i, photo := range photos {
-		photoResponses[i] = photo.ToPhotoResponse(h.lycheeBaseURL)
+	for i := range photos {
+		photoResponses[i] = h.photoResponse(&photos[i])
 	}
 
+	response := queuePage(photoResponses, total, limit, offset, hasMore)
+
+	setPagination(w, response.pagination())
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// queuePage returns the response for a page of a photo queue holding
+// total photos
+func queuePage(photos []models.PhotoResponse, total, limit, offset int, hasMore bool) PhotosNeedingMetadataResponse {
 	response := PhotosNeedingMetadataResponse{
-		Photos: photoResponses,
-		Total:  len(photoResponses),
+		Photos:  photos,
+		Total:   total,
+		Limit:   limit,
+		Offset:  offset,
+		HasMore: hasMore,
 	}
+	if hasMore {
+		response.NextOffset = offset + limit
+	}
+	return response
+}
+
+// pagination describes the page for a versioned API response
+func (r PhotosNeedingMetadataResponse) pagination() Pagination {
+	p := Pagination{Limit: r.Limit, Offset: r.Offset, Total: &r.Total, HasMore: r.HasMore, NextAfter: r.NextAfter}
+	if r.HasMore {
+		p.NextOffset = &r.NextOffset
+	}
+	return p
+}
+
+// aiReviewIDs returns the IDs of the photos in the re-review queue, before
+// filtering
+func aiReviewIDs(store *sidecar.Store) []string {
+	states := store.Photos(func(_ string, p sidecar.PhotoState) bool {
+		return p.NeedsTitleReview()
+	})
+	ids := make([]string, 0, len(states))
+	for id := range states {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// aiReviewPhotos returns a page of the re-review queue, and whether more
+// photos follow it
+func (h *PhotoHandler) aiReviewPhotos(filter db.PhotoFilter, limit, offset int) ([]models.PhotoResponse, bool, error) {
+	states := h.sidecar.Photos(func(_ string, p sidecar.PhotoState) bool {
+		return p.NeedsTitleReview()
+	})
+	ids := make([]string, 0, len(states))
+	for id := range states {
+		ids = append(ids, id)
+	}
+
+	photos, err := h.db.GetPhotosByIDs(ids, filter, limit+1, offset)
+	if err != nil {
+		return nil, false, err
+	}
+	hasMore := len(photos) > limit
+	if hasMore {
+		photos = photos[:limit]
+	}
+
+	// Don't offer photos edited in Lychee since the AI title was saved,
+	// even if the background reconciliation hasn't flagged them yet
+	photoResponses := make([]models.PhotoResponse, 0, len(photos))
+	for i := range photos {
+		if states[photos[i].ID].EditedExternally(photos[i].UpdatedAt) {
+			h.flagExternalEdit(photos[i].ID, photos[i].UpdatedAt)
+			continue
+		}
+		photoResponses = append(photoResponses, h.photoResponse(&photos[i]))
+	}
+	return photoResponses, hasMore, nil
+}
+
+// Queue names accepted by the next parameter of UpdatePhoto, matching the
+// queue endpoints' paths
+const (
+	queueNeedsMetadata = "needsmetadata"
+	queueAIReview      = "aireview"
+)
+
+// nextInQueue returns the first photo in the named queue other than
+// excludeID, or nil if there is none
+func (h *PhotoHandler) nextInQueue(queue string, filter db.PhotoFilter, excludeID string) (*models.PhotoResponse, error) {
+	// The excluded photo may still be at the head of the queue, e.g. after
+	// only its album was changed
+	const limit = 2
+	applyExclusions(h.sidecar, &filter)
+
+	var candidates []models.PhotoResponse
+	switch queue {
+	case queueNeedsMetadata:
+		photos, err := h.db.GetPhotosNeedingMetadata(filter, limit, 0)
+		if err != nil {
+			return nil, err
+		}
+		for i := range photos {
+			candidates = append(candidates, h.photoResponse(&photos[i]))
+		}
+	case queueAIReview:
+		photos, _, err := h.aiReviewPhotos(filter, limit, 0)
+		if err != nil {
+			return nil, err
+		}
+		candidates = photos
+	default:
+		return nil, fmt.Errorf("unknown queue %q", queue)
+	}
+
+	for i := range candidates {
+		if candidates[i].ID != excludeID {
+			return &candidates[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// flagExternalEdit records that a photo was edited directly in Lychee,
+// re-checking against the latest sidecar state
+func (h *PhotoHandler) flagExternalEdit(photoID string, lycheeUpdatedAt time.Time) {
+	now := time.Now().UTC()
+	err := h.sidecar.UpdatePhoto(photoID, func(state *sidecar.PhotoState) {
+		if state.ExternalEditAt == nil && state.EditedExternally(lycheeUpdatedAt) {
+			state.ExternalEditAt = &now
+		}
+	})
+	if err != nil {
+		log.Printf("Failed to flag external edit of photo %s: %v", photoID, err)
+	}
+}
+
+// ApproveTitle handles POST requests marking a photo's AI-written title as
+// reviewed by a person, removing it from the re-review queue
+func (h *PhotoHandler) ApproveTitle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w)
+		return
+	}
+
+	photoID, valid := extractPhotoIDFromPath(r.URL.Path)
+	if !valid {
+		InvalidID(w, "photo ID")
+		return
+	}
+
+	if !checkPhotoAccess(w, r, h.db, photoID) {
+		return
+	}
+
+	state, ok := h.sidecar.Photo(photoID)
+	if !ok || state.TitleProvenance != models.ProvenanceAI {
+		NotFound(w, fmt.Sprintf("Photo '%s' does not have an AI-written title", photoID))
+		return
+	}
+	if state.ExternalEditAt != nil {
+		Conflict(w, fmt.Sprintf("Photo '%s' was edited in Lychee after its AI title was saved", photoID))
+		return
+	}
+
+	now := time.Now().UTC()
+	if err := h.sidecar.UpdatePhoto(photoID, func(state *sidecar.PhotoState) {
+		state.TitleReviewedAt = &now
+		state.TitleReviewedBy = auth.Actor(r.Context())
+	}); err != nil {
+		log.Printf("Failed to record title approval for photo %s: %v", photoID, err)
+		InternalServerError(w, "Failed to record title approval. Please try again.")
+		return
+	}
+	h.publish(r.Context(), feed.EventApproved, photoID)
 
 	w.Header().Set("Content-Type", constants.ContentTypeJSON)
-	_ = json.NewEncoder(w).Encode(response)
+	_ = json.NewEncoder(w).Encode(struct {
+		Success bool `json:"success"`
+	}{Success: true})
 }
 
 // GetPhotoByID handles GET requests to retrieve a specific photo by ID
@@ -111,6 +695,10 @@ func (h *PhotoHandler) GetPhotoByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !checkPhotoAccess(w, r, h.db, photoID) {
+		return
+	}
+
 	photo, err := h.db.GetPhotoByID(photoID)
 	if err != nil {
 		DatabaseError(w, fmt.Sprintf("get photo by ID %s", photoID), err)
@@ -122,7 +710,7 @@ func (h *PhotoHandler) GetPhotoByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := photo.ToPhotoResponse(h.lycheeBaseURL)
+	response := h.photoResponse(photo)
 
 	w.Header().Set("Content-Type", constants.ContentTypeJSON)
 	_ = json.NewEncoder(w).Encode(response)
@@ -130,147 +718,277 @@ func (h *PhotoHandler) GetPhotoByID(w http.ResponseWriter, r *http.Request) {
 
 func (h *PhotoHandler) UpdatePhoto(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPut {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		MethodNotAllowed(w)
 		return
 	}
 
 	// Extract and validate photo ID from URL path
 	photoID, valid := extractPhotoIDFromPath(r.URL.Path)
 	if !valid {
-		w.Header().Set("Content-Type", constants.ContentTypeJSON)
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(ErrorResponse{
-			Error: "Invalid photo ID format. Must be 1-64 characters, alphanumeric with underscores and hyphens only.",
-		})
+		InvalidID(w, "photo ID")
 		return
 	}
 
 	// Parse and validate JSON input
 	var update models.PhotoUpdate
 	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
-		w.Header().Set("Content-Type", constants.ContentTypeJSON)
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(ErrorResponse{
-			Error: fmt.Sprintf("Invalid JSON format: %v", err),
-		})
+		InvalidJSON(w, err)
 		return
 	}
 
 	// Validate and sanitize the update data
 	if validationErrors := ValidatePhotoUpdate(&update); len(validationErrors) > 0 {
-		w.Header().Set("Content-Type", constants.ContentTypeJSON)
-		w.WriteHeader(http.StatusBadRequest)
-		errorMessages := make([]string, len(validationErrors))
-		for i, err := range validationErrors {
-			errorMessages[i] = err.Error()
-		}
-		_ = json.NewEncoder(w).Encode(map[string]interface{}{
-			"error":   "Validation failed",
-			"details": errorMessages,
-		})
+		ValidationFailed(w, validationErrors)
+		return
+	}
+
+	// Respect the Lychee user's permissions for both the photo and any
+	// album it is being moved to
+	if !checkPhotoAccess(w, r, h.db, photoID) {
+		return
+	}
+	if update.AlbumID != nil && *update.AlbumID != "" && !checkAlbumAccess(w, r, h.db, *update.AlbumID) {
 		return
 	}
 
-	// Update the photo
+	// Updating a missing photo would change nothing in Lychee, but would
+	// still record provenance for it in the sidecar store
+	times, err := h.db.GetPhotoUpdateTimes([]string{photoID})
+	if err != nil {
+		DatabaseError(w, fmt.Sprintf("get photo by ID %s", photoID), err)
+		return
+	}
+	if _, ok := times[photoID]; !ok {
+		NotFound(w, fmt.Sprintf("Photo with ID '%s' not found", photoID))
+		return
+	}
+
+	// With ?next=needsmetadata or ?next=aireview, the response includes the
+	// next photo in that queue, filtered by the queue endpoints' parameters
+	nextQueue := r.URL.Query().Get("next")
+	var nextFilter db.PhotoFilter
+	if nextQueue != "" {
+		if nextQueue != queueNeedsMetadata && nextQueue != queueAIReview {
+			BadRequest(w, fmt.Sprintf("Invalid next parameter. Must be %q or %q.", queueNeedsMetadata, queueAIReview), nil)
+			return
+		}
+		var ok bool
+		if nextFilter, _, _, ok = parseQueueParams(w, r); !ok || !restrictFilter(w, r, h.db, &nextFilter) || !applyColorFilter(w, r, h.sidecar, &nextFilter) {
+			return
+		}
+		if nextQueue == queueNeedsMetadata && (!parseNeedsParam(w, r, &nextFilter) || !parseSortParams(w, r, &nextFilter)) {
+			return
+		}
+	}
+
+	// Provenance reflects the fields the client set, not the description
+	// rewritten to carry a change note
+	provenanceUpdate := update
+
+	// Record title provenance in the description if enabled
+	if h.opts.ChangeNotes && update.Title != nil {
+		if err := h.addChangeNote(photoID, &update); err != nil {
+			log.Printf("Failed to add change note for photo %s: %v", photoID, err)
+			InternalServerError(w, "Failed to update photo. Please try again.")
+			return
+		}
+	}
+
+	// Update the photo, keeping its current metadata to revert to
+	previous := h.previousMetadata(r.Context(), map[string]models.PhotoUpdate{photoID: update})
 	if err := h.db.UpdatePhoto(photoID, update); err != nil {
-		log.Printf("Failed to update photo %s: %v", photoID, err)
-		w.Header().Set("Content-Type", constants.ContentTypeJSON)
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(ErrorResponse{
-			Error: "Failed to update photo. Please try again.",
-		})
+		DatabaseError(w, fmt.Sprintf("update of photo %s", photoID), err)
 		return
 	}
 
-	// Get updated photo
+	// Record provenance of the new values; Lychee itself has been updated
+	// at this point, so a failure here is logged rather than reported
+	if err := h.recordProvenance(r.Context(), photoID, provenanceUpdate, previous[photoID]); err != nil {
+		log.Printf("Failed to record provenance for photo %s: %v", photoID, err)
+	}
+	if update.Title != nil {
+		h.publish(r.Context(), feed.EventTitled, photoID)
+	} else if !update.Empty() {
+		h.publish(r.Context(), feed.EventUpdated, photoID)
+	}
+
+	// Get updated photo, which may have been deleted in the meantime
 	photo, err := h.db.GetPhotoByID(photoID)
 	if err != nil {
 		log.Printf("Failed to get updated photo %s: %v", photoID, err)
-		w.Header().Set("Content-Type", constants.ContentTypeJSON)
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(ErrorResponse{
-			Error: "Photo updated successfully but failed to retrieve updated data.",
-		})
+		InternalServerError(w, "Photo updated successfully but failed to retrieve updated data.")
+		return
+	}
+	if photo == nil {
+		NotFound(w, fmt.Sprintf("Photo with ID '%s' not found", photoID))
 		return
 	}
 
 	response := struct {
-		Success bool                 `json:"success"`
-		Photo   models.PhotoResponse `json:"photo"`
+		Success bool                  `json:"success"`
+		Photo   models.PhotoResponse  `json:"photo"`
+		Next    *models.PhotoResponse `json:"next,omitempty"`
 	}{
 		Success: true,
-		Photo:   photo.ToPhotoResponse(h.lycheeBaseURL),
+		Photo:   h.photoResponse(photo),
+	}
+
+	// The update has succeeded regardless, so a failure here only omits
+	// the hint and the client falls back to fetching the queue
+	if nextQueue != "" {
+		if response.Next, err = h.nextInQueue(nextQueue, nextFilter, photoID); err != nil {
+			log.Printf("Failed to get next photo in %s queue after updating %s (filter=%s): %v", nextQueue, photoID, formatFilter(nextFilter), err)
+		}
 	}
 
 	w.Header().Set("Content-Type", constants.ContentTypeJSON)
 	_ = json.NewEncoder(w).Encode(response)
 }
 
+// recordProvenance stores how the updated title and description were
+// produced. Values without an explicit source are assumed to be manual.
+// It also records the photo's new updated_at in Lychee, against which
+// later edits made directly in Lychee are detected, and previous, if not
+// nil, as the metadata the update can be reverted to.
+func (h *PhotoHandler) recordProvenance(ctx context.Context, photoID string, update models.PhotoUpdate, previous *sidecar.PreviousMetadata) error {
+	if update.Empty() {
+		return nil
+	}
+
+	var lycheeUpdatedAt *time.Time
+	if times, err := h.db.GetPhotoUpdateTimes([]string{photoID}); err != nil {
+		log.Printf("Failed to get updated_at for photo %s: %v", photoID, err)
+	} else if t, ok := times[photoID]; ok {
+		lycheeUpdatedAt = &t
+	}
+
+	return h.sidecar.UpdatePhoto(photoID, func(state *sidecar.PhotoState) {
+		state.LycheeUpdatedAt = lycheeUpdatedAt
+		state.ExternalEditAt = nil
+		state.UpdatedBy = auth.Actor(ctx)
+		if previous != nil {
+			state.Previous = previous
+		}
+		if update.Title != nil {
+			now := time.Now().UTC()
+			state.TitledAt = &now
+			state.TitleReviewedAt = nil
+			state.TitleReviewedBy = ""
+			state.TitleProvenance = models.ProvenanceManual
+			if update.TitleSource != nil {
+				state.TitleProvenance = *update.TitleSource
+			}
+		}
+		if update.Description != nil {
+			state.DescriptionProvenance = models.ProvenanceManual
+			if update.DescriptionSource != nil {
+				state.DescriptionProvenance = *update.DescriptionSource
+			}
+		} else if update.ClearDescription {
+			state.DescriptionProvenance = ""
+		}
+	})
+}
+
+// applyAITitle saves an AI-generated title as if it had been submitted
+// unchanged by the user
+func (h *PhotoHandler) applyAITitle(ctx context.Context, photoID, title string) error {
+	source := models.ProvenanceAI
+	update := models.PhotoUpdate{Title: &title, TitleSource: &source}
+	provenanceUpdate := update
+
+	if h.opts.ChangeNotes {
+		if err := h.addChangeNote(photoID, &update); err != nil {
+			return fmt.Errorf("failed to add change note: %w", err)
+		}
+	}
+
+	previous := h.previousMetadata(ctx, map[string]models.PhotoUpdate{photoID: update})
+	if err := h.db.UpdatePhoto(photoID, update); err != nil {
+		return err
+	}
+
+	if err := h.recordProvenance(ctx, photoID, provenanceUpdate, previous[photoID]); err != nil {
+		log.Printf("Failed to record provenance for photo %s: %v", photoID, err)
+	}
+	h.publish(ctx, feed.EventTitled, photoID)
+	return nil
+}
+
+// addChangeNote sets update.Description to the new (or current, unless it
+// is being cleared) description with a title provenance note appended. The
+// note is skipped if it would push the description past its maximum length.
+func (h *PhotoHandler) addChangeNote(photoID string, update *models.PhotoUpdate) error {
+	var description string
+	if update.Description != nil {
+		description = *update.Description
+	} else if !update.ClearDescription {
+		photo, err := h.db.GetPhotoByID(photoID)
+		if err != nil {
+			return fmt.Errorf("failed to get photo: %w", err)
+		}
+		if photo == nil {
+			return fmt.Errorf("photo not found")
+		}
+		if photo.Description != nil {
+			description = *photo.Description
+		}
+	}
+
+	source := models.ProvenanceManual
+	if update.TitleSource != nil {
+		source = *update.TitleSource
+	}
+
+	noted := withChangeNote(description, source, time.Now())
+	if textLength(noted) > MaxDescriptionLength {
+		log.Printf("Skipping change note for photo %s: description would exceed %d characters", photoID, MaxDescriptionLength)
+		return nil
+	}
+
+	update.Description = &noted
+	return nil
+}
+
 func (h *PhotoHandler) GenerateAITitle(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		MethodNotAllowed(w)
 		return
 	}
 
-	if h.aiClient == nil {
-		w.Header().Set("Content-Type", constants.ContentTypeJSON)
-		w.WriteHeader(http.StatusServiceUnavailable)
-		_ = json.NewEncoder(w).Encode(ErrorResponse{
-			Error: "AI title generation is not configured. Please check your AI backend configuration.",
-		})
+	if h.aiClient() == nil {
+		AINotConfigured(w)
 		return
 	}
 
 	// Extract and validate photo ID from URL path
 	photoID, valid := extractPhotoIDFromPath(r.URL.Path)
 	if !valid {
-		w.Header().Set("Content-Type", constants.ContentTypeJSON)
-		w.WriteHeader(http.StatusBadRequest)
-		_ = json.NewEncoder(w).Encode(ErrorResponse{
-			Error: "Invalid photo ID format. Must be 1-64 characters, alphanumeric with underscores and hyphens only.",
-		})
+		InvalidID(w, "photo ID")
+		return
+	}
+
+	if !checkPhotoAccess(w, r, h.db, photoID) {
 		return
 	}
 
 	// Get photo details
 	photo, err := h.db.GetPhotoByID(photoID)
 	if err != nil {
-		log.Printf("Failed to get photo by ID %s for AI title generation: %v", photoID, err)
-		w.Header().Set("Content-Type", constants.ContentTypeJSON)
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(ErrorResponse{
-			Error: "Failed to retrieve photo details. Please try again.",
-		})
+		DatabaseError(w, fmt.Sprintf("fetch of photo %s for AI title generation", photoID), err)
 		return
 	}
 
 	if photo == nil {
-		w.Header().Set("Content-Type", constants.ContentTypeJSON)
-		w.WriteHeader(http.StatusNotFound)
-		_ = json.NewEncoder(w).Encode(ErrorResponse{
-			Error: fmt.Sprintf("Photo with ID '%s' not found.", photoID),
-		})
+		NotFound(w, fmt.Sprintf("Photo with ID '%s' not found.", photoID))
 		return
 	}
 
-	// Construct photo URLs
-	photoResponse := photo.ToPhotoResponse(h.lycheeBaseURL)
-
-	// Prefer large URL for AI processing, fall back to original
-	imageURL := photoResponse.LargeURL
-	if imageURL == "" {
-		log.Printf("Large variant not available for photo %s, using original", photoID)
-		imageURL = photoResponse.FullURL
-	}
+	// force=true bypasses the title cache
+	force := r.URL.Query().Get("force") == "true"
 
-	// Validate image URL
-	if imageURL == "" {
-		log.Printf("No image URL available for photo %s", photoID)
-		w.Header().Set("Content-Type", constants.ContentTypeJSON)
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(ErrorResponse{
-			Error: "Photo image URL is not available.",
-		})
+	language, ok := parseLanguageParam(w, r)
+	if !ok {
 		return
 	}
 
@@ -278,58 +996,341 @@ func (h *PhotoHandler) GenerateAITitle(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), constants.AIGenerationTimeout)
 	defer cancel()
 
-	log.Printf("Generating AI title for photo %s using image URL: %s", photoID, imageURL)
-	title, err := h.aiClient.GenerateTitle(ctx, imageURL)
+	result, err := h.titlePhoto(ctx, photo, titleRequest{force: force, language: language})
+	if errors.Is(err, ai.ErrQueueFull) {
+		aiQueueFull(w, err)
+		return
+	}
+	if err != nil {
+		status, code, message := titleError(err)
+		sendProblem(w, status, code, message, nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	_ = json.NewEncoder(w).Encode(result.response())
+}
+
+// GenerateTitleResponse reports a generated AI title
+type GenerateTitleResponse struct {
+	Success bool   `json:"success"`
+	Title   string `json:"title"`
+	Applied bool   `json:"applied"`
+	// Cached is true when the title came from the title cache
+	Cached bool `json:"cached"`
+	// ImageVariant is the size variant the title was generated from
+	ImageVariant string `json:"image_variant"`
+}
+
+// aiUnavailableMessage is shown while the AI guard refuses requests
+const aiUnavailableMessage = "The AI backend is not responding. Please try again in a minute."
+
+// aiQueueFullMessage is shown when the AI limiter's queue is full
+const aiQueueFullMessage = "Too many AI generations are in progress. Please try again shortly."
+
+// acquireGeneration waits for a slot to run an AI generation in, returning
+// a function that releases it. Background work waits however long the
+// queue is; other requests fail with ai.ErrQueueFull once it is full.
+func (h *PhotoHandler) acquireGeneration(ctx context.Context, background bool, onQueued func(position int)) (func(), error) {
+	if background {
+		return h.opts.AILimiter.Wait(ctx)
+	}
+	return h.opts.AILimiter.Acquire(ctx, onQueued)
+}
+
+// aiQueueFull sends a 429 response for a generation refused because the
+// AI limiter's queue is full, with the limiter's state as details
+func aiQueueFull(w http.ResponseWriter, err error) {
+	var details interface{}
+	var queueFull *ai.QueueFullError
+	if errors.As(err, &queueFull) {
+		details = queueFull.LimiterStats
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(constants.AIQueueRetryAfter.Seconds())))
+	sendProblem(w, StatusTooManyRequests, ErrorCodeAIQueueFull, aiQueueFullMessage, details)
+}
+
+// Errors returned by titlePhoto
+var (
+	errAINotConfigured = errors.New("AI title generation is not configured")
+	errAIDisabled      = errors.New("AI title generation is disabled for the photo's album")
+	errNoImageURL      = errors.New("photo image URL is not available")
+	errEmptyTitle      = errors.New("AI generated an empty title")
+)
+
+// titleRequest adjusts titlePhoto
+type titleRequest struct {
+	// apply saves the title to Lychee even if the album isn't set to
+	// auto-apply
+	apply bool
+	// force generates a new title even if one is cached
+	force bool
+	// language overrides the album's and the default title language
+	language string
+	// onToken, if not nil, is called with each piece of the title as it is
+	// generated, when the AI backend supports streaming
+	onToken func(string)
+	// onQueued, if not nil, is called with the request's position in the
+	// queue while it waits for a generation slot
+	onQueued func(position int)
+	// background waits for a generation slot however long the queue is,
+	// rather than failing with ai.ErrQueueFull
+	background bool
+}
+
+// titleResult is the outcome of titlePhoto
+type titleResult struct {
+	title string
+	// variant is the image size variant the title was generated from
+	variant string
+	// cached is true when the title came from the title cache
+	cached bool
+	// applied reports whether the title was saved to Lychee
+	applied bool
+}
+
+// response returns the API response for the result
+func (r titleResult) response() GenerateTitleResponse {
+	return GenerateTitleResponse{
+		Success:      true,
+		Title:        r.title,
+		Applied:      r.applied,
+		Cached:       r.cached,
+		ImageVariant: r.variant,
+	}
+}
+
+// titlePhoto generates an AI title for photo using its album's AI settings,
+// or returns a cached one. The title is saved to Lychee if req.apply is
+// true or the album is set to auto-apply.
+func (h *PhotoHandler) titlePhoto(ctx context.Context, photo *models.PhotoWithSizeVariants, req titleRequest) (titleResult, error) {
+	photoID := photo.ID
+
+	// Apply per-album AI settings
+	var albumSettings sidecar.AlbumSettings
+	if photo.AlbumID != nil {
+		albumSettings = h.sidecar.Album(*photo.AlbumID)
+	}
+	if albumSettings.Excluded {
+		return titleResult{}, errAIDisabled
+	}
+	titleOpts := ai.TitleOptions{
+		Style:    h.titleStyle(ctx, albumSettings),
+		Language: h.titleLanguage(ctx, albumSettings, req.language),
+	}
+	if h.opts.LocationInPrompt {
+		titleOpts.Location = h.photoLocation(ctx, photo)
+	}
+	if h.opts.DateInPrompt && h.opts.Locale != nil && photo.TakenAt != nil {
+		titleOpts.TakenOn = h.opts.Locale.FormatDate(*photo.TakenAt)
+	}
 
-	// If large URL failed, try with original as fallback
-	if err != nil && photoResponse.LargeURL != "" && imageURL == photoResponse.LargeURL {
-		log.Printf("Failed with large variant, retrying with original for photo %s: %v", photoID, err)
-		imageURL = photoResponse.FullURL
-		if imageURL != "" {
-			title, err = h.aiClient.GenerateTitle(ctx, imageURL)
+	var cacheKey string
+	if h.opts.TitleCache != nil {
+		cacheKey = titlecache.Key(photo.Checksum, titleOpts)
+		if entry, ok := h.opts.TitleCache.Get(cacheKey); ok && !req.force {
+			log.Printf("Using cached AI title for photo %s: %s", photoID, entry.Title)
+			result := titleResult{title: entry.Title, variant: entry.Variant, cached: true}
+			result.applied = h.maybeApplyAITitle(ctx, photoID, entry.Title, req.apply || albumSettings.AutoApply)
+			return result, nil
 		}
 	}
+
+	// Construct photo URLs
+	variants := aiImageVariants(photo.ToPhotoResponse(h.imageURLPattern))
+	if len(variants) == 0 {
+		log.Printf("No image URL available for photo %s", photoID)
+		return titleResult{}, errNoImageURL
+	}
+
+	release, err := h.acquireGeneration(ctx, req.background, req.onQueued)
 	if err != nil {
-		log.Printf("Failed to generate AI title for photo %s: %v", photoID, err)
-		w.Header().Set("Content-Type", constants.ContentTypeJSON)
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(ErrorResponse{
-			Error: "Failed to generate AI title. Please check your network connection and try again.",
+		return titleResult{}, err
+	}
+	defer release()
+
+	// Generate with the client in use now, even if the backend is reloaded
+	// meanwhile
+	client := h.aiClient()
+	if client == nil {
+		return titleResult{}, errAINotConfigured
+	}
+
+	ctx = ai.WithStorageDisk(ctx, photo.StorageDisk())
+
+	// Try each variant in turn while the image itself can't be fetched
+	// (e.g. a missing original); other failures are retried by the guard
+	var title, variant string
+	for _, v := range variants {
+		variant = v.name
+		log.Printf("Generating AI title for photo %s using %s image URL: %s", photoID, v.name, v.url)
+		err = h.opts.AIUsage.Track(ctx, "title", func(ctx context.Context) error {
+			return h.opts.AIGuard.Do(ctx, func(ctx context.Context) error {
+				var err error
+				streamer, ok := client.(ai.TitleStreamer)
+				if !ok || req.onToken == nil {
+					title, err = client.GenerateTitle(ctx, v.url, titleOpts)
+					return err
+				}
+
+				// Text already streamed to the client can't be taken back,
+				// so a stream that fails partway isn't retried
+				streamed := false
+				title, err = streamer.StreamTitle(ctx, v.url, titleOpts, func(text string) {
+					streamed = true
+					req.onToken(text)
+				})
+				if err != nil && streamed {
+					return ai.Permanent(err)
+				}
+				return err
+			})
 		})
-		return
+		if err == nil || !errors.Is(err, ai.ErrImageUnavailable) {
+			break
+		}
+		log.Printf("Failed to fetch %s variant for photo %s: %v", v.name, photoID, err)
+	}
+	if err != nil {
+		log.Printf("Failed to generate AI title for photo %s: %v", photoID, err)
+		return titleResult{variant: variant}, err
 	}
 
-	// Sanitize and validate the generated title
-	title = sanitizeText(strings.Trim(strings.TrimSpace(title), `"'`))
+	// Sanitize and validate the generated title. Length is checked before
+	// HTML escaping, as for user-entered titles, so truncation can't split
+	// an entity.
+	title = strings.Trim(strings.TrimSpace(title), `"'`)
 	// Strip trailing period if present
 	title = strings.TrimSuffix(title, ".")
+	if n := textLength(title); n > MaxTitleLength {
+		log.Printf("AI generated title too long for photo %s: %d characters", photoID, n)
+		title = truncateTitle(title, MaxTitleLength)
+	}
+	title = sanitizeText(title)
 	if title == "" {
 		log.Printf("AI generated empty title for photo %s", photoID)
-		w.Header().Set("Content-Type", constants.ContentTypeJSON)
-		w.WriteHeader(http.StatusInternalServerError)
-		_ = json.NewEncoder(w).Encode(ErrorResponse{
-			Error: "AI generated an empty title. Please try again.",
-		})
-		return
+		return titleResult{variant: variant}, errEmptyTitle
 	}
 
-	// Validate the generated title length
-	if len(title) > MaxTitleLength {
-		log.Printf("AI generated title too long for photo %s: %d characters", photoID, len(title))
-		// Truncate to max length
-		title = title[:MaxTitleLength]
+	log.Printf("Successfully generated AI title for photo %s: %s", photoID, title)
+
+	if cacheKey != "" {
+		entry := titlecache.Entry{Title: title, Variant: variant, CreatedAt: time.Now().UTC()}
+		if err := h.opts.TitleCache.Put(cacheKey, entry); err != nil {
+			log.Printf("Failed to cache AI title for photo %s: %v", photoID, err)
+		}
 	}
 
-	log.Printf("Successfully generated AI title for photo %s: %s", photoID, title)
+	// Save the title right away if requested or the album is set to auto-apply
+	result := titleResult{title: title, variant: variant}
+	result.applied = h.maybeApplyAITitle(ctx, photoID, title, req.apply || albumSettings.AutoApply)
+	return result, nil
+}
 
-	response := struct {
-		Success bool   `json:"success"`
-		Title   string `json:"title"`
-	}{
-		Success: true,
-		Title:   title,
+// titleLanguage returns the language AI output should be written in: the
+// request's, else the album's, else the preference of the person making
+// the request, else the configured default
+func (h *PhotoHandler) titleLanguage(ctx context.Context, albumSettings sidecar.AlbumSettings, requested string) string {
+	switch {
+	case requested != "":
+		return requested
+	case albumSettings.Language != "":
+		return albumSettings.Language
 	}
+	if prefs := h.userPreferences(ctx); prefs.Language != "" {
+		return prefs.Language
+	}
+	return h.opts.TitleLanguage
+}
 
-	w.Header().Set("Content-Type", constants.ContentTypeJSON)
-	_ = json.NewEncoder(w).Encode(response)
+// titleStyle returns the title style: the album's, or else the preference
+// of the person making the request
+func (h *PhotoHandler) titleStyle(ctx context.Context, albumSettings sidecar.AlbumSettings) string {
+	if albumSettings.Style != "" {
+		return albumSettings.Style
+	}
+	return h.userPreferences(ctx).Style
+}
+
+// userPreferences returns the preferences of the person making a request,
+// if they can be identified
+func (h *PhotoHandler) userPreferences(ctx context.Context) sidecar.UserPreferences {
+	actor := auth.Actor(ctx)
+	if actor == "" {
+		return sidecar.UserPreferences{}
+	}
+	return h.sidecar.User(actor)
+}
+
+// parseLanguageParam parses the optional language query parameter
+// overriding the title language. On failure it sends a 400 response and
+// returns ok == false.
+func parseLanguageParam(w http.ResponseWriter, r *http.Request) (language string, ok bool) {
+	language = strings.TrimSpace(r.URL.Query().Get("language"))
+	if err := validatePromptText(language, MaxAlbumLanguageLength); err != nil {
+		BadRequest(w, fmt.Sprintf("Invalid language parameter: %s.", err), nil)
+		return "", false
+	}
+	return language, true
+}
+
+// maybeApplyAITitle saves an AI title to Lychee if apply is true, reporting
+// whether it was saved
+func (h *PhotoHandler) maybeApplyAITitle(ctx context.Context, photoID, title string, apply bool) bool {
+	if !apply {
+		return false
+	}
+	if err := h.applyAITitle(ctx, photoID, title); err != nil {
+		log.Printf("Failed to apply AI title for photo %s: %v", photoID, err)
+		return false
+	}
+	log.Printf("Applied AI title for photo %s", photoID)
+	return true
+}
+
+// TitlePhoto generates an AI title for the photo with the given ID, saving
+// it if apply is true or the album is set to auto-apply. It implements
+// jobs.Titler.
+func (h *PhotoHandler) TitlePhoto(ctx context.Context, photoID string, apply bool) (title string, applied bool, err error) {
+	if h.aiClient() == nil {
+		return "", false, errAINotConfigured
+	}
+
+	photo, err := h.db.GetPhotoByID(photoID)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get photo: %w", err)
+	}
+	if photo == nil {
+		return "", false, errors.New("photo not found")
+	}
+
+	result, err := h.titlePhoto(ctx, photo, titleRequest{apply: apply, background: true})
+	return result.title, result.applied, err
+}
+
+// imageVariant is a candidate image for AI title generation
+type imageVariant struct {
+	name string
+	url  string
+}
+
+// aiImageVariants lists a photo's available images in the order they should
+// be tried for AI title generation: the large variant, which is plenty for
+// the model, then the original, then smaller variants for photos whose
+// larger files are missing
+func aiImageVariants(photo models.PhotoResponse) []imageVariant {
+	candidates := []imageVariant{
+		{name: "large", url: photo.LargeURL},
+		{name: "original", url: photo.FullURL},
+		{name: "medium", url: photo.MediumURL},
+		{name: "thumbnail", url: photo.ThumbnailURL},
+	}
+
+	variants := make([]imageVariant, 0, len(candidates))
+	for _, c := range candidates {
+		if c.url != "" {
+			variants = append(variants, c)
+		}
+	}
+	return variants
 }
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/ai"
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/db"
+)
+
+const (
+	progressBadgeLabel = "untitled photos"
+
+	// Badge colors, matching the shields.io named palette
+	badgeColorGreen  = "#4c1"
+	badgeColorYellow = "#dfb317"
+	badgeColorOrange = "#fe7d37"
+
+	// Counts at or above this threshold are shown in orange rather than yellow
+	badgeOrangeThreshold = 100
+)
+
+// AI backend status values reported by the dashboard widget
+const (
+	AIStatusDisabled    = "disabled"
+	AIStatusOK          = "ok"
+	AIStatusUnavailable = "unavailable"
+)
+
+// AIStatus describes the configured AI backend for status reporting
+type AIStatus struct {
+	// Backend is the configured backend name, or empty if AI is not configured
+	Backend string
+	// Available is true if the backend was initialized successfully
+	Available bool
+}
+
+// aiStatusOf returns the status of the AI backend held by holder
+func aiStatusOf(holder *ai.Holder) AIStatus {
+	return AIStatus{Backend: holder.Backend(), Available: holder.Client() != nil}
+}
+
+// Status returns a short status string for the AI backend
+func (s AIStatus) Status() string {
+	switch {
+	case s.Backend == "":
+		return AIStatusDisabled
+	case s.Available:
+		return AIStatusOK
+	default:
+		return AIStatusUnavailable
+	}
+}
+
+// ProgressHandler serves library hygiene status suitable for embedding in
+// dashboards: a shields.io-style SVG badge, a JSON feed, and a summary for
+// dashboard widgets
+type ProgressHandler struct {
+	db        *db.DB
+	aiBackend *ai.Holder
+}
+
+// NewProgressHandler creates a new ProgressHandler with the provided dependencies
+func NewProgressHandler(database *db.DB, aiBackend *ai.Holder) *ProgressHandler {
+	return &ProgressHandler{
+		db:        database,
+		aiBackend: aiBackend,
+	}
+}
+
+// ProgressResponse is compatible with the shields.io endpoint badge schema
+// (https://shields.io/badges/endpoint-badge), with the raw count included
+type ProgressResponse struct {
+	SchemaVersion  int    `json:"schemaVersion"`
+	Label          string `json:"label"`
+	Message        string `json:"message"`
+	Color          string `json:"color"`
+	UntitledPhotos int    `json:"untitled_photos"`
+}
+
+// GetProgressJSON handles GET requests for the untitled photo count as JSON
+func (h *ProgressHandler) GetProgressJSON(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w)
+		return
+	}
+
+	count, err := h.db.CountPhotosNeedingMetadata(db.PhotoFilter{})
+	if err != nil {
+		DatabaseError(w, "count photos needing metadata", err)
+		return
+	}
+
+	response := ProgressResponse{
+		SchemaVersion:  1,
+		Label:          progressBadgeLabel,
+		Message:        strconv.Itoa(count),
+		Color:          badgeColor(count),
+		UntitledPhotos: count,
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode progress response: %v", err)
+	}
+}
+
+// WidgetResponse is a flat summary intended for the gethomepage.dev
+// "customapi" widget, whose field mappings address top-level keys
+type WidgetResponse struct {
+	UntitledPhotos     int        `json:"untitled_photos"`
+	AlbumsWithUntitled int        `json:"albums_with_untitled"`
+	LastActivity       *time.Time `json:"last_activity"`
+	AIBackend          string     `json:"ai_backend"`
+	AIStatus           string     `json:"ai_status"`
+}
+
+// GetWidget handles GET requests for the dashboard widget summary
+func (h *ProgressHandler) GetWidget(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w)
+		return
+	}
+
+	count, err := h.db.CountPhotosNeedingMetadata(db.PhotoFilter{})
+	if err != nil {
+		DatabaseError(w, "count photos needing metadata", err)
+		return
+	}
+
+	albums, err := h.db.GetAlbumsWithPhotoCounts(db.PhotoFilter{})
+	if err != nil {
+		DatabaseError(w, "get albums with photo counts", err)
+		return
+	}
+
+	lastActivity, err := h.db.GetLastPhotoUpdate()
+	if err != nil {
+		DatabaseError(w, "get last photo update", err)
+		return
+	}
+
+	aiStatus := aiStatusOf(h.aiBackend)
+	response := WidgetResponse{
+		UntitledPhotos:     count,
+		AlbumsWithUntitled: len(albums),
+		LastActivity:       lastActivity,
+		AIBackend:          aiStatus.Backend,
+		AIStatus:           aiStatus.Status(),
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	w.Header().Set("Cache-Control", "no-cache")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode widget response: %v", err)
+	}
+}
+
+// GetBadgeSVG handles GET requests for a shields.io-style SVG badge showing
+// the untitled photo count
+func (h *ProgressHandler) GetBadgeSVG(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w)
+		return
+	}
+
+	count, err := h.db.CountPhotosNeedingMetadata(db.PhotoFilter{})
+	if err != nil {
+		DatabaseError(w, "count photos needing metadata", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", "no-cache")
+	_, _ = w.Write([]byte(renderBadge(progressBadgeLabel, strconv.Itoa(count), badgeColor(count))))
+}
+
+// badgeColor picks a badge color for the given untitled photo count
+func badgeColor(count int) string {
+	switch {
+	case count == 0:
+		return badgeColorGreen
+	case count < badgeOrangeThreshold:
+		return badgeColorYellow
+	default:
+		return badgeColorOrange
+	}
+}
+
+// renderBadge renders a flat shields.io-style badge. Text widths are
+// approximated from character counts, which is close enough for the
+// short labels and numbers used here.
+func renderBadge(label, message, color string) string {
+	const charWidth = 7
+	const padding = 10
+
+	labelWidth := len(label)*charWidth + padding
+	messageWidth := len(message)*charWidth + padding
+	totalWidth := labelWidth + messageWidth
+
+	label = html.EscapeString(label)
+	message = html.EscapeString(message)
+
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">
+<title>%[4]s: %[5]s</title>
+<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>
+<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>
+<g clip-path="url(#r)"><rect width="%[2]d" height="20" fill="#555"/><rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/><rect width="%[1]d" height="20" fill="url(#s)"/></g>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="%[7]d" y="15" fill="#010101" fill-opacity=".3">%[4]s</text><text x="%[7]d" y="14">%[4]s</text>
+<text x="%[8]d" y="15" fill="#010101" fill-opacity=".3">%[5]s</text><text x="%[8]d" y="14">%[5]s</text>
+</g>
+</svg>`, totalWidth, labelWidth, messageWidth, label, message, color, labelWidth/2, labelWidth+messageWidth/2)
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/db"
+	"github.com/cdzombak/lychee-meta-tool/backend/models"
+	"github.com/cdzombak/lychee-meta-tool/backend/sidecar"
+)
+
+// ProvenanceHandler handles HTTP requests for metadata provenance recorded
+// in the sidecar store
+type ProvenanceHandler struct {
+	sidecar *sidecar.Store
+	db      *db.DB
+}
+
+// NewProvenanceHandler creates a new ProvenanceHandler backed by the given
+// sidecar store. The database is used to limit photos to those the
+// request's Lychee user may edit.
+func NewProvenanceHandler(sidecarStore *sidecar.Store, database *db.DB) *ProvenanceHandler {
+	return &ProvenanceHandler{sidecar: sidecarStore, db: database}
+}
+
+// PhotoProvenance is the recorded provenance of a single photo's metadata
+type PhotoProvenance struct {
+	PhotoID               string            `json:"photo_id"`
+	TitleProvenance       models.Provenance `json:"title_provenance,omitempty"`
+	DescriptionProvenance models.Provenance `json:"description_provenance,omitempty"`
+	ExternalEditAt        *time.Time        `json:"external_edit_at,omitempty"`
+	// UpdatedBy is who last saved metadata through the tool, when known
+	UpdatedBy string    `json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ProvenanceResponse represents the response for provenance queries
+type ProvenanceResponse struct {
+	Photos []PhotoProvenance `json:"photos"`
+	Total  int               `json:"total"`
+}
+
+// GetProvenance handles GET requests listing photos by metadata provenance.
+// The optional title and description query parameters (ai, ai_edited or
+// manual) filter on the respective field, updated_by on who last saved
+// metadata, and conflicts=true lists only photos since edited directly in
+// Lychee. Requests bound to a Lychee user only see photos that user may
+// edit.
+func (h *ProvenanceHandler) GetProvenance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w)
+		return
+	}
+
+	query := r.URL.Query()
+	titleFilter, ok := parseProvenanceParam(w, "title", query.Get("title"))
+	if !ok {
+		return
+	}
+	descriptionFilter, ok := parseProvenanceParam(w, "description", query.Get("description"))
+	if !ok {
+		return
+	}
+	updatedBy := strings.TrimSpace(query.Get("updated_by"))
+	conflictsOnly, valid := parseBoolParam(query.Get("conflicts"))
+	if !valid {
+		BadRequest(w, "Invalid conflicts parameter. Must be true or false.", nil)
+		return
+	}
+
+	states := h.sidecar.Photos(func(_ string, p sidecar.PhotoState) bool {
+		if titleFilter != "" && p.TitleProvenance != titleFilter {
+			return false
+		}
+		if descriptionFilter != "" && p.DescriptionProvenance != descriptionFilter {
+			return false
+		}
+		if updatedBy != "" && p.UpdatedBy != updatedBy {
+			return false
+		}
+		if conflictsOnly && p.ExternalEditAt == nil {
+			return false
+		}
+		return p.TitleProvenance != "" || p.DescriptionProvenance != ""
+	})
+
+	userID, ok := lycheeUserID(w, r, h.db)
+	if !ok {
+		return
+	}
+	if userID != nil {
+		ids := make([]string, 0, len(states))
+		for id := range states {
+			ids = append(ids, id)
+		}
+		editable, err := h.db.EditablePhotoIDs(*userID, ids)
+		if err != nil {
+			DatabaseError(w, "check photo permissions", err)
+			return
+		}
+		for id := range states {
+			if !editable[id] {
+				delete(states, id)
+			}
+		}
+	}
+
+	photos := make([]PhotoProvenance, 0, len(states))
+	for id, p := range states {
+		photos = append(photos, PhotoProvenance{
+			PhotoID:               id,
+			TitleProvenance:       p.TitleProvenance,
+			DescriptionProvenance: p.DescriptionProvenance,
+			ExternalEditAt:        p.ExternalEditAt,
+			UpdatedBy:             p.UpdatedBy,
+			UpdatedAt:             p.UpdatedAt,
+		})
+	}
+	sort.Slice(photos, func(i, j int) bool { return photos[i].UpdatedAt.After(photos[j].UpdatedAt) })
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	if err := json.NewEncoder(w).Encode(ProvenanceResponse{Photos: photos, Total: len(photos)}); err != nil {
+		log.Printf("Failed to encode provenance response: %v", err)
+	}
+}
+
+// parseProvenanceParam validates an optional provenance query parameter,
+// sending a 400 response and returning false if it is invalid
+func parseProvenanceParam(w http.ResponseWriter, name, value string) (models.Provenance, bool) {
+	value = sanitizeQueryParam(value)
+	if value == "" {
+		return "", true
+	}
+
+	p := models.Provenance(value)
+	if !p.Valid() {
+		BadRequest(w, "Invalid "+name+" parameter: "+errInvalidProvenance, nil)
+		return "", false
+	}
+	return p, true
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/reconcile"
+)
+
+// ReconcileHandler handles HTTP requests for reconciliation against edits
+// made directly in Lychee
+type ReconcileHandler struct {
+	reconciler *reconcile.Reconciler
+}
+
+// NewReconcileHandler creates a new ReconcileHandler
+func NewReconcileHandler(reconciler *reconcile.Reconciler) *ReconcileHandler {
+	return &ReconcileHandler{reconciler: reconciler}
+}
+
+// ReconcileResponse reports the outcome of a reconciliation pass
+type ReconcileResponse struct {
+	Result *reconcile.Result `json:"result"`
+}
+
+// HandleReconcile dispatches reconciliation requests: GET returns the most
+// recent pass's result, and POST runs a pass immediately
+func (h *ReconcileHandler) HandleReconcile(w http.ResponseWriter, r *http.Request) {
+	var response ReconcileResponse
+
+	switch r.Method {
+	case http.MethodGet:
+		if result, ok := h.reconciler.Last(); ok {
+			response.Result = &result
+		}
+	case http.MethodPost:
+		result, err := h.reconciler.Reconcile()
+		if err != nil {
+			log.Printf("Reconciliation with Lychee failed: %v", err)
+			InternalServerError(w, "Reconciliation failed. Please try again.")
+			return
+		}
+		response.Result = &result
+	default:
+		MethodNotAllowed(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode reconcile response: %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/auth"
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/db"
+	"github.com/cdzombak/lychee-meta-tool/backend/feed"
+	"github.com/cdzombak/lychee-meta-tool/backend/models"
+	"github.com/cdzombak/lychee-meta-tool/backend/sidecar"
+)
+
+// RevertPhotoResponse is the photo after a revert
+type RevertPhotoResponse struct {
+	Success bool                 `json:"success"`
+	Photo   models.PhotoResponse `json:"photo"`
+}
+
+// RevertPhoto handles POST requests restoring a photo's title, description
+// and album to what they were before the tool last changed any of them, so
+// an accidental save or a bad AI title can be undone. The values replaced
+// become the photo's new revert point, so reverting again redoes the
+// change. A photo edited in Lychee since is only reverted with
+// ?force=true.
+func (h *PhotoHandler) RevertPhoto(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w)
+		return
+	}
+
+	photoID, valid := extractPhotoIDFromPath(r.URL.Path)
+	if !valid {
+		InvalidID(w, "photo ID")
+		return
+	}
+
+	force, valid := parseBoolParam(r.URL.Query().Get("force"))
+	if !valid {
+		BadRequest(w, "Invalid force parameter. Must be true or false.", nil)
+		return
+	}
+
+	if !checkPhotoAccess(w, r, h.db, photoID) {
+		return
+	}
+
+	state, ok := h.sidecar.Photo(photoID)
+	if !ok || state.Previous == nil {
+		NotFound(w, fmt.Sprintf("Photo '%s' has no change to revert", photoID))
+		return
+	}
+	previous := state.Previous
+
+	photo, err := h.db.GetPhotoByID(photoID)
+	if err != nil {
+		DatabaseError(w, fmt.Sprintf("get photo by ID %s", photoID), err)
+		return
+	}
+	if photo == nil {
+		NotFound(w, fmt.Sprintf("Photo with ID '%s' not found", photoID))
+		return
+	}
+	if !force && (state.ExternalEditAt != nil || state.EditedExternally(photo.UpdatedAt)) {
+		Conflict(w, fmt.Sprintf("Photo '%s' was edited in Lychee since the tool last saved it; pass force=true to revert anyway", photoID))
+		return
+	}
+
+	update := models.PhotoUpdate{
+		Title:       &previous.Title,
+		TitleSource: &previous.TitleProvenance,
+	}
+	if previous.Description != nil {
+		update.Description = previous.Description
+		update.DescriptionSource = &previous.DescriptionProvenance
+	} else {
+		update.ClearDescription = true
+	}
+	if previous.AlbumID != nil {
+		existing, err := h.db.GetExistingAlbumIDs([]string{*previous.AlbumID})
+		if err != nil {
+			DatabaseError(w, "check albums", err)
+			return
+		}
+		if !existing[*previous.AlbumID] {
+			Conflict(w, fmt.Sprintf("Photo '%s' can't be moved back to album '%s', which no longer exists", photoID, *previous.AlbumID))
+			return
+		}
+		if !checkAlbumAccess(w, r, h.db, *previous.AlbumID) {
+			return
+		}
+		update.AlbumID = previous.AlbumID
+	} else {
+		update.ClearAlbum = true
+	}
+
+	// The metadata being replaced becomes the new revert point
+	current := snapshotMetadata(r.Context(), photo, state)
+	if err := h.db.UpdatePhoto(photoID, update); err != nil {
+		DatabaseError(w, fmt.Sprintf("revert of photo %s", photoID), err)
+		return
+	}
+	log.Printf("Reverted photo %s to its metadata from before %s", photoID, previous.ReplacedAt.Format(time.RFC3339))
+
+	// Lychee has been updated at this point, so failures here are logged
+	// rather than reported
+	if err := h.recordProvenance(r.Context(), photoID, update, current); err != nil {
+		log.Printf("Failed to record provenance for photo %s: %v", photoID, err)
+	}
+	h.publish(r.Context(), feed.EventUpdated, photoID)
+
+	reverted, err := h.db.GetPhotoByID(photoID)
+	if err != nil || reverted == nil {
+		log.Printf("Failed to get reverted photo %s: %v", photoID, err)
+		InternalServerError(w, "Photo reverted successfully but failed to retrieve updated data.")
+		return
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	_ = json.NewEncoder(w).Encode(RevertPhotoResponse{
+		Success: true,
+		Photo:   h.photoResponse(reverted),
+	})
+}
+
+// previousMetadata snapshots the current metadata of the photos whose
+// updates change their title, description or album, before the updates
+// are saved, keyed by photo ID. A failure is logged, and the updates then
+// go ahead without a revert point.
+func (h *PhotoHandler) previousMetadata(ctx context.Context, updates map[string]models.PhotoUpdate) map[string]*sidecar.PreviousMetadata {
+	var ids []string
+	for id, update := range updates {
+		if update.Title != nil || update.Description != nil || update.ClearDescription || update.AlbumID != nil || update.ClearAlbum {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	photos, err := h.db.GetPhotos(db.PhotoFilter{IDs: ids}, 0, 0)
+	if err != nil {
+		log.Printf("Failed to snapshot metadata of %d photo(s) before updating them: %v", len(ids), err)
+		return nil
+	}
+
+	previous := make(map[string]*sidecar.PreviousMetadata, len(photos))
+	for i := range photos {
+		state, _ := h.sidecar.Photo(photos[i].ID)
+		previous[photos[i].ID] = snapshotMetadata(ctx, &photos[i], state)
+	}
+	return previous
+}
+
+// snapshotMetadata returns photo's current title, description and album,
+// with their provenance from state, as replaced now by the request's actor
+func snapshotMetadata(ctx context.Context, photo *models.PhotoWithSizeVariants, state sidecar.PhotoState) *sidecar.PreviousMetadata {
+	return &sidecar.PreviousMetadata{
+		Title:                 photo.Title,
+		Description:           photo.Description,
+		AlbumID:               photo.AlbumID,
+		TitleProvenance:       state.TitleProvenance,
+		DescriptionProvenance: state.DescriptionProvenance,
+		ReplacedAt:            time.Now().UTC(),
+		ReplacedBy:            auth.Actor(ctx),
+	}
+}
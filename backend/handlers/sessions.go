@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/auth"
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+)
+
+// SessionHandler handles HTTP requests opening, ending and administering
+// API sessions
+type SessionHandler struct {
+	store *auth.SessionStore
+}
+
+// NewSessionHandler creates a new SessionHandler backed by the given
+// session store
+func NewSessionHandler(store *auth.SessionStore) *SessionHandler {
+	return &SessionHandler{store: store}
+}
+
+// SessionResponse describes a session, with its secret when it was just
+// opened. The secret is not retrievable afterward.
+type SessionResponse struct {
+	Session auth.Session `json:"session"`
+	Secret  string       `json:"secret,omitempty"`
+}
+
+// SessionsResponse lists the open sessions
+type SessionsResponse struct {
+	Sessions []auth.Session `json:"sessions"`
+}
+
+// HandleSession handles a client's own session: POST opens one with the
+// request's API token, GET describes the session the request was made
+// with, and DELETE ends it
+func (h *SessionHandler) HandleSession(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.openSession(w, r)
+	case http.MethodGet:
+		session, ok := auth.SessionFromContext(r.Context())
+		if !ok {
+			BadRequest(w, "This request wasn't made with a session.", nil)
+			return
+		}
+		writeSession(w, http.StatusOK, SessionResponse{Session: session})
+	case http.MethodDelete:
+		session, ok := auth.SessionFromContext(r.Context())
+		if !ok {
+			BadRequest(w, "This request wasn't made with a session.", nil)
+			return
+		}
+		// The session may have been revoked since the request was authenticated
+		_ = h.store.Revoke(session.ID)
+		log.Printf("Ended session %s of API token %q", session.ID, session.TokenName)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		MethodNotAllowed(w)
+	}
+}
+
+// openSession opens a session with the API token the request was
+// authenticated with. Sessions can't be opened with another session, which
+// would outlive its absolute timeout.
+func (h *SessionHandler) openSession(w http.ResponseWriter, r *http.Request) {
+	token, ok := auth.FromContext(r.Context())
+	if _, viaSession := auth.SessionFromContext(r.Context()); !ok || viaSession {
+		BadRequest(w, "Sessions are opened with an API token in the Authorization header.", nil)
+		return
+	}
+
+	session, secret, err := h.store.Open(token, r.UserAgent())
+	if err != nil {
+		log.Printf("Failed to open session: %v", err)
+		InternalServerError(w, "Failed to open a session. Please try again.")
+		return
+	}
+
+	log.Printf("Opened session %s with API token %q", session.ID, token.Name)
+	writeSession(w, http.StatusCreated, SessionResponse{Session: session, Secret: secret})
+}
+
+// HandleSessions dispatches session administration requests: GET lists the
+// open sessions, and DELETE on /api/admin/sessions/{id} revokes one
+func (h *SessionHandler) HandleSessions(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, SessionsAdminAPIPrefix), "/")
+
+	switch {
+	case id == "" && r.Method == http.MethodGet:
+		w.Header().Set("Content-Type", constants.ContentTypeJSON)
+		if err := json.NewEncoder(w).Encode(SessionsResponse{Sessions: h.store.List()}); err != nil {
+			log.Printf("Failed to encode sessions response: %v", err)
+		}
+	case id != "" && r.Method == http.MethodDelete:
+		if err := h.store.Revoke(id); err != nil {
+			NotFound(w, err.Error())
+			return
+		}
+		log.Printf("Revoked session %s", id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		MethodNotAllowed(w)
+	}
+}
+
+func writeSession(w http.ResponseWriter, status int, resp SessionResponse) {
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("Failed to encode session response: %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/db"
+	"github.com/cdzombak/lychee-meta-tool/backend/feed"
+	"github.com/cdzombak/lychee-meta-tool/backend/models"
+)
+
+// ShiftTakenAtRequest moves the capture times of a set of photos by a fixed
+// offset, e.g. to correct a camera whose clock was left on home time during
+// a trip. Photos are selected by IDs, or by album and capture time.
+type ShiftTakenAtRequest struct {
+	IDs     []string `json:"ids,omitempty"`
+	AlbumID *string  `json:"album_id,omitempty"`
+	// TakenAfter and TakenBefore limit the photos to those taken at or
+	// after and before these times, before shifting
+	TakenAfter  *time.Time `json:"taken_after,omitempty"`
+	TakenBefore *time.Time `json:"taken_before,omitempty"`
+	// Offset is added to each capture time, as a duration such as "-9h"
+	// or "5h30m"
+	Offset string `json:"offset"`
+	// DryRun previews the change without saving it
+	DryRun bool `json:"dry_run"`
+}
+
+// TakenAtShift is one photo's capture time before and after a shift
+type TakenAtShift struct {
+	ID      string    `json:"id"`
+	Title   string    `json:"title"`
+	TakenAt time.Time `json:"taken_at"`
+	Shifted time.Time `json:"shifted_taken_at"`
+}
+
+// ShiftTakenAtResponse lists the photos shifted, or that would be in a dry
+// run. Selected photos without a capture time are left alone and listed in
+// Skipped.
+type ShiftTakenAtResponse struct {
+	Success bool           `json:"success"`
+	DryRun  bool           `json:"dry_run"`
+	Updated int            `json:"updated"`
+	Photos  []TakenAtShift `json:"photos"`
+	Skipped []string       `json:"skipped,omitempty"`
+}
+
+// ShiftTakenAt handles POST requests shifting the capture times of the
+// selected photos by an offset. The photos are updated in a single
+// transaction, so either all of them move or none do.
+func (h *PhotoHandler) ShiftTakenAt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w)
+		return
+	}
+
+	var req ShiftTakenAtRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		InvalidJSON(w, err)
+		return
+	}
+
+	offset, err := time.ParseDuration(req.Offset)
+	if err != nil || offset == 0 || offset.Abs() > constants.MaxTakenAtShift {
+		BadRequest(w, fmt.Sprintf("offset must be a non-zero duration such as \"-9h\" or \"5h30m\", at most %d hours either way.", int(constants.MaxTakenAtShift.Hours())), nil)
+		return
+	}
+
+	// Require a selection, so a mistake can't shift the whole library
+	if len(req.IDs) == 0 && req.AlbumID == nil {
+		BadRequest(w, "Select photos with ids or album_id.", nil)
+		return
+	}
+	if len(req.IDs) > constants.MaxTakenAtShiftPhotos {
+		BadRequest(w, fmt.Sprintf("At most %d photos can be shifted at once.", constants.MaxTakenAtShiftPhotos), nil)
+		return
+	}
+	for _, id := range req.IDs {
+		if !validatePhotoID(id) {
+			InvalidID(w, "photo ID")
+			return
+		}
+	}
+	if req.AlbumID != nil && !validateAlbumID(*req.AlbumID) {
+		InvalidID(w, "album ID")
+		return
+	}
+	if req.TakenAfter != nil && req.TakenBefore != nil && !req.TakenAfter.Before(*req.TakenBefore) {
+		BadRequest(w, "taken_after must be before taken_before.", nil)
+		return
+	}
+
+	filter := db.PhotoFilter{
+		AlbumID:     req.AlbumID,
+		TakenAfter:  req.TakenAfter,
+		TakenBefore: req.TakenBefore,
+	}
+	if len(req.IDs) > 0 {
+		if !checkPhotosAccess(w, r, h.db, req.IDs) {
+			return
+		}
+		filter.IDs = req.IDs
+	}
+	if !restrictFilter(w, r, h.db, &filter) {
+		return
+	}
+
+	// Fetch one more photo than allowed to tell whether there are too many
+	photos, err := h.db.GetPhotos(filter, constants.MaxTakenAtShiftPhotos+1, 0)
+	if err != nil {
+		DatabaseError(w, "retrieve photos", err)
+		return
+	}
+	if len(photos) > constants.MaxTakenAtShiftPhotos {
+		BadRequest(w, fmt.Sprintf("More than %d photos match; narrow the selection with taken_after and taken_before.", constants.MaxTakenAtShiftPhotos), nil)
+		return
+	}
+
+	response := ShiftTakenAtResponse{Success: true, DryRun: req.DryRun, Photos: []TakenAtShift{}}
+	updates := make(map[string]models.PhotoUpdate, len(photos))
+	for _, photo := range photos {
+		if photo.TakenAt == nil {
+			response.Skipped = append(response.Skipped, photo.ID)
+			continue
+		}
+		shifted := photo.TakenAt.Add(offset).UTC()
+		update := models.PhotoUpdate{TakenAt: &shifted}
+		if validationErrors := ValidatePhotoUpdate(&update); len(validationErrors) > 0 {
+			BadRequest(w, fmt.Sprintf("Photo %s can't be shifted: taken_at %s.", photo.ID, validationErrors[0].Message), nil)
+			return
+		}
+		updates[photo.ID] = update
+		response.Photos = append(response.Photos, TakenAtShift{
+			ID:      photo.ID,
+			Title:   photo.Title,
+			TakenAt: photo.TakenAt.UTC(),
+			Shifted: shifted,
+		})
+	}
+
+	if !req.DryRun && len(updates) > 0 {
+		if err := h.db.UpdatePhotos(updates); err != nil {
+			log.Printf("Failed to shift taken_at of %d photos: %v", len(updates), err)
+			InternalServerError(w, "Failed to update photos. Please try again.")
+			return
+		}
+		log.Printf("Shifted taken_at of %d photos by %s", len(updates), offset)
+		response.Updated = len(updates)
+
+		// Lychee has been updated at this point, so failures here are
+		// logged rather than reported
+		photoIDs := make([]string, 0, len(updates))
+		for id, update := range updates {
+			photoIDs = append(photoIDs, id)
+			if err := h.recordProvenance(r.Context(), id, update, nil); err != nil {
+				log.Printf("Failed to record provenance for photo %s: %v", id, err)
+			}
+		}
+		h.publish(r.Context(), feed.EventUpdated, photoIDs...)
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode taken_at shift response: %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/db"
+	"github.com/cdzombak/lychee-meta-tool/backend/feed"
+	"github.com/cdzombak/lychee-meta-tool/backend/sidecar"
+)
+
+// SkipRequest lists photos to add to the ignore list
+type SkipRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// SkipResponse reports a bulk skip and how to undo it
+type SkipResponse struct {
+	Success bool `json:"success"`
+	Skipped int  `json:"skipped"`
+	// BatchID identifies the skip for UndoSkip until UndoUntil
+	BatchID   string    `json:"batch_id"`
+	UndoUntil time.Time `json:"undo_until"`
+}
+
+// UndoSkipRequest identifies a bulk skip to undo
+type UndoSkipRequest struct {
+	BatchID string `json:"batch_id"`
+}
+
+// UndoSkipResponse reports an undone bulk skip
+type UndoSkipResponse struct {
+	Success  bool `json:"success"`
+	Restored int  `json:"restored"`
+}
+
+// SkipPhotos handles POST requests adding photos to the ignore list, which
+// takes them out of the needsmetadata and aireview queues
+func (h *PhotoHandler) SkipPhotos(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w)
+		return
+	}
+
+	var req SkipRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		InvalidJSON(w, err)
+		return
+	}
+	if len(req.IDs) == 0 {
+		BadRequest(w, "ids must list at least one photo ID.", nil)
+		return
+	}
+	if len(req.IDs) > MaxLimit {
+		BadRequest(w, fmt.Sprintf("At most %d photos can be skipped at once.", MaxLimit), nil)
+		return
+	}
+	for _, id := range req.IDs {
+		if !validatePhotoID(id) {
+			InvalidID(w, "photo ID")
+			return
+		}
+	}
+
+	if !checkPhotosAccess(w, r, h.db, req.IDs) {
+		return
+	}
+
+	batchID, err := newSkipBatchID()
+	if err != nil {
+		log.Printf("Failed to generate skip batch ID: %v", err)
+		InternalServerError(w, "Failed to skip photos. Please try again.")
+		return
+	}
+
+	now := time.Now().UTC()
+	err = h.sidecar.UpdatePhotosByID(req.IDs, func(state *sidecar.PhotoState) {
+		state.SkippedAt = &now
+		state.SkipBatch = batchID
+	})
+	if err != nil {
+		log.Printf("Failed to skip %d photos: %v", len(req.IDs), err)
+		InternalServerError(w, "Failed to skip photos. Please try again.")
+		return
+	}
+
+	log.Printf("Skipped %d photos (batch %s)", len(req.IDs), batchID)
+	h.publish(r.Context(), feed.EventSkipped, req.IDs...)
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	if err := json.NewEncoder(w).Encode(SkipResponse{
+		Success:   true,
+		Skipped:   len(req.IDs),
+		BatchID:   batchID,
+		UndoUntil: now.Add(constants.SkipUndoWindow),
+	}); err != nil {
+		log.Printf("Failed to encode skip response: %v", err)
+	}
+}
+
+// UndoSkip handles POST requests restoring the photos of a bulk skip to the
+// queues, within constants.SkipUndoWindow of the skip
+func (h *PhotoHandler) UndoSkip(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w)
+		return
+	}
+
+	var req UndoSkipRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		InvalidJSON(w, err)
+		return
+	}
+	if req.BatchID == "" {
+		BadRequest(w, "batch_id is required.", nil)
+		return
+	}
+
+	batch := h.sidecar.Photos(func(_ string, p sidecar.PhotoState) bool {
+		return p.SkippedAt != nil && p.SkipBatch == req.BatchID
+	})
+	if len(batch) == 0 {
+		NotFound(w, "No skipped photos found for this batch_id.")
+		return
+	}
+	for _, p := range batch {
+		if time.Since(*p.SkippedAt) > constants.SkipUndoWindow {
+			Gone(w, "The undo window for this skip has passed.")
+			return
+		}
+	}
+
+	var restored []string
+	err := h.sidecar.UpdatePhotos(func(id string, p *sidecar.PhotoState) bool {
+		if p.SkippedAt == nil || p.SkipBatch != req.BatchID {
+			return false
+		}
+		p.SkippedAt = nil
+		p.SkipBatch = ""
+		restored = append(restored, id)
+		return true
+	})
+	if err != nil {
+		log.Printf("Failed to undo skip batch %s: %v", req.BatchID, err)
+		InternalServerError(w, "Failed to undo skip. Please try again.")
+		return
+	}
+
+	log.Printf("Undid skip of %d photos (batch %s)", len(restored), req.BatchID)
+	h.publish(r.Context(), feed.EventUnskipped, restored...)
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	if err := json.NewEncoder(w).Encode(UndoSkipResponse{Success: true, Restored: len(restored)}); err != nil {
+		log.Printf("Failed to encode undo skip response: %v", err)
+	}
+}
+
+// applyExclusions adds the photos on the ignore list, and the albums
+// excluded from the queues, to filter's exclusions. An album selected
+// explicitly by filter is not excluded.
+func applyExclusions(store *sidecar.Store, filter *db.PhotoFilter) {
+	for id := range store.Photos(func(_ string, p sidecar.PhotoState) bool {
+		return p.SkippedAt != nil
+	}) {
+		filter.ExcludeIDs = append(filter.ExcludeIDs, id)
+	}
+
+	for id, settings := range store.Albums() {
+		if settings.ExcludedFromQueue && (filter.AlbumID == nil || *filter.AlbumID != id) {
+			filter.ExcludeAlbumIDs = append(filter.ExcludeAlbumIDs, id)
+		}
+	}
+}
+
+// newSkipBatchID returns a random identifier for a bulk skip
+func newSkipBatchID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
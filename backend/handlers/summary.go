@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/summary"
+)
+
+// SummaryHandler handles HTTP requests for the review activity summary
+// that is emailed weekly
+type SummaryHandler struct {
+	summarizer *summary.Summarizer
+	mailer     *summary.Mailer
+}
+
+// NewSummaryHandler creates a new SummaryHandler. mailer is nil when
+// summary emails aren't configured.
+func NewSummaryHandler(summarizer *summary.Summarizer, mailer *summary.Mailer) *SummaryHandler {
+	return &SummaryHandler{summarizer: summarizer, mailer: mailer}
+}
+
+// SummaryResponse is a summary of the past week's review activity, with the
+// text of its email
+type SummaryResponse struct {
+	Summary summary.Summary `json:"summary"`
+	Subject string          `json:"subject"`
+	Text    string          `json:"text"`
+	// Sent is true when the summary was emailed
+	Sent bool `json:"sent"`
+}
+
+// HandleSummary dispatches summary requests: GET previews the summary of
+// the past week, and POST emails it now
+func (h *SummaryHandler) HandleSummary(w http.ResponseWriter, r *http.Request) {
+	to := time.Now()
+	from := to.Add(-constants.SummaryPeriod)
+
+	var response SummaryResponse
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		response.Summary, err = h.summarizer.Build(from, to)
+	case http.MethodPost:
+		if h.mailer == nil {
+			ServiceUnavailable(w, "Summary emails are not configured. Set summary_email in the configuration.")
+			return
+		}
+		response.Summary, err = h.summarizer.Send(h.mailer, from, to)
+		response.Sent = err == nil
+	default:
+		MethodNotAllowed(w)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to prepare activity summary: %v", err)
+		InternalServerError(w, "Failed to prepare the activity summary. Please try again.")
+		return
+	}
+	response.Subject = response.Summary.Subject()
+	response.Text = response.Summary.Text()
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode summary response: %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/ai"
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/feed"
+	"github.com/cdzombak/lychee-meta-tool/backend/sidecar"
+)
+
+// GenerateTagsRequest adjusts AI tag generation. The body is optional.
+type GenerateTagsRequest struct {
+	// Count is the maximum number of tags to suggest
+	Count int `json:"count"`
+	// Apply adds the suggested tags to the photo's existing tags in Lychee
+	Apply bool `json:"apply"`
+	// Language overrides the album's and the default language for tags
+	Language string `json:"language"`
+}
+
+// GenerateTagsResponse lists suggested tags for a photo
+type GenerateTagsResponse struct {
+	Success bool     `json:"success"`
+	Tags    []string `json:"tags"`
+	Applied bool     `json:"applied"`
+	// ImageVariant is the size variant the tags were generated from
+	ImageVariant string `json:"image_variant"`
+}
+
+// GenerateAITags handles POST requests asking the AI backend for keyword
+// tags for a photo, optionally adding them to the photo in Lychee
+func (h *PhotoHandler) GenerateAITags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		MethodNotAllowed(w)
+		return
+	}
+
+	tagger, ok := h.aiClient().(ai.TagGenerator)
+	if !ok {
+		ServiceUnavailable(w, "AI tag generation is not available. Please check your AI backend configuration.")
+		return
+	}
+
+	photoID, valid := extractPhotoIDFromPath(r.URL.Path)
+	if !valid {
+		InvalidID(w, "photo ID")
+		return
+	}
+
+	var req GenerateTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		InvalidJSON(w, err)
+		return
+	}
+	if req.Count < 0 || req.Count > ai.MaxTagCount {
+		BadRequest(w, fmt.Sprintf("Invalid count. Must be a number between 1 and %d.", ai.MaxTagCount), nil)
+		return
+	}
+	req.Language = strings.TrimSpace(req.Language)
+	if err := validatePromptText(req.Language, MaxAlbumLanguageLength); err != nil {
+		BadRequest(w, fmt.Sprintf("Invalid language: %s.", err), nil)
+		return
+	}
+
+	if !checkPhotoAccess(w, r, h.db, photoID) {
+		return
+	}
+
+	photo, err := h.db.GetPhotoByID(photoID)
+	if err != nil {
+		DatabaseError(w, "retrieve photo", err)
+		return
+	}
+	if photo == nil {
+		NotFound(w, fmt.Sprintf("Photo with ID '%s' not found.", photoID))
+		return
+	}
+
+	var albumSettings sidecar.AlbumSettings
+	if photo.AlbumID != nil {
+		albumSettings = h.sidecar.Album(*photo.AlbumID)
+		if albumSettings.Excluded {
+			Forbidden(w, "AI generation is disabled for this photo's album.")
+			return
+		}
+	}
+	opts := ai.TagOptions{Count: req.Count, Language: h.titleLanguage(r.Context(), albumSettings, req.Language)}
+
+	variants := aiImageVariants(photo.ToPhotoResponse(h.imageURLPattern))
+	if len(variants) == 0 {
+		InternalServerError(w, "Photo image URL is not available.")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), constants.AIGenerationTimeout)
+	defer cancel()
+
+	release, err := h.acquireGeneration(ctx, false, nil)
+	if errors.Is(err, ai.ErrQueueFull) {
+		aiQueueFull(w, err)
+		return
+	}
+	if err != nil {
+		log.Printf("Gave up waiting to generate AI tags for photo %s: %v", photoID, err)
+		ServiceUnavailable(w, aiQueueFullMessage)
+		return
+	}
+	defer release()
+	ctx = ai.WithStorageDisk(ctx, photo.StorageDisk())
+
+	// Try each variant in turn while the image itself can't be fetched, as
+	// for titles
+	var tags []string
+	var variant string
+	for _, v := range variants {
+		variant = v.name
+		log.Printf("Generating AI tags for photo %s using %s image URL: %s", photoID, v.name, v.url)
+		err = h.opts.AIUsage.Track(ctx, "tags", func(ctx context.Context) error {
+			return h.opts.AIGuard.Do(ctx, func(ctx context.Context) error {
+				var err error
+				tags, err = tagger.GenerateTags(ctx, v.url, opts)
+				return err
+			})
+		})
+		if err == nil || !errors.Is(err, ai.ErrImageUnavailable) {
+			break
+		}
+		log.Printf("Failed to fetch %s variant for photo %s: %v", v.name, photoID, err)
+	}
+	if errors.Is(err, ai.ErrBackendUnavailable) {
+		AIUnavailable(w)
+		return
+	}
+	if errors.Is(err, ai.ErrNoVision) {
+		AINoVision(w, err)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to generate AI tags for photo %s: %v", photoID, err)
+		InternalServerError(w, "Failed to generate AI tags. Please check your network connection and try again.")
+		return
+	}
+	for i, tag := range tags {
+		tags[i] = sanitizeText(tag)
+	}
+
+	applied := false
+	if req.Apply {
+		if err := h.addPhotoTags(photoID, tags); err != nil {
+			DatabaseError(w, "apply photo tags", err)
+			return
+		}
+		applied = true
+		log.Printf("Applied %d AI tags to photo %s", len(tags), photoID)
+		h.publish(r.Context(), feed.EventUpdated, photoID)
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	if err := json.NewEncoder(w).Encode(GenerateTagsResponse{
+		Success:      true,
+		Tags:         tags,
+		Applied:      applied,
+		ImageVariant: variant,
+	}); err != nil {
+		log.Printf("Failed to encode tags response: %v", err)
+	}
+}
+
+// addPhotoTags adds tags to a photo's existing tags, skipping any it
+// already has (ignoring case)
+func (h *PhotoHandler) addPhotoTags(photoID string, tags []string) error {
+	existing, err := h.db.GetPhotoTags([]string{photoID})
+	if err != nil {
+		return err
+	}
+
+	merged := existing[photoID]
+	seen := make(map[string]bool, len(merged)+len(tags))
+	for _, tag := range merged {
+		seen[strings.ToLower(tag)] = true
+	}
+	for _, tag := range tags {
+		if key := strings.ToLower(tag); !seen[key] {
+			seen[key] = true
+			merged = append(merged, tag)
+		}
+	}
+
+	return h.db.SetPhotoTags(photoID, merged)
+}
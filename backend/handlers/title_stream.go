@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/ai"
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+)
+
+// Server-sent event types sent by StreamAITitle
+const (
+	// titleEventToken carries a piece of the title as it is generated
+	titleEventToken = "token"
+	// titleEventDone carries the final GenerateTitleResponse. Its title is
+	// cleaned up and may differ slightly from the streamed text.
+	titleEventDone = "done"
+	// titleEventError carries a Problem; the stream ends after it
+	titleEventError = "error"
+	// titleEventQueued carries a TitleQueued while the request waits for
+	// a generation slot, each time its position changes
+	titleEventQueued = "queued"
+)
+
+// TitleToken is the payload of a token event
+type TitleToken struct {
+	Text string `json:"text"`
+}
+
+// TitleQueued is the payload of a queued event
+type TitleQueued struct {
+	// Position is the request's place in the queue; 1 is next
+	Position int `json:"position"`
+}
+
+// StreamAITitle handles GET requests to generate an AI title as a stream of
+// server-sent events, so clients can show the title as it is written. It
+// accepts the same force parameter as GenerateAITitle. Backends that can't
+// stream send no token events, only the final title.
+func (h *PhotoHandler) StreamAITitle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		MethodNotAllowed(w)
+		return
+	}
+
+	if h.aiClient() == nil {
+		AINotConfigured(w)
+		return
+	}
+
+	photoID, valid := extractPhotoIDFromPath(r.URL.Path)
+	if !valid {
+		InvalidID(w, "photo ID")
+		return
+	}
+
+	if !checkPhotoAccess(w, r, h.db, photoID) {
+		return
+	}
+
+	photo, err := h.db.GetPhotoByID(photoID)
+	if err != nil {
+		DatabaseError(w, "retrieve photo", err)
+		return
+	}
+	if photo == nil {
+		NotFound(w, fmt.Sprintf("Photo with ID '%s' not found.", photoID))
+		return
+	}
+
+	language, ok := parseLanguageParam(w, r)
+	if !ok {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		InternalServerError(w, "Streaming is not supported.")
+		return
+	}
+
+	// Generation can outlast the server's write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Failed to clear write deadline for title stream: %v", err)
+	}
+
+	w.Header().Set("Content-Type", constants.ContentTypeEventStream)
+	w.Header().Set("Cache-Control", "no-cache")
+	// Ask reverse proxies such as nginx not to buffer the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	send := func(event string, payload interface{}) {
+		if err := writeEvent(w, event, payload); err != nil {
+			log.Printf("Failed to write %s event for photo %s: %v", event, photoID, err)
+			return
+		}
+		flusher.Flush()
+	}
+
+	// Stop generating if the client goes away
+	ctx, cancel := context.WithTimeout(r.Context(), constants.AIGenerationTimeout)
+	defer cancel()
+
+	result, err := h.titlePhoto(ctx, photo, titleRequest{
+		force:    r.URL.Query().Get("force") == "true",
+		language: language,
+		onToken: func(text string) {
+			send(titleEventToken, TitleToken{Text: text})
+		},
+		onQueued: func(position int) {
+			send(titleEventQueued, TitleQueued{Position: position})
+		},
+	})
+	if err != nil {
+		status, code, message := titleError(err)
+		send(titleEventError, newProblem(w, status, code, message, nil))
+		return
+	}
+	send(titleEventDone, result.response())
+}
+
+// titleError returns the status, error code and message reported for an
+// error from titlePhoto
+func titleError(err error) (status int, code, message string) {
+	switch {
+	case errors.Is(err, errAINotConfigured):
+		return StatusServiceUnavailable, ErrorCodeAINotConfigured, ErrorAINotConfigured
+	case errors.Is(err, errAIDisabled):
+		return StatusForbidden, ErrorCodeAIDisabled, "AI title generation is disabled for this photo's album."
+	case errors.Is(err, ai.ErrBackendUnavailable):
+		return StatusServiceUnavailable, ErrorCodeAIUnavailable, aiUnavailableMessage
+	case errors.Is(err, ai.ErrNoVision):
+		return StatusServiceUnavailable, ErrorCodeAINoVision, noVisionMessage(err)
+	case errors.Is(err, ai.ErrQueueFull):
+		return StatusTooManyRequests, ErrorCodeAIQueueFull, aiQueueFullMessage
+	case errors.Is(err, errNoImageURL):
+		return StatusInternalServerError, ErrorCodeInternal, "Photo image URL is not available."
+	case errors.Is(err, errEmptyTitle):
+		return StatusInternalServerError, ErrorCodeInternal, "AI generated an empty title. Please try again."
+	default:
+		return StatusInternalServerError, ErrorCodeInternal, "Failed to generate AI title. Please check your network connection and try again."
+	}
+}
+
+// writeEvent writes a server-sent event with a JSON payload
+func writeEvent(w http.ResponseWriter, event string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
@@ -4,44 +4,68 @@ import (
 	"fmt"
 	"html"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 	"unicode/utf8"
 
 	"github.com/cdzombak/lychee-meta-tool/backend/constants"
 	"github.com/cdzombak/lychee-meta-tool/backend/models"
+	"github.com/cdzombak/lychee-meta-tool/backend/sidecar"
 )
 
 const (
 	// API path constants
-	PhotosAPIPrefix = "/api/photos/"
+	PhotosAPIPrefix    = "/api/photos/"
 	PhotosAPIPrefixLen = 12
 
 	// Query parameter limits (using constants)
-	DefaultLimit = constants.DefaultPhotoLimit
-	MaxLimit = constants.MaxPhotoLimit
 	MinOffset = constants.MinPhotoOffset
-	
+
 	// ID validation (using constants)
 	MinPhotoIDLength = constants.MinIDLength
 	MaxPhotoIDLength = constants.MaxIDLength
 
 	// Text field limits (using constants)
-	MaxTitleLength = constants.MaxPhotoTitleLength
+	MaxTitleLength       = constants.MaxPhotoTitleLength
 	MaxDescriptionLength = constants.MaxPhotoDescriptionLength
-	MaxAlbumIDLength = constants.MaxIDLength
+	MaxAlbumIDLength     = constants.MaxIDLength
+
+	// Album AI settings limits
+	MaxAlbumStyleLength    = 200
+	MaxAlbumLanguageLength = constants.MaxTitleLanguageLength
 
 	// Content validation
 	MinContentLength = 0
 )
 
+// Page size limits, set from the queue configuration by SetPageLimits
+var (
+	DefaultLimit = constants.DefaultPhotoLimit
+	MaxLimit     = constants.MaxPhotoLimit
+)
+
+// SetPageLimits sets the number of photos returned per page when a request
+// doesn't set a limit, and the most a request may ask for. It must be
+// called before any requests are served.
+func SetPageLimits(defaultLimit, maxLimit int) {
+	DefaultLimit = defaultLimit
+	MaxLimit = maxLimit
+}
+
+var errInvalidProvenance = fmt.Sprintf("must be one of %q, %q or %q", models.ProvenanceAI, models.ProvenanceAIEdited, models.ProvenanceManual)
+
+var errInvalidLicense = fmt.Sprintf("must be %q, %q, %q or a Creative Commons license such as %q", models.LicenseNone, "reserved", "CC0", "CC-BY-4.0")
+
 var (
 	// Validation patterns
 	photoIDPattern = regexp.MustCompile(constants.PhotoIDPattern)
 	albumIDPattern = regexp.MustCompile(constants.AlbumIDPattern)
-	
+
 	// Dangerous patterns to detect potential security issues
-	scriptTagPattern = regexp.MustCompile(`(?i)<script[^>]*>.*?</script>`)
-	javascriptPattern = regexp.MustCompile(`(?i)javascript:`)
+	scriptTagPattern     = regexp.MustCompile(`(?i)<script[^>]*>.*?</script>`)
+	javascriptPattern    = regexp.MustCompile(`(?i)javascript:`)
 	dangerousHTMLPattern = regexp.MustCompile(`(?i)<[^>]*on\w+\s*=`)
 )
 
@@ -50,15 +74,15 @@ func validatePhotoID(id string) bool {
 	if len(id) < MinPhotoIDLength || len(id) > MaxPhotoIDLength {
 		return false
 	}
-	
+
 	// Check for valid UTF-8
 	if !utf8.ValidString(id) {
 		return false
 	}
-	
+
 	// Remove any potential file extensions for validation
 	cleanID := removePotentialExtensions(id)
-	
+
 	return photoIDPattern.MatchString(cleanID)
 }
 
@@ -67,15 +91,15 @@ func validateAlbumID(id string) bool {
 	if id == "" {
 		return true // Empty album ID is valid (means no album)
 	}
-	
+
 	if len(id) < MinPhotoIDLength || len(id) > MaxAlbumIDLength {
 		return false
 	}
-	
+
 	if !utf8.ValidString(id) {
 		return false
 	}
-	
+
 	return albumIDPattern.MatchString(id)
 }
 
@@ -98,21 +122,21 @@ func extractPhotoIDFromPath(path string) (string, bool) {
 	if len(path) < PhotosAPIPrefixLen {
 		return "", false
 	}
-	
+
 	photoID := path[PhotosAPIPrefixLen:]
 	if photoID == "" {
 		return "", false
 	}
-	
+
 	// Remove any trailing slash or additional path components
 	if slashIndex := strings.Index(photoID, "/"); slashIndex != -1 {
 		photoID = photoID[:slashIndex]
 	}
-	
+
 	if !validatePhotoID(photoID) {
 		return "", false
 	}
-	
+
 	return photoID, true
 }
 
@@ -135,6 +159,20 @@ func validateOffset(offset int) int {
 	return offset
 }
 
+// parseBoolParam parses an optional boolean query parameter. An empty value
+// is false; valid is false if the value is not a recognized boolean.
+func parseBoolParam(param string) (value, valid bool) {
+	param = sanitizeQueryParam(param)
+	if param == "" {
+		return false, true
+	}
+	value, err := strconv.ParseBool(param)
+	if err != nil {
+		return false, false
+	}
+	return value, true
+}
+
 // ValidationError represents a validation error with details
 type ValidationError struct {
 	Field   string
@@ -184,17 +222,82 @@ func ValidatePhotoUpdate(update *models.PhotoUpdate) []ValidationError {
 		}
 	}
 
+	// Validate taken_at
+	if update.TakenAt != nil {
+		if err := validateTakenAt(*update.TakenAt); err != nil {
+			errors = append(errors, ValidationError{Field: "taken_at", Message: err.Error(), Value: update.TakenAt.Format(time.RFC3339)})
+		}
+	}
+
+	// Validate geotag
+	errors = append(errors, validateGeotag(update)...)
+
+	// Validate license
+	if update.License != nil && !models.ValidLicense(*update.License) {
+		errors = append(errors, ValidationError{Field: "license", Message: errInvalidLicense, Value: *update.License})
+	}
+
+	// Validate provenance
+	if update.TitleSource != nil && !update.TitleSource.Valid() {
+		errors = append(errors, ValidationError{Field: "title_source", Message: errInvalidProvenance, Value: *update.TitleSource})
+	}
+	if update.DescriptionSource != nil && !update.DescriptionSource.Valid() {
+		errors = append(errors, ValidationError{Field: "description_source", Message: errInvalidProvenance, Value: *update.DescriptionSource})
+	}
+
+	return errors
+}
+
+// ValidateAlbumSettings validates and normalizes per-album AI settings.
+// Style and language are inserted into AI prompts, so they are limited to
+// a single short line.
+func ValidateAlbumSettings(settings *sidecar.AlbumSettings) []ValidationError {
+	var errors []ValidationError
+
+	settings.Style = strings.TrimSpace(settings.Style)
+	if err := validatePromptText(settings.Style, MaxAlbumStyleLength); err != nil {
+		errors = append(errors, ValidationError{Field: "style", Message: err.Error(), Value: settings.Style})
+	}
+
+	settings.Language = strings.TrimSpace(settings.Language)
+	if err := validatePromptText(settings.Language, MaxAlbumLanguageLength); err != nil {
+		errors = append(errors, ValidationError{Field: "language", Message: err.Error(), Value: settings.Language})
+	}
+
+	return errors
+}
+
+// ValidateUserPreferences validates and normalizes a person's AI
+// preferences, which are limited like album settings
+func ValidateUserPreferences(prefs *sidecar.UserPreferences) []ValidationError {
+	settings := sidecar.AlbumSettings{Style: prefs.Style, Language: prefs.Language}
+	errors := ValidateAlbumSettings(&settings)
+	prefs.Style, prefs.Language = settings.Style, settings.Language
 	return errors
 }
 
+// validatePromptText validates user-provided text destined for an AI prompt
+func validatePromptText(text string, maxLength int) error {
+	if !utf8.ValidString(text) {
+		return fmt.Errorf("contains invalid UTF-8 characters")
+	}
+	if textLength(text) > maxLength {
+		return fmt.Errorf("too long (max %d characters)", maxLength)
+	}
+	if strings.ContainsAny(text, "\r\n") {
+		return fmt.Errorf("must be a single line")
+	}
+	return nil
+}
+
 // validateAndSanitizeTitle validates a photo title
 func validateAndSanitizeTitle(title string) error {
 	if !utf8.ValidString(title) {
 		return fmt.Errorf("title contains invalid UTF-8 characters")
 	}
 
-	if len(title) > MaxTitleLength {
-		return fmt.Errorf("title too long (max %d characters, got %d)", MaxTitleLength, len(title))
+	if n := textLength(title); n > MaxTitleLength {
+		return fmt.Errorf("title too long (max %d characters, got %d)", MaxTitleLength, n)
 	}
 
 	if containsDangerousContent(title) {
@@ -210,8 +313,8 @@ func validateAndSanitizeDescription(description string) error {
 		return fmt.Errorf("description contains invalid UTF-8 characters")
 	}
 
-	if len(description) > MaxDescriptionLength {
-		return fmt.Errorf("description too long (max %d characters, got %d)", MaxDescriptionLength, len(description))
+	if n := textLength(description); n > MaxDescriptionLength {
+		return fmt.Errorf("description too long (max %d characters, got %d)", MaxDescriptionLength, n)
 	}
 
 	if containsDangerousContent(description) {
@@ -221,6 +324,43 @@ func validateAndSanitizeDescription(description string) error {
 	return nil
 }
 
+// validateGeotag checks that an update's coordinates are in range, and
+// that latitude and longitude are set together and not alongside clearing
+// the geotag
+func validateGeotag(update *models.PhotoUpdate) []ValidationError {
+	var errors []ValidationError
+	if (update.Latitude == nil) != (update.Longitude == nil) {
+		errors = append(errors, ValidationError{Field: "latitude", Message: "latitude and longitude must be set together"})
+	}
+	if update.Latitude != nil && (*update.Latitude < -90 || *update.Latitude > 90) {
+		errors = append(errors, ValidationError{Field: "latitude", Message: "must be between -90 and 90", Value: *update.Latitude})
+	}
+	if update.Longitude != nil && (*update.Longitude < -180 || *update.Longitude > 180) {
+		errors = append(errors, ValidationError{Field: "longitude", Message: "must be between -180 and 180", Value: *update.Longitude})
+	}
+	if update.Altitude != nil {
+		if *update.Altitude < constants.MinAltitude || *update.Altitude > constants.MaxAltitude {
+			errors = append(errors, ValidationError{Field: "altitude", Message: fmt.Sprintf("must be between %d and %d meters", constants.MinAltitude, constants.MaxAltitude), Value: *update.Altitude})
+		} else if update.ClearCoordinates {
+			errors = append(errors, ValidationError{Field: "altitude", Message: "cannot be set while clearing latitude and longitude", Value: *update.Altitude})
+		}
+	}
+	return errors
+}
+
+// validateTakenAt checks that a photo's taken_at is plausible: no earlier
+// than the oldest photograph, and no later than a day from now, allowing
+// for time zone mistakes
+func validateTakenAt(takenAt time.Time) error {
+	if takenAt.UTC().Year() < constants.MinTakenAtYear {
+		return fmt.Errorf("cannot be before %d", constants.MinTakenAtYear)
+	}
+	if takenAt.After(time.Now().Add(24 * time.Hour)) {
+		return fmt.Errorf("cannot be in the future")
+	}
+	return nil
+}
+
 // containsDangerousContent checks for potentially dangerous content
 func containsDangerousContent(text string) bool {
 	return scriptTagPattern.MatchString(text) ||
@@ -232,18 +372,91 @@ func containsDangerousContent(text string) bool {
 func sanitizeText(text string) string {
 	// Trim whitespace
 	text = strings.TrimSpace(text)
-	
+
 	// HTML escape to prevent XSS
 	text = html.EscapeString(text)
-	
+
 	// Normalize line endings
 	text = strings.ReplaceAll(text, "\r\n", "\n")
 	text = strings.ReplaceAll(text, "\r", "\n")
-	
+
 	return text
 }
 
+// textLength returns the length of text in characters (Unicode code
+// points), which is how Lychee's database columns measure their limits.
+// Byte length over-counts anything outside ASCII.
+func textLength(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+// isClusterExtender reports whether r continues the preceding character
+// rather than starting a new one: combining marks, variation selectors,
+// emoji skin tone modifiers and tag characters, and zero-width joiners
+func isClusterExtender(r rune) bool {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me):
+		return true
+	case r == '\u200D', r >= 0xFE00 && r <= 0xFE0F:
+		return true
+	case r >= 0x1F3FB && r <= 0x1F3FF, r >= 0xE0020 && r <= 0xE007F:
+		return true
+	}
+	return false
+}
+
+// isRegionalIndicator reports whether r is one of the letters that make up
+// flag emoji
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+// truncateRunes shortens runes to at most maxLength, backing off so that a
+// character and its combining marks, or the parts of a joined emoji
+// sequence, are never separated
+func truncateRunes(runes []rune, maxLength int) []rune {
+	if len(runes) <= maxLength {
+		return runes
+	}
+	cut := maxLength
+	for cut > 0 && (isClusterExtender(runes[cut]) || runes[cut-1] == '\u200D') {
+		cut--
+	}
+	// Flags are pairs of regional indicators; don't keep half of one
+	pairs := 0
+	for i := cut - 1; i >= 0 && isRegionalIndicator(runes[i]); i-- {
+		pairs++
+	}
+	if pairs%2 == 1 && isRegionalIndicator(runes[cut]) {
+		cut--
+	}
+	return runes[:cut]
+}
+
+// truncateTitle shortens title to at most maxLength characters, cutting at
+// the last word boundary that fits. A single word longer than maxLength is
+// cut between characters.
+func truncateTitle(title string, maxLength int) string {
+	runes := []rune(title)
+	if len(runes) <= maxLength {
+		return title
+	}
+
+	cut := truncateRunes(runes, maxLength)
+	if !unicode.IsSpace(runes[len(cut)]) {
+		for i := len(cut) - 1; i > 0; i-- {
+			if unicode.IsSpace(cut[i]) {
+				cut = cut[:i]
+				break
+			}
+		}
+	}
+	return strings.TrimRightFunc(string(cut), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	})
+}
+
 // sanitizeQueryParam sanitizes query parameters
 func sanitizeQueryParam(param string) string {
 	return strings.TrimSpace(html.EscapeString(param))
-}
\ No newline at end of file
+}
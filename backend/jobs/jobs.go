@@ -0,0 +1,482 @@
+// Package jobs runs long-running work, such as generating AI titles for a
+// whole album, hashing the library to find duplicates or auditing it for
+// missing media, in the background.
+//
+// Jobs are queued and run one at a time, so a batch never competes with
+// itself for the AI backend. Job state is kept in memory only; jobs that are
+// queued or running when the server stops are lost.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/auth"
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/db"
+	"github.com/cdzombak/lychee-meta-tool/backend/mediacheck"
+	"github.com/cdzombak/lychee-meta-tool/backend/models"
+)
+
+// Job types
+const (
+	TypeGenerateTitles = "generate-titles"
+	TypeComputeHashes  = "compute-hashes"
+	TypeCheckMedia     = "check-media"
+)
+
+// Job states
+const (
+	StateQueued    = "queued"
+	StateRunning   = "running"
+	StateCompleted = "completed"
+	StateCancelled = "cancelled"
+	StateFailed    = "failed"
+)
+
+// maxQueuedJobs bounds the number of jobs waiting to run
+const maxQueuedJobs = 16
+
+// maxFinishedJobs is the number of finished jobs kept for status queries
+const maxFinishedJobs = 50
+
+// ErrQueueFull is returned by Submit when too many jobs are waiting
+var ErrQueueFull = errors.New("too many jobs are queued")
+
+// ErrNotFound is returned for unknown job IDs
+var ErrNotFound = errors.New("job not found")
+
+// ErrFinished is returned when cancelling a job that has already finished
+var ErrFinished = errors.New("job has already finished")
+
+// Titler generates AI titles for photos
+type Titler interface {
+	// TitlePhoto generates a title for a photo, saving it to Lychee if
+	// apply is true (or the photo's album is set to auto-apply)
+	TitlePhoto(ctx context.Context, photoID string, apply bool) (title string, applied bool, err error)
+}
+
+// TitleRequest describes a batch title generation job
+type TitleRequest struct {
+	// Filter selects the photos needing metadata to title
+	Filter db.PhotoFilter
+	// Limit caps the number of photos titled
+	Limit int
+	// Apply saves each title to Lychee rather than only reporting it
+	Apply bool
+}
+
+// Hasher computes perceptual hashes of photos
+type Hasher interface {
+	// HashPhoto computes and caches a photo's hash, reporting whether it
+	// was computed rather than already cached
+	HashPhoto(ctx context.Context, photo *models.PhotoWithSizeVariants) (bool, error)
+}
+
+// HashRequest describes a job computing perceptual hashes
+type HashRequest struct {
+	// Filter selects the photos to hash
+	Filter db.PhotoFilter
+}
+
+// MediaChecker checks that photos' image files can be retrieved
+type MediaChecker interface {
+	// CheckPhoto returns the photo's size variants that can't be
+	// retrieved, or none if all can
+	CheckPhoto(ctx context.Context, photo *models.PhotoWithSizeVariants) []mediacheck.Problem
+}
+
+// MediaRequest describes a job auditing photos' media
+type MediaRequest struct {
+	// Filter selects the photos to check
+	Filter db.PhotoFilter
+}
+
+// Result is the outcome of a job for a single photo. Hash and media jobs
+// only record failures.
+type Result struct {
+	PhotoID string `json:"photo_id"`
+	Title   string `json:"title,omitempty"`
+	Applied bool   `json:"applied"`
+	Error   string `json:"error,omitempty"`
+	// Media lists the size variants a media job found broken
+	Media []mediacheck.Problem `json:"media,omitempty"`
+}
+
+// Job is a snapshot of a background job's state
+type Job struct {
+	ID         string  `json:"id"`
+	Type       string  `json:"type"`
+	State      string  `json:"state"`
+	AlbumID    *string `json:"album_id,omitempty"`
+	PublicOnly bool    `json:"public_only,omitempty"`
+	Apply      bool    `json:"apply"`
+	Total      int     `json:"total"`
+	Processed  int     `json:"processed"`
+	Succeeded  int     `json:"succeeded"`
+	Failed     int     `json:"failed"`
+	Error      string  `json:"error,omitempty"`
+	// CreatedBy is who submitted the job (see auth.Actor), to whom its
+	// changes are attributed
+	CreatedBy  string     `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Results    []Result   `json:"results,omitempty"`
+}
+
+// Finished reports whether the job has stopped running for good
+func (j Job) Finished() bool {
+	return j.State == StateCompleted || j.State == StateCancelled || j.State == StateFailed
+}
+
+// task is the work done by a job of a particular type
+type task interface {
+	// photos lists the photos the job processes
+	photos(m *Manager) ([]models.PhotoWithSizeVariants, error)
+	// process handles one photo, returning its result and whether the
+	// result should be kept
+	process(ctx context.Context, m *Manager, photo *models.PhotoWithSizeVariants) (Result, bool)
+}
+
+// job is a Job with the state needed to run it
+type job struct {
+	Job
+	task   task
+	cancel context.CancelFunc
+}
+
+// Manager queues and runs jobs
+type Manager struct {
+	db      *db.DB
+	titler  Titler
+	hasher  Hasher
+	checker MediaChecker
+
+	mu    sync.Mutex
+	jobs  map[string]*job
+	queue chan *job
+}
+
+// NewManager creates a Manager. Call Run to start processing jobs.
+func NewManager(database *db.DB, titler Titler, hasher Hasher, checker MediaChecker) *Manager {
+	return &Manager{
+		db:      database,
+		titler:  titler,
+		hasher:  hasher,
+		checker: checker,
+		jobs:    make(map[string]*job),
+		queue:   make(chan *job, maxQueuedJobs),
+	}
+}
+
+// Run processes queued jobs one at a time until ctx is cancelled. A job
+// running at that point is cancelled.
+func (m *Manager) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-m.queue:
+			m.run(ctx, j)
+		}
+	}
+}
+
+// SubmitTitles queues a batch title generation job
+func (m *Manager) SubmitTitles(ctx context.Context, req TitleRequest) (Job, error) {
+	return m.submit(ctx, Job{
+		Type:       TypeGenerateTitles,
+		AlbumID:    req.Filter.AlbumID,
+		PublicOnly: req.Filter.PublicOnly,
+		Apply:      req.Apply,
+	}, titleTask{req})
+}
+
+// SubmitHashes queues a job computing perceptual hashes
+func (m *Manager) SubmitHashes(ctx context.Context, req HashRequest) (Job, error) {
+	return m.submit(ctx, Job{
+		Type:       TypeComputeHashes,
+		AlbumID:    req.Filter.AlbumID,
+		PublicOnly: req.Filter.PublicOnly,
+	}, hashTask{req})
+}
+
+// SubmitMediaCheck queues a job checking that photos' media can be
+// retrieved
+func (m *Manager) SubmitMediaCheck(ctx context.Context, req MediaRequest) (Job, error) {
+	return m.submit(ctx, Job{
+		Type:       TypeCheckMedia,
+		AlbumID:    req.Filter.AlbumID,
+		PublicOnly: req.Filter.PublicOnly,
+	}, mediaTask{req})
+}
+
+// submit queues a job described by info, on behalf of the actor behind ctx
+func (m *Manager) submit(ctx context.Context, info Job, t task) (Job, error) {
+	id, err := newJobID()
+	if err != nil {
+		return Job{}, fmt.Errorf("failed to generate job ID: %w", err)
+	}
+
+	info.ID = id
+	info.State = StateQueued
+	info.CreatedBy = auth.Actor(ctx)
+	info.CreatedAt = time.Now().UTC()
+	j := &job{Job: info, task: t}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	select {
+	case m.queue <- j:
+	default:
+		return Job{}, ErrQueueFull
+	}
+	m.jobs[id] = j
+	m.pruneLocked()
+
+	log.Printf("Queued %s job %s", j.Type, id)
+	return j.snapshot(true), nil
+}
+
+// List returns all known jobs, newest first, without per-photo results
+func (m *Manager) List() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	jobs := make([]Job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		jobs = append(jobs, j.snapshot(false))
+	}
+	sort.Slice(jobs, func(a, b int) bool {
+		return jobs[a].CreatedAt.After(jobs[b].CreatedAt)
+	})
+	return jobs
+}
+
+// Get returns a job including its per-photo results
+func (m *Manager) Get(id string) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return j.snapshot(true), true
+}
+
+// Cancel stops a queued or running job. Photos already titled keep their
+// titles.
+func (m *Manager) Cancel(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	if j.Finished() {
+		return j.snapshot(true), ErrFinished
+	}
+
+	if j.cancel != nil {
+		j.cancel()
+	}
+	j.finishLocked(StateCancelled, "")
+	log.Printf("Cancelled %s job %s", j.Type, id)
+	return j.snapshot(true), nil
+}
+
+// run executes a job, attributing its changes to whoever submitted it
+func (m *Manager) run(ctx context.Context, j *job) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if j.CreatedBy != "" {
+		ctx = auth.WithActor(ctx, j.CreatedBy)
+	}
+
+	m.mu.Lock()
+	if j.Finished() {
+		// Cancelled while queued
+		m.mu.Unlock()
+		return
+	}
+	now := time.Now().UTC()
+	j.State = StateRunning
+	j.StartedAt = &now
+	j.cancel = cancel
+	m.mu.Unlock()
+
+	photos, err := j.task.photos(m)
+	if err != nil {
+		log.Printf("Job %s failed to list photos: %v", j.ID, err)
+		m.mu.Lock()
+		j.finishLocked(StateFailed, "failed to list photos")
+		m.mu.Unlock()
+		return
+	}
+
+	m.mu.Lock()
+	j.Total = len(photos)
+	m.mu.Unlock()
+	log.Printf("Running %s job %s over %d photo(s)", j.Type, j.ID, len(photos))
+
+	for i := range photos {
+		if ctx.Err() != nil {
+			break
+		}
+
+		result, keep := j.task.process(ctx, m, &photos[i])
+
+		m.mu.Lock()
+		if j.Finished() {
+			// Cancelled during processing; the result is discarded unless
+			// a title was already saved
+			if result.Applied {
+				j.Results = append(j.Results, result)
+			}
+			m.mu.Unlock()
+			break
+		}
+		j.Processed++
+		if result.Error != "" {
+			j.Failed++
+		} else {
+			j.Succeeded++
+		}
+		if keep {
+			j.Results = append(j.Results, result)
+		}
+		m.mu.Unlock()
+	}
+
+	m.mu.Lock()
+	if !j.Finished() {
+		j.finishLocked(StateCompleted, "")
+	}
+	log.Printf("%s job %s %s: %d succeeded, %d failed", j.Type, j.ID, j.State, j.Succeeded, j.Failed)
+	m.mu.Unlock()
+}
+
+// titleTask generates AI titles for photos needing metadata
+type titleTask struct {
+	req TitleRequest
+}
+
+func (t titleTask) photos(m *Manager) ([]models.PhotoWithSizeVariants, error) {
+	return m.db.GetPhotosNeedingMetadata(t.req.Filter, t.req.Limit, 0)
+}
+
+func (t titleTask) process(ctx context.Context, m *Manager, photo *models.PhotoWithSizeVariants) (Result, bool) {
+	ctx, cancel := context.WithTimeout(ctx, constants.AIGenerationTimeout)
+	defer cancel()
+
+	title, applied, err := m.titler.TitlePhoto(ctx, photo.ID, t.req.Apply)
+	result := Result{PhotoID: photo.ID, Title: title, Applied: applied}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result, true
+}
+
+// hashTask computes perceptual hashes for every matching photo. Only
+// failures are kept, since a job may cover the whole library.
+type hashTask struct {
+	req HashRequest
+}
+
+func (t hashTask) photos(m *Manager) ([]models.PhotoWithSizeVariants, error) {
+	return m.db.GetPhotos(t.req.Filter, 0, 0)
+}
+
+func (t hashTask) process(ctx context.Context, m *Manager, photo *models.PhotoWithSizeVariants) (Result, bool) {
+	if _, err := m.hasher.HashPhoto(ctx, photo); err != nil {
+		return Result{PhotoID: photo.ID, Error: err.Error()}, true
+	}
+	return Result{PhotoID: photo.ID}, false
+}
+
+// mediaTask checks every matching photo's size variants. Only photos with
+// broken media are kept, making the results a report of them.
+type mediaTask struct {
+	req MediaRequest
+}
+
+func (t mediaTask) photos(m *Manager) ([]models.PhotoWithSizeVariants, error) {
+	return m.db.GetPhotos(t.req.Filter, 0, 0)
+}
+
+func (t mediaTask) process(ctx context.Context, m *Manager, photo *models.PhotoWithSizeVariants) (Result, bool) {
+	problems := m.checker.CheckPhoto(ctx, photo)
+	if len(problems) == 0 {
+		return Result{PhotoID: photo.ID}, false
+	}
+	if problems[0].Variant == "" {
+		return Result{PhotoID: photo.ID, Error: problems[0].Error}, true
+	}
+	return Result{
+		PhotoID: photo.ID,
+		Error:   fmt.Sprintf("%d image(s) can't be retrieved", len(problems)),
+		Media:   problems,
+	}, true
+}
+
+// finishLocked moves the job to a final state. The caller must hold the
+// Manager's lock.
+func (j *job) finishLocked(state, message string) {
+	now := time.Now().UTC()
+	j.State = state
+	j.Error = message
+	j.FinishedAt = &now
+	j.cancel = nil
+}
+
+// snapshot copies the job's public state. The caller must hold the
+// Manager's lock.
+func (j *job) snapshot(withResults bool) Job {
+	s := j.Job
+	s.Results = nil
+	if withResults {
+		s.Results = append([]Result(nil), j.Results...)
+	}
+	return s
+}
+
+// pruneLocked forgets the oldest finished jobs beyond maxFinishedJobs. The
+// caller must hold m.mu.
+func (m *Manager) pruneLocked() {
+	var finished []*job
+	for _, j := range m.jobs {
+		if j.Finished() {
+			finished = append(finished, j)
+		}
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+
+	sort.Slice(finished, func(a, b int) bool {
+		return finished[a].CreatedAt.Before(finished[b].CreatedAt)
+	})
+	for _, j := range finished[:len(finished)-maxFinishedJobs] {
+		delete(m.jobs, j.ID)
+	}
+}
+
+// newJobID returns a random job identifier
+func newJobID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
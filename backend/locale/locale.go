@@ -0,0 +1,224 @@
+// Package locale formats dates and place names following the library
+// owner's conventions, so that dates shown to reviewers, place names looked
+// up for photos and the context given to AI prompts read the way the owner
+// would write them.
+//
+// Only the conventions the tool needs are covered: a long date format and
+// month names for a handful of languages, the order of place name parts,
+// and leaving out the owner's own country. Other languages fall back to
+// ISO 8601 dates.
+package locale
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// PlaceOrder is the order in which the parts of a place name are written
+type PlaceOrder string
+
+const (
+	// PlaceOrderSmallFirst writes the most specific part first, e.g.
+	// "Ann Arbor, Michigan, United States"
+	PlaceOrderSmallFirst PlaceOrder = "small_first"
+	// PlaceOrderLargeFirst writes the country first, e.g.
+	// "Japan, Tokyo, Shibuya"
+	PlaceOrderLargeFirst PlaceOrder = "large_first"
+)
+
+// DefaultTag is the language tag used when none is configured
+const DefaultTag = "en-US"
+
+// tagPattern matches the language and optional region of a BCP 47 tag,
+// ignoring any script or variant subtags between them
+var tagPattern = regexp.MustCompile(`^([a-zA-Z]{2,3})(?:[-_][a-zA-Z0-9]{4,8})*(?:[-_]([a-zA-Z]{2}|[0-9]{3}))?(?:[-_][a-zA-Z0-9]{4,8})*$`)
+
+// language holds a language's date conventions
+type language struct {
+	// dateLayout is the default long date layout (see time.Layout),
+	// written with English month names that are replaced with months
+	dateLayout string
+	// months are the month names, January first; empty for languages
+	// whose default layout is numeric
+	months [12]string
+	// largeFirst is true if place names are written country first
+	largeFirst bool
+}
+
+var languages = map[string]language{
+	"en": {
+		dateLayout: "2 January 2006",
+		months:     [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+	},
+	"de": {
+		dateLayout: "2. January 2006",
+		months:     [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+	},
+	"fr": {
+		dateLayout: "2 January 2006",
+		months:     [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+	},
+	"es": {
+		dateLayout: "2 de January de 2006",
+		months:     [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+	},
+	"it": {
+		dateLayout: "2 January 2006",
+		months:     [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+	},
+	"nl": {
+		dateLayout: "2 January 2006",
+		months:     [12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+	},
+	"pt": {
+		dateLayout: "2 de January de 2006",
+		months:     [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+	},
+	"ja": {dateLayout: "2006年1月2日", largeFirst: true},
+	"zh": {dateLayout: "2006年1月2日", largeFirst: true},
+	"ko": {dateLayout: "2006년 1월 2일", largeFirst: true},
+	"hu": {dateLayout: "2006. 01. 02.", largeFirst: true},
+}
+
+// isoLanguage is used for languages without known conventions
+var isoLanguage = language{dateLayout: "2006-01-02"}
+
+// Options configure a Locale. The zero value formats as DefaultTag does,
+// in the server's time zone.
+type Options struct {
+	// Tag is a BCP 47 language tag, e.g. "en-GB" or "de"
+	Tag string
+	// DateFormat overrides the language's date layout, in Go's reference
+	// time notation (see time.Layout)
+	DateFormat string
+	// TimeZone is the IANA name of the zone dates are shown in; empty
+	// uses the server's zone
+	TimeZone string
+	// PlaceOrder overrides the language's place name order
+	PlaceOrder PlaceOrder
+	// HomeCountry is left out of place names in that country, e.g.
+	// "United States" to write "Ann Arbor, Michigan"
+	HomeCountry string
+}
+
+// Locale formats dates and place names. It is safe for concurrent use.
+type Locale struct {
+	tag         string
+	lang        string
+	dateLayout  string
+	months      [12]string
+	zone        *time.Location
+	largeFirst  bool
+	homeCountry string
+}
+
+// New creates a Locale
+func New(opts Options) (*Locale, error) {
+	tag := strings.TrimSpace(opts.Tag)
+	if tag == "" {
+		tag = DefaultTag
+	}
+	m := tagPattern.FindStringSubmatch(tag)
+	if m == nil {
+		return nil, fmt.Errorf("invalid language tag %q (e.g. en-US or de)", tag)
+	}
+	lang := strings.ToLower(m[1])
+	region := strings.ToUpper(m[2])
+
+	conventions, ok := languages[lang]
+	if !ok {
+		conventions = isoLanguage
+	}
+	// Month first in the US and a few other English-speaking places
+	if lang == "en" && (region == "" || region == "US" || region == "PH" || region == "CA") {
+		conventions.dateLayout = "January 2, 2006"
+	}
+
+	l := &Locale{
+		tag:         tag,
+		lang:        lang,
+		dateLayout:  conventions.dateLayout,
+		months:      conventions.months,
+		zone:        time.Local,
+		largeFirst:  conventions.largeFirst,
+		homeCountry: strings.TrimSpace(opts.HomeCountry),
+	}
+
+	if opts.DateFormat != "" {
+		l.dateLayout = opts.DateFormat
+	}
+	if opts.TimeZone != "" {
+		zone, err := time.LoadLocation(opts.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("unknown time zone %q: %w", opts.TimeZone, err)
+		}
+		l.zone = zone
+	}
+	switch opts.PlaceOrder {
+	case "":
+	case PlaceOrderSmallFirst:
+		l.largeFirst = false
+	case PlaceOrderLargeFirst:
+		l.largeFirst = true
+	default:
+		return nil, fmt.Errorf("unknown place order %q (must be %s or %s)", opts.PlaceOrder, PlaceOrderSmallFirst, PlaceOrderLargeFirst)
+	}
+
+	return l, nil
+}
+
+// Tag returns the locale's language tag
+func (l *Locale) Tag() string {
+	return l.tag
+}
+
+// Language returns the locale's language subtag, e.g. "de" for "de-AT"
+func (l *Locale) Language() string {
+	return l.lang
+}
+
+// FormatDate formats the date of t in the locale's time zone, e.g.
+// "May 4, 2024" or "4. Mai 2024"
+func (l *Locale) FormatDate(t time.Time) string {
+	t = t.In(l.zone)
+	s := t.Format(l.dateLayout)
+	if l.months[0] == "" {
+		return s
+	}
+
+	// Translate the English month name the layout produced, if any; full
+	// names are replaced first, as they begin with the abbreviations
+	month := l.months[t.Month()-1]
+	english := t.Month().String()
+	if strings.Contains(s, english) {
+		return strings.Replace(s, english, month, 1)
+	}
+	if abbr := english[:3]; strings.Contains(s, abbr) {
+		short := []rune(month)
+		if len(short) > 3 {
+			short = short[:3]
+		}
+		return strings.Replace(s, abbr, string(short), 1)
+	}
+	return s
+}
+
+// FormatPlace joins the parts of a place name, given from most to least
+// specific (e.g. city, region, country), in the locale's order. The home
+// country is left out, unless it is the only part.
+func (l *Locale) FormatPlace(parts []string) string {
+	if n := len(parts); n > 1 && l.homeCountry != "" && strings.EqualFold(parts[n-1], l.homeCountry) {
+		parts = parts[:n-1]
+	}
+	if !l.largeFirst {
+		return strings.Join(parts, ", ")
+	}
+
+	reversed := make([]string, len(parts))
+	for i, part := range parts {
+		reversed[len(parts)-1-i] = part
+	}
+	return strings.Join(reversed, ", ")
+}
@@ -0,0 +1,240 @@
+// Package maintenance prunes state the tool keeps beside Lychee once it has
+// outlived its use: sidecar entries for photos and albums deleted from
+// Lychee, and cached AI titles for images no longer in the library or older
+// than a configured age.
+//
+// Nothing here touches Lychee itself. Pruning is cautious: if none of the
+// photos, albums or images the tool knows of are found in Lychee, the
+// database is more likely misconfigured than emptied, so that part of the
+// pass is skipped with a warning.
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/db"
+	"github.com/cdzombak/lychee-meta-tool/backend/sidecar"
+	"github.com/cdzombak/lychee-meta-tool/backend/titlecache"
+)
+
+// Options configure a Maintainer
+type Options struct {
+	// TitleCacheMaxAge expires cached titles older than this; zero keeps
+	// them until their image leaves Lychee
+	TitleCacheMaxAge time.Duration
+}
+
+// Result summarizes a maintenance pass
+type Result struct {
+	// DryRun is set if nothing was pruned, only counted
+	DryRun bool `json:"dry_run"`
+	// OrphanedPhotos and OrphanedAlbums are sidecar entries for photos
+	// and albums no longer in Lychee
+	OrphanedPhotos int `json:"orphaned_photos"`
+	OrphanedAlbums int `json:"orphaned_albums"`
+	// StaleTitles are cached titles for images no longer in Lychee, and
+	// ExpiredTitles those older than the maximum age
+	StaleTitles   int `json:"stale_titles"`
+	ExpiredTitles int `json:"expired_titles"`
+	// Warnings explain parts of the pass that were skipped
+	Warnings []string  `json:"warnings,omitempty"`
+	Finished time.Time `json:"finished"`
+}
+
+// Pruned returns the number of entries the pass pruned, or would have in a
+// dry run
+func (r Result) Pruned() int {
+	return r.OrphanedPhotos + r.OrphanedAlbums + r.StaleTitles + r.ExpiredTitles
+}
+
+// Maintainer prunes the sidecar store and title cache
+type Maintainer struct {
+	db      *db.DB
+	sidecar *sidecar.Store
+	titles  *titlecache.Cache
+	opts    Options
+
+	mu   sync.Mutex // serializes passes
+	last *Result
+}
+
+// New creates a Maintainer
+func New(database *db.DB, sidecarStore *sidecar.Store, titles *titlecache.Cache, opts Options) *Maintainer {
+	return &Maintainer{db: database, sidecar: sidecarStore, titles: titles, opts: opts}
+}
+
+// Run prunes every interval until ctx is cancelled
+func (m *Maintainer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if result, err := m.Prune(false); err != nil {
+			log.Printf("Maintenance failed: %v", err)
+		} else if result.Pruned() > 0 {
+			log.Printf("Maintenance pruned %d orphaned photo(s) and %d orphaned album(s) from the sidecar store, and %d stale and %d expired cached title(s)",
+				result.OrphanedPhotos, result.OrphanedAlbums, result.StaleTitles, result.ExpiredTitles)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Prune performs a single pass. With dryRun, it only counts what it would
+// prune.
+func (m *Maintainer) Prune(dryRun bool) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := Result{DryRun: dryRun}
+
+	photoIDs, err := m.orphanedPhotos(&result)
+	if err != nil {
+		return Result{}, err
+	}
+	albumIDs, err := m.orphanedAlbums(&result)
+	if err != nil {
+		return Result{}, err
+	}
+	titleKeys, err := m.prunableTitles(&result)
+	if err != nil {
+		return Result{}, err
+	}
+	result.OrphanedPhotos = len(photoIDs)
+	result.OrphanedAlbums = len(albumIDs)
+
+	if !dryRun {
+		if _, err := m.sidecar.DeletePhotos(photoIDs); err != nil {
+			return Result{}, fmt.Errorf("failed to prune photos from the sidecar store: %w", err)
+		}
+		if _, err := m.sidecar.DeleteAlbums(albumIDs); err != nil {
+			return Result{}, fmt.Errorf("failed to prune albums from the sidecar store: %w", err)
+		}
+		if _, err := m.titles.Delete(titleKeys); err != nil {
+			return Result{}, fmt.Errorf("failed to prune the title cache: %w", err)
+		}
+	}
+
+	result.Finished = time.Now().UTC()
+	if !dryRun {
+		m.last = &result
+	}
+	return result, nil
+}
+
+// orphanedPhotos returns the IDs of photos with sidecar state that are no
+// longer in Lychee
+func (m *Maintainer) orphanedPhotos(result *Result) ([]string, error) {
+	states := m.sidecar.Photos(nil)
+	ids := make([]string, 0, len(states))
+	for id := range states {
+		ids = append(ids, id)
+	}
+
+	times, err := m.db.GetPhotoUpdateTimes(ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) > 0 && len(times) == 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("None of the %d photos in the sidecar store are in Lychee; left them alone", len(ids)))
+		return nil, nil
+	}
+
+	var orphaned []string
+	for _, id := range ids {
+		if _, ok := times[id]; !ok {
+			orphaned = append(orphaned, id)
+		}
+	}
+	return orphaned, nil
+}
+
+// orphanedAlbums returns the IDs of albums with sidecar settings that are no
+// longer in Lychee. Smart albums are never orphaned.
+func (m *Maintainer) orphanedAlbums(result *Result) ([]string, error) {
+	var ids []string
+	for id := range m.sidecar.Albums() {
+		if !db.IsSmartAlbum(id) {
+			ids = append(ids, id)
+		}
+	}
+
+	existing, err := m.db.GetExistingAlbumIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) > 0 && len(existing) == 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("None of the %d albums in the sidecar store are in Lychee; left them alone", len(ids)))
+		return nil, nil
+	}
+
+	var orphaned []string
+	for _, id := range ids {
+		if !existing[id] {
+			orphaned = append(orphaned, id)
+		}
+	}
+	return orphaned, nil
+}
+
+// prunableTitles returns the keys of cached titles for images no longer in
+// Lychee or older than the maximum age, counting each kind in result
+func (m *Maintainer) prunableTitles(result *Result) ([]string, error) {
+	entries := m.titles.Entries()
+	seen := make(map[string]bool)
+	var checksums []string
+	for key := range entries {
+		if checksum := titlecache.Checksum(key); !seen[checksum] {
+			seen[checksum] = true
+			checksums = append(checksums, checksum)
+		}
+	}
+
+	existing, err := m.db.GetExistingChecksums(checksums)
+	if err != nil {
+		return nil, err
+	}
+	checkExisting := true
+	if len(checksums) > 0 && len(existing) == 0 {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("None of the %d images in the title cache are in Lychee; left their titles alone", len(checksums)))
+		checkExisting = false
+	}
+
+	var keys []string
+	var cutoff time.Time
+	if m.opts.TitleCacheMaxAge > 0 {
+		cutoff = time.Now().Add(-m.opts.TitleCacheMaxAge)
+	}
+	for key, entry := range entries {
+		switch {
+		case checkExisting && !existing[titlecache.Checksum(key)]:
+			result.StaleTitles++
+		case !cutoff.IsZero() && entry.CreatedAt.Before(cutoff):
+			result.ExpiredTitles++
+		default:
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Last returns the result of the most recent pass that pruned, rather than
+// a dry run, if any
+func (m *Maintainer) Last() (Result, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.last == nil {
+		return Result{}, false
+	}
+	return *m.last, true
+}
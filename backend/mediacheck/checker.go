@@ -0,0 +1,61 @@
+// Package mediacheck verifies that photos' image files can still be
+// retrieved, so that missing or unreachable files are found before AI
+// generation fails on them.
+package mediacheck
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/ai"
+	"github.com/cdzombak/lychee-meta-tool/backend/models"
+)
+
+// Problem is a size variant whose image can't be retrieved
+type Problem struct {
+	// Variant is the size variant's type, e.g. "thumb" or "original"; empty
+	// if the photo has no size variants at all
+	Variant string `json:"variant,omitempty"`
+	URL     string `json:"url,omitempty"`
+	Error   string `json:"error"`
+}
+
+// Checker checks photos' size variants the way the AI pipeline fetches
+// them: through the configured storage disks, or else over HTTP
+type Checker struct {
+	imageURLPattern string
+}
+
+// NewChecker creates a Checker that builds image URLs using imageURLPattern
+func NewChecker(imageURLPattern string) *Checker {
+	return &Checker{imageURLPattern: imageURLPattern}
+}
+
+// CheckPhoto checks each of a photo's size variants at once, returning the
+// ones that can't be retrieved, or none if all can
+func (c *Checker) CheckPhoto(ctx context.Context, photo *models.PhotoWithSizeVariants) []Problem {
+	images := photo.ToPhotoResponse(c.imageURLPattern).Images
+	if len(images) == 0 {
+		return []Problem{{Error: "photo has no image files"}}
+	}
+	ctx = ai.WithStorageDisk(ctx, photo.StorageDisk())
+
+	errs := make([]error, len(images))
+	var wg sync.WaitGroup
+	for i, image := range images {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = ai.CheckImage(ctx, image.URL)
+		}()
+	}
+	wg.Wait()
+
+	var problems []Problem
+	for i, err := range errs {
+		if err != nil {
+			problems = append(problems, Problem{Variant: images[i].Type, URL: images[i].URL, Error: err.Error()})
+		}
+	}
+	return problems
+}
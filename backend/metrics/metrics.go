@@ -0,0 +1,180 @@
+// Package metrics takes snapshots of the tool's state, such as the review
+// backlog and AI usage, in the Prometheus text exposition format. Snapshots
+// are served for scraping and can be pushed to a Prometheus Pushgateway,
+// for setups without a Prometheus server that can reach the tool.
+//
+// Metrics are gathered when a snapshot is taken rather than kept up to
+// date as things happen, so there is no dependency on a client library.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/ai"
+	"github.com/cdzombak/lychee-meta-tool/backend/db"
+)
+
+// Namespace prefixes every metric name
+const Namespace = "lychee_meta_tool"
+
+// ContentType is the media type of the text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Metric types
+const (
+	TypeGauge   = "gauge"
+	TypeCounter = "counter"
+)
+
+// Label is a metric label
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is one value of a metric family
+type Sample struct {
+	Labels []Label
+	Value  float64
+}
+
+// Family is a set of samples sharing a name, help text and type
+type Family struct {
+	// Name is the metric name without Namespace, e.g. "photos_needing_title"
+	Name    string
+	Help    string
+	Type    string
+	Samples []Sample
+}
+
+// Collector takes snapshots of the tool's metrics. It is safe for
+// concurrent use.
+type Collector struct {
+	db    *db.DB
+	usage *ai.UsageTracker
+}
+
+// NewCollector creates a Collector. usage may be nil when no AI backend is
+// configured.
+func NewCollector(database *db.DB, usage *ai.UsageTracker) *Collector {
+	return &Collector{db: database, usage: usage}
+}
+
+// Collect takes a snapshot of the metrics
+func (c *Collector) Collect() ([]Family, error) {
+	photos, err := c.db.CountPhotos(db.PhotoFilter{})
+	if err != nil {
+		return nil, err
+	}
+	missing, err := c.db.CountMissingFields(db.PhotoFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	stats := c.db.Stats()
+	families := []Family{
+		gauge("photos", "Photos in Lychee", float64(photos)),
+		gauge("photos_needing_title", "Photos whose title is empty or generated by a camera or app", float64(missing.Title)),
+		gauge("photos_missing_description", "Photos without a description", float64(missing.Description)),
+		gauge("db_open_connections", "Open connections to the Lychee database", float64(stats.OpenConnections)),
+		gauge("db_in_use_connections", "Connections to the Lychee database in use", float64(stats.InUse)),
+		counter("db_wait_count_total", "Times a query waited for a free database connection", float64(stats.WaitCount)),
+		counter("db_wait_seconds_total", "Time queries spent waiting for a free database connection", stats.WaitDuration.Seconds()),
+	}
+
+	if c.usage != nil {
+		report := c.usage.Report()
+		models := make([]string, 0, len(report.Models))
+		for model := range report.Models {
+			models = append(models, model)
+		}
+		slices.Sort(models)
+
+		requests := Family{Name: "ai_requests_total", Help: "AI requests since the server started", Type: TypeCounter}
+		failures := Family{Name: "ai_request_failures_total", Help: "Failed AI requests since the server started", Type: TypeCounter}
+		input := Family{Name: "ai_input_tokens_total", Help: "Input tokens sent to AI models since the server started", Type: TypeCounter}
+		output := Family{Name: "ai_output_tokens_total", Help: "Output tokens received from AI models since the server started", Type: TypeCounter}
+		cost := Family{Name: "ai_estimated_cost_usd_total", Help: "Estimated cost of AI requests to models with a known price, in US dollars", Type: TypeCounter}
+		for _, model := range models {
+			totals := report.Models[model]
+			labels := []Label{{"backend", report.Backend}, {"model", model}}
+			requests.Samples = append(requests.Samples, Sample{labels, float64(totals.Requests)})
+			failures.Samples = append(failures.Samples, Sample{labels, float64(totals.Failures)})
+			input.Samples = append(input.Samples, Sample{labels, float64(totals.InputTokens)})
+			output.Samples = append(output.Samples, Sample{labels, float64(totals.OutputTokens)})
+			cost.Samples = append(cost.Samples, Sample{labels, totals.EstimatedCost})
+		}
+		families = append(families, requests, failures, input, output, cost)
+	}
+
+	return families, nil
+}
+
+func gauge(name, help string, value float64) Family {
+	return Family{Name: name, Help: help, Type: TypeGauge, Samples: []Sample{{Value: value}}}
+}
+
+func counter(name, help string, value float64) Family {
+	return Family{Name: name, Help: help, Type: TypeCounter, Samples: []Sample{{Value: value}}}
+}
+
+// Write writes families to w in the text exposition format. Families
+// without samples are left out.
+func Write(w io.Writer, families []Family) error {
+	var b strings.Builder
+	for _, f := range families {
+		if len(f.Samples) == 0 {
+			continue
+		}
+		name := Namespace + "_" + f.Name
+		fmt.Fprintf(&b, "# HELP %s %s\n", name, escapeHelp(f.Help))
+		fmt.Fprintf(&b, "# TYPE %s %s\n", name, f.Type)
+		for _, s := range f.Samples {
+			b.WriteString(name)
+			if len(s.Labels) > 0 {
+				b.WriteByte('{')
+				for i, l := range s.Labels {
+					if i > 0 {
+						b.WriteByte(',')
+					}
+					fmt.Fprintf(&b, "%s=\"%s\"", l.Name, escapeLabel(l.Value))
+				}
+				b.WriteByte('}')
+			}
+			b.WriteByte(' ')
+			b.WriteString(formatValue(s.Value))
+			b.WriteByte('\n')
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// escapeHelp escapes a help text as the format requires
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+// escapeLabel escapes a label value as the format requires
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
+
+// formatValue formats a sample value, spelling out special values as the
+// format requires
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
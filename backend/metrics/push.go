@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+)
+
+// PushOptions configure a Pusher
+type PushOptions struct {
+	// URL is the Pushgateway's base URL, e.g. "http://pushgateway:9091"
+	URL string
+	// Job and Instance group the pushed metrics on the Pushgateway;
+	// Instance may be empty
+	Job      string
+	Instance string
+	// Username and Password authenticate with HTTP basic auth if Username
+	// is set
+	Username string
+	Password string
+}
+
+// Pusher pushes snapshots from a Collector to a Prometheus Pushgateway.
+// Each push replaces the metrics previously pushed for its job and instance.
+type Pusher struct {
+	collector *Collector
+	opts      PushOptions
+	client    *http.Client
+}
+
+// NewPusher creates a Pusher
+func NewPusher(collector *Collector, opts PushOptions) *Pusher {
+	return &Pusher{
+		collector: collector,
+		opts:      opts,
+		client:    &http.Client{Timeout: constants.MetricsPushTimeout},
+	}
+}
+
+// Run pushes every interval until ctx is cancelled
+func (p *Pusher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := p.Push(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to push metrics: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Push takes a snapshot and pushes it
+func (p *Pusher) Push(ctx context.Context) error {
+	families, err := p.collector.Collect()
+	if err != nil {
+		return fmt.Errorf("failed to collect metrics: %w", err)
+	}
+	var body bytes.Buffer
+	if err := Write(&body, families); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, p.groupingURL(), &body)
+	if err != nil {
+		return fmt.Errorf("failed to create push request: %w", err)
+	}
+	req.Header.Set("Content-Type", ContentType)
+	req.Header.Set("User-Agent", constants.AppName+"/"+constants.AppVersion)
+	if p.opts.Username != "" {
+		req.SetBasicAuth(p.opts.Username, p.opts.Password)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to push metrics: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("pushgateway returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// groupingURL returns the URL of the Pushgateway's group for the job and
+// instance
+func (p *Pusher) groupingURL() string {
+	u := strings.TrimSuffix(p.opts.URL, "/") + "/metrics" + groupingLabel("job", p.opts.Job)
+	if p.opts.Instance != "" {
+		u += groupingLabel("instance", p.opts.Instance)
+	}
+	return u
+}
+
+// groupingLabel encodes a grouping label as a URL path segment pair. Values
+// containing a slash, which can't be escaped in the path, use the
+// Pushgateway's base64 form.
+func groupingLabel(name, value string) string {
+	if strings.Contains(value, "/") {
+		return "/" + name + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value))
+	}
+	return "/" + name + "/" + url.PathEscape(value)
+}
@@ -3,32 +3,62 @@ package models
 import "time"
 
 type Album struct {
-	ID          string     `json:"id" db:"id"`
-	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
-	PublishedAt *time.Time `json:"published_at" db:"published_at"`
-	Title       string     `json:"title" db:"title"`
-	Description *string    `json:"description" db:"description"`
-	OwnerID     int        `json:"owner_id" db:"owner_id"`
-	IsNSFW      bool       `json:"is_nsfw" db:"is_nsfw"`
-	IsPinned    bool       `json:"is_pinned" db:"is_pinned"`
-	SortingCol  *string    `json:"sorting_col" db:"sorting_col"`
-	SortingOrder *string   `json:"sorting_order" db:"sorting_order"`
-	Copyright   *string    `json:"copyright" db:"copyright"`
-	PhotoLayout *string    `json:"photo_layout" db:"photo_layout"`
-	PhotoTimeline *string  `json:"photo_timeline" db:"photo_timeline"`
+	ID            string     `json:"id" db:"id"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+	PublishedAt   *time.Time `json:"published_at" db:"published_at"`
+	Title         string     `json:"title" db:"title"`
+	Description   *string    `json:"description" db:"description"`
+	OwnerID       int        `json:"owner_id" db:"owner_id"`
+	IsNSFW        bool       `json:"is_nsfw" db:"is_nsfw"`
+	IsPinned      bool       `json:"is_pinned" db:"is_pinned"`
+	SortingCol    *string    `json:"sorting_col" db:"sorting_col"`
+	SortingOrder  *string    `json:"sorting_order" db:"sorting_order"`
+	Copyright     *string    `json:"copyright" db:"copyright"`
+	PhotoLayout   *string    `json:"photo_layout" db:"photo_layout"`
+	PhotoTimeline *string    `json:"photo_timeline" db:"photo_timeline"`
+	// ParentID is the album containing this one, from Lychee's albums
+	// table; nil for top-level albums
+	ParentID *string `json:"parent_id" db:"parent_id"`
+}
+
+// ToAlbumResponse converts an Album to an AlbumResponse
+func (a *Album) ToAlbumResponse() AlbumResponse {
+	createdAt := a.CreatedAt
+	return AlbumResponse{
+		ID:          a.ID,
+		Title:       a.Title,
+		Description: a.Description,
+		IsNSFW:      a.IsNSFW,
+		ParentID:    a.ParentID,
+		CreatedAt:   &createdAt,
+	}
 }
 
 type AlbumResponse struct {
-	ID    string `json:"id"`
-	Title string `json:"title"`
+	ID          string  `json:"id"`
+	Title       string  `json:"title"`
+	Description *string `json:"description,omitempty"`
+	IsNSFW      bool    `json:"is_nsfw"`
+	// ParentID is set for albums nested in another album
+	ParentID *string `json:"parent_id,omitempty"`
+	// CreatedAt is unset for smart albums
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	// Smart is true for Lychee's built-in smart albums (starred, recent, public)
+	Smart bool `json:"smart,omitempty"`
+	// MissingTitleCount and MissingDescriptionCount are only set when
+	// listing albums with photos needing metadata
+	MissingTitleCount       int `json:"missing_title_count,omitempty"`
+	MissingDescriptionCount int `json:"missing_description_count,omitempty"`
 }
 
 type AlbumWithPhotoCount struct {
 	Album
-	PhotoCount int `json:"photo_count" db:"photo_count"`
+	// PhotoCount is the number of photos needing a title
+	PhotoCount              int `json:"photo_count" db:"photo_count"`
+	MissingDescriptionCount int `json:"missing_description_count" db:"missing_description_count"`
 }
 
 type AlbumsResponse struct {
 	Albums []AlbumResponse `json:"albums"`
-}
\ No newline at end of file
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// PhotoGroup is a set of near-identical photos, such as a burst of shots,
+// that can be titled once
+type PhotoGroup struct {
+	// ID is the ID of the group's first photo
+	ID string `json:"id"`
+	// PhotoIDs lists the group's photos in the order they were taken
+	PhotoIDs []string `json:"photo_ids"`
+}
+
+// GroupBursts finds bursts among photos: photos taken with the same camera,
+// each within window of the one before. Photos without a capture time or
+// camera are never grouped. Only groups of two or more photos are returned,
+// ordered by where their first member appears in photos.
+func GroupBursts(photos []PhotoWithSizeVariants, window time.Duration) []PhotoGroup {
+	type shot struct {
+		index   int
+		id      string
+		takenAt time.Time
+	}
+
+	byCamera := make(map[string][]shot)
+	for i := range photos {
+		p := &photos[i]
+		camera := cameraKey(p.Make, p.Model)
+		if camera == "" || p.TakenAt == nil {
+			continue
+		}
+		byCamera[camera] = append(byCamera[camera], shot{index: i, id: p.ID, takenAt: *p.TakenAt})
+	}
+
+	type indexedGroup struct {
+		first int
+		group PhotoGroup
+	}
+	var groups []indexedGroup
+	for _, shots := range byCamera {
+		sort.SliceStable(shots, func(i, j int) bool {
+			return shots[i].takenAt.Before(shots[j].takenAt)
+		})
+
+		start := 0
+		for i := 1; i <= len(shots); i++ {
+			if i < len(shots) && shots[i].takenAt.Sub(shots[i-1].takenAt) <= window {
+				continue
+			}
+			if i-start > 1 {
+				g := indexedGroup{first: shots[start].index, group: PhotoGroup{ID: shots[start].id}}
+				for _, s := range shots[start:i] {
+					g.group.PhotoIDs = append(g.group.PhotoIDs, s.id)
+					g.first = min(g.first, s.index)
+				}
+				groups = append(groups, g)
+			}
+			start = i
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].first < groups[j].first
+	})
+	result := make([]PhotoGroup, len(groups))
+	for i, g := range groups {
+		result[i] = g.group
+	}
+	return result
+}
+
+// cameraKey identifies a camera by make and model, or returns an empty
+// string if neither is known
+func cameraKey(cameraMake, cameraModel *string) string {
+	normalize := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return strings.ToLower(strings.TrimSpace(*s))
+	}
+	mk, md := normalize(cameraMake), normalize(cameraModel)
+	if mk == "" && md == "" {
+		return ""
+	}
+	return mk + "|" + md
+}
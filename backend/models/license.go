@@ -0,0 +1,26 @@
+package models
+
+import "slices"
+
+// LicenseNone is Lychee's license value for a photo with no license of its
+// own, which inherits its album's or the gallery's default license
+const LicenseNone = "none"
+
+// Licenses are the license values Lychee accepts for a photo, as in its
+// LicenseType enum
+var Licenses = []string{
+	LicenseNone,
+	"reserved",
+	"CC0",
+	"CC-BY-1.0", "CC-BY-2.0", "CC-BY-2.5", "CC-BY-3.0", "CC-BY-4.0",
+	"CC-BY-ND-1.0", "CC-BY-ND-2.0", "CC-BY-ND-2.5", "CC-BY-ND-3.0", "CC-BY-ND-4.0",
+	"CC-BY-SA-1.0", "CC-BY-SA-2.0", "CC-BY-SA-2.5", "CC-BY-SA-3.0", "CC-BY-SA-4.0",
+	"CC-BY-NC-1.0", "CC-BY-NC-2.0", "CC-BY-NC-2.5", "CC-BY-NC-3.0", "CC-BY-NC-4.0",
+	"CC-BY-NC-ND-1.0", "CC-BY-NC-ND-2.0", "CC-BY-NC-ND-2.5", "CC-BY-NC-ND-3.0", "CC-BY-NC-ND-4.0",
+	"CC-BY-NC-SA-1.0", "CC-BY-NC-SA-2.0", "CC-BY-NC-SA-2.5", "CC-BY-NC-SA-3.0", "CC-BY-NC-SA-4.0",
+}
+
+// ValidLicense reports whether license is a value Lychee accepts
+func ValidLicense(license string) bool {
+	return slices.Contains(Licenses, license)
+}
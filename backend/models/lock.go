@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// EditLock marks a photo as being edited by someone, so that other
+// reviewers can leave it alone. Locks are advisory: saving a photo locked
+// by someone else still succeeds.
+type EditLock struct {
+	// Holder is who is editing the photo (see auth.Actor); empty for an
+	// anonymous reviewer
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -4,8 +4,15 @@
 package models
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
 )
 
 // Photo represents a photo record from the Lychee database.
@@ -47,25 +54,190 @@ type PhotoWithAlbum struct {
 }
 
 // PhotoUpdate represents the fields that can be updated for a photo.
-// All fields are optional (pointers) to support partial updates.
+// All fields are optional (pointers) to support partial updates. In JSON,
+// an omitted field is left unchanged, while "description": null clears the
+// description and "album_id": null moves the photo out of its album.
 type PhotoUpdate struct {
 	Title       *string `json:"title"`
 	Description *string `json:"description"`
 	AlbumID     *string `json:"album_id"`
+	// Starred stars or unstars the photo in Lychee
+	Starred *bool `json:"starred"`
+	// License is one of Licenses
+	License *string `json:"license"`
+	// TakenAt is when the photo was taken, as an RFC 3339 timestamp
+	TakenAt *time.Time `json:"taken_at"`
+	// Latitude and Longitude geotag the photo, in decimal degrees; they
+	// are set together. Altitude is in meters.
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	Altitude  *float64 `json:"altitude"`
+	// TitleSource and DescriptionSource record how the new values were
+	// produced. They are kept in the sidecar store, not in Lychee.
+	TitleSource       *Provenance `json:"title_source,omitempty"`
+	DescriptionSource *Provenance `json:"description_source,omitempty"`
+	// ClearDescription sets the description to NULL; it is ignored if
+	// Description is set
+	ClearDescription bool `json:"-"`
+	// ClearAlbum moves the photo out of its album, to Unsorted; it is
+	// ignored if AlbumID is set
+	ClearAlbum bool `json:"-"`
+	// ClearTakenAt sets taken_at to NULL; it is ignored if TakenAt is set
+	ClearTakenAt bool `json:"-"`
+	// ClearCoordinates removes the photo's geotag, including its altitude
+	ClearCoordinates bool `json:"-"`
+	// ClearAltitude sets altitude to NULL; it is ignored if Altitude is set
+	ClearAltitude bool `json:"-"`
+}
+
+// errNullTitle is returned when decoding a PhotoUpdate that sets the title
+// to null; Lychee requires every photo to have a title
+var errNullTitle = errors.New("title cannot be null")
+
+// errPartialNullCoordinates is returned when decoding a PhotoUpdate that
+// clears only one of latitude and longitude
+var errPartialNullCoordinates = errors.New("latitude and longitude must be cleared together")
+
+// UnmarshalJSON decodes a PhotoUpdate, telling fields explicitly set to
+// null, which are cleared, from omitted fields
+func (u *PhotoUpdate) UnmarshalJSON(data []byte) error {
+	// photoUpdate has PhotoUpdate's fields but not this method
+	type photoUpdate PhotoUpdate
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	var decoded photoUpdate
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	isNull := func(name string) bool {
+		value, ok := fields[name]
+		return ok && bytes.Equal(bytes.TrimSpace(value), []byte("null"))
+	}
+	if isNull("title") {
+		return errNullTitle
+	}
+	if isNull("latitude") != isNull("longitude") {
+		return errPartialNullCoordinates
+	}
+
+	*u = PhotoUpdate(decoded)
+	u.ClearDescription = isNull("description")
+	u.ClearAlbum = isNull("album_id")
+	u.ClearTakenAt = isNull("taken_at")
+	u.ClearCoordinates = isNull("latitude")
+	u.ClearAltitude = isNull("altitude")
+	return nil
+}
+
+// ChangesDescription reports whether the update sets or clears the
+// description
+func (u PhotoUpdate) ChangesDescription() bool {
+	return u.Description != nil || u.ClearDescription
+}
+
+// ChangesTakenAt reports whether the update sets or clears taken_at
+func (u PhotoUpdate) ChangesTakenAt() bool {
+	return u.TakenAt != nil || u.ClearTakenAt
+}
+
+// ChangesGeotag reports whether the update sets or clears any of the
+// photo's coordinates
+func (u PhotoUpdate) ChangesGeotag() bool {
+	return u.Latitude != nil || u.Longitude != nil || u.Altitude != nil ||
+		u.ClearCoordinates || u.ClearAltitude
+}
+
+// Empty reports whether the update changes nothing
+func (u PhotoUpdate) Empty() bool {
+	return u.Title == nil && !u.ChangesDescription() && !u.ChangesAlbum() &&
+		u.Starred == nil && u.License == nil && !u.ChangesTakenAt() && !u.ChangesGeotag()
+}
+
+// ChangesAlbum reports whether the update moves the photo to another album
+// or out of its album
+func (u PhotoUpdate) ChangesAlbum() bool {
+	return u.AlbumID != nil || u.ClearAlbum
+}
+
+// Provenance records how a metadata value was produced
+type Provenance string
+
+const (
+	// ProvenanceAI means the value is an AI suggestion saved unchanged
+	ProvenanceAI Provenance = "ai"
+	// ProvenanceAIEdited means an AI suggestion was edited before saving
+	ProvenanceAIEdited Provenance = "ai_edited"
+	// ProvenanceManual means the value was written by a person
+	ProvenanceManual Provenance = "manual"
+)
+
+// Valid reports whether p is a known provenance value
+func (p Provenance) Valid() bool {
+	switch p {
+	case ProvenanceAI, ProvenanceAIEdited, ProvenanceManual:
+		return true
+	default:
+		return false
+	}
 }
 
 // PhotoResponse represents the JSON response format for photo data.
 // It includes computed URLs for thumbnail and full-size images.
 type PhotoResponse struct {
-	ID           string  `json:"id"`
-	Title        string  `json:"title"`
-	Description  *string `json:"description"`
-	AlbumID      *string `json:"album_id"`
-	AlbumTitle   *string `json:"album_title"`
-	ThumbnailURL string  `json:"thumbnail_url"`
-	LargeURL     string  `json:"large_url"`
-	FullURL      string  `json:"full_url"`
-	Type         string  `json:"type"`
+	ID           string     `json:"id"`
+	Title        string     `json:"title"`
+	Description  *string    `json:"description"`
+	AlbumID      *string    `json:"album_id"`
+	AlbumTitle   *string    `json:"album_title"`
+	ThumbnailURL string     `json:"thumbnail_url"`
+	LargeURL     string     `json:"large_url"`
+	MediumURL    string     `json:"medium_url"`
+	FullURL      string     `json:"full_url"`
+	Type         string     `json:"type"`
+	Starred      bool       `json:"starred"`
+	License      string     `json:"license"`
+	TakenAt      *time.Time `json:"taken_at,omitempty"`
+	// TakenAtDisplay is the date in TakenAt formatted for the library's
+	// locale, e.g. "May 4, 2024"
+	TakenAtDisplay string   `json:"taken_at_display,omitempty"`
+	Latitude       *float64 `json:"latitude,omitempty"`
+	Longitude      *float64 `json:"longitude,omitempty"`
+	Altitude       *float64 `json:"altitude,omitempty"`
+	// Images lists every available size, smallest first, for use as a
+	// responsive image set (e.g. an <img> srcset)
+	Images []ImageVariant `json:"images,omitempty"`
+	// Width and Height are the dimensions of the largest variant, and Ratio
+	// is its aspect ratio (width / height), so layouts can reserve space
+	// before images load. They are omitted when unknown.
+	Width  int     `json:"width,omitempty"`
+	Height int     `json:"height,omitempty"`
+	Ratio  float64 `json:"ratio,omitempty"`
+	// Blurhash is a BlurHash placeholder to show while the image loads.
+	// It is computed in the background and absent until ready.
+	Blurhash string `json:"blurhash,omitempty"`
+	// DominantColor is the thumbnail's most common color as "#rrggbb",
+	// computed alongside Blurhash
+	DominantColor string `json:"dominant_color,omitempty"`
+
+	// Provenance from the sidecar store, if known
+	TitleProvenance       Provenance `json:"title_provenance,omitempty"`
+	DescriptionProvenance Provenance `json:"description_provenance,omitempty"`
+	// ExternalEditAt is set when the photo was found to have been edited
+	// directly in Lychee after the tool last saved it
+	ExternalEditAt *time.Time `json:"external_edit_at,omitempty"`
+	// EditLock is set while someone is editing the photo
+	EditLock *EditLock `json:"edit_lock,omitempty"`
+}
+
+// ImageVariant is one size of a photo's image
+type ImageVariant struct {
+	Type   string `json:"type"`
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
 }
 
 // NeedsMetadata determines if a photo requires metadata updates.
@@ -88,25 +260,49 @@ func (p *Photo) hasEmptyDescription() bool {
 	return p.Description == nil || *p.Description == ""
 }
 
-// ToPhotoResponse converts a PhotoWithSizeVariants to a PhotoResponse with proper URL generation
-func (p *PhotoWithSizeVariants) ToPhotoResponse(lycheeBaseURL string) PhotoResponse {
+// ToPhotoResponse converts a PhotoWithSizeVariants to a PhotoResponse with proper URL generation.
+// imageURLPattern is an image URL with constants.ImageURLShortPath in place of each image's path;
+// see config.Config.ImageURLPattern.
+func (p *PhotoWithSizeVariants) ToPhotoResponse(imageURLPattern string) PhotoResponse {
 	thumbnailURL := ""
 	largeURL := ""
+	mediumURL := ""
 	fullURL := ""
 
 	// Construct thumbnail URL
 	if p.ThumbnailPath != nil && *p.ThumbnailPath != "" {
-		thumbnailURL = constructImageURL(lycheeBaseURL, *p.ThumbnailPath)
+		thumbnailURL = constructImageURL(imageURLPattern, *p.ThumbnailPath)
 	}
 
 	// Construct large URL (for AI processing)
 	if p.LargePath != nil && *p.LargePath != "" {
-		largeURL = constructImageURL(lycheeBaseURL, *p.LargePath)
+		largeURL = constructImageURL(imageURLPattern, *p.LargePath)
+	}
+
+	// Construct medium URL (AI fallback when larger variants are missing)
+	if p.MediumPath != nil && *p.MediumPath != "" {
+		mediumURL = constructImageURL(imageURLPattern, *p.MediumPath)
 	}
 
 	// Construct full/original image URL
 	if p.OriginalPath != nil && *p.OriginalPath != "" {
-		fullURL = constructImageURL(lycheeBaseURL, *p.OriginalPath)
+		fullURL = constructImageURL(imageURLPattern, *p.OriginalPath)
+	}
+
+	var images []ImageVariant
+	for _, v := range p.SizeVariants {
+		images = append(images, ImageVariant{
+			Type:   v.Type.String(),
+			URL:    constructImageURL(imageURLPattern, v.ShortPath),
+			Width:  v.Width,
+			Height: v.Height,
+		})
+	}
+
+	width, height := p.Dimensions()
+	var ratio float64
+	if width > 0 && height > 0 {
+		ratio = math.Round(float64(width)/float64(height)*10000) / 10000
 	}
 
 	return PhotoResponse{
@@ -117,20 +313,51 @@ func (p *PhotoWithSizeVariants) ToPhotoResponse(lycheeBaseURL string) PhotoRespo
 		AlbumTitle:   p.AlbumTitle,
 		ThumbnailURL: thumbnailURL,
 		LargeURL:     largeURL,
+		MediumURL:    mediumURL,
 		FullURL:      fullURL,
 		Type:         p.Type,
+		Starred:      p.IsStarred,
+		License:      p.License,
+		TakenAt:      p.TakenAt,
+		Latitude:     p.Latitude,
+		Longitude:    p.Longitude,
+		Altitude:     p.Altitude,
+		Images:       images,
+		Width:        width,
+		Height:       height,
+		Ratio:        ratio,
+	}
+}
+
+// VersionImageURLs adds a "v" query parameter derived from updatedAt to the
+// response's image URLs, so that caches keyed on the URL miss once the
+// photo has been edited
+func (r *PhotoResponse) VersionImageURLs(updatedAt time.Time) {
+	version := strconv.FormatInt(updatedAt.Unix(), 10)
+	urls := []*string{&r.ThumbnailURL, &r.LargeURL, &r.MediumURL, &r.FullURL}
+	for i := range r.Images {
+		urls = append(urls, &r.Images[i].URL)
+	}
+	for _, u := range urls {
+		if *u == "" {
+			continue
+		}
+		separator := "?"
+		if strings.Contains(*u, "?") {
+			separator = "&"
+		}
+		*u += separator + "v=" + version
 	}
 }
 
-// constructImageURL builds a proper URL from the Lychee base URL and image path
-func constructImageURL(baseURL, imagePath string) string {
-	if baseURL == "" || imagePath == "" {
+// constructImageURL builds a proper URL from the image URL pattern and image path
+func constructImageURL(pattern, imagePath string) string {
+	if pattern == "" || imagePath == "" {
 		return ""
 	}
 
-	// Ensure base URL doesn't end with slash and image path doesn't start with slash
-	baseURL = strings.TrimSuffix(baseURL, "/")
+	// The pattern supplies any separator before the path
 	imagePath = strings.TrimPrefix(imagePath, "/")
 
-	return baseURL + "/uploads/" + imagePath
+	return strings.ReplaceAll(pattern, constants.ImageURLShortPath, imagePath)
 }
@@ -5,13 +5,13 @@ type SizeVariantType int
 
 const (
 	// Based on Lychee's size variant types
-	SizeVariantOriginal    SizeVariantType = 0
-	SizeVariantSmall2x     SizeVariantType = 1
-	SizeVariantSmall       SizeVariantType = 2
-	SizeVariantMedium2x    SizeVariantType = 3
-	SizeVariantMedium      SizeVariantType = 4
-	SizeVariantSmallThumb  SizeVariantType = 5
-	SizeVariantThumb       SizeVariantType = 6
+	SizeVariantOriginal   SizeVariantType = 0
+	SizeVariantSmall2x    SizeVariantType = 1
+	SizeVariantSmall      SizeVariantType = 2
+	SizeVariantMedium2x   SizeVariantType = 3
+	SizeVariantMedium     SizeVariantType = 4
+	SizeVariantSmallThumb SizeVariantType = 5
+	SizeVariantThumb      SizeVariantType = 6
 )
 
 // SizeVariant represents a photo size variant in the Lychee database
@@ -32,7 +32,32 @@ type PhotoWithSizeVariants struct {
 	PhotoWithAlbum
 	ThumbnailPath *string `json:"thumbnail_path" db:"thumbnail_path"`
 	LargePath     *string `json:"large_path" db:"large_path"`
+	MediumPath    *string `json:"medium_path" db:"medium_path"`
 	OriginalPath  *string `json:"original_path" db:"original_path"`
+	// SizeVariants lists every variant with a file, smallest first
+	SizeVariants []SizeVariant `json:"size_variants,omitempty"`
+}
+
+// Dimensions returns the width and height of the photo's largest size
+// variant, or zeros if no variant has known dimensions
+func (p *PhotoWithSizeVariants) Dimensions() (width, height int) {
+	for _, v := range p.SizeVariants {
+		if v.Width*v.Height > width*height {
+			width, height = v.Width, v.Height
+		}
+	}
+	return width, height
+}
+
+// StorageDisk returns the Lychee storage disk holding the photo's size
+// variants, or "" if it has none
+func (p *PhotoWithSizeVariants) StorageDisk() string {
+	for _, v := range p.SizeVariants {
+		if v.StorageDisk != "" {
+			return v.StorageDisk
+		}
+	}
+	return ""
 }
 
 // GetThumbnailVariant returns the thumbnail size variant type
@@ -65,4 +90,4 @@ func (s SizeVariantType) String() string {
 	default:
 		return "unknown"
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,8 @@
+package models
+
+// LycheeUser represents a user account from the Lychee database
+type LycheeUser struct {
+	ID              int    `json:"id" db:"id"`
+	Username        string `json:"username" db:"username"`
+	MayAdministrate bool   `json:"may_administrate" db:"may_administrate"`
+}
@@ -3,20 +3,68 @@ package ollama
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cdzombak/lychee-meta-tool/backend/ai"
 	"github.com/cdzombak/lychee-meta-tool/backend/constants"
 	"github.com/ollama/ollama/api"
+	"github.com/ollama/ollama/envconfig"
 )
 
-var _ ai.Client = (*Client)(nil)
+var (
+	_ ai.Client              = (*Client)(nil)
+	_ ai.ModelStatusReporter = (*Client)(nil)
+	_ ai.ModelLister         = (*Client)(nil)
+	_ ai.TagGenerator        = (*Client)(nil)
+	_ ai.AltTextGenerator    = (*Client)(nil)
+	_ ai.TitleStreamer       = (*Client)(nil)
+)
+
+// BackendName is the name under which the Ollama backend is registered
+const BackendName = "ollama"
+
+func init() {
+	ai.Register(BackendName, func(settings ai.Settings) (ai.Client, error) {
+		client, err := NewClient(settings["url"], settings["model"])
+		if err != nil {
+			return nil, err
+		}
+		if client.numPredict, err = settings.Int("num_predict", 0); err != nil {
+			return nil, err
+		}
+		if client.titleWords, err = settings.Int("title_words", 0); err != nil {
+			return nil, err
+		}
+		if client.temperature, err = settings.Float("temperature", DefaultTemperature); err != nil {
+			return nil, err
+		}
+		if client.topP, err = settings.Float("top_p", DefaultTopP); err != nil {
+			return nil, err
+		}
+		if client.numCtx, err = settings.Int("num_ctx", 0); err != nil {
+			return nil, err
+		}
+		if v := settings["keep_alive"]; v != "" {
+			if client.keepAlive, err = ParseKeepAlive(v); err != nil {
+				return nil, fmt.Errorf("setting keep_alive: %w", err)
+			}
+		}
+		if settings.Get("auto_pull", "false") == "true" {
+			client.EnableAutoPull()
+		} else {
+			client.checkModel()
+		}
+		return client, nil
+	})
+}
 
 // Constants for configuration and limits (using shared constants)
 const (
@@ -30,22 +78,21 @@ const (
 	HTTPTimeout = constants.ImageDownloadTimeout
 )
 
+// Sampling options used unless configured otherwise
+const (
+	DefaultTemperature = 0.7
+	DefaultTopP        = 0.9
+)
+
 // Default prompts for different scenarios
 const (
 	DetailedPrompt = "Provide a title for this photo. The title should be eloquent and concise, suitable for an artistic photograph but not pretentious. The title should be just a few words at most; shorter is usually better. You MUST provide _only_ the title as your response."
 	SimplePrompt   = "Describe this image with a short, artistic title (3-5 words maximum):"
 )
 
-// Image format validation (using shared constants where possible)
-var (
-	validImageTypes = []string{constants.MimeJPEG, "image/jpg", constants.MimePNG, constants.MimeWEBP, constants.MimeGIF}
-	validImageSigs  = map[string][]byte{
-		"jpeg": {0xFF, 0xD8, 0xFF},
-		"png":  {0x89, 0x50, 0x4E, 0x47},
-		"gif":  {0x47, 0x49, 0x46, 0x38},
-		"webp": {0x52, 0x49, 0x46, 0x46}, // RIFF header, WEBP at offset 8
-	}
-)
+// acceptedImageTypes are the image formats sent to Ollama as-is; others
+// are transcoded to JPEG first
+var acceptedImageTypes = []string{constants.MimeJPEG, constants.MimePNG, constants.MimeWEBP}
 
 // GenerationStrategy represents different approaches to send images to Ollama
 type GenerationStrategy int
@@ -60,7 +107,22 @@ const (
 // Client wraps the Ollama API client with additional functionality
 type Client struct {
 	client *api.Client
-	model  string
+	// pullClient has no timeout, since model downloads can take a long time
+	pullClient *api.Client
+	model      string
+	autoPull   bool
+	state      modelState
+	// numPredict caps generated tokens; 0 leaves the server default
+	numPredict int
+	// titleWords is the default TitleOptions.Words
+	titleWords int
+	// temperature and topP are the sampling options for every generation
+	temperature, topP float64
+	// numCtx sets the context window size; 0 leaves the model's default
+	numCtx int
+	// keepAlive is how long Ollama keeps the model loaded after a request;
+	// nil leaves the server default
+	keepAlive *api.Duration
 }
 
 // NewClient creates a new Ollama client with the specified URL and model
@@ -70,30 +132,67 @@ func NewClient(url, model string) (*Client, error) {
 	}
 
 	httpClient := &http.Client{
-		Timeout: DefaultTimeout,
+		Timeout:   DefaultTimeout,
+		Transport: ai.Transport,
 	}
 
-	var client *api.Client
+	// Pulls stream progress for as long as the download takes
+	pullHTTPClient := &http.Client{Transport: ai.Transport}
+
+	var client, pullClient *api.Client
 	if url != "" {
 		parsedURL, err := parseURL(url)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse Ollama URL %q: %w", url, err)
 		}
 		client = api.NewClient(parsedURL, httpClient)
+		pullClient = api.NewClient(parsedURL, pullHTTPClient)
 		log.Printf("Ollama client configured with URL: %s, Model: %s", url, model)
 	} else {
-		var err error
-		client, err = api.ClientFromEnvironment()
-		if err != nil {
-			return nil, fmt.Errorf("failed to create Ollama client from environment: %w", err)
+		// As api.ClientFromEnvironment does, but sending the outbound
+		// headers
+		host := envconfig.Host()
+		client = api.NewClient(host, httpClient)
+		pullClient = api.NewClient(host, pullHTTPClient)
+		log.Printf("Ollama client configured from environment (%s), Model: %s", host, model)
+	}
+
+	c := &Client{
+		client:      client,
+		pullClient:  pullClient,
+		model:       model,
+		temperature: DefaultTemperature,
+		topP:        DefaultTopP,
+	}
+	c.state.status = ai.ModelStatus{Model: model, State: ai.ModelUnknown}
+	return c, nil
+}
+
+// EnableAutoPull makes the client download its model if the Ollama server
+// doesn't have it, rather than failing every generation. The check starts
+// right away in the background.
+func (c *Client) EnableAutoPull() {
+	c.autoPull = true
+	c.checkModel()
+}
+
+// ParseKeepAlive parses a keep_alive value the way Ollama does: a duration
+// such as "10m", or a number of seconds. Negative values keep the model
+// loaded indefinitely and zero unloads it after each request.
+func ParseKeepAlive(v string) (*api.Duration, error) {
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		seconds, numErr := strconv.ParseFloat(v, 64)
+		if numErr != nil {
+			return nil, fmt.Errorf("invalid keep_alive %q: use a duration like 10m or a number of seconds", v)
 		}
-		log.Printf("Ollama client configured from environment, Model: %s", model)
+		d = time.Duration(seconds * float64(time.Second))
 	}
-
-	return &Client{
-		client: client,
-		model:  model,
-	}, nil
+	if d < 0 {
+		// Sent to Ollama as -1
+		d = -1
+	}
+	return &api.Duration{Duration: d}, nil
 }
 
 // parseURL validates and parses a URL string
@@ -119,133 +218,134 @@ func parseURL(rawURL string) (*url.URL, error) {
 }
 
 // GenerateTitle downloads an image and generates a title using Ollama AI
-func (c *Client) GenerateTitle(ctx context.Context, imageURL string) (string, error) {
+func (c *Client) GenerateTitle(ctx context.Context, imageURL string, opts ai.TitleOptions) (string, error) {
 	if imageURL == "" {
 		return "", fmt.Errorf("image URL cannot be empty")
 	}
 
-	// Download image with validation
-	imageData, contentType, err := c.downloadImage(ctx, imageURL)
+	if err := c.ensureModel(ctx); err != nil {
+		return "", err
+	}
+
+	if opts.Words == 0 {
+		opts.Words = c.titleWords
+	}
+
+	img, err := ai.FetchImage(ctx, imageURL)
 	if err != nil {
 		return "", fmt.Errorf("failed to download image: %w", err)
 	}
+	img, err = img.ConvertTo(acceptedImageTypes...)
+	if err != nil {
+		return "", fmt.Errorf("failed to prepare image: %w", err)
+	}
 
 	// Generate title using strategy pattern with fallbacks
-	return c.generateTitleWithFallback(ctx, imageData, contentType)
+	return c.generateTitleWithFallback(ctx, img.Data, img.ContentType, opts)
 }
 
-// downloadImage downloads and validates an image from the given URL
-func (c *Client) downloadImage(ctx context.Context, imageURL string) ([]byte, string, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to create request: %w", err)
+// StreamTitle generates a title like GenerateTitle, calling onToken with
+// each piece of the reply as Ollama streams it. Only the first generation
+// strategy is used, since text already streamed can't be taken back if a
+// fallback were needed.
+func (c *Client) StreamTitle(ctx context.Context, imageURL string, opts ai.TitleOptions, onToken func(string)) (string, error) {
+	if imageURL == "" {
+		return "", fmt.Errorf("image URL cannot be empty")
 	}
 
-	client := http.Client{Timeout: HTTPTimeout}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to download: %w", err)
+	if err := c.ensureModel(ctx); err != nil {
+		return "", err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	if opts.Words == 0 {
+		opts.Words = c.titleWords
 	}
 
-	contentType := resp.Header.Get("Content-Type")
-	log.Printf("Downloaded image: Content-Type=%s, Status=%d, URL=%s", contentType, resp.StatusCode, imageURL)
-
-	if !isValidImageType(contentType) {
-		return nil, "", fmt.Errorf("unsupported image type: %s", contentType)
+	img, err := ai.FetchImage(ctx, imageURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to download image: %w", err)
 	}
-
-	imageData, err := io.ReadAll(resp.Body)
+	img, err = img.ConvertTo(acceptedImageTypes...)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to read image data: %w", err)
+		return "", fmt.Errorf("failed to prepare image: %w", err)
 	}
 
-	if len(imageData) == 0 {
-		return nil, "", fmt.Errorf("received empty image data")
+	return c.executeGeneration(ctx, api.ImageData(img.Data), DetailedPrompt+opts.PromptSuffix(), c.numPredict, onToken)
+}
+
+// GenerateTags downloads an image and asks Ollama for keyword tags
+func (c *Client) GenerateTags(ctx context.Context, imageURL string, opts ai.TagOptions) ([]string, error) {
+	if imageURL == "" {
+		return nil, fmt.Errorf("image URL cannot be empty")
 	}
 
-	if !hasValidImageSignature(imageData) {
-		return nil, "", fmt.Errorf("invalid image format or corrupted data")
+	if err := c.ensureModel(ctx); err != nil {
+		return nil, err
 	}
 
-	if len(imageData) > MaxImageSize {
-		log.Printf("Warning: Large image detected (%d bytes), may cause performance issues", len(imageData))
+	img, err := ai.FetchImage(ctx, imageURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download image: %w", err)
+	}
+	img, err = img.ConvertTo(acceptedImageTypes...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare image: %w", err)
 	}
 
-	log.Printf("Image validation successful: %d bytes", len(imageData))
-	return imageData, contentType, nil
-}
+	// num_predict is sized for titles, so leave tag length to the server
+	response, err := c.executeGeneration(ctx, api.ImageData(img.Data), opts.Prompt(), 0, nil)
+	if err != nil {
+		return nil, err
+	}
 
-// isValidImageType checks if the content type is supported
-func isValidImageType(contentType string) bool {
-	contentType = strings.ToLower(contentType)
-	for _, validType := range validImageTypes {
-		if strings.Contains(contentType, validType) {
-			return true
-		}
+	tags := ai.ParseTags(response, opts)
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("received no tags")
 	}
-	return false
+
+	log.Printf("Generated %d tags", len(tags))
+	return tags, nil
 }
 
-// hasValidImageSignature checks if the data starts with a valid image file signature
-func hasValidImageSignature(data []byte) bool {
-	if len(data) < 4 {
-		return false
+// GenerateAltText downloads an image and asks Ollama to describe it for
+// screen readers
+func (c *Client) GenerateAltText(ctx context.Context, imageURL string, opts ai.AltTextOptions) (string, error) {
+	if imageURL == "" {
+		return "", fmt.Errorf("image URL cannot be empty")
 	}
 
-	// Check JPEG signature
-	if bytes := validImageSigs["jpeg"]; len(data) >= len(bytes) {
-		if compareBytes(data[:len(bytes)], bytes) {
-			return true
-		}
+	if err := c.ensureModel(ctx); err != nil {
+		return "", err
 	}
 
-	// Check PNG signature
-	if bytes := validImageSigs["png"]; len(data) >= len(bytes) {
-		if compareBytes(data[:len(bytes)], bytes) {
-			return true
-		}
+	img, err := ai.FetchImage(ctx, imageURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to download image: %w", err)
 	}
-
-	// Check GIF signature
-	if bytes := validImageSigs["gif"]; len(data) >= len(bytes) {
-		if compareBytes(data[:len(bytes)], bytes) {
-			return true
-		}
+	img, err = img.ConvertTo(acceptedImageTypes...)
+	if err != nil {
+		return "", fmt.Errorf("failed to prepare image: %w", err)
 	}
 
-	// Check WebP signature (RIFF header + WEBP at offset 8)
-	if bytes := validImageSigs["webp"]; len(data) >= len(bytes) {
-		if compareBytes(data[:len(bytes)], bytes) && len(data) >= 12 {
-			webpSig := []byte{0x57, 0x45, 0x42, 0x50} // "WEBP"
-			if compareBytes(data[8:12], webpSig) {
-				return true
-			}
-		}
+	// The generate API has no separate system prompt, so the instructions
+	// lead the prompt; num_predict is sized for titles, so leave the
+	// length to the server
+	response, err := c.executeGeneration(ctx, api.ImageData(img.Data), ai.AltTextSystemPrompt+"\n\n"+opts.Prompt(), 0, nil)
+	if err != nil {
+		return "", err
 	}
 
-	return false
-}
-
-// compareBytes compares two byte slices for equality
-func compareBytes(a, b []byte) bool {
-	if len(a) != len(b) {
-		return false
-	}
-	for i := range a {
-		if a[i] != b[i] {
-			return false
-		}
+	altText := ai.CleanAltText(response)
+	if altText == "" {
+		return "", fmt.Errorf("received empty alt text")
 	}
-	return true
+
+	log.Printf("Generated alt text: %s", altText)
+	return altText, nil
 }
 
 // generateTitleWithFallback tries multiple strategies to generate a title
-func (c *Client) generateTitleWithFallback(ctx context.Context, imageBytes []byte, contentType string) (string, error) {
+func (c *Client) generateTitleWithFallback(ctx context.Context, imageBytes []byte, contentType string, opts ai.TitleOptions) (string, error) {
 	strategies := []GenerationStrategy{
 		StrategyRawBytes,
 		StrategyBase64,
@@ -257,12 +357,17 @@ func (c *Client) generateTitleWithFallback(ctx context.Context, imageBytes []byt
 	for i, strategy := range strategies {
 		log.Printf("Attempting strategy %d/%d: %s", i+1, len(strategies), strategyName(strategy))
 
-		title, err := c.generateTitleWithStrategy(ctx, imageBytes, contentType, strategy)
+		title, err := c.generateTitleWithStrategy(ctx, imageBytes, contentType, strategy, opts)
 		if err == nil && title != "" {
 			log.Printf("Success with strategy: %s, title: %s", strategyName(strategy), title)
 			return title, nil
 		}
 
+		if errors.Is(err, ai.ErrNoVision) {
+			// No way of sending the image will help
+			return "", err
+		}
+
 		lastErr = err
 		if err != nil {
 			log.Printf("Strategy %s failed: %v", strategyName(strategy), err)
@@ -291,7 +396,7 @@ func strategyName(strategy GenerationStrategy) string {
 }
 
 // generateTitleWithStrategy generates a title using the specified strategy
-func (c *Client) generateTitleWithStrategy(ctx context.Context, imageBytes []byte, contentType string, strategy GenerationStrategy) (string, error) {
+func (c *Client) generateTitleWithStrategy(ctx context.Context, imageBytes []byte, contentType string, strategy GenerationStrategy, opts ai.TitleOptions) (string, error) {
 	var imageData api.ImageData
 	var cleanup func()
 
@@ -331,7 +436,7 @@ func (c *Client) generateTitleWithStrategy(ctx context.Context, imageBytes []byt
 		prompt = SimplePrompt
 	}
 
-	return c.executeGeneration(ctx, imageData, prompt)
+	return c.executeGeneration(ctx, imageData, prompt+opts.PromptSuffix(), c.numPredict, nil)
 }
 
 // createTempFile creates a temporary file with the image data
@@ -351,29 +456,46 @@ func (c *Client) createTempFile(imageBytes []byte, contentType string) (string,
 	return tmpFile.Name(), nil
 }
 
-// executeGeneration performs the actual API call to Ollama
-func (c *Client) executeGeneration(ctx context.Context, imageData api.ImageData, prompt string) (string, error) {
+// executeGeneration performs the actual API call to Ollama. numPredict caps
+// the generated tokens; 0 leaves the server default. If onToken is not nil,
+// the response is streamed and onToken is called with each piece of it.
+func (c *Client) executeGeneration(ctx context.Context, imageData api.ImageData, prompt string, numPredict int, onToken func(string)) (string, error) {
+	options := map[string]interface{}{
+		"temperature": c.temperature,
+		"top_p":       c.topP,
+	}
+	if numPredict > 0 {
+		options["num_predict"] = numPredict
+	}
+	if c.numCtx > 0 {
+		options["num_ctx"] = c.numCtx
+	}
+
 	req := &api.GenerateRequest{
-		Model:  c.model,
-		Prompt: prompt,
-		Images: []api.ImageData{imageData},
-		Stream: &[]bool{false}[0],
-		Options: map[string]interface{}{
-			"temperature": 0.7,
-			"top_p":       0.9,
-		},
+		Model:     c.model,
+		Prompt:    prompt,
+		Images:    []api.ImageData{imageData},
+		Stream:    &[]bool{onToken != nil}[0],
+		Options:   options,
+		KeepAlive: c.keepAlive,
 	}
 
 	var fullResponse strings.Builder
 	err := c.client.Generate(ctx, req, func(resp api.GenerateResponse) error {
+		if resp.Done {
+			ai.ReportUsage(ctx, ai.Usage{InputTokens: resp.PromptEvalCount, OutputTokens: resp.EvalCount})
+		}
 		if resp.Response != "" {
 			fullResponse.WriteString(resp.Response)
+			if onToken != nil {
+				onToken(resp.Response)
+			}
 		}
 		return nil
 	})
 
 	if err != nil {
-		return "", fmt.Errorf("generation failed: %w", err)
+		return "", ai.VisionError(c.model, fmt.Errorf("generation failed: %w", err))
 	}
 
 	result := strings.TrimSpace(fullResponse.String())
@@ -0,0 +1,184 @@
+package ollama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/ai"
+	"github.com/ollama/ollama/api"
+	"github.com/ollama/ollama/types/model"
+)
+
+// pullLogInterval limits how often pull progress is logged
+const pullLogInterval = 10 * time.Second
+
+// modelState tracks whether the configured model is present on the Ollama
+// server, and the progress of pulling it if not
+type modelState struct {
+	mu     sync.Mutex
+	status ai.ModelStatus
+}
+
+func (s *modelState) get() ai.ModelStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+func (s *modelState) set(fn func(*ai.ModelStatus)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&s.status)
+}
+
+// ModelStatus reports whether the configured model is available
+func (c *Client) ModelStatus() ai.ModelStatus {
+	return c.state.get()
+}
+
+// ensureModel checks that the configured model is present on the Ollama
+// server. If it is missing and auto-pull is enabled, a pull is started in
+// the background and an error describing its progress is returned until it
+// completes.
+func (c *Client) ensureModel(ctx context.Context) error {
+	status := c.state.get()
+	switch status.State {
+	case ai.ModelReady:
+		return nil
+	case ai.ModelPulling:
+		return pullingError(status)
+	}
+
+	resp, err := c.client.Show(ctx, &api.ShowRequest{Model: c.model})
+	if err == nil {
+		// Servers older than 0.6.4 don't report capabilities; assume the
+		// best and let generation fail if the model can't see images
+		if len(resp.Capabilities) > 0 && !slices.Contains(resp.Capabilities, model.CapabilityVision) {
+			noVision := ai.NoVisionError{Model: c.model}
+			c.state.set(func(s *ai.ModelStatus) {
+				*s = ai.ModelStatus{Model: c.model, State: ai.ModelNoVision, Error: noVision.Error()}
+			})
+			return noVision
+		}
+		c.state.set(func(s *ai.ModelStatus) {
+			*s = ai.ModelStatus{Model: c.model, State: ai.ModelReady}
+		})
+		return nil
+	}
+
+	var statusErr api.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		// The server may be unreachable; let generation report the error
+		return nil
+	}
+
+	if !c.autoPull {
+		c.state.set(func(s *ai.ModelStatus) {
+			*s = ai.ModelStatus{Model: c.model, State: ai.ModelMissing}
+		})
+		return fmt.Errorf("model %q is not present on the Ollama server; pull it with `ollama pull %s` or enable ollama.auto_pull", c.model, c.model)
+	}
+
+	started := false
+	c.state.set(func(s *ai.ModelStatus) {
+		if s.State != ai.ModelPulling {
+			*s = ai.ModelStatus{Model: c.model, State: ai.ModelPulling}
+			started = true
+		}
+	})
+	if started {
+		go c.pullModel()
+	}
+	return pullingError(c.state.get())
+}
+
+// checkModel checks in the background whether the configured model is
+// present and can accept images, so that /api/ai/status reports problems
+// before the first generation
+func (c *Client) checkModel() {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), HTTPTimeout)
+		defer cancel()
+		if err := c.ensureModel(ctx); err != nil {
+			log.Printf("Ollama model check: %v", err)
+		}
+	}()
+}
+
+// pullModel downloads the configured model, logging progress. It runs
+// independently of any request so that a slow download isn't cancelled.
+func (c *Client) pullModel() {
+	log.Printf("Ollama model %q not found; pulling it", c.model)
+
+	var lastLog time.Time
+	err := c.pullClient.Pull(context.Background(), &api.PullRequest{Model: c.model}, func(resp api.ProgressResponse) error {
+		c.state.set(func(s *ai.ModelStatus) {
+			s.CompletedBytes = resp.Completed
+			s.TotalBytes = resp.Total
+		})
+		if time.Since(lastLog) >= pullLogInterval {
+			lastLog = time.Now()
+			if resp.Total > 0 {
+				log.Printf("Pulling Ollama model %q: %s (%d%%)", c.model, resp.Status, resp.Completed*100/resp.Total)
+			} else {
+				log.Printf("Pulling Ollama model %q: %s", c.model, resp.Status)
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		log.Printf("Failed to pull Ollama model %q: %v", c.model, err)
+		c.state.set(func(s *ai.ModelStatus) {
+			*s = ai.ModelStatus{Model: c.model, State: ai.ModelFailed, Error: err.Error()}
+		})
+		return
+	}
+
+	log.Printf("Pulled Ollama model %q", c.model)
+	c.state.set(func(s *ai.ModelStatus) {
+		*s = ai.ModelStatus{Model: c.model, State: ai.ModelReady}
+	})
+}
+
+// pullingError describes an in-progress pull to callers awaiting a title
+func pullingError(status ai.ModelStatus) error {
+	if status.TotalBytes > 0 {
+		return fmt.Errorf("model %q is being downloaded (%d%% complete); try again shortly", status.Model, status.CompletedBytes*100/status.TotalBytes)
+	}
+	return fmt.Errorf("model %q is being downloaded; try again shortly", status.Model)
+}
+
+// Ping checks that the Ollama server is reachable and returns its version
+func (c *Client) Ping(ctx context.Context) (string, error) {
+	version, err := c.client.Version(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach Ollama: %w", err)
+	}
+	return version, nil
+}
+
+// ListModels lists the models installed on the Ollama server
+func (c *Client) ListModels(ctx context.Context) (ai.ModelList, error) {
+	resp, err := c.client.List(ctx)
+	if err != nil {
+		return ai.ModelList{}, fmt.Errorf("failed to list Ollama models: %w", err)
+	}
+
+	list := ai.ModelList{Configured: c.model, Models: make([]ai.ModelInfo, 0, len(resp.Models))}
+	for _, m := range resp.Models {
+		modifiedAt := m.ModifiedAt
+		list.Models = append(list.Models, ai.ModelInfo{Name: m.Name, Size: m.Size, ModifiedAt: &modifiedAt})
+		// Ollama fills in the default tag for models configured without one
+		if m.Name == c.model || m.Name == c.model+":latest" {
+			list.Installed = true
+		}
+	}
+	return list, nil
+}
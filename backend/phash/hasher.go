@@ -0,0 +1,67 @@
+package phash
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/gif"  // register decoder for thumbnails
+	_ "image/jpeg" // register decoder for thumbnails
+	_ "image/png"  // register decoder for thumbnails
+
+	"github.com/cdzombak/lychee-meta-tool/backend/ai"
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/models"
+	"github.com/cdzombak/lychee-meta-tool/backend/sidecar"
+)
+
+// Hasher computes photos' perceptual hashes from their thumbnails and
+// caches them in the sidecar store
+type Hasher struct {
+	store           *sidecar.Store
+	imageURLPattern string
+}
+
+// NewHasher creates a Hasher that fetches thumbnails using imageURLPattern
+func NewHasher(store *sidecar.Store, imageURLPattern string) *Hasher {
+	return &Hasher{
+		store:           store,
+		imageURLPattern: imageURLPattern,
+	}
+}
+
+// HashPhoto computes and caches a photo's hash, unless one is already
+// cached for its current thumbnail. It reports whether a hash was computed.
+func (h *Hasher) HashPhoto(ctx context.Context, photo *models.PhotoWithSizeVariants) (bool, error) {
+	if photo.ThumbnailPath == nil || *photo.ThumbnailPath == "" {
+		return false, fmt.Errorf("photo has no thumbnail")
+	}
+	thumbPath := *photo.ThumbnailPath
+	if state, ok := h.store.Photo(photo.ID); ok && state.PHash != "" && state.PHashPath == thumbPath {
+		return false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ai.WithStorageDisk(ctx, photo.StorageDisk()), constants.ImageDownloadTimeout)
+	defer cancel()
+
+	img, err := ai.FetchImage(ctx, photo.ToPhotoResponse(h.imageURLPattern).ThumbnailURL)
+	if err != nil {
+		return false, err
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(img.Data))
+	if err != nil {
+		return false, fmt.Errorf("failed to decode thumbnail: %w", err)
+	}
+	hash, err := Compute(decoded)
+	if err != nil {
+		return false, err
+	}
+
+	// A job may hash the whole library, so the store isn't rewritten for
+	// each photo; a lost hash is computed again
+	h.store.UpdatePhotoDeferred(photo.ID, func(s *sidecar.PhotoState) {
+		s.PHash = Format(hash)
+		s.PHashPath = thumbPath
+	})
+	return true, nil
+}
@@ -0,0 +1,187 @@
+// Package phash computes perceptual hashes of photos, which are close for
+// visually similar images even after re-encoding, resizing or light edits,
+// and groups photos whose hashes are near-identical.
+package phash
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+	"strconv"
+)
+
+const (
+	// sampleSize is the side of the grayscale image the DCT is taken of
+	sampleSize = 32
+	// hashSize is the side of the block of low-frequency DCT coefficients
+	// that make up the hash
+	hashSize = 8
+)
+
+// Compute returns the 64-bit perceptual hash (pHash) of img: each bit
+// records whether one of the lowest-frequency DCT coefficients of a 32x32
+// grayscale version of img is above their median
+func Compute(img image.Image) (uint64, error) {
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return 0, fmt.Errorf("image is empty")
+	}
+
+	// Downsample to grayscale by averaging the pixels in each cell
+	var gray [sampleSize][sampleSize]float64
+	for y := 0; y < sampleSize; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/sampleSize
+		y1 := max(bounds.Min.Y+(y+1)*bounds.Dy()/sampleSize, y0+1)
+		for x := 0; x < sampleSize; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/sampleSize
+			x1 := max(bounds.Min.X+(x+1)*bounds.Dx()/sampleSize, x0+1)
+
+			var sum float64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					r, g, b, _ := img.At(sx, sy).RGBA()
+					sum += 0.299*float64(r>>8) + 0.587*float64(g>>8) + 0.114*float64(b>>8)
+				}
+			}
+			gray[y][x] = sum / float64((y1-y0)*(x1-x0))
+		}
+	}
+
+	coeffs := dct(gray)
+
+	// The DC coefficient reflects overall brightness only, so it's left
+	// out of the median
+	values := make([]float64, 0, hashSize*hashSize)
+	for v := 0; v < hashSize; v++ {
+		for u := 0; u < hashSize; u++ {
+			values = append(values, coeffs[v][u])
+		}
+	}
+	sorted := append([]float64(nil), values[1:]...)
+	sort.Float64s(sorted)
+	median := (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+
+	var hash uint64
+	for i, c := range values {
+		if c > median {
+			hash |= 1 << uint(len(values)-1-i)
+		}
+	}
+	return hash, nil
+}
+
+// dct returns the 2D type-II discrete cosine transform of m, computed only
+// for the low frequencies used in the hash
+func dct(m [sampleSize][sampleSize]float64) [hashSize][hashSize]float64 {
+	var cosines [hashSize][sampleSize]float64
+	for u := 0; u < hashSize; u++ {
+		for x := 0; x < sampleSize; x++ {
+			cosines[u][x] = math.Cos(float64(2*x+1) * float64(u) * math.Pi / (2 * sampleSize))
+		}
+	}
+
+	// Transform rows, then columns
+	var rows [sampleSize][hashSize]float64
+	for y := 0; y < sampleSize; y++ {
+		for u := 0; u < hashSize; u++ {
+			var sum float64
+			for x := 0; x < sampleSize; x++ {
+				sum += m[y][x] * cosines[u][x]
+			}
+			rows[y][u] = sum
+		}
+	}
+
+	var out [hashSize][hashSize]float64
+	for v := 0; v < hashSize; v++ {
+		for u := 0; u < hashSize; u++ {
+			var sum float64
+			for y := 0; y < sampleSize; y++ {
+				sum += rows[y][u] * cosines[v][y]
+			}
+			out[v][u] = sum
+		}
+	}
+	return out
+}
+
+// Distance returns the number of bits that differ between two hashes. Up
+// to about 10 of 64 usually means the same picture.
+func Distance(a, b uint64) int {
+	n := 0
+	for x := a ^ b; x != 0; x &= x - 1 {
+		n++
+	}
+	return n
+}
+
+// Format returns a hash as 16 hex digits, as stored in the sidecar store
+func Format(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+// Parse parses a hash produced by Format
+func Parse(s string) (uint64, error) {
+	if len(s) != 16 {
+		return 0, fmt.Errorf("invalid perceptual hash %q", s)
+	}
+	hash, err := strconv.ParseUint(s, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid perceptual hash %q", s)
+	}
+	return hash, nil
+}
+
+// Group returns the groups of photos, keyed by ID, whose hashes are linked
+// by chains of hashes within maxDistance of each other. Only groups of two
+// or more are returned, largest first; IDs within a group are sorted.
+func Group(hashes map[string]uint64, maxDistance int) [][]string {
+	ids := make([]string, 0, len(hashes))
+	for id := range hashes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	// Union-find over every pair of photos within maxDistance
+	parent := make([]int, len(ids))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i := range ids {
+		for j := i + 1; j < len(ids); j++ {
+			if Distance(hashes[ids[i]], hashes[ids[j]]) <= maxDistance {
+				if a, b := find(i), find(j); a != b {
+					parent[b] = a
+				}
+			}
+		}
+	}
+
+	members := make(map[int][]string)
+	for i, id := range ids {
+		root := find(i)
+		members[root] = append(members[root], id)
+	}
+
+	var groups [][]string
+	for _, group := range members {
+		if len(group) > 1 {
+			groups = append(groups, group)
+		}
+	}
+	sort.Slice(groups, func(a, b int) bool {
+		if len(groups[a]) != len(groups[b]) {
+			return len(groups[a]) > len(groups[b])
+		}
+		return groups[a][0] < groups[b][0]
+	})
+	return groups
+}
@@ -0,0 +1,170 @@
+package placeholder
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strconv"
+)
+
+// maxSampleSize bounds the pixels sampled along each axis when finding the
+// dominant color
+const maxSampleSize = 64
+
+// Color families, for filtering photos by color
+const (
+	FamilyRed    = "red"
+	FamilyOrange = "orange"
+	FamilyBrown  = "brown"
+	FamilyYellow = "yellow"
+	FamilyGreen  = "green"
+	FamilyCyan   = "cyan"
+	FamilyBlue   = "blue"
+	FamilyPurple = "purple"
+	FamilyPink   = "pink"
+	FamilyBlack  = "black"
+	FamilyWhite  = "white"
+	FamilyGray   = "gray"
+)
+
+// Families lists every color family
+var Families = []string{
+	FamilyRed, FamilyOrange, FamilyBrown, FamilyYellow, FamilyGreen, FamilyCyan,
+	FamilyBlue, FamilyPurple, FamilyPink, FamilyBlack, FamilyWhite, FamilyGray,
+}
+
+// RGB is an 8-bit sRGB color
+type RGB struct {
+	R, G, B uint8
+}
+
+// Hex returns the color as "#rrggbb"
+func (c RGB) Hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// ParseHex parses a "#rrggbb" color
+func ParseHex(s string) (RGB, error) {
+	if len(s) != 7 || s[0] != '#' {
+		return RGB{}, fmt.Errorf("invalid color %q", s)
+	}
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return RGB{}, fmt.Errorf("invalid color %q", s)
+	}
+	return RGB{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v)}, nil
+}
+
+// DominantColor returns the most common color in img. Pixels are grouped
+// into coarse buckets (4 bits per channel), and the average of the most
+// populated bucket is returned. Mostly transparent pixels are ignored.
+func DominantColor(img image.Image) RGB {
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return RGB{}
+	}
+	width := min(bounds.Dx(), maxSampleSize)
+	height := min(bounds.Dy(), maxSampleSize)
+
+	type bucket struct {
+		count   int
+		r, g, b int
+	}
+	buckets := make(map[int]*bucket)
+	var best *bucket
+
+	for y := 0; y < height; y++ {
+		sy := bounds.Min.Y + y*bounds.Dy()/height
+		for x := 0; x < width; x++ {
+			sx := bounds.Min.X + x*bounds.Dx()/width
+			r, g, b, a := img.At(sx, sy).RGBA()
+			if a < 0x8000 {
+				continue
+			}
+			r, g, b = r>>8, g>>8, b>>8
+
+			key := int(r>>4)<<8 | int(g>>4)<<4 | int(b>>4)
+			bk, ok := buckets[key]
+			if !ok {
+				bk = &bucket{}
+				buckets[key] = bk
+			}
+			bk.count++
+			bk.r += int(r)
+			bk.g += int(g)
+			bk.b += int(b)
+			if best == nil || bk.count > best.count {
+				best = bk
+			}
+		}
+	}
+
+	if best == nil {
+		return RGB{}
+	}
+	return RGB{
+		R: uint8(best.r / best.count),
+		G: uint8(best.g / best.count),
+		B: uint8(best.b / best.count),
+	}
+}
+
+// Family returns the color family c belongs to
+func (c RGB) Family() string {
+	h, s, l := c.hsl()
+	switch {
+	case l < 0.15:
+		return FamilyBlack
+	case l > 0.9:
+		return FamilyWhite
+	case s < 0.15:
+		return FamilyGray
+	case h < 15 || h >= 345:
+		return FamilyRed
+	case h < 45 && l < 0.4:
+		return FamilyBrown
+	case h < 45:
+		return FamilyOrange
+	case h < 70:
+		return FamilyYellow
+	case h < 170:
+		return FamilyGreen
+	case h < 200:
+		return FamilyCyan
+	case h < 260:
+		return FamilyBlue
+	case h < 290:
+		return FamilyPurple
+	default:
+		return FamilyPink
+	}
+}
+
+// hsl returns the color's hue in degrees and its saturation and lightness
+// between 0 and 1
+func (c RGB) hsl() (h, s, l float64) {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	maxC := math.Max(r, math.Max(g, b))
+	minC := math.Min(r, math.Min(g, b))
+	l = (maxC + minC) / 2
+
+	delta := maxC - minC
+	if delta == 0 {
+		return 0, 0, l
+	}
+	s = delta / (1 - math.Abs(2*l-1))
+
+	switch maxC {
+	case r:
+		h = math.Mod((g-b)/delta, 6)
+	case g:
+		h = (b-r)/delta + 2
+	default:
+		h = (r-g)/delta + 4
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+	return h, s, l
+}
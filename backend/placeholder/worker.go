@@ -0,0 +1,137 @@
+// Package placeholder computes lightweight stand-ins for photos, a BlurHash
+// and a dominant color, from each photo's thumbnail. Clients show them while
+// the real image loads.
+package placeholder
+
+import (
+	"bytes"
+	"context"
+	"image"
+	_ "image/gif"  // register decoder for thumbnails
+	_ "image/jpeg" // register decoder for thumbnails
+	_ "image/png"  // register decoder for thumbnails
+	"log"
+	"sync"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/ai"
+	"github.com/cdzombak/lychee-meta-tool/backend/blurhash"
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/sidecar"
+)
+
+// maxQueued bounds the photos waiting for a placeholder. Requests beyond it
+// are dropped and made again the next time the photo is listed.
+const maxQueued = 1000
+
+// Placeholder is a photo's cached placeholder
+type Placeholder struct {
+	Blurhash string
+	// Color is the dominant color as "#rrggbb"
+	Color string
+}
+
+type request struct {
+	photoID   string
+	thumbPath string
+	thumbURL  string
+	// storageDisk is the Lychee storage disk holding the thumbnail
+	storageDisk string
+}
+
+// Worker computes placeholders in the background, from each photo's
+// thumbnail, and caches them in the sidecar store
+type Worker struct {
+	store *sidecar.Store
+	queue chan request
+
+	mu      sync.Mutex
+	pending map[string]bool
+	// failed records thumbnails that couldn't be processed, so they aren't
+	// retried until restart
+	failed map[string]bool
+}
+
+// NewWorker creates a Worker. Call Run to start processing requests.
+func NewWorker(store *sidecar.Store) *Worker {
+	return &Worker{
+		store:   store,
+		queue:   make(chan request, maxQueued),
+		pending: make(map[string]bool),
+		failed:  make(map[string]bool),
+	}
+}
+
+// Lookup returns the cached placeholder for a photo whose thumbnail has the
+// given short path, or requests one and returns the zero Placeholder. The
+// thumbnail is fetched from storageDisk (see ai.WithStorageDisk).
+func (w *Worker) Lookup(photoID, thumbPath, thumbURL, storageDisk string) Placeholder {
+	if thumbPath == "" || thumbURL == "" {
+		return Placeholder{}
+	}
+	if state, ok := w.store.Photo(photoID); ok && state.BlurhashPath == thumbPath && state.DominantColor != "" {
+		return Placeholder{Blurhash: state.Blurhash, Color: state.DominantColor}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.pending[photoID] || w.failed[thumbPath] {
+		return Placeholder{}
+	}
+	select {
+	case w.queue <- request{photoID: photoID, thumbPath: thumbPath, thumbURL: thumbURL, storageDisk: storageDisk}:
+		w.pending[photoID] = true
+	default:
+	}
+	return Placeholder{}
+}
+
+// Run computes requested placeholders until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case req := <-w.queue:
+			p, err := w.compute(ctx, req.thumbURL, req.storageDisk)
+			if err == nil {
+				// Listing a large library queues many photos at once, so
+				// the store isn't rewritten for each; a lost placeholder
+				// is computed again
+				w.store.UpdatePhotoDeferred(req.photoID, func(s *sidecar.PhotoState) {
+					s.Blurhash = p.Blurhash
+					s.DominantColor = p.Color
+					s.BlurhashPath = req.thumbPath
+				})
+			}
+
+			w.mu.Lock()
+			delete(w.pending, req.photoID)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Failed to compute placeholder for photo %s: %v", req.photoID, err)
+				w.failed[req.thumbPath] = true
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+// compute fetches a thumbnail from storageDisk and returns its placeholder
+func (w *Worker) compute(ctx context.Context, thumbURL, storageDisk string) (Placeholder, error) {
+	ctx, cancel := context.WithTimeout(ai.WithStorageDisk(ctx, storageDisk), constants.ImageDownloadTimeout)
+	defer cancel()
+
+	img, err := ai.FetchImage(ctx, thumbURL)
+	if err != nil {
+		return Placeholder{}, err
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(img.Data))
+	if err != nil {
+		return Placeholder{}, err
+	}
+
+	hash, err := blurhash.Encode(decoded, blurhash.DefaultXComponents, blurhash.DefaultYComponents)
+	if err != nil {
+		return Placeholder{}, err
+	}
+	return Placeholder{Blurhash: hash, Color: DominantColor(decoded).Hex()}, nil
+}
@@ -0,0 +1,137 @@
+// Package reconcile detects photos edited directly in Lychee after the tool
+// last wrote to them.
+//
+// Whenever the tool saves a photo it records the photo's resulting
+// updated_at timestamp in the sidecar store. A later, newer updated_at means
+// someone changed the photo in Lychee itself; such photos are flagged so
+// that the tool's own queues never present stale suggestions that would
+// overwrite the fresher human edit.
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/db"
+	"github.com/cdzombak/lychee-meta-tool/backend/sidecar"
+)
+
+// Result summarizes a reconciliation pass
+type Result struct {
+	// Checked is the number of tracked photos compared against Lychee
+	Checked int `json:"checked"`
+	// Conflicts is the number of photos newly flagged as edited in Lychee
+	Conflicts int `json:"conflicts"`
+	// Baselined is the number of photos that had no recorded Lychee
+	// timestamp yet and now use the current one as their baseline
+	Baselined int `json:"baselined"`
+	// Missing is the number of tracked photos no longer in Lychee
+	Missing  int       `json:"missing"`
+	Finished time.Time `json:"finished"`
+}
+
+// Reconciler compares the sidecar store against Lychee
+type Reconciler struct {
+	db      *db.DB
+	sidecar *sidecar.Store
+
+	mu   sync.Mutex // serializes passes
+	last *Result
+}
+
+// New creates a Reconciler
+func New(database *db.DB, sidecarStore *sidecar.Store) *Reconciler {
+	return &Reconciler{db: database, sidecar: sidecarStore}
+}
+
+// Run reconciles every interval until ctx is cancelled
+func (r *Reconciler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if result, err := r.Reconcile(); err != nil {
+			log.Printf("Reconciliation with Lychee failed: %v", err)
+		} else if result.Conflicts > 0 {
+			log.Printf("Reconciliation found %d photo(s) edited directly in Lychee", result.Conflicts)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile performs a single pass, flagging tracked photos whose Lychee
+// updated_at is newer than the one recorded at the tool's last write
+func (r *Reconciler) Reconcile() (Result, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Only photos whose metadata the tool wrote are tracked; states holding
+	// just a skip or a cached placeholder say nothing about Lychee edits
+	states := r.sidecar.Photos(func(_ string, p sidecar.PhotoState) bool {
+		return p.ExternalEditAt == nil && (p.TitleProvenance != "" || p.DescriptionProvenance != "")
+	})
+	ids := make([]string, 0, len(states))
+	for id := range states {
+		ids = append(ids, id)
+	}
+
+	times, err := r.db.GetPhotoUpdateTimes(ids)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Checked: len(ids)}
+	for _, id := range ids {
+		if _, ok := times[id]; !ok {
+			result.Missing++
+		}
+	}
+
+	// Decide under the store's lock, so a write by the tool since the
+	// states were read is never mistaken for an external edit
+	now := time.Now().UTC()
+	err = r.sidecar.UpdatePhotos(func(id string, p *sidecar.PhotoState) bool {
+		updatedAt, ok := times[id]
+		if !ok || p.ExternalEditAt != nil {
+			return false
+		}
+
+		switch {
+		case p.LycheeUpdatedAt == nil:
+			p.LycheeUpdatedAt = &updatedAt
+			result.Baselined++
+			return true
+		case p.EditedExternally(updatedAt):
+			p.ExternalEditAt = &now
+			result.Conflicts++
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to update sidecar store: %w", err)
+	}
+
+	result.Finished = now
+	r.last = &result
+	return result, nil
+}
+
+// Last returns the result of the most recent successful pass, if any
+func (r *Reconciler) Last() (Result, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.last == nil {
+		return Result{}, false
+	}
+	return *r.last, true
+}
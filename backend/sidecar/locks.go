@@ -0,0 +1,66 @@
+package sidecar
+
+import (
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/models"
+)
+
+// editLock is a photo's edit lock and the client session holding it
+type editLock struct {
+	models.EditLock
+	owner string
+}
+
+// LockPhoto takes or renews the edit lock on a photo for the client
+// session owner, which Holder names to other reviewers, until ttl from
+// now. If someone else holds an unexpired lock, it is returned with false.
+// Locks are kept in memory only, since they expire within minutes anyway.
+func (s *Store) LockPhoto(id, owner, holder string, ttl time.Duration) (models.EditLock, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	if l, ok := s.locks[id]; ok && l.owner != owner && now.Before(l.ExpiresAt) {
+		return l.EditLock, false
+	}
+
+	l := editLock{EditLock: models.EditLock{Holder: holder, ExpiresAt: now.Add(ttl)}, owner: owner}
+	s.locks[id] = l
+	s.pruneLocksLocked(now)
+	return l.EditLock, true
+}
+
+// UnlockPhoto releases the edit lock on a photo if the client session owner
+// holds it, reporting whether it did
+func (s *Store) UnlockPhoto(id, owner string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if l, ok := s.locks[id]; ok && l.owner == owner {
+		delete(s.locks, id)
+		return true
+	}
+	return false
+}
+
+// PhotoLock returns the unexpired edit lock on a photo, if there is one
+func (s *Store) PhotoLock(id string) (models.EditLock, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	l, ok := s.locks[id]
+	if !ok || !time.Now().Before(l.ExpiresAt) {
+		return models.EditLock{}, false
+	}
+	return l.EditLock, true
+}
+
+// pruneLocksLocked forgets expired locks. The caller must hold s.mu.
+func (s *Store) pruneLocksLocked(now time.Time) {
+	for id, l := range s.locks {
+		if !now.Before(l.ExpiresAt) {
+			delete(s.locks, id)
+		}
+	}
+}
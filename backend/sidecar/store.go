@@ -0,0 +1,462 @@
+// Package sidecar stores lychee-meta-tool's own per-photo state alongside,
+// but separate from, the Lychee database.
+//
+// Lychee's schema has no place for tool-specific data such as the provenance
+// of a title, so it is kept in a small JSON file instead. The whole state is
+// held in memory and rewritten atomically on each change, which is plenty for
+// the size of a personal photo library. Recomputable caches, such as hashes
+// computed for the whole library, are written in batches instead.
+package sidecar
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/models"
+)
+
+// stateVersion is the current on-disk format version
+const stateVersion = 1
+
+// PhotoState is the tool's sidecar state for a single photo
+type PhotoState struct {
+	TitleProvenance       models.Provenance `json:"title_provenance,omitempty"`
+	DescriptionProvenance models.Provenance `json:"description_provenance,omitempty"`
+	// TitledAt is when a title was last saved through the tool
+	TitledAt *time.Time `json:"titled_at,omitempty"`
+	// TitleReviewedAt is set when a person approves an AI-written title
+	// without changing it, taking the photo out of the re-review queue
+	TitleReviewedAt *time.Time `json:"title_reviewed_at,omitempty"`
+	// TitleReviewedBy and UpdatedBy identify who approved the title and
+	// who last saved metadata through the tool (see auth.Actor), or who
+	// submitted the job that saved it; empty when that was anonymous
+	TitleReviewedBy string `json:"title_reviewed_by,omitempty"`
+	UpdatedBy       string `json:"updated_by,omitempty"`
+	// LycheeUpdatedAt is the photo's updated_at in Lychee right after the
+	// tool last wrote to it
+	LycheeUpdatedAt *time.Time `json:"lychee_updated_at,omitempty"`
+	// ExternalEditAt is set when the photo is found to have been edited
+	// directly in Lychee since the tool last wrote to it. The recorded
+	// provenance may no longer describe the current metadata.
+	ExternalEditAt *time.Time `json:"external_edit_at,omitempty"`
+	// SkippedAt is set when the photo is put on the ignore list, taking it
+	// out of the tool's queues
+	SkippedAt *time.Time `json:"skipped_at,omitempty"`
+	// SkipBatch identifies the bulk skip request that set SkippedAt, so it
+	// can be undone as a whole
+	SkipBatch string `json:"skip_batch,omitempty"`
+	// Blurhash and DominantColor ("#rrggbb") are placeholders computed from
+	// the photo's thumbnail, whose short path is BlurhashPath; a different
+	// thumbnail makes them stale
+	Blurhash      string `json:"blurhash,omitempty"`
+	DominantColor string `json:"dominant_color,omitempty"`
+	BlurhashPath  string `json:"blurhash_path,omitempty"`
+	// PHash is the perceptual hash of the thumbnail at PHashPath, as 16 hex
+	// digits, used to find duplicates
+	PHash     string `json:"phash,omitempty"`
+	PHashPath string `json:"phash_path,omitempty"`
+	// Previous is the photo's metadata before the tool last changed its
+	// title, description or album, so that the change can be reverted
+	Previous  *PreviousMetadata `json:"previous,omitempty"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// PreviousMetadata is a photo's title, description and album, with their
+// provenance, as they were before a change made through the tool
+type PreviousMetadata struct {
+	Title                 string            `json:"title"`
+	Description           *string           `json:"description,omitempty"`
+	AlbumID               *string           `json:"album_id,omitempty"`
+	TitleProvenance       models.Provenance `json:"title_provenance,omitempty"`
+	DescriptionProvenance models.Provenance `json:"description_provenance,omitempty"`
+	// ReplacedAt and ReplacedBy record when and by whom (see auth.Actor)
+	// these values were replaced
+	ReplacedAt time.Time `json:"replaced_at"`
+	ReplacedBy string    `json:"replaced_by,omitempty"`
+}
+
+// NeedsTitleReview reports whether the photo's title was written by AI and
+// has not yet been approved by a person, edited in Lychee since, or skipped
+func (p PhotoState) NeedsTitleReview() bool {
+	return p.TitleProvenance == models.ProvenanceAI && p.TitleReviewedAt == nil && p.ExternalEditAt == nil && p.SkippedAt == nil
+}
+
+// EditedExternally reports whether a photo whose updated_at in Lychee is
+// lycheeUpdatedAt has been edited outside the tool since its last write
+func (p PhotoState) EditedExternally(lycheeUpdatedAt time.Time) bool {
+	return p.LycheeUpdatedAt != nil && lycheeUpdatedAt.After(*p.LycheeUpdatedAt)
+}
+
+// AlbumSettings are per-album overrides for AI title generation
+type AlbumSettings struct {
+	// Style is a free-form description of the desired title style
+	Style string `json:"style,omitempty"`
+	// Language is the language titles should be written in
+	Language string `json:"language,omitempty"`
+	// AutoApply saves generated titles immediately instead of only
+	// suggesting them
+	AutoApply bool `json:"auto_apply"`
+	// Excluded disables AI title generation for the album
+	Excluded bool `json:"excluded"`
+	// ExcludedFromQueue leaves the album's photos out of the tool's queues
+	// unless the album is selected explicitly
+	ExcludedFromQueue bool `json:"excluded_from_queue"`
+}
+
+// UserPreferences are a person's defaults for AI generation, keyed by the
+// actor identity (see auth.Actor). Album settings take precedence.
+type UserPreferences struct {
+	// Style is a free-form description of the desired title style
+	Style string `json:"style,omitempty"`
+	// Language is the language titles should be written in
+	Language string `json:"language,omitempty"`
+}
+
+type state struct {
+	Version int                         `json:"version"`
+	Photos  map[string]*PhotoState      `json:"photos"`
+	Albums  map[string]*AlbumSettings   `json:"albums,omitempty"`
+	Users   map[string]*UserPreferences `json:"users,omitempty"`
+}
+
+// Store is a JSON-file-backed sidecar store. A Store with an empty path
+// keeps state in memory only.
+type Store struct {
+	mu    sync.RWMutex
+	path  string
+	state state
+	// locks are photos' edit locks, keyed by photo ID
+	locks map[string]editLock
+	// saveTimer persists changes made by UpdatePhotoDeferred, if any are
+	// yet to be saved
+	saveTimer *time.Timer
+}
+
+// Open loads the sidecar store from path, creating an empty store if the
+// file does not exist yet. An empty path yields an in-memory store.
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path:  path,
+		locks: map[string]editLock{},
+		state: state{Version: stateVersion, Photos: map[string]*PhotoState{}, Albums: map[string]*AlbumSettings{}, Users: map[string]*UserPreferences{}},
+	}
+
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("failed to read sidecar store: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &s.state); err != nil {
+		return nil, fmt.Errorf("failed to parse sidecar store %s: %w", path, err)
+	}
+	if s.state.Version > stateVersion {
+		return nil, fmt.Errorf("sidecar store %s has unsupported version %d", path, s.state.Version)
+	}
+	if s.state.Photos == nil {
+		s.state.Photos = map[string]*PhotoState{}
+	}
+	if s.state.Albums == nil {
+		s.state.Albums = map[string]*AlbumSettings{}
+	}
+	if s.state.Users == nil {
+		s.state.Users = map[string]*UserPreferences{}
+	}
+	s.state.Version = stateVersion
+
+	return s, nil
+}
+
+// Persistent reports whether the store is backed by a file
+func (s *Store) Persistent() bool {
+	return s.path != ""
+}
+
+// Photo returns the sidecar state for a photo
+func (s *Store) Photo(id string) (PhotoState, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.state.Photos[id]
+	if !ok {
+		return PhotoState{}, false
+	}
+	return *p, true
+}
+
+// UpdatePhoto applies fn to a photo's state (creating it if needed), stamps
+// it with the current time, and persists the store
+func (s *Store) UpdatePhoto(id string, fn func(*PhotoState)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.state.Photos[id]
+	if !ok {
+		p = &PhotoState{}
+		s.state.Photos[id] = p
+	}
+	fn(p)
+	p.UpdatedAt = time.Now().UTC()
+
+	return s.saveLocked()
+}
+
+// UpdatePhotoDeferred is UpdatePhoto for state that can be recomputed if
+// lost, such as a cached hash. Rather than rewriting the file for every
+// photo, the store is persisted constants.SidecarSaveDelay after the first
+// of a run of such changes, or sooner along with any other change.
+func (s *Store) UpdatePhotoDeferred(id string, fn func(*PhotoState)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.state.Photos[id]
+	if !ok {
+		p = &PhotoState{}
+		s.state.Photos[id] = p
+	}
+	fn(p)
+	p.UpdatedAt = time.Now().UTC()
+
+	if s.path != "" && s.saveTimer == nil {
+		s.saveTimer = time.AfterFunc(constants.SidecarSaveDelay, s.deferredSave)
+	}
+}
+
+// deferredSave persists changes made by UpdatePhotoDeferred, unless they
+// have been saved since
+func (s *Store) deferredSave() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.saveTimer == nil {
+		return
+	}
+	if err := s.saveLocked(); err != nil {
+		log.Printf("Failed to save sidecar store: %v", err)
+	}
+}
+
+// Flush persists changes made by UpdatePhotoDeferred that are yet to be
+// saved, e.g. before shutting down
+func (s *Store) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.saveTimer == nil {
+		return nil
+	}
+	return s.saveLocked()
+}
+
+// UpdatePhotos applies fn to every photo's state, stamping and persisting
+// those for which fn returns true. The store is written at most once.
+func (s *Store) UpdatePhotos(fn func(id string, p *PhotoState) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changed := false
+	now := time.Now().UTC()
+	for id, p := range s.state.Photos {
+		if fn(id, p) {
+			p.UpdatedAt = now
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	return s.saveLocked()
+}
+
+// UpdatePhotosByID applies fn to the state of each of the given photos
+// (creating it if needed), stamps them with the current time, and persists
+// the store once
+func (s *Store) UpdatePhotosByID(ids []string, fn func(*PhotoState)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	for _, id := range ids {
+		p, ok := s.state.Photos[id]
+		if !ok {
+			p = &PhotoState{}
+			s.state.Photos[id] = p
+		}
+		fn(p)
+		p.UpdatedAt = now
+	}
+
+	return s.saveLocked()
+}
+
+// Photos returns the state of every photo for which match returns true,
+// keyed by photo ID. A nil match returns all photos.
+func (s *Store) Photos(match func(id string, p PhotoState) bool) map[string]PhotoState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string]PhotoState)
+	for id, p := range s.state.Photos {
+		if match == nil || match(id, *p) {
+			result[id] = *p
+		}
+	}
+	return result
+}
+
+// DeletePhotos forgets the state of the given photos, e.g. once they have
+// been deleted from Lychee, and persists the store. It returns how many
+// photos had state.
+func (s *Store) DeletePhotos(ids []string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := 0
+	for _, id := range ids {
+		if _, ok := s.state.Photos[id]; ok {
+			delete(s.state.Photos, id)
+			deleted++
+		}
+	}
+	if deleted == 0 {
+		return 0, nil
+	}
+
+	return deleted, s.saveLocked()
+}
+
+// Album returns the AI settings for an album; albums without overrides
+// return the zero value
+func (s *Store) Album(id string) AlbumSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if a, ok := s.state.Albums[id]; ok {
+		return *a
+	}
+	return AlbumSettings{}
+}
+
+// SetAlbum stores the AI settings for an album and persists the store.
+// Setting the zero value removes the album's overrides.
+func (s *Store) SetAlbum(id string, settings AlbumSettings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if settings == (AlbumSettings{}) {
+		delete(s.state.Albums, id)
+	} else {
+		s.state.Albums[id] = &settings
+	}
+
+	return s.saveLocked()
+}
+
+// Albums returns the AI settings of every album with overrides, keyed by album ID
+func (s *Store) Albums() map[string]AlbumSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string]AlbumSettings, len(s.state.Albums))
+	for id, a := range s.state.Albums {
+		result[id] = *a
+	}
+	return result
+}
+
+// DeleteAlbums removes the settings of the given albums, e.g. once they
+// have been deleted from Lychee, and persists the store. It returns how
+// many albums had settings.
+func (s *Store) DeleteAlbums(ids []string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := 0
+	for _, id := range ids {
+		if _, ok := s.state.Albums[id]; ok {
+			delete(s.state.Albums, id)
+			deleted++
+		}
+	}
+	if deleted == 0 {
+		return 0, nil
+	}
+
+	return deleted, s.saveLocked()
+}
+
+// User returns a person's preferences; people without any return the zero
+// value
+func (s *Store) User(actor string) UserPreferences {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if u, ok := s.state.Users[actor]; ok {
+		return *u
+	}
+	return UserPreferences{}
+}
+
+// SetUser stores a person's preferences and persists the store. Setting the
+// zero value removes them.
+func (s *Store) SetUser(actor string, prefs UserPreferences) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if prefs == (UserPreferences{}) {
+		delete(s.state.Users, actor)
+	} else {
+		s.state.Users[actor] = &prefs
+	}
+
+	return s.saveLocked()
+}
+
+// saveLocked writes the store to disk atomically. The caller must hold s.mu.
+func (s *Store) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	// Deferred changes are saved along with this one
+	if s.saveTimer != nil {
+		s.saveTimer.Stop()
+		s.saveTimer = nil
+	}
+
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode sidecar store: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file for sidecar store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write sidecar store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write sidecar store: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace sidecar store: %w", err)
+	}
+	return nil
+}
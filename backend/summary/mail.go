@@ -0,0 +1,134 @@
+package summary
+
+import (
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+)
+
+// MailerOptions configure how summaries are emailed
+type MailerOptions struct {
+	// Host and Port locate the SMTP server
+	Host string
+	Port int
+	// Username and Password authenticate with the server, if set. Servers
+	// other than localhost must offer TLS for them to be sent.
+	Username string
+	Password string
+	// ImplicitTLS connects with TLS from the start (usually port 465)
+	// rather than upgrading with STARTTLS when the server offers it
+	ImplicitTLS bool
+	From        string
+	To          []string
+}
+
+// Mailer sends emails through an SMTP server
+type Mailer struct {
+	opts MailerOptions
+}
+
+// NewMailer creates a Mailer
+func NewMailer(opts MailerOptions) *Mailer {
+	return &Mailer{opts: opts}
+}
+
+// Send emails a plain text message to the configured recipients
+func (m *Mailer) Send(subject, body string) error {
+	addr := net.JoinHostPort(m.opts.Host, strconv.Itoa(m.opts.Port))
+	tlsConfig := &tls.Config{ServerName: m.opts.Host}
+
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: constants.SMTPTimeout}
+	if m.opts.ImplicitTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server %s: %w", addr, err)
+	}
+	if err := conn.SetDeadline(time.Now().Add(constants.SMTPTimeout)); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to set SMTP deadline: %w", err)
+	}
+
+	client, err := smtp.NewClient(conn, m.opts.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session with %s: %w", addr, err)
+	}
+	defer client.Close()
+
+	if !m.opts.ImplicitTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return fmt.Errorf("failed to start TLS with %s: %w", addr, err)
+			}
+		}
+	}
+
+	if m.opts.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.opts.Username, m.opts.Password, m.opts.Host)); err != nil {
+			return fmt.Errorf("failed to authenticate with %s: %w", addr, err)
+		}
+	}
+
+	// The envelope takes bare addresses, without display names
+	from, err := mail.ParseAddress(m.opts.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender %q: %w", m.opts.From, err)
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("SMTP server refused sender %s: %w", from.Address, err)
+	}
+	for _, recipient := range m.opts.To {
+		to, err := mail.ParseAddress(recipient)
+		if err != nil {
+			return fmt.Errorf("invalid recipient %q: %w", recipient, err)
+		}
+		if err := client.Rcpt(to.Address); err != nil {
+			return fmt.Errorf("SMTP server refused recipient %s: %w", to.Address, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	if _, err := w.Write(m.message(subject, body)); err != nil {
+		w.Close()
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// message formats an email with CRLF line endings
+func (m *Mailer) message(subject, body string) []byte {
+	var b strings.Builder
+	header := func(name, value string) {
+		b.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", m.opts.From)
+	header("To", strings.Join(m.opts.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "8bit")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,63 @@
+package summary
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+)
+
+// Schedule is a weekly time of day at which to send summaries
+type Schedule struct {
+	Weekday  time.Weekday
+	Hour     int
+	Location *time.Location
+}
+
+// Next returns the first scheduled time after t
+func (s Schedule) Next(t time.Time) time.Time {
+	t = t.In(s.Location)
+	next := time.Date(t.Year(), t.Month(), t.Day(), s.Hour, 0, 0, 0, s.Location)
+	next = next.AddDate(0, 0, (int(s.Weekday)-int(next.Weekday())+7)%7)
+	if !next.After(t) {
+		next = next.AddDate(0, 0, 7)
+	}
+	return next
+}
+
+// Send emails the summary of the activity from from until to, and reports
+// AI usage after it in later summaries
+func (s *Summarizer) Send(mailer *Mailer, from, to time.Time) (Summary, error) {
+	summary, err := s.Build(from, to)
+	if err != nil {
+		return Summary{}, err
+	}
+	if err := mailer.Send(summary.Subject(), summary.Text()); err != nil {
+		return Summary{}, fmt.Errorf("failed to email summary: %w", err)
+	}
+	s.markSent(summary)
+	return summary, nil
+}
+
+// Run emails a summary of the preceding week at each scheduled time until
+// ctx is cancelled
+func (s *Summarizer) Run(ctx context.Context, mailer *Mailer, schedule Schedule) {
+	for {
+		next := schedule.Next(time.Now())
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if _, err := s.Send(mailer, next.Add(-constants.SummaryPeriod), next); err != nil {
+			log.Printf("Failed to send weekly summary: %v", err)
+		} else {
+			log.Printf("Sent weekly summary to %d recipient(s)", len(mailer.opts.To))
+		}
+	}
+}
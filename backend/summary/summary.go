@@ -0,0 +1,189 @@
+// Package summary builds and emails a periodic report of review activity:
+// photos titled through the tool, the remaining backlog, and AI usage.
+//
+// Activity is read from the sidecar store, which records when and by whom
+// each title was saved, approved or skipped. AI usage comes from the usage
+// tracker, which only counts requests since the server started, so each
+// summary reports the usage since the previous one was sent.
+package summary
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/ai"
+	"github.com/cdzombak/lychee-meta-tool/backend/db"
+	"github.com/cdzombak/lychee-meta-tool/backend/models"
+	"github.com/cdzombak/lychee-meta-tool/backend/sidecar"
+)
+
+// Summary is the review activity over a period
+type Summary struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	// Titled counts photos whose titles were saved during the period, by
+	// title provenance
+	Titled   int                       `json:"titled"`
+	ByOrigin map[models.Provenance]int `json:"by_origin"`
+	// ByPerson counts titled photos by who saved them (see auth.Actor);
+	// photos titled anonymously, or by jobs submitted anonymously, are
+	// counted under ""
+	ByPerson map[string]int `json:"by_person"`
+	// Approved counts AI-written titles approved unchanged
+	Approved int `json:"approved"`
+	// Skipped counts photos put on the ignore list
+	Skipped int `json:"skipped"`
+	// Remaining is the number of photos still needing a title
+	Remaining int `json:"remaining"`
+	// AIBackend is the configured AI backend, empty if none
+	AIBackend string `json:"ai_backend,omitempty"`
+	// AIUsage is the AI usage since AIUsageSince, which is when the
+	// previous summary was sent or else when the server started
+	AIUsage      ai.UsageTotals `json:"ai_usage"`
+	AIUsageSince time.Time      `json:"ai_usage_since"`
+	// aiUsageTotals is the AI usage since the server started
+	aiUsageTotals ai.UsageTotals
+}
+
+// originLabels describe title provenances in summaries
+var originLabels = []struct {
+	origin models.Provenance
+	label  string
+}{
+	{models.ProvenanceAI, "AI, as suggested"},
+	{models.ProvenanceAIEdited, "AI, edited"},
+	{models.ProvenanceManual, "By hand"},
+}
+
+// Summarizer builds summaries
+type Summarizer struct {
+	db      *db.DB
+	sidecar *sidecar.Store
+	usage   *ai.UsageTracker
+
+	mu sync.Mutex
+	// sentUsage and sentAt are the AI usage totals when the previous
+	// summary was sent, and when that was
+	sentUsage ai.UsageTotals
+	sentAt    time.Time
+}
+
+// NewSummarizer creates a Summarizer. usage may be nil, in which case
+// summaries report no AI usage.
+func NewSummarizer(database *db.DB, sidecarStore *sidecar.Store, usage *ai.UsageTracker) *Summarizer {
+	return &Summarizer{db: database, sidecar: sidecarStore, usage: usage}
+}
+
+// Build summarizes the activity from from until to
+func (s *Summarizer) Build(from, to time.Time) (Summary, error) {
+	summary := Summary{
+		From:     from,
+		To:       to,
+		ByOrigin: map[models.Provenance]int{},
+		ByPerson: map[string]int{},
+	}
+
+	within := func(t *time.Time) bool {
+		return t != nil && !t.Before(from) && t.Before(to)
+	}
+	for _, p := range s.sidecar.Photos(nil) {
+		if within(p.TitledAt) && p.TitleProvenance != "" {
+			summary.Titled++
+			summary.ByOrigin[p.TitleProvenance]++
+			summary.ByPerson[p.UpdatedBy]++
+		}
+		if within(p.TitleReviewedAt) {
+			summary.Approved++
+		}
+		if within(p.SkippedAt) {
+			summary.Skipped++
+		}
+	}
+
+	remaining, err := s.db.CountPhotosNeedingMetadata(db.PhotoFilter{})
+	if err != nil {
+		return Summary{}, fmt.Errorf("failed to count photos needing metadata: %w", err)
+	}
+	summary.Remaining = remaining
+
+	if s.usage != nil {
+		report := s.usage.Report()
+		s.mu.Lock()
+		summary.AIBackend = report.Backend
+		summary.aiUsageTotals = report.UsageTotals
+		summary.AIUsage = report.UsageTotals.Sub(s.sentUsage)
+		summary.AIUsageSince = report.Since
+		if s.sentAt.After(report.Since) {
+			summary.AIUsageSince = s.sentAt
+		}
+		s.mu.Unlock()
+	}
+
+	return summary, nil
+}
+
+// markSent makes later summaries report AI usage after summary's
+func (s *Summarizer) markSent(summary Summary) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sentUsage = summary.aiUsageTotals
+	s.sentAt = summary.To
+}
+
+// Subject returns the email subject line for summary
+func (summary Summary) Subject() string {
+	return fmt.Sprintf("Lychee metadata summary: %d titled, %d remaining", summary.Titled, summary.Remaining)
+}
+
+// Text renders summary as the plain text body of an email
+func (summary Summary) Text() string {
+	var b strings.Builder
+	const day = "Mon Jan 2, 2006"
+
+	fmt.Fprintf(&b, "Review activity from %s to %s\n\n", summary.From.Format(day), summary.To.Format(day))
+
+	fmt.Fprintf(&b, "Photos titled: %d\n", summary.Titled)
+	for _, o := range originLabels {
+		if n := summary.ByOrigin[o.origin]; n > 0 {
+			fmt.Fprintf(&b, "  %s: %d\n", o.label, n)
+		}
+	}
+	fmt.Fprintf(&b, "AI titles approved: %d\n", summary.Approved)
+	fmt.Fprintf(&b, "Photos skipped: %d\n", summary.Skipped)
+	fmt.Fprintf(&b, "Remaining backlog: %d photos without a title\n", summary.Remaining)
+
+	if len(summary.ByPerson) > 0 {
+		people := make([]string, 0, len(summary.ByPerson))
+		for person := range summary.ByPerson {
+			people = append(people, person)
+		}
+		// Most prolific first
+		sort.Slice(people, func(i, j int) bool {
+			if summary.ByPerson[people[i]] != summary.ByPerson[people[j]] {
+				return summary.ByPerson[people[i]] > summary.ByPerson[people[j]]
+			}
+			return people[i] < people[j]
+		})
+		b.WriteString("\nTitled by:\n")
+		for _, person := range people {
+			name := person
+			if name == "" {
+				name = "(anonymous or automatic)"
+			}
+			fmt.Fprintf(&b, "  %s: %d\n", name, summary.ByPerson[person])
+		}
+	}
+
+	if summary.AIBackend != "" {
+		u := summary.AIUsage
+		fmt.Fprintf(&b, "\nAI usage (%s) since %s:\n", summary.AIBackend, summary.AIUsageSince.Format(day))
+		fmt.Fprintf(&b, "  Requests: %d (%d failed)\n", u.Requests, u.Failures)
+		fmt.Fprintf(&b, "  Tokens: %d in, %d out\n", u.InputTokens, u.OutputTokens)
+		fmt.Fprintf(&b, "  Estimated cost: $%.2f\n", u.EstimatedCost)
+	}
+
+	return b.String()
+}
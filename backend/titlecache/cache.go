@@ -0,0 +1,196 @@
+// Package titlecache remembers AI-generated titles by photo checksum, so
+// generating a title for the same image again returns right away instead
+// of querying the model.
+//
+// Entries are held in memory and, when a path is configured, written to a
+// JSON file so they survive restarts. Entries are scoped to the AI backend
+// and model that produced them; changing either starts an empty cache.
+package titlecache
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/ai"
+)
+
+// Entry is a cached title
+type Entry struct {
+	Title string `json:"title"`
+	// Variant is the size variant the title was generated from
+	Variant   string    `json:"variant"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// file is the on-disk format
+type file struct {
+	Scope   string           `json:"scope"`
+	Entries map[string]Entry `json:"entries"`
+}
+
+// Cache is a title cache, safe for concurrent use
+type Cache struct {
+	path  string
+	scope string
+
+	mu      sync.RWMutex
+	entries map[string]Entry
+}
+
+// Open loads the cache at path, or returns an empty in-memory cache if path
+// is empty. scope identifies the backend and model; a file written under a
+// different scope is ignored.
+func Open(path, scope string) (*Cache, error) {
+	c := &Cache{
+		path:    path,
+		scope:   scope,
+		entries: make(map[string]Entry),
+	}
+	if path == "" {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read title cache: %w", err)
+	}
+
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse title cache %s: %w", path, err)
+	}
+	if f.Scope == scope && f.Entries != nil {
+		c.entries = f.Entries
+	}
+
+	return c, nil
+}
+
+// Key returns the cache key for a photo checksum and the title options it
+// is generated with, or an empty string if the checksum is unknown
+func Key(checksum string, opts ai.TitleOptions) string {
+	if checksum == "" {
+		return ""
+	}
+	parts := []string{checksum, opts.Style, opts.Language, fmt.Sprint(opts.Words)}
+	// Appended only when set, so keys cached before locations and dates
+	// were included stay valid
+	if opts.Location != "" {
+		parts = append(parts, opts.Location)
+	}
+	if opts.TakenOn != "" {
+		parts = append(parts, "taken "+opts.TakenOn)
+	}
+	return strings.Join(parts, "\x1f")
+}
+
+// Checksum returns the photo checksum key was made from
+func Checksum(key string) string {
+	checksum, _, _ := strings.Cut(key, "\x1f")
+	return checksum
+}
+
+// Get returns the entry for key
+func (c *Cache) Get(key string) (Entry, bool) {
+	if key == "" {
+		return Entry{}, false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	entry, ok := c.entries[key]
+	return entry, ok
+}
+
+// Put stores an entry for key and persists the cache
+func (c *Cache) Put(key string, entry Entry) error {
+	if key == "" {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = entry
+	return c.saveLocked()
+}
+
+// Entries returns every cached entry, keyed by cache key
+func (c *Cache) Entries() map[string]Entry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return maps.Clone(c.entries)
+}
+
+// Delete removes the entries for keys and persists the cache. It returns
+// how many were cached.
+func (c *Cache) Delete(keys []string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	deleted := 0
+	for _, key := range keys {
+		if _, ok := c.entries[key]; ok {
+			delete(c.entries, key)
+			deleted++
+		}
+	}
+	if deleted == 0 {
+		return 0, nil
+	}
+	return deleted, c.saveLocked()
+}
+
+// SetScope changes the backend and model the cache holds titles for, e.g.
+// when the AI backend is reloaded. Titles cached under a different scope
+// are discarded.
+func (c *Cache) SetScope(scope string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if scope == c.scope {
+		return nil
+	}
+	c.scope = scope
+	c.entries = make(map[string]Entry)
+	return c.saveLocked()
+}
+
+// saveLocked atomically rewrites the cache file, if there is one. The caller
+// must hold c.mu.
+func (c *Cache) saveLocked() error {
+	if c.path == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(file{Scope: c.scope, Entries: c.entries})
+	if err != nil {
+		return fmt.Errorf("failed to encode title cache: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary title cache file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write title cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write title cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("failed to replace title cache: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/config"
+	"github.com/cdzombak/lychee-meta-tool/backend/demo"
+)
+
+// loadDemo creates a sample library in a new temporary directory and returns
+// the configuration serving it, along with the directory, which the caller
+// should remove on exit
+func loadDemo(configPath string) (*config.Config, string, error) {
+	dir, err := os.MkdirTemp("", "lychee-meta-tool-demo-")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+
+	lib, err := demo.Create(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, "", err
+	}
+
+	cfg, err := config.LoadDemo(configPath, lib.DBPath, lib.UploadsPath)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, "", fmt.Errorf("failed to load config: %w", err)
+	}
+
+	return cfg, dir, nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/ai"
+	"github.com/cdzombak/lychee-meta-tool/backend/config"
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/db"
+	"github.com/cdzombak/lychee-meta-tool/backend/eval"
+	"github.com/cdzombak/lychee-meta-tool/backend/models"
+	"github.com/cdzombak/lychee-meta-tool/backend/sidecar"
+)
+
+// evalResult is one photo's outcome in an evaluation run
+type evalResult struct {
+	PhotoID   string     `json:"photo_id"`
+	Reference string     `json:"reference"`
+	Generated string     `json:"generated,omitempty"`
+	Score     eval.Score `json:"score"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// runEval implements the `eval` subcommand. It generates titles for photos
+// that already have human-written titles and reports how closely the
+// generated titles match, so that models and prompts can be compared before
+// running them over untitled photos. Nothing is written to Lychee.
+func runEval(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("eval", flag.ExitOnError)
+	count := fs.Int("n", 20, "Number of titled photos to evaluate")
+	albumID := fs.String("album", "", "Only evaluate photos in this album (or smart album: starred, recent, public)")
+	model := fs.String("model", "", "Override the configured AI model")
+	style := fs.String("style", "", "Title style to request, as with per-album settings")
+	language := fs.String("language", cfg.AI.TitleLanguage, "Title language to request, as with per-album settings (default: ai.title_language)")
+	jsonOutput := fs.Bool("json", false, "Print one JSON result per line instead of a table")
+	_ = fs.Parse(args)
+
+	backend, configured := cfg.AIBackend()
+	if backend == "" {
+		return fmt.Errorf("no AI backend is configured")
+	}
+	settings := make(ai.Settings, len(configured)+1)
+	for k, v := range configured {
+		settings[k] = v
+	}
+	if *model != "" {
+		settings["model"] = *model
+	}
+	client, err := ai.New(backend, settings)
+	if err != nil {
+		return fmt.Errorf("failed to initialize %s AI backend: %w", backend, err)
+	}
+
+	database, err := db.Connect(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer database.Close()
+
+	// Titles the tool saved unedited from an AI suggestion are not a
+	// human reference, so they are left out
+	sidecarStore, err := sidecar.Open(cfg.Sidecar.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open sidecar store: %w", err)
+	}
+
+	var filter db.PhotoFilter
+	if *albumID != "" {
+		filter.AlbumID = albumID
+	}
+	// Over-fetch to make up for photos skipped as AI-written or imageless
+	photos, err := database.GetTitledPhotos(filter, *count*2)
+	if err != nil {
+		return err
+	}
+
+	opts := ai.TitleOptions{Style: *style, Language: *language}
+	var summary eval.Summary
+	var results []evalResult
+	for i := range photos {
+		if len(results) >= *count {
+			break
+		}
+
+		photo := &photos[i]
+		if state, ok := sidecarStore.Photo(photo.ID); ok && state.TitleProvenance == models.ProvenanceAI {
+			continue
+		}
+		imageURL := photo.ToPhotoResponse(cfg.ImageURLPattern()).LargeURL
+		if imageURL == "" {
+			continue
+		}
+
+		result := evalResult{PhotoID: photo.ID, Reference: photo.Title}
+		ctx, cancel := context.WithTimeout(context.Background(), constants.AIGenerationTimeout)
+		generated, err := client.GenerateTitle(ctx, imageURL, opts)
+		cancel()
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Generated = generated
+			result.Score = eval.Compare(generated, photo.Title)
+			summary.Add(result.Score)
+		}
+		results = append(results, result)
+
+		if *jsonOutput {
+			if err := json.NewEncoder(os.Stdout).Encode(result); err != nil {
+				return err
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "Evaluated %d/%d\r", len(results), *count)
+		}
+	}
+
+	if *jsonOutput {
+		return json.NewEncoder(os.Stdout).Encode(struct {
+			Summary eval.Summary `json:"summary"`
+		}{summary})
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PHOTO\tWORDS\tCHARS\tREFERENCE\tGENERATED")
+	for _, r := range results {
+		if r.Error != "" {
+			fmt.Fprintf(tw, "%s\t-\t-\t%s\terror: %s\n", r.PhotoID, r.Reference, r.Error)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%.2f\t%.2f\t%s\t%s\n", r.PhotoID, r.Score.Words, r.Score.Characters, r.Reference, r.Generated)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n%s backend: %d titles scored (%d failed), mean word similarity %.2f, mean character similarity %.2f, %d exact matches\n",
+		backend, summary.Count, len(results)-summary.Count, summary.MeanWords, summary.MeanCharacters, summary.ExactMatches)
+	return nil
+}
@@ -8,10 +8,29 @@ const api = axios.create({
   }
 })
 
-// Request interceptor for logging
+// API token storage (only needed when the server has API tokens configured).
+// What's stored is normally the secret of a session opened with the token,
+// which expires, rather than the token itself.
+const TOKEN_STORAGE_KEY = 'lycheeMetaToolToken'
+
+export const getApiToken = () => localStorage.getItem(TOKEN_STORAGE_KEY)
+
+export const setApiToken = (token) => {
+  if (token) {
+    localStorage.setItem(TOKEN_STORAGE_KEY, token)
+  } else {
+    localStorage.removeItem(TOKEN_STORAGE_KEY)
+  }
+}
+
+// Request interceptor for logging and authentication
 api.interceptors.request.use(
   (config) => {
     console.log(`API Request: ${config.method?.toUpperCase()} ${config.url}`)
+    const token = getApiToken()
+    if (token) {
+      config.headers.Authorization = `Bearer ${token}`
+    }
     return config
   },
   (error) => {
@@ -19,13 +38,39 @@ api.interceptors.request.use(
   }
 )
 
+// Exchange an API token for a session secret, falling back to the token
+// itself if a session can't be opened
+const openSession = async (token) => {
+  try {
+    const response = await axios.post('/api/sessions', null, {
+      headers: { Authorization: `Bearer ${token}` }
+    })
+    return response.data.secret || token
+  } catch (error) {
+    console.error('Failed to open session:', error.response?.data || error.message)
+    return token
+  }
+}
+
 // Response interceptor for error handling
 api.interceptors.response.use(
   (response) => {
     return response
   },
-  (error) => {
+  async (error) => {
     console.error('API Error:', error.response?.data || error.message)
+
+    // Prompt for an API token once and retry if the server requires one,
+    // as when the session has expired
+    if (error.response?.status === 401 && !error.config._retriedWithToken) {
+      const token = window.prompt('This server requires an API token:')
+      if (token) {
+        setApiToken(await openSession(token.trim()))
+        error.config._retriedWithToken = true
+        return api.request(error.config)
+      }
+    }
+
     return Promise.reject(error)
   }
 )
@@ -36,6 +81,16 @@ export const photosAPI = {
     return api.get('/photos/needsmetadata', { params })
   },
 
+  // Get photos whose AI-written titles await human re-review
+  getPhotosForAIReview(params = {}) {
+    return api.get('/photos/aireview', { params })
+  },
+
+  // Approve a photo's AI-written title without changing it
+  approveTitle(id) {
+    return api.post(`/photos/${id}/approve-title`)
+  },
+
   // Get a specific photo by ID
   getPhotoById(id) {
     return api.get(`/photos/${id}`)
@@ -44,6 +99,86 @@ export const photosAPI = {
   // Update photo metadata
   updatePhoto(id, data) {
     return api.put(`/photos/${id}`, data)
+  },
+
+  // Take or renew the edit lock on a photo for this browser session
+  lockPhoto(id, session) {
+    return api.post(`/photos/${id}/lock`, { session })
+  },
+
+  // Release the edit lock on a photo
+  unlockPhoto(id, session) {
+    return api.delete(`/photos/${id}/lock`, { data: { session } })
+  },
+
+  // Generate an AI title suggestion (AI generation can take up to ~2 minutes).
+  // Titles are cached per image; force asks the model for a new one.
+  generateTitle(id, { force = false } = {}) {
+    return api.post(`/photos/${id}/generate-title`, null, {
+      timeout: 150000,
+      params: force ? { force: true } : {}
+    })
+  },
+
+  // Generate an AI title suggestion, calling onToken with each piece of text
+  // as the model writes it, and onQueued with the request's position while
+  // it waits for the server to start generating. Resolves with the same data
+  // as generateTitle. Uses fetch rather than EventSource so the API token can
+  // be sent.
+  async streamTitle(id, { force = false, onToken = () => {}, onQueued = () => {} } = {}) {
+    const headers = {}
+    const token = getApiToken()
+    if (token) {
+      headers.Authorization = `Bearer ${token}`
+    }
+
+    const url = `/api/photos/${id}/generate-title/stream${force ? '?force=true' : ''}`
+    console.log(`API Request: GET ${url}`)
+    const response = await fetch(url, { headers })
+    if (!response.ok) {
+      // Shaped like an axios error so callers can handle both alike
+      const data = await response.json().catch(() => ({}))
+      const error = new Error(data.error || `HTTP ${response.status}`)
+      error.response = { status: response.status, data }
+      throw error
+    }
+
+    const reader = response.body.getReader()
+    const decoder = new TextDecoder()
+    let buffer = ''
+    for (;;) {
+      const { value, done } = await reader.read()
+      if (done) break
+      buffer += decoder.decode(value, { stream: true })
+
+      // Events are separated by a blank line
+      let end
+      while ((end = buffer.indexOf('\n\n')) !== -1) {
+        const raw = buffer.slice(0, end)
+        buffer = buffer.slice(end + 2)
+
+        let event = 'message'
+        let data = ''
+        for (const line of raw.split('\n')) {
+          if (line.startsWith('event:')) event = line.slice(6).trim()
+          else if (line.startsWith('data:')) data += line.slice(5).trim()
+        }
+        const payload = data ? JSON.parse(data) : {}
+
+        if (event === 'token') {
+          onToken(payload.text)
+        } else if (event === 'queued') {
+          onQueued(payload.position)
+        } else if (event === 'done') {
+          reader.cancel()
+          return payload
+        } else if (event === 'error') {
+          reader.cancel()
+          throw new Error(payload.error || 'Failed to generate AI title')
+        }
+      }
+    }
+    throw new Error('AI title stream ended unexpectedly')
   }
 }
 
@@ -54,8 +189,31 @@ export const albumsAPI = {
   },
 
   // Get albums that have photos needing metadata
-  getAlbumsWithPhotoCounts() {
-    return api.get('/albums/withphotocounts')
+  getAlbumsWithPhotoCounts(params = {}) {
+    return api.get('/albums/withphotocounts', { params })
+  },
+
+  // Exclude an album's photos from the queues, or include them again
+  setAlbumExcluded(id, excluded) {
+    return api.post(`/albums/${id}/exclude`, { excluded })
+  }
+}
+
+export const changesAPI = {
+  // Wait for changes to photos after the event since (null to get the
+  // current position), for up to timeout seconds
+  poll(since, timeout = 25) {
+    return api.get('/photos/changes', {
+      timeout: (timeout + 10) * 1000,
+      params: since === null ? {} : { since, timeout }
+    })
+  }
+}
+
+export const featuresAPI = {
+  // Get the optional features the server supports
+  getFeatures() {
+    return api.get('/features')
   }
 }
 
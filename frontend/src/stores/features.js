@@ -0,0 +1,26 @@
+import { defineStore } from 'pinia'
+import { featuresAPI } from '../api/client'
+
+// Optional features the server supports. Until they're loaded everything is
+// assumed available, so controls don't flicker out and back in.
+export const useFeaturesStore = defineStore('features', {
+  state: () => ({
+    features: null
+  }),
+
+  getters: {
+    enabled: (state) => (name) => state.features === null || state.features[name] === true
+  },
+
+  actions: {
+    async loadFeatures() {
+      try {
+        const response = await featuresAPI.getFeatures()
+        this.features = response.data.features
+      } catch (error) {
+        // Older servers don't report features; leave everything shown
+        console.error('Failed to load features:', error)
+      }
+    }
+  }
+})
@@ -1,7 +1,21 @@
 import { defineStore } from 'pinia'
-import { photosAPI, albumsAPI } from '../api/client'
+import { photosAPI, albumsAPI, changesAPI } from '../api/client'
 
-const DEFAULT_PHOTO_LIMIT = 1000
+// Load the next page once the current photo is this close to the end
+const LOAD_MORE_THRESHOLD = 10
+
+// How long to wait before polling the change feed again after a failure
+const CHANGES_RETRY_DELAY = 10000
+
+// Identifies this page to the server's edit locks, so that the same person's
+// other tabs count as someone else
+const EDIT_SESSION = crypto.randomUUID()
+
+// Change feed events that take photos out of each queue
+const QUEUE_EXIT_EVENTS = {
+  needsmetadata: ['titled', 'skipped', 'deleted'],
+  aireview: ['titled', 'approved', 'skipped', 'deleted']
+}
 
 export const usePhotosStore = defineStore('photos', {
   state: () => ({
@@ -9,10 +23,42 @@ export const usePhotosStore = defineStore('photos', {
     albums: [],
     currentPhotoIndex: 0,
     loading: false,
+    loadingMore: false,
+    // Pagination hints from the server: whether more photos are queued and
+    // where the next page starts, as a cursor (the needs-metadata queue) or
+    // an offset (the re-review queue)
+    hasMore: false,
+    nextAfter: null,
+    nextOffset: 0,
+    // Number of photos in the whole queue, across pages
+    total: 0,
     error: null,
+    // ID of the photo this page holds the edit lock on
+    heldLockId: null,
+    // Edit locks held by others, by photo ID, as learned since the photos
+    // were loaded; null means a photo was unlocked
+    editLocks: {},
     filter: {
-      albumId: null
-    }
+      albumId: null,
+      // 'needsmetadata' for untitled photos, 'aireview' to re-review AI-written titles
+      mode: 'needsmetadata',
+      // What photos in the needsmetadata queue lack: '' (a title),
+      // 'description', or 'both' for either
+      needs: '',
+      // Only show photos that are publicly visible in Lychee
+      publicOnly: false,
+      // Order of the needsmetadata queue: '' (newest uploads first),
+      // 'created_at', 'taken_at', 'filesize', 'album' or 'random', and
+      // 'asc' or 'desc' ('' for the server's default direction)
+      sort: '',
+      direction: '',
+      // Capture date range, as YYYY-MM-DD in the browser's time zone; the
+      // end date is included
+      takenFrom: '',
+      takenTo: ''
+    },
+    // Seed of the random sort, so later pages continue the same shuffle
+    sortSeed: null
   }),
 
   getters: {
@@ -34,22 +80,69 @@ export const usePhotosStore = defineStore('photos', {
 
     albumById: (state) => {
       return (id) => state.albums.find(album => album.id === id)
+    },
+
+    // The edit lock someone else holds on a photo, if any
+    editLockFor: (state) => {
+      return (photo) => {
+        if (!photo || photo.id === state.heldLockId) return null
+        return photo.id in state.editLocks ? state.editLocks[photo.id] : (photo.edit_lock || null)
+      }
     }
   },
 
   actions: {
+    queueParams() {
+      const params = {}
+      if (this.filter.albumId) {
+        params.album_id = this.filter.albumId
+      }
+      if (this.filter.publicOnly) {
+        params.public = true
+      }
+      if (this.filter.takenFrom) {
+        params.taken_after = new Date(`${this.filter.takenFrom}T00:00`).toISOString()
+      }
+      if (this.filter.takenTo) {
+        // Photos taken before the following midnight
+        const end = new Date(`${this.filter.takenTo}T00:00`)
+        end.setDate(end.getDate() + 1)
+        params.taken_before = end.toISOString()
+      }
+      if (this.filter.mode === 'needsmetadata' && this.filter.needs) {
+        params.needs = this.filter.needs
+      }
+      if (this.filter.mode === 'needsmetadata' && this.filter.sort) {
+        params.sort = this.filter.sort
+        if (this.filter.direction) {
+          params.direction = this.filter.direction
+        }
+        if (this.sortSeed) {
+          params.seed = this.sortSeed
+        }
+      }
+      return params
+    },
+
+    async fetchQueuePage(params) {
+      const response = this.filter.mode === 'aireview'
+        ? await photosAPI.getPhotosForAIReview(params)
+        : await photosAPI.getPhotosNeedingMetadata(params)
+      this.hasMore = !!response.data.has_more
+      this.nextAfter = response.data.next_after || null
+      this.nextOffset = response.data.next_offset || 0
+      this.total = response.data.total || 0
+      this.sortSeed = response.data.seed || null
+      return response.data.photos || []
+    },
+
     async loadPhotos() {
       this.loading = true
       this.error = null
       
       try {
-        const params = { limit: DEFAULT_PHOTO_LIMIT }
-        if (this.filter.albumId) {
-          params.album_id = this.filter.albumId
-        }
-
-        const response = await photosAPI.getPhotosNeedingMetadata(params)
-        this.photos = response.data.photos || []
+        // The server's configured page size applies when no limit is given
+        this.photos = await this.fetchQueuePage(this.queueParams())
         
         // Reset current photo index if no photos or out of bounds
         if (this.photos.length === 0) {
@@ -65,9 +158,42 @@ export const usePhotosStore = defineStore('photos', {
       }
     },
 
+    async loadMorePhotos() {
+      if (!this.hasMore || this.loading || this.loadingMore) {
+        return
+      }
+      this.loadingMore = true
+
+      try {
+        // The cursor stays put as photos are titled; offsets don't
+        const params = this.nextAfter
+          ? { ...this.queueParams(), after: this.nextAfter }
+          : { ...this.queueParams(), offset: this.nextOffset }
+        const page = await this.fetchQueuePage(params)
+        // Titled photos leave the queue, which can shift an offset page
+        // back onto photos already loaded
+        const loaded = new Set(this.photos.map(photo => photo.id))
+        this.photos.push(...page.filter(photo => !loaded.has(photo.id)))
+      } catch (error) {
+        console.error('Failed to load more photos:', error)
+      } finally {
+        this.loadingMore = false
+      }
+    },
+
+    loadMoreIfNeeded() {
+      if (this.currentPhotoIndex >= this.photos.length - LOAD_MORE_THRESHOLD) {
+        this.loadMorePhotos()
+      }
+    },
+
     async loadAlbums() {
       try {
-        const response = await albumsAPI.getAlbumsWithPhotoCounts()
+        const params = this.filter.publicOnly ? { public: true } : {}
+        if (this.filter.needs) {
+          params.needs = this.filter.needs
+        }
+        const response = await albumsAPI.getAlbumsWithPhotoCounts(params)
         this.albums = response.data.albums || []
       } catch (error) {
         console.error('Failed to load albums:', error)
@@ -79,15 +205,7 @@ export const usePhotosStore = defineStore('photos', {
         const response = await photosAPI.updatePhoto(id, data)
         
         // Remove the updated photo from the list since it no longer needs metadata
-        const photoIndex = this.photos.findIndex(photo => photo.id === id)
-        if (photoIndex !== -1) {
-          this.photos.splice(photoIndex, 1)
-          
-          // Adjust current photo index
-          if (this.currentPhotoIndex >= this.photos.length) {
-            this.currentPhotoIndex = Math.max(0, this.photos.length - 1)
-          }
-        }
+        this.removePhoto(id)
         
         return response.data
       } catch (error) {
@@ -96,9 +214,156 @@ export const usePhotosStore = defineStore('photos', {
       }
     },
 
+    async approveTitle(id) {
+      try {
+        const response = await photosAPI.approveTitle(id)
+        this.removePhoto(id)
+        return response.data
+      } catch (error) {
+        const errorMessage = error.response?.data?.error || 'Failed to approve title'
+        throw new Error(errorMessage)
+      }
+    },
+
+    removePhoto(id) {
+      const photoIndex = this.photos.findIndex(photo => photo.id === id)
+      if (photoIndex !== -1) {
+        this.photos.splice(photoIndex, 1)
+        // The photo left the server's queue too, so later pages start earlier
+        this.nextOffset = Math.max(0, this.nextOffset - 1)
+        this.total = Math.max(0, this.total - 1)
+        
+        // Adjust current photo index
+        if (this.currentPhotoIndex >= this.photos.length) {
+          this.currentPhotoIndex = Math.max(0, this.photos.length - 1)
+        }
+        this.loadMoreIfNeeded()
+      }
+    },
+
+    // Follow the server's change feed, so photos other reviewers finish
+    // leave this queue and album counts stay current. Runs until the page
+    // is closed.
+    async watchChanges() {
+      let since = null
+      for (;;) {
+        try {
+          const response = await changesAPI.poll(since)
+          const { events = [], last_id: lastId, reset } = response.data
+          if (reset) {
+            await Promise.all([this.loadPhotos(), this.loadAlbums()])
+          } else if (events.length > 0) {
+            this.applyChanges(events)
+          }
+          since = lastId
+        } catch (error) {
+          console.error('Failed to poll for changes:', error)
+          await new Promise(resolve => setTimeout(resolve, CHANGES_RETRY_DELAY))
+        }
+      }
+    },
+
+    applyChanges(events) {
+      const exits = QUEUE_EXIT_EVENTS[this.filter.mode] || []
+      const currentId = this.currentPhoto?.id
+      for (const event of events) {
+        if (event.type === 'locked' || event.type === 'unlocked') {
+          for (const id of event.photo_ids) {
+            this.editLocks[id] = event.type === 'locked' ? { holder: event.actor || '', expires_at: null } : null
+          }
+          continue
+        }
+        if (!exits.includes(event.type)) continue
+        // Leave the photo being edited alone rather than yanking it away
+        for (const id of event.photo_ids.filter(id => id !== currentId)) {
+          this.removePhoto(id)
+        }
+      }
+      this.loadAlbums()
+    },
+
+    // Take or renew the edit lock on a photo, releasing the one held on
+    // any other. If someone else holds it, their lock is recorded instead.
+    async lockPhoto(id) {
+      if (this.heldLockId && this.heldLockId !== id) {
+        this.releaseLock()
+      }
+      try {
+        const response = await photosAPI.lockPhoto(id, EDIT_SESSION)
+        this.heldLockId = id
+        this.editLocks[id] = null
+        return response.data.lock
+      } catch (error) {
+        if (error.response?.status === 409) {
+          this.editLocks[id] = error.response.data.details?.lock || { holder: '', expires_at: null }
+        } else {
+          console.error(`Failed to lock photo ${id}:`, error)
+        }
+        return null
+      }
+    },
+
+    // Release the edit lock this page holds, if any
+    releaseLock() {
+      const id = this.heldLockId
+      if (!id) return
+      this.heldLockId = null
+      photosAPI.unlockPhoto(id, EDIT_SESSION).catch(error => {
+        console.error(`Failed to unlock photo ${id}:`, error)
+      })
+    },
+
+    async excludeAlbum(id) {
+      try {
+        await albumsAPI.setAlbumExcluded(id, true)
+      } catch (error) {
+        const errorMessage = error.response?.data?.error || 'Failed to exclude album'
+        throw new Error(errorMessage)
+      }
+      this.clearFilter()
+      this.loadAlbums()
+    },
+
+    setMode(mode) {
+      this.filter.mode = mode
+      this.currentPhotoIndex = 0
+      this.loadPhotos()
+    },
+
+    setNeeds(needs) {
+      this.filter.needs = needs
+      this.currentPhotoIndex = 0
+      this.loadPhotos()
+      this.loadAlbums()
+    },
+
+    setSort(sort, direction = '') {
+      this.filter.sort = sort
+      this.filter.direction = direction
+      // Start a new shuffle
+      this.sortSeed = null
+      this.currentPhotoIndex = 0
+      this.loadPhotos()
+    },
+
+    setTakenRange(takenFrom, takenTo) {
+      this.filter.takenFrom = takenFrom
+      this.filter.takenTo = takenTo
+      this.currentPhotoIndex = 0
+      this.loadPhotos()
+    },
+
+    setPublicOnly(publicOnly) {
+      this.filter.publicOnly = publicOnly
+      this.currentPhotoIndex = 0
+      this.loadPhotos()
+      this.loadAlbums()
+    },
+
     selectPhoto(index) {
       if (index >= 0 && index < this.photos.length) {
         this.currentPhotoIndex = index
+        this.loadMoreIfNeeded()
       }
     },
 
@@ -112,6 +377,7 @@ export const usePhotosStore = defineStore('photos', {
     nextPhoto() {
       if (this.hasNext) {
         this.currentPhotoIndex++
+        this.loadMoreIfNeeded()
       }
     },
 
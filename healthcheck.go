@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// healthcheckTimeout bounds how long the healthcheck subcommand waits for
+// the server to respond before reporting failure.
+const healthcheckTimeout = 5 * time.Second
+
+// runHealthcheck requests /api/health from the server listening on the given
+// local port and returns an error unless it responds with 200 OK.
+// It backs the `healthcheck` subcommand, which lets container images define
+// a HEALTHCHECK without shipping curl or wget.
+func runHealthcheck(port int) error {
+	client := &http.Client{Timeout: healthcheckTimeout}
+
+	resp, err := client.Get(fmt.Sprintf("http://127.0.0.1:%d/api/health", port))
+	if err != nil {
+		return fmt.Errorf("health request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server unhealthy: HTTP %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -14,6 +14,8 @@
 // Usage:
 //
 //	lychee-meta-tool -config config.yaml
+//	lychee-meta-tool -config config.yaml healthcheck
+//	lychee-meta-tool -demo
 //
 // Configuration is provided via a YAML file specifying database connection,
 // server settings, Lychee base URL, and optional Ollama configuration.
@@ -22,6 +24,7 @@ package main
 import (
 	"context"
 	"embed"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io/fs"
@@ -34,10 +37,27 @@ import (
 	"time"
 
 	"github.com/cdzombak/lychee-meta-tool/backend/ai"
+	"github.com/cdzombak/lychee-meta-tool/backend/auth"
 	"github.com/cdzombak/lychee-meta-tool/backend/config"
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
 	"github.com/cdzombak/lychee-meta-tool/backend/db"
+	"github.com/cdzombak/lychee-meta-tool/backend/feed"
+	"github.com/cdzombak/lychee-meta-tool/backend/geocode"
 	"github.com/cdzombak/lychee-meta-tool/backend/handlers"
-	"github.com/cdzombak/lychee-meta-tool/backend/ollama"
+	"github.com/cdzombak/lychee-meta-tool/backend/jobs"
+	"github.com/cdzombak/lychee-meta-tool/backend/locale"
+	"github.com/cdzombak/lychee-meta-tool/backend/maintenance"
+	"github.com/cdzombak/lychee-meta-tool/backend/mediacheck"
+	"github.com/cdzombak/lychee-meta-tool/backend/metrics"
+	"github.com/cdzombak/lychee-meta-tool/backend/phash"
+	"github.com/cdzombak/lychee-meta-tool/backend/placeholder"
+	"github.com/cdzombak/lychee-meta-tool/backend/reconcile"
+	"github.com/cdzombak/lychee-meta-tool/backend/sidecar"
+	"github.com/cdzombak/lychee-meta-tool/backend/summary"
+	"github.com/cdzombak/lychee-meta-tool/backend/titlecache"
+
+	// AI backends register themselves with the ai package on import
+	_ "github.com/cdzombak/lychee-meta-tool/backend/ollama"
 )
 
 // frontendFS embeds the built frontend assets into the binary.
@@ -52,6 +72,7 @@ var version = "dev"
 func main() {
 	configPath := flag.String("config", "config.yaml", "Path to configuration file")
 	showVersion := flag.Bool("version", false, "Show version information")
+	demoMode := flag.Bool("demo", false, "Serve a sample library instead of connecting to Lychee; the config file is optional")
 	flag.Parse()
 
 	if *showVersion {
@@ -59,9 +80,59 @@ func main() {
 		return
 	}
 
-	cfg, err := config.Load(*configPath)
+	var cfg *config.Config
+	var err error
+	if *demoMode {
+		var demoDir string
+		cfg, demoDir, err = loadDemo(*configPath)
+		if err != nil {
+			log.Fatalf("Failed to create demo library: %v", err)
+		}
+		defer os.RemoveAll(demoDir)
+		log.Printf("Demo mode: serving a sample library from %s", demoDir)
+	} else {
+		cfg, err = config.Load(*configPath)
+		if err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+	}
+
+	if flag.Arg(0) == "healthcheck" {
+		if err := runHealthcheck(cfg.Server.Port); err != nil {
+			fmt.Fprintf(os.Stderr, "unhealthy: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("ok")
+		return
+	}
+
+	// Identify the tool, and pass any proxy credentials, on outbound requests
+	userAgent := cfg.Outbound.UserAgent
+	if userAgent == "" {
+		userAgent = "lychee-meta-tool/" + version
+	}
+	ai.SetOutboundHeaders(userAgent, cfg.Outbound.Headers)
+
+	// Read images from where each storage disk keeps them rather than over
+	// HTTP, where configured
+	fetchers, err := storageFetchers(cfg)
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		log.Fatalf("Invalid storage configuration: %v", err)
+	}
+	if len(fetchers) > 0 {
+		if err := ai.UseStorage(cfg.ImageURLPattern(), fetchers); err != nil {
+			log.Fatalf("Invalid storage configuration: %v", err)
+		}
+		for disk := range fetchers {
+			log.Printf("Reading images from storage disk %s", disk)
+		}
+	}
+
+	if flag.Arg(0) == "eval" {
+		if err := runEval(cfg, flag.Args()[1:]); err != nil {
+			log.Fatalf("Evaluation failed: %v", err)
+		}
+		return
 	}
 
 	database, err := db.Connect(cfg)
@@ -72,58 +143,350 @@ func main() {
 
 	log.Printf("Connected to %s database", database.Driver())
 
+	// Confirm lychee_base_url serves media, without delaying startup. The
+	// demo library's media is served by this server, which isn't listening yet.
+	media := newMediaProbe(database, cfg.ImageURLPattern())
+	go func() {
+		if *demoMode {
+			return
+		}
+		if err := media.Check(context.Background()); err != nil {
+			log.Printf("Warning: lychee_base_url does not appear to serve Lychee media: %v", err)
+			log.Printf("Thumbnails and AI title generation will fail until lychee_base_url is corrected")
+		} else {
+			log.Printf("Verified that %s serves Lychee media", cfg.LycheeBaseURL)
+		}
+	}()
+
 	var aiClient ai.Client
-	if cfg.IsOllamaEnabled() {
-		var err error
-		aiClient, err = ollama.NewClient(cfg.Ollama.URL, cfg.Ollama.Model)
+	backend, settings := cfg.AIBackend()
+	if backend != "" {
+		client, err := ai.New(backend, settings)
 		if err != nil {
-			log.Printf("Warning: Failed to initialize Ollama client: %v", err)
+			log.Printf("Warning: Failed to initialize %s AI backend: %v", backend, err)
 			log.Printf("AI title generation will be disabled")
 		} else {
-			log.Printf("Ollama client initialized with model %s at %s", cfg.Ollama.Model, cfg.Ollama.URL)
-		}
-	} else if cfg.IsOpenAIEnabled() {
-		model := cfg.OpenAI.Model
-		if model == "" {
-			model = ai.DefaultModel
+			aiClient = client
+			log.Printf("%s AI backend initialized", backend)
+			if ai.HEIFConverter() == "" {
+				log.Printf("Warning: heif-dec or ImageMagick not found; HEIC and AVIF photos can only be titled from their JPEG size variants")
+			}
+			if lister, ok := client.(ai.ModelLister); ok {
+				go checkModelInstalled(lister, backend)
+			}
+			if ai.VideoFrameExtractor() == "" {
+				log.Printf("Warning: ffmpeg not found; videos can only be titled from thumbnails generated by Lychee")
+			}
 		}
-		var err error
-		aiClient, err = ai.NewOpenAIClient(cfg.OpenAI.URL, cfg.OpenAI.APIKey, model)
+	}
+	// Hold the client so POST /api/admin/ai/reload can replace it
+	aiBackend := ai.NewHolder(backend, settings["model"], aiClient)
+	// Retry failed AI requests, and fail fast while the backend is down
+	aiGuard := ai.NewGuard()
+	// Limit concurrent AI generations so they don't overload the backend
+	aiLimiter := ai.NewLimiter(cfg.AI.MaxConcurrentGenerations, cfg.AI.MaxQueuedGenerations)
+	if aiLimiter != nil {
+		log.Printf("Limiting AI generations to %d at once", cfg.AI.MaxConcurrentGenerations)
+	}
+
+	// Limit the expensive read requests so many clients at once can't
+	// saturate the Lychee database
+	queryLimiter := handlers.NewQueryLimiter(cfg.Database.MaxConcurrentQueries, cfg.Database.MaxQueuedQueries)
+	if queryLimiter != nil {
+		log.Printf("Limiting expensive queries to %d at once", cfg.Database.MaxConcurrentQueries)
+	}
+
+	// Track the tokens and estimated cost of AI requests, including those
+	// to a backend enabled by reloading
+	prices := make(map[string]ai.Price, len(cfg.AI.Pricing))
+	for model, p := range cfg.AI.Pricing {
+		prices[model] = ai.Price{InputPerMillion: p.InputPerMillion, OutputPerMillion: p.OutputPerMillion}
+	}
+	aiUsage := ai.NewUsageTracker(backend, settings["model"], prices)
+
+	titleCache, err := titlecache.Open(cfg.AI.TitleCachePath, backend+"/"+settings["model"])
+	if err != nil {
+		log.Fatalf("Failed to open title cache: %v", err)
+	}
+
+	sidecarStore, err := sidecar.Open(cfg.Sidecar.Path)
+	if err != nil {
+		log.Fatalf("Failed to open sidecar store: %v", err)
+	}
+	if !sidecarStore.Persistent() {
+		log.Printf("Warning: sidecar.path is not set; metadata provenance will not persist across restarts")
+	}
+
+	// Compute BlurHash and dominant color placeholders for listed photos in
+	// the background
+	placeholders := placeholder.NewWorker(sidecarStore)
+	placeholdersCtx, stopPlaceholders := context.WithCancel(context.Background())
+	defer stopPlaceholders()
+	go placeholders.Run(placeholdersCtx)
+
+	loc, err := locale.New(cfg.LocaleOptions())
+	if err != nil {
+		log.Fatalf("Invalid locale configuration: %v", err)
+	}
+
+	var geocoder *geocode.Client
+	if cfg.Geocoding.Enabled {
+		geocoder = geocode.New(cfg.Geocoding.URL, cfg.Geocoding.UserAgent, cfg.Geocoding.Language, loc)
+		log.Printf("Reverse geocoding enabled using %s", cfg.Geocoding.URL)
+	}
+
+	handlers.SetPageLimits(cfg.Queue.PageSize, cfg.Queue.MaxPageSize)
+
+	// Announce changes to photos, including new imports, to connected
+	// reviewers
+	changeFeed := feed.NewHub()
+	changeFeedCtx, stopChangeFeed := context.WithCancel(context.Background())
+	defer stopChangeFeed()
+	go changeFeed.WatchImports(changeFeedCtx, database, constants.ChangesImportInterval)
+	changesHandler := handlers.NewChangesHandler(changeFeed, database)
+
+	photoHandler := handlers.NewPhotoHandler(database, sidecarStore, cfg.ImageURLPattern(), aiBackend, handlers.PhotoHandlerOptions{
+		ChangeNotes:      cfg.Editing.ChangeNotes,
+		CacheBustImages:  cfg.Editing.CacheBustImages,
+		AllowDestructive: cfg.Editing.AllowDestructive,
+		Placeholders:     placeholders,
+		TitleCache:       titleCache,
+		AIGuard:          aiGuard,
+		AIUsage:          aiUsage,
+		AILimiter:        aiLimiter,
+		Geocoder:         geocoder,
+		LocationInPrompt: cfg.Geocoding.IncludeInPrompt,
+		Locale:           loc,
+		DateInPrompt:     cfg.Locale.IncludeDateInPrompt,
+		TitleLanguage:    cfg.AI.TitleLanguage,
+		Feed:             changeFeed,
+	})
+	albumHandler := handlers.NewAlbumHandler(database, sidecarStore)
+	adminHandler := handlers.NewAdminHandler(database, queryLimiter, *configPath)
+	progressHandler := handlers.NewProgressHandler(database, aiBackend)
+	aiHandler := handlers.NewAIHandler(aiBackend, handlers.AIHandlerOptions{
+		Guard:      aiGuard,
+		Usage:      aiUsage,
+		Limiter:    aiLimiter,
+		TitleCache: titleCache,
+		ConfigPath: *configPath,
+	})
+	provenanceHandler := handlers.NewProvenanceHandler(sidecarStore, database)
+	exportHandler := handlers.NewExportHandler(database, sidecarStore, cfg.LycheeBaseURL)
+
+	// Run batch AI title generation and hashing jobs in the background
+	jobManager := jobs.NewManager(database, photoHandler, phash.NewHasher(sidecarStore, cfg.ImageURLPattern()), mediacheck.NewChecker(cfg.ImageURLPattern()))
+	jobHandler := handlers.NewJobHandler(jobManager, database, sidecarStore, aiBackend)
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go jobManager.Run(jobsCtx)
+
+	// Periodically detect photos edited directly in Lychee since the tool saved them
+	reconciler := reconcile.New(database, sidecarStore)
+	reconcileHandler := handlers.NewReconcileHandler(reconciler)
+	reconcileCtx, stopReconcile := context.WithCancel(context.Background())
+	defer stopReconcile()
+	if cfg.Sidecar.ReconcileMinutes > 0 {
+		go reconciler.Run(reconcileCtx, time.Duration(cfg.Sidecar.ReconcileMinutes)*time.Minute)
+	}
+
+	// Periodically prune sidecar entries and cached titles that have
+	// outlived their photos
+	maintainer := maintenance.New(database, sidecarStore, titleCache, maintenance.Options{
+		TitleCacheMaxAge: time.Duration(cfg.Maintenance.TitleCacheMaxAgeDays) * 24 * time.Hour,
+	})
+	maintenanceHandler := handlers.NewMaintenanceHandler(maintainer)
+	maintenanceCtx, stopMaintenance := context.WithCancel(context.Background())
+	defer stopMaintenance()
+	if cfg.Maintenance.IntervalHours > 0 {
+		go maintainer.Run(maintenanceCtx, time.Duration(cfg.Maintenance.IntervalHours)*time.Hour)
+	}
+
+	// Serve metrics for scraping, and push them to a Pushgateway if
+	// configured
+	metricsCollector := metrics.NewCollector(database, aiUsage)
+	metricsHandler := handlers.NewMetricsHandler(metricsCollector)
+	metricsCtx, stopMetrics := context.WithCancel(context.Background())
+	defer stopMetrics()
+	if push := cfg.Metrics.Push; push.URL != "" {
+		pusher := metrics.NewPusher(metricsCollector, metrics.PushOptions{
+			URL:      push.URL,
+			Job:      push.Job,
+			Instance: push.Instance,
+			Username: push.Username,
+			Password: push.Password,
+		})
+		go pusher.Run(metricsCtx, time.Duration(push.IntervalSeconds)*time.Second)
+		log.Printf("Pushing metrics to %s every %ds", push.URL, push.IntervalSeconds)
+	}
+
+	// Email a weekly summary of review activity, if configured
+	summarizer := summary.NewSummarizer(database, sidecarStore, aiUsage)
+	var summaryMailer *summary.Mailer
+	summaryCtx, stopSummary := context.WithCancel(context.Background())
+	defer stopSummary()
+	if cfg.SummaryEmail.Enabled {
+		weekday, _ := config.ParseWeekday(cfg.SummaryEmail.Weekday)
+		location, err := time.LoadLocation(cfg.SummaryEmail.Timezone)
 		if err != nil {
-			log.Printf("Warning: Failed to initialize OpenAI client: %v", err)
-			log.Printf("AI title generation will be disabled")
-		} else {
-			log.Printf("OpenAI client initialized with model %s at %s", model, cfg.OpenAI.URL)
+			log.Fatalf("Invalid summary_email timezone: %v", err)
 		}
+		summaryMailer = summary.NewMailer(summary.MailerOptions{
+			Host:        cfg.SummaryEmail.SMTP.Host,
+			Port:        cfg.SummaryEmail.SMTP.Port,
+			Username:    cfg.SummaryEmail.SMTP.Username,
+			Password:    cfg.SummaryEmail.SMTP.Password,
+			ImplicitTLS: cfg.SummaryEmail.SMTP.ImplicitTLS,
+			From:        cfg.SummaryEmail.From,
+			To:          cfg.SummaryEmail.To,
+		})
+		schedule := summary.Schedule{Weekday: weekday, Hour: cfg.SummaryEmail.Hour, Location: location}
+		go summarizer.Run(summaryCtx, summaryMailer, schedule)
+		log.Printf("Weekly summary emails enabled; next one due %s", schedule.Next(time.Now()).Format(time.RFC1123))
 	}
+	summaryHandler := handlers.NewSummaryHandler(summarizer, summaryMailer)
 
-	photoHandler := handlers.NewPhotoHandler(database, cfg.LycheeBaseURL, aiClient)
-	albumHandler := handlers.NewAlbumHandler(database)
+	tokenStore := auth.NewTokenStore()
+	for _, t := range cfg.Auth.Tokens {
+		scope, err := auth.ParseScope(t.Scope)
+		if err != nil {
+			log.Fatalf("Invalid API token %q: %v", t.Name, err)
+		}
+		if err := tokenStore.Add(auth.Token{Name: t.Name, Scope: scope, LycheeUser: t.LycheeUser}, t.Token); err != nil {
+			log.Fatalf("Invalid API token %q: %v", t.Name, err)
+		}
+	}
+	if tokenStore.Enabled() {
+		log.Printf("API token authentication enabled with %d configured token(s)", len(cfg.Auth.Tokens))
+	}
+	tokenHandler := handlers.NewTokenHandler(tokenStore, database)
+	sessionStore := auth.NewSessionStore(tokenStore,
+		time.Duration(cfg.Auth.Sessions.IdleTimeoutMinutes)*time.Minute,
+		time.Duration(cfg.Auth.Sessions.AbsoluteTimeoutHours)*time.Hour)
+	sessionHandler := handlers.NewSessionHandler(sessionStore)
+	meHandler := handlers.NewMeHandler(sidecarStore)
+
+	var forwardAuth *auth.ForwardAuth
+	if cfg.Auth.ForwardAuth.Enabled {
+		forwardAuth, err = auth.NewForwardAuth(cfg.Auth.ForwardAuth.Headers, cfg.Auth.ForwardAuth.TrustedProxies)
+		if err != nil {
+			log.Fatalf("Invalid forward_auth configuration: %v", err)
+		}
+		roles := make(map[string]auth.Scope, len(cfg.Auth.ForwardAuth.Roles))
+		for identity, role := range cfg.Auth.ForwardAuth.Roles {
+			if roles[identity], err = auth.ParseScope(role); err != nil {
+				log.Fatalf("Invalid forward_auth role for %q: %v", identity, err)
+			}
+		}
+		var defaultRole auth.Scope
+		if cfg.Auth.ForwardAuth.DefaultRole != "" {
+			if defaultRole, err = auth.ParseScope(cfg.Auth.ForwardAuth.DefaultRole); err != nil {
+				log.Fatalf("Invalid forward_auth default_role: %v", err)
+			}
+		}
+		forwardAuth.SetRoles(roles, defaultRole)
+		log.Printf("Trusting forward-auth identity headers from %s", strings.Join(cfg.Auth.ForwardAuth.TrustedProxies, ", "))
+	}
+	featuresHandler := handlers.NewFeaturesHandler(aiBackend, tokenStore, handlers.FeatureOptions{
+		Geocoding: geocoder != nil,
+		Delete:    cfg.Editing.AllowDestructive,
+		AuthProxy: forwardAuth != nil,
+	})
 
 	mux := http.NewServeMux()
 
 	// API routes
-	mux.HandleFunc("/api/photos/needsmetadata", photoHandler.GetPhotosNeedingMetadata)
+	mux.HandleFunc("/api/photos/needsmetadata", queryLimiter.Limit(photoHandler.GetPhotosNeedingMetadata))
+	mux.HandleFunc("/api/photos/aireview", queryLimiter.Limit(photoHandler.GetPhotosForAIReview))
+	mux.HandleFunc("/api/photos/skip", photoHandler.SkipPhotos)
+	mux.HandleFunc("/api/photos/skip/undo", photoHandler.UndoSkip)
+	mux.HandleFunc("/api/photos/bulk-title", photoHandler.BulkTitle)
+	mux.HandleFunc("/api/photos/shift-taken-at", photoHandler.ShiftTakenAt)
+	mux.HandleFunc("/api/photos/duplicates", queryLimiter.Limit(photoHandler.GetDuplicates))
+	mux.HandleFunc("/api/photos/search", queryLimiter.Limit(photoHandler.SearchPhotos))
+	mux.HandleFunc("/api/photos/changes", changesHandler.GetChanges)
 	mux.HandleFunc("/api/photos/", func(w http.ResponseWriter, r *http.Request) {
-		if strings.HasSuffix(r.URL.Path, "/generate-title") && r.Method == http.MethodPost {
+		if strings.HasSuffix(r.URL.Path, "/generate-title/stream") && r.Method == http.MethodGet {
+			photoHandler.StreamAITitle(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/generate-title") && r.Method == http.MethodPost {
 			photoHandler.GenerateAITitle(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/generate-tags") && r.Method == http.MethodPost {
+			photoHandler.GenerateAITags(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/generate-alt-text") && r.Method == http.MethodPost {
+			photoHandler.GenerateAltText(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/approve-title") && r.Method == http.MethodPost {
+			photoHandler.ApproveTitle(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/revert") && r.Method == http.MethodPost {
+			photoHandler.RevertPhoto(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/geocode") && r.Method == http.MethodPost {
+			photoHandler.GeocodePhoto(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/lock") && r.Method == http.MethodPost {
+			photoHandler.LockPhoto(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/lock") && r.Method == http.MethodDelete {
+			photoHandler.UnlockPhoto(w, r)
 		} else if r.Method == http.MethodPut {
 			photoHandler.UpdatePhoto(w, r)
+		} else if r.Method == http.MethodDelete {
+			photoHandler.DeletePhoto(w, r)
 		} else {
 			photoHandler.GetPhotoByID(w, r)
 		}
 	})
+	mux.HandleFunc(handlers.JobsAPIPrefix, jobHandler.ListJobs)
+	mux.HandleFunc(handlers.JobsAPIPrefix+"/generate-titles", jobHandler.GenerateTitles)
+	mux.HandleFunc(handlers.JobsAPIPrefix+"/compute-hashes", jobHandler.ComputeHashes)
+	mux.HandleFunc(handlers.JobsAPIPrefix+"/check-media", jobHandler.CheckMedia)
+	mux.HandleFunc(handlers.JobsAPIPrefix+"/", jobHandler.HandleJob)
 	mux.HandleFunc("/api/albums", albumHandler.GetAlbums)
-	mux.HandleFunc("/api/albums/withphotocounts", albumHandler.GetAlbumsWithPhotoCounts)
-
-	// Health check
+	mux.HandleFunc("/api/albums/withphotocounts", queryLimiter.Limit(albumHandler.GetAlbumsWithPhotoCounts))
+	mux.HandleFunc("/api/albums/settings", albumHandler.GetAllAlbumSettings)
+	mux.HandleFunc("/api/albums/", albumHandler.HandleAlbumSettings)
+
+	mux.HandleFunc("/api/provenance", provenanceHandler.GetProvenance)
+	mux.HandleFunc("/api/me", meHandler.HandleMe)
+	mux.HandleFunc("/api/features", featuresHandler.GetFeatures)
+	mux.HandleFunc("/api/export/decisions.jsonl", exportHandler.ExportDecisions)
+	mux.HandleFunc("/api/progress.json", queryLimiter.Limit(progressHandler.GetProgressJSON))
+	mux.HandleFunc("/api/badge.svg", queryLimiter.Limit(progressHandler.GetBadgeSVG))
+	mux.HandleFunc("/api/widget", queryLimiter.Limit(progressHandler.GetWidget))
+	mux.HandleFunc("/api/metrics", queryLimiter.Limit(metricsHandler.GetMetrics))
+
+	// Admin routes
+	mux.HandleFunc("/api/ai/status", aiHandler.GetStatus)
+	mux.HandleFunc("/api/ai/models", aiHandler.ListModels)
+	mux.HandleFunc("/api/ai/usage", aiHandler.GetUsage)
+	mux.HandleFunc("/api/health/ai", aiHandler.CheckHealth)
+	mux.HandleFunc("/api/admin/db", adminHandler.GetDBStats)
+	mux.HandleFunc("/api/admin/db/reconnect", adminHandler.ReconnectDB)
+	mux.HandleFunc("/api/admin/db/slow", adminHandler.GetSlowQueries)
+	mux.HandleFunc("/api/admin/ai/reload", aiHandler.ReloadBackend)
+	mux.HandleFunc("/api/admin/reconcile", reconcileHandler.HandleReconcile)
+	mux.HandleFunc("/api/admin/maintenance", maintenanceHandler.HandleMaintenance)
+	mux.HandleFunc("/api/admin/summary", summaryHandler.HandleSummary)
+	mux.HandleFunc(handlers.TokensAPIPrefix, tokenHandler.HandleTokens)
+	mux.HandleFunc(handlers.TokensAPIPrefix+"/", tokenHandler.HandleTokens)
+	mux.HandleFunc(handlers.SessionsAPIPath, sessionHandler.HandleSession)
+	mux.HandleFunc(handlers.SessionsAdminAPIPrefix, sessionHandler.HandleSessions)
+	mux.HandleFunc(handlers.SessionsAdminAPIPrefix+"/", sessionHandler.HandleSessions)
+
+	// Health check. A Lychee media failure is reported as degraded rather
+	// than unhealthy, since restarting this server won't fix it. The media
+	// probe's cached result is reported, refreshed in the background, so
+	// frequent container health checks don't each fetch from Lychee.
 	mux.HandleFunc("/api/health", func(w http.ResponseWriter, r *http.Request) {
 		if err := database.Health(); err != nil {
-			http.Error(w, "Database unhealthy", http.StatusServiceUnavailable)
+			handlers.ServiceUnavailable(w, "Database unhealthy")
 			return
 		}
+		health := map[string]string{"status": "ok", "media": "ok"}
+		if checked, err := media.Cached(); checked.IsZero() {
+			health["media"] = "unknown"
+		} else if err != nil {
+			health["status"] = "degraded"
+			health["media"] = err.Error()
+		}
 		w.Header().Set("Content-Type", "application/json")
-		_, _ = w.Write([]byte(`{"status":"ok"}`))
+		_ = json.NewEncoder(w).Encode(health)
 	})
 
 	// Serve frontend static files from embedded filesystem
@@ -133,6 +496,11 @@ func main() {
 		log.Fatalf("Failed to create dist sub filesystem: %v", err)
 	}
 
+	// In demo mode, serve the sample library's images as Lychee would
+	if *demoMode {
+		mux.Handle("/uploads/", http.StripPrefix("/uploads/", http.FileServer(http.Dir(cfg.LycheeUploadsPath))))
+	}
+
 	mux.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// For SPA, serve index.html for any non-API route that doesn't exist
 		if strings.HasPrefix(r.URL.Path, "/api/") {
@@ -151,8 +519,9 @@ func main() {
 		http.FileServer(http.FS(distFS)).ServeHTTP(w, r)
 	}))
 
-	// Add CORS middleware
-	handler := corsMiddleware(mux, cfg.Server.CORS.AllowedOrigins)
+	// Add auth, CORS and security header middleware, and serve the
+	// enveloped API under /api/v1
+	handler := securityHeadersMiddleware(corsMiddleware(handlers.APIVersionMiddleware(handlers.ForwardAuthMiddleware(handlers.AuthMiddleware(mux, tokenStore, sessionStore, forwardAuth), forwardAuth)), cfg.Server.CORS.AllowedOrigins), cfg)
 
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
@@ -176,6 +545,16 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopReconcile()
+	stopMaintenance()
+	stopMetrics()
+	stopSummary()
+	stopJobs()
+	stopPlaceholders()
+	// Hashes and placeholders computed in the background are saved lazily
+	if err := sidecarStore.Flush(); err != nil {
+		log.Printf("Failed to save sidecar store: %v", err)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -203,16 +582,11 @@ func corsMiddleware(next http.Handler, allowedOrigins []string) http.Handler {
 		if allowed {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 			// Only allow methods actually used by the application
-			w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, POST, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, POST, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 			w.Header().Set("Access-Control-Max-Age", "86400") // Cache preflight for 24 hours
 		}
 
-		// Add security headers
-		w.Header().Set("X-Content-Type-Options", "nosniff")
-		w.Header().Set("X-Frame-Options", "DENY")
-		w.Header().Set("X-XSS-Protection", "1; mode=block")
-
 		if r.Method == "OPTIONS" {
 			if allowed {
 				w.WriteHeader(http.StatusOK)
@@ -225,3 +599,43 @@ func corsMiddleware(next http.Handler, allowedOrigins []string) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+func securityHeadersMiddleware(next http.Handler, cfg *config.Config) http.Handler {
+	cspHeader := "Content-Security-Policy"
+	if cfg.Server.CSP.ReportOnly {
+		cspHeader = "Content-Security-Policy-Report-Only"
+	}
+	csp := cfg.ContentSecurityPolicy()
+
+	// X-Frame-Options cannot express an allowlist, so it is only sent when
+	// framing is denied outright; otherwise CSP frame-ancestors governs
+	denyFraming := len(cfg.Server.FrameAncestors) == 0
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(cspHeader, csp)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Referrer-Policy", "same-origin")
+		if denyFraming {
+			w.Header().Set("X-Frame-Options", "DENY")
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkModelInstalled warns if the AI backend's server doesn't offer the
+// configured model, so a typo in its name shows up at startup rather than
+// as failed title generation
+func checkModelInstalled(lister ai.ModelLister, backend string) {
+	ctx, cancel := context.WithTimeout(context.Background(), constants.DefaultHTTPTimeout)
+	defer cancel()
+
+	list, err := lister.ListModels(ctx)
+	if err != nil {
+		log.Printf("Warning: could not list %s models: %v", backend, err)
+		return
+	}
+	if !list.Installed {
+		log.Printf("Warning: model %q is not available on the %s server (%d models available; see /api/ai/models)", list.Configured, backend, len(list.Models))
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/ai"
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+	"github.com/cdzombak/lychee-meta-tool/backend/db"
+)
+
+// mediaProbeBytes is how much of the sample image is read to check its
+// file signature
+const mediaProbeBytes = 512
+
+// mediaProbe checks that lychee_base_url (via image_url_template) actually
+// serves the library's images by fetching a sample thumbnail. A typo in the
+// base URL otherwise only shows up as broken images and failed AI generation.
+type mediaProbe struct {
+	db              *db.DB
+	imageURLPattern string
+
+	// probing serializes probes; mu guards the last result, and is never
+	// held while probing so that Cached doesn't wait on one
+	probing    sync.Mutex
+	mu         sync.Mutex
+	checked    time.Time
+	err        error
+	refreshing bool
+}
+
+func newMediaProbe(database *db.DB, imageURLPattern string) *mediaProbe {
+	return &mediaProbe{db: database, imageURLPattern: imageURLPattern}
+}
+
+// Check returns the result of the last probe, probing again if it is older
+// than constants.MediaProbeInterval
+func (p *mediaProbe) Check(ctx context.Context) error {
+	p.probing.Lock()
+	defer p.probing.Unlock()
+
+	if checked, err := p.last(); p.fresh(checked) {
+		return err
+	}
+	err := p.probe(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.checked = time.Now()
+	p.err = err
+	return err
+}
+
+// Cached returns the result of the last probe and when it finished, zero
+// if none has, without waiting for a probe. If the result is older than
+// constants.MediaProbeInterval, a new probe is started in the background,
+// so that frequent health checks neither fetch media each time nor wait
+// for a slow Lychee.
+func (p *mediaProbe) Cached() (time.Time, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.fresh(p.checked) && !p.refreshing {
+		p.refreshing = true
+		go func() {
+			_ = p.Check(context.Background())
+			p.mu.Lock()
+			p.refreshing = false
+			p.mu.Unlock()
+		}()
+	}
+	return p.checked, p.err
+}
+
+// last returns the result of the last probe and when it finished
+func (p *mediaProbe) last() (time.Time, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.checked, p.err
+}
+
+// fresh reports whether a probe that finished at checked is recent enough
+// to reuse
+func (p *mediaProbe) fresh(checked time.Time) bool {
+	return !checked.IsZero() && time.Since(checked) < constants.MediaProbeInterval
+}
+
+// probe fetches the newest photo's thumbnail and confirms that the response
+// is an image. A library without photos passes.
+func (p *mediaProbe) probe(ctx context.Context) error {
+	photo, err := p.db.GetSamplePhoto()
+	if err != nil {
+		return err
+	}
+	if photo == nil {
+		return nil
+	}
+
+	imageURL := photo.ToPhotoResponse(p.imageURLPattern).ThumbnailURL
+	ctx, cancel := context.WithTimeout(ctx, constants.MediaProbeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+	if err != nil {
+		return fmt.Errorf("invalid thumbnail URL %s: %w", imageURL, err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", mediaProbeBytes-1))
+
+	client := http.Client{Transport: ai.Transport}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch thumbnail %s: %w", imageURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("thumbnail %s returned HTTP %d", imageURL, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, mediaProbeBytes))
+	if err != nil {
+		return fmt.Errorf("failed to read thumbnail %s: %w", imageURL, err)
+	}
+	if ai.DetectImageType(data) == "" {
+		return fmt.Errorf("thumbnail %s is not an image (Content-Type %q)", imageURL, resp.Header.Get("Content-Type"))
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/cdzombak/lychee-meta-tool/backend/ai"
+	"github.com/cdzombak/lychee-meta-tool/backend/config"
+	"github.com/cdzombak/lychee-meta-tool/backend/constants"
+)
+
+// storageFetchers builds a Fetcher for each configured storage disk, with
+// lychee_uploads_path as shorthand for a local default disk
+func storageFetchers(cfg *config.Config) (map[string]ai.Fetcher, error) {
+	fetchers := make(map[string]ai.Fetcher, len(cfg.Storage.Disks)+1)
+	if cfg.LycheeUploadsPath != "" {
+		fetcher, err := ai.NewLocalFetcher(cfg.LycheeUploadsPath)
+		if err != nil {
+			return nil, fmt.Errorf("lychee_uploads_path: %w", err)
+		}
+		fetchers[constants.DefaultStorageDisk] = fetcher
+	}
+
+	for name, disk := range cfg.Storage.Disks {
+		var fetcher ai.Fetcher
+		var err error
+		switch disk.Type {
+		case "http":
+			fetcher = ai.NewHTTPFetcher(disk.Headers)
+		case "local":
+			fetcher, err = ai.NewLocalFetcher(disk.Path)
+		case "s3":
+			fetcher, err = ai.NewS3Fetcher(ai.S3Options{
+				Bucket:          disk.Bucket,
+				Region:          disk.Region,
+				Endpoint:        disk.Endpoint,
+				Prefix:          disk.Prefix,
+				AccessKeyID:     disk.AccessKeyID,
+				SecretAccessKey: disk.SecretAccessKey,
+				SessionToken:    disk.SessionToken,
+			})
+		case "lychee":
+			lycheeURL := disk.URL
+			if lycheeURL == "" {
+				lycheeURL = cfg.LycheeBaseURL
+			}
+			fetcher, err = ai.NewLycheeFetcher(lycheeURL, disk.APIToken)
+		default:
+			err = fmt.Errorf("unknown type %q", disk.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("storage disk %q: %w", name, err)
+		}
+		fetchers[name] = fetcher
+	}
+
+	return fetchers, nil
+}